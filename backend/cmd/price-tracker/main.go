@@ -2,22 +2,48 @@ package main
 
 import (
 	"backend/internal/config"
+	"backend/internal/constant"
 	"backend/internal/db"
 	"backend/price-tracker/controllers"
+	"backend/price-tracker/models"
 	"backend/price-tracker/repositories"
 	"backend/price-tracker/services"
+	"backend/price-tracker/services/backfill"
+	"backend/price-tracker/services/bus"
+	"backend/price-tracker/services/mail"
+	"backend/price-tracker/services/reconnect"
+	"backend/price-tracker/services/stream"
+	"backend/price-tracker/services/symbols"
 	ws2 "backend/price-tracker/services/ws"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/uptrace/bun"
 	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	// Stopped by SIGTERM from the orchestrator, or by Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Config
 	cache := sync.Map{}
 
-	var sqliteCfg config.SqliteConfig
-	err := config.GetConfig(&sqliteCfg)
+	var databaseCfg config.DatabaseConfig
+	err := config.GetConfig(&databaseCfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -28,36 +54,352 @@ func main() {
 		log.Fatal(err)
 	}
 
+	var indexPriceCfg config.IndexPriceConfig
+	err = config.GetConfig(&indexPriceCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var freshnessCfg config.FreshnessConfig
+	err = config.GetConfig(&freshnessCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var streamCfg config.StreamConfig
+	err = config.GetConfig(&streamCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var alertCfg config.AlertConfig
+	err = config.GetConfig(&alertCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var webhookCfg config.WebhookConfig
+	err = config.GetConfig(&webhookCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var anomalyCfg config.AnomalyConfig
+	err = config.GetConfig(&anomalyCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var emailCfg config.EmailConfig
+	err = config.GetConfig(&emailCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Repository
-	sqliteDB, err := db.NewSqliteDB(sqliteCfg)
+	repository, database, err := newRepository(databaseCfg.DatabaseDriver)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The bulk writes come from several goroutines, those finding the database busy are retried
+	repository = repositories.NewBusyRetryRepository(repository, cfg)
+
+	// Every worker runs until the shutdown, which then waits for them to return
+	var workers sync.WaitGroup
+	run := func(worker func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker(ctx)
+		}()
+	}
+
+	// Markets metadata, symbols are split by quote asset suffix until it is loaded
+	binanceSymbols := symbols.NewBinanceSymbolRegistry(cfg.BinanceAPIURL, cfg.QuoteAssets)
+	err = binanceSymbols.Load(ctx)
 	if err != nil {
-		log.Fatalf("connecting to sqlite database: %v", err)
+		slog.Error("load binance exchange info", "err", err)
 	}
-	repository := repositories.NewSqliteRepository(sqliteDB)
+	run(controllers.NewSymbolWorker(binanceSymbols, cfg.SymbolReloadInterval).Run)
+
+	// Backfill of the history missed before the workers start
+	backfiller := backfill.NewBinanceBackfiller(
+		cfg.BinanceAPIURL, cfg.BackfillSymbols, cfg.BackfillLookback, cfg.BackfillMaxWeight, binanceSymbols, repository)
+	run(func(ctx context.Context) {
+		err := backfiller.Backfill(ctx)
+		if err != nil {
+			slog.Error("backfill binance klines", "err", err)
+		}
+	})
+
+	// Event bus, the workers publish what they receive and every consumer subscribes to it
+	priceCache := services.NewPriceCache(&cache)
+	priceWriter := services.NewPriceWriter(repository, cfg)
+	broadcaster := stream.NewPriceBroadcaster()
+	hub := stream.NewHub(streamCfg.StreamMaxSubscriptions)
 
-	// Worker
-	ws := ws2.NewBinanceWebsocket(&cache, cfg.BinanceWebSocketURL, cfg.WsFetchInterval, repository)
-	worker := controllers.NewPriceTrackingWorker(ws)
+	priceTicks := bus.New[models.PriceTick]("prices")
+	priceTicks.Subscribe("cache", cfg.EventQueueSize, priceCache.CachePrices)
+	priceTicks.SubscribeBatch("database", cfg.EventQueueSize, cfg.WriteBatchMaxTicks, cfg.WriteBatchWait, priceWriter.WritePrices)
+	priceTicks.Subscribe("sse", cfg.EventQueueSize, func(tick models.PriceTick) {
+		broadcaster.Publish(tick.Prices)
+	})
+	priceTicks.Subscribe("websocket", cfg.EventQueueSize, func(tick models.PriceTick) {
+		hub.Publish(tick.Prices)
+	})
 
-	// Run worker to fetch data automatically
-	worker.Run()
+	statsTicks := bus.New[models.MarketStatsTick]("market_stats")
+	statsTicks.Subscribe("cache", cfg.EventQueueSize, priceCache.CacheMarketStats)
+	statsTicks.SubscribeBatch("database", cfg.EventQueueSize, cfg.WriteBatchMaxTicks, cfg.WriteBatchWait, priceWriter.WriteMarketStats)
+
+	// Anomalies, the workers publish their prices through the detection which hands them on to the prices bus,
+	// without those quarantined
+	anomalies := bus.New[models.Anomaly]("anomalies")
+	anomalyService := services.NewAnomalyService(repository, priceTicks, anomalies, anomalyCfg)
+	anomalies.SubscribeBatch("database", cfg.EventQueueSize, cfg.WriteBatchMaxTicks, cfg.WriteBatchWait, anomalyService.SaveAnomalies)
+
+	// Alerts, evaluated on every tick and pushed to the websocket clients subscribed to their market
+	alertEvents := bus.New[models.AlertEvent]("alerts")
+	alertEvents.Subscribe("websocket", cfg.EventQueueSize, func(event models.AlertEvent) {
+		hub.PublishEvent(event.Symbol, event.Currency, models.ClientEvent{
+			Type: models.ClientEventAlert,
+			Data: event,
+		})
+	})
+	// Webhooks, the alerts are added to the outbox the webhook worker posts from
+	webhookService := services.NewWebhookService(repository, webhookCfg)
+	alertEvents.Subscribe("webhooks", cfg.EventQueueSize, func(event models.AlertEvent) {
+		err := webhookService.Enqueue(context.Background(), event.WebhookEvent())
+		if err != nil {
+			slog.Error("enqueue alert webhooks", "rule", event.RuleID, "err", err)
+		}
+	})
+	run(controllers.NewWebhookWorker(webhookService, webhookCfg.WebhookPollInterval).Run)
+	// Email, the alerts of the rules with an address are collected into digests the email worker sends
+	var emailService services.EmailService
+	if emailCfg.EmailSMTPHost != "" {
+		emailService = services.NewEmailService(mail.NewSender(emailCfg), emailCfg)
+		alertEvents.Subscribe("email", cfg.EventQueueSize, emailService.Add)
+		run(controllers.NewEmailWorker(emailService, emailCfg.EmailPollInterval).Run)
+	}
+
+	alertService := services.NewAlertService(repository, alertEvents, alertCfg)
+	err = alertService.Load(ctx)
+	if err != nil {
+		slog.Error("load alert rules", "err", err)
+	}
+	priceTicks.Subscribe("alerts", cfg.EventQueueSize, alertService.Evaluate)
+
+	// Workers, one per exchange, each reconnecting behind its own circuit breaker
+	var priceTrackingWorkers []controllers.PriceTrackingWorker
+	for _, exchange := range cfg.Exchanges {
+		ws, err := newWebSocketFetcher(exchange, cfg, binanceSymbols, anomalyService, statsTicks)
+		if err != nil {
+			log.Fatal(err)
+		}
+		worker := controllers.NewPriceTrackingWorker(exchange, ws, reconnect.NewBreaker(exchange, cfg), cfg)
+		priceTrackingWorkers = append(priceTrackingWorkers, worker)
+
+		// Run worker to fetch data automatically
+		run(worker.Run)
+	}
+
+	// Index price computed across exchanges, published through the anomaly detection like their prices
+	indexPriceService := services.NewIndexPriceService(&cache, anomalyService, indexPriceCfg)
+	run(controllers.NewIndexPriceWorker(indexPriceService, indexPriceCfg.IndexInterval).Run)
+
+	// Gaps left by outages, repaired from the klines
+	gapService := services.NewGapService(repository, backfiller, binanceSymbols, cfg)
+	run(controllers.NewGapWorker(gapService, cfg.GapScanInterval).Run)
+
+	// Retention of raw prices and candles
+	retentionService := services.NewRetentionService(repository, cfg)
+	run(controllers.NewRetentionWorker(retentionService, cfg.RetentionInterval).Run)
 
 	// Service
-	priceTrackingService := services.NewPriceTrackingService(&cache, repository)
+	priceTrackingService := services.NewPriceTrackingService(&cache, repository, cfg.BinanceAPIURL,
+		reconnect.NewBreaker("binance_api", cfg), freshnessCfg)
+	conversionService := services.NewConversionService(&cache)
+	priceStreamService := services.NewPriceStreamService(broadcaster, repository, streamCfg)
 
 	// Controller
 	controller := controllers.NewPriceTrackerController(priceTrackingService)
+	conversionController := controllers.NewConversionController(conversionService)
+	gapController := controllers.NewGapController(gapService)
+	priceStreamController := controllers.NewPriceStreamController(priceTrackingService, priceStreamService, streamCfg)
+	clientWebsocketController := controllers.NewClientWebsocketController(priceTrackingService, hub, streamCfg)
+	anomalyController := controllers.NewAnomalyController(anomalyService, anomalyCfg)
+	alertController := controllers.NewAlertController(alertService, alertCfg)
+	webhookController := controllers.NewWebhookController(webhookService, webhookCfg)
+	eventBusController := controllers.NewEventBusController(priceTicks, statsTicks, anomalies, alertEvents)
+	healthController := controllers.NewHealthController(priceTrackingWorkers...)
 
 	// Router
 	r := gin.Default()
 
+	r.GET("/health", healthController.GetHealth)
 	r.GET("/list/name", controller.GetCryptoList)
+	r.GET("/convert", conversionController.Convert)
+	r.GET("/anomalies", anomalyController.GetAnomalies)
+	r.GET("/ws", controllers.EndOnShutdown(ctx), clientWebsocketController.Serve)
 
 	price := r.Group("/price/")
 	{
 		price.GET("/latest", controller.GetLatestPrice)
 		price.GET("/interval", controller.GetPriceHistory)
+		price.GET("/candles", controller.GetCandles)
+		price.GET("/stream", controllers.EndOnShutdown(ctx), priceStreamController.StreamPrices)
+	}
+
+	alerts := r.Group("/alerts/")
+	{
+		alerts.GET("/rules", alertController.GetRules)
+		alerts.POST("/rules", alertController.CreateRule)
+		alerts.GET("/rules/:id", alertController.GetRule)
+		alerts.PUT("/rules/:id", alertController.UpdateRule)
+		alerts.DELETE("/rules/:id", alertController.DeleteRule)
+		alerts.GET("/history", alertController.GetHistory)
+	}
+
+	webhooks := r.Group("/webhooks")
+	{
+		webhooks.GET("", webhookController.GetWebhooks)
+		webhooks.POST("", webhookController.CreateWebhook)
+		webhooks.GET("/:id", webhookController.GetWebhook)
+		webhooks.PUT("/:id", webhookController.UpdateWebhook)
+		webhooks.DELETE("/:id", webhookController.DeleteWebhook)
+		webhooks.GET("/deliveries", webhookController.GetDeliveries)
+		webhooks.POST("/deliveries/:id/retry", webhookController.RetryDelivery)
+	}
+
+	admin := r.Group("/admin/")
+	{
+		admin.GET("/gaps", gapController.GetGaps)
+		admin.GET("/events", eventBusController.GetStats)
 	}
 
-	r.Run()
+	server := &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+	}
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// The streams ended with ctx, the other requests in flight are completed
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		slog.Error("shut down http server", "err", err)
+	}
+
+	// The workers close their exchange websockets, then the buses hand the queued ticks
+	// to their subscribers and the database writer writes its last batches, then the alerts they fired are pushed
+	// and the emails collected are sent
+	err = waitUntil(shutdownCtx, func() {
+		workers.Wait()
+		priceTicks.Close()
+		statsTicks.Close()
+		anomalies.Close()
+		alertEvents.Close()
+		if emailService != nil {
+			err := emailService.Flush(shutdownCtx)
+			if err != nil {
+				slog.Error("flush emails", "err", err)
+			}
+		}
+	})
+	if err != nil {
+		slog.Error("drain event buses", "err", err)
+	}
+
+	err = database.Close()
+	if err != nil {
+		slog.Error("close database", "err", err)
+	}
+
+	slog.Info("shut down")
+}
+
+// waitUntil runs f, or returns the error of ctx if it is done first
+func waitUntil(ctx context.Context, f func()) error {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newRepository returns the repository of the database driver selected in config
+// along with its database, to close on shutdown
+func newRepository(driver string) (repositories.Repository, *bun.DB, error) {
+	switch driver {
+	case constant.DriverSqlite:
+		var sqliteCfg config.SqliteConfig
+		err := config.GetConfig(&sqliteCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sqliteDB, err := db.NewSqliteDB(sqliteCfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting to sqlite database: %w", err)
+		}
+
+		return repositories.NewSqliteRepository(sqliteDB), sqliteDB, nil
+	case constant.DriverPostgres:
+		var postgresCfg config.PostgresConfig
+		err := config.GetConfig(&postgresCfg)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		postgresDB, err := db.NewPostgresDB(postgresCfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting to postgres database: %w", err)
+		}
+
+		return repositories.NewPostgresRepository(postgresDB, postgresCfg.PostgresTimescale), postgresDB, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported database driver '%s'", driver)
+	}
+}
+
+// newWebSocketFetcher returns the fetcher of an exchange listed in config
+func newWebSocketFetcher(
+	exchange string,
+	cfg config.PriceTrackerConfig,
+	binanceSymbols symbols.SymbolRegistry,
+	prices bus.Publisher[models.PriceTick],
+	stats bus.Publisher[models.MarketStatsTick],
+) (ws2.WebSocketFetcher, error) {
+	switch exchange {
+	case constant.SourceBinance:
+		return ws2.NewBinanceWebsocket(
+			cfg.BinanceWebSocketURL, cfg.BinanceTradeSymbols, cfg.BinanceTickerSymbols, binanceSymbols, prices, stats), nil
+	case constant.SourceCoinbase:
+		return ws2.NewCoinbaseWebsocket(cfg.CoinbaseWebSocketURL, cfg.CoinbaseProducts, prices), nil
+	case constant.SourceKraken:
+		return ws2.NewKrakenWebsocket(cfg.KrakenWebSocketURL, cfg.KrakenPairs, prices), nil
+	case constant.SourceOkx:
+		return ws2.NewOkxWebsocket(cfg.OkxWebSocketURL, cfg.OkxInstruments, prices), nil
+	default:
+		return nil, fmt.Errorf("unsupported exchange '%s'", exchange)
+	}
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/constant"
+	"backend/internal/db"
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+	"log"
+	"os"
+	"text/tabwriter"
+)
+
+const migrateUsage = "usage: price-tracker migrate [up|down|status]"
+
+// runMigrate handles the migrate subcommand:
+// up applies the pending migrations, down rolls back the last applied group, status lists the migrations
+func runMigrate(args []string) {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	var databaseCfg config.DatabaseConfig
+	err := config.GetConfig(&databaseCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bunDB, err := openDB(databaseCfg.DatabaseDriver)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer bunDB.Close()
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		err = db.Migrate(ctx, bunDB)
+	case "down":
+		err = rollback(ctx, db.NewMigrator(bunDB))
+	case "status":
+		err = printStatus(ctx, db.NewMigrator(bunDB))
+	default:
+		err = fmt.Errorf("unknown migrate command '%s', %s", command, migrateUsage)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// openDB connects to the database of the driver selected in config without migrating it
+func openDB(driver string) (*bun.DB, error) {
+	switch driver {
+	case constant.DriverSqlite:
+		var sqliteCfg config.SqliteConfig
+		err := config.GetConfig(&sqliteCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return db.OpenSqliteDB(sqliteCfg)
+	case constant.DriverPostgres:
+		var postgresCfg config.PostgresConfig
+		err := config.GetConfig(&postgresCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return db.OpenPostgresDB(postgresCfg)
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%s'", driver)
+	}
+}
+
+func rollback(ctx context.Context, migrator *migrate.Migrator) error {
+	err := migrator.Init(ctx)
+	if err != nil {
+		return fmt.Errorf("init migrations: %w", err)
+	}
+
+	err = db.Lock(ctx, migrator)
+	if err != nil {
+		return err
+	}
+	defer migrator.Unlock(ctx)
+
+	err = db.CheckSchemaVersion(ctx, migrator)
+	if err != nil {
+		return err
+	}
+
+	group, err := migrator.Rollback(ctx)
+	if err != nil {
+		return fmt.Errorf("rollback: %w", err)
+	}
+
+	if group.IsZero() {
+		fmt.Println("there are no migrations to roll back")
+		return nil
+	}
+	fmt.Printf("rolled back %s\n", group)
+
+	return nil
+}
+
+func printStatus(ctx context.Context, migrator *migrate.Migrator) error {
+	err := migrator.Init(ctx)
+	if err != nil {
+		return fmt.Errorf("init migrations: %w", err)
+	}
+
+	ms, err := migrator.MigrationsWithStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("select migrations: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tGROUP\tMIGRATED AT")
+	for _, m := range ms {
+		migratedAt := "pending"
+		if m.GroupID > 0 {
+			migratedAt = m.MigratedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\n", m.String(), m.GroupID, migratedAt)
+	}
+
+	unknown, err := migrator.MissingMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("select applied migrations: %w", err)
+	}
+	for _, m := range unknown {
+		fmt.Fprintf(w, "%s\t%d\tunknown to this version\n", m.Name, m.GroupID)
+	}
+
+	return w.Flush()
+}
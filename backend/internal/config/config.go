@@ -3,20 +3,293 @@ package config
 import (
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
+	"time"
 )
 
+// validator is implemented by the configs checking their values once read
+type validator interface {
+	Validate() error
+}
+
 func GetConfig(cfg any) error {
 	err := envconfig.Process("", cfg)
 	if err != nil {
 		return fmt.Errorf("read config from env %v", err)
 	}
 
+	if v, ok := cfg.(validator); ok {
+		err = v.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+
 	return nil
 }
 
 type PriceTrackerConfig struct {
-	BinanceWebSocketURL string `envconfig:"BINANCE_WEBSOCKET_URL" required:"true"`
-	WsFetchInterval     int64  `envconfig:"WEBSOCKET_FETCH_INTERVAL" default:"1m"`
+	Port string `envconfig:"PORT" default:"8080"`
+	// ShutdownTimeout bounds the time to finish the requests in flight and write the queued ticks on SIGTERM
+	ShutdownTimeout time.Duration `envconfig:"SHUTDOWN_TIMEOUT" default:"20s"`
+
+	// Exchanges lists the sources to start a worker for
+	Exchanges []string `envconfig:"EXCHANGES" default:"binance"`
+	// QuoteAssets lists the currencies whose markets are stored
+	QuoteAssets []string `envconfig:"QUOTE_ASSETS" default:"USDT"`
+	// SymbolReloadInterval is how often the markets metadata is loaded again, retrying a failed load
+	// and picking up the markets listed since
+	SymbolReloadInterval time.Duration `envconfig:"SYMBOL_RELOAD_INTERVAL" default:"5m"`
+
+	BinanceAPIURL       string   `envconfig:"BINANCE_API_URL" default:"https://api.binance.com"`
+	BinanceWebSocketURL string   `envconfig:"BINANCE_WEBSOCKET_URL" required:"true"`
+	BinanceTradeSymbols []string `envconfig:"BINANCE_TRADE_SYMBOLS" default:"BTCUSDT,ETHUSDT"`
+	// BinanceTickerSymbols lists the symbols whose bid, ask and 24h stats are tracked
+	BinanceTickerSymbols []string `envconfig:"BINANCE_TICKER_SYMBOLS" default:"BTCUSDT,ETHUSDT"`
+
+	// BackfillSymbols lists the Binance symbols whose klines are fetched on startup
+	BackfillSymbols []string `envconfig:"BACKFILL_SYMBOLS" default:"BTCUSDT,ETHUSDT"`
+	// BackfillLookback is how far back the klines are fetched, 0 disables the backfill
+	BackfillLookback time.Duration `envconfig:"BACKFILL_LOOKBACK" default:"24h"`
+	// BackfillMaxWeight is the request weight per minute the backfill stays under,
+	// out of the limit Binance shares with every other request from the same IP
+	BackfillMaxWeight int `envconfig:"BACKFILL_MAX_WEIGHT" default:"1000"`
+
+	// EventQueueSize bounds the events waiting for each subscriber of the event bus,
+	// further events are dropped until the subscriber catches up
+	EventQueueSize int `envconfig:"EVENT_QUEUE_SIZE" default:"1024"`
+
+	// WriteBatchWait is how long the database writer collects ticks after the first one before writing them
+	WriteBatchWait time.Duration `envconfig:"WRITE_BATCH_WAIT" default:"1s"`
+	// WriteBatchMaxTicks writes the collected ticks early once that many are waiting
+	WriteBatchMaxTicks int `envconfig:"WRITE_BATCH_MAX_TICKS" default:"100"`
+	// WriteBatchMaxRows bounds the prices written by a single insert
+	WriteBatchMaxRows int `envconfig:"WRITE_BATCH_MAX_ROWS" default:"2000"`
+	// WriteMaxRetries is the number of times a write is retried while the database is locked
+	WriteMaxRetries int `envconfig:"WRITE_MAX_RETRIES" default:"5"`
+	// WriteRetryBackoff is the wait before the first retry, doubled after each one
+	WriteRetryBackoff time.Duration `envconfig:"WRITE_RETRY_BACKOFF" default:"100ms"`
+
+	// GapScanInterval is how often the stored series are scanned for gaps to repair
+	GapScanInterval time.Duration `envconfig:"GAP_SCAN_INTERVAL" default:"5m"`
+	// GapThreshold is the time without prices that makes a gap, above the interval of the streams
+	GapThreshold time.Duration `envconfig:"GAP_THRESHOLD" default:"2m"`
+	// GapLookback is how far back each scan looks for gaps
+	GapLookback time.Duration `envconfig:"GAP_LOOKBACK" default:"24h"`
+	// GapMaxAttempts is the number of failed repairs after which a gap is given up
+	GapMaxAttempts int `envconfig:"GAP_MAX_ATTEMPTS" default:"3"`
+
+	// ReconnectInitialDelay is the wait after a failed connection to an exchange,
+	// doubled after each consecutive failure up to ReconnectMaxDelay
+	ReconnectInitialDelay time.Duration `envconfig:"RECONNECT_INITIAL_DELAY" default:"1s"`
+	ReconnectMaxDelay     time.Duration `envconfig:"RECONNECT_MAX_DELAY" default:"5m"`
+	// ReconnectJitter is the fraction of each wait drawn at random
+	ReconnectJitter float64 `envconfig:"RECONNECT_JITTER" default:"0.5"`
+	// ReconnectStableAfter is how long a connection must last for its drop not to count as a failure
+	ReconnectStableAfter time.Duration `envconfig:"RECONNECT_STABLE_AFTER" default:"1m"`
+	// BreakerThreshold is the number of consecutive failures opening the circuit of an exchange,
+	// no connection is attempted then for BreakerCooldown at least
+	BreakerThreshold int           `envconfig:"BREAKER_THRESHOLD" default:"5"`
+	BreakerCooldown  time.Duration `envconfig:"BREAKER_COOLDOWN" default:"1m"`
+
+	CoinbaseWebSocketURL string   `envconfig:"COINBASE_WEBSOCKET_URL" default:"wss://ws-feed.exchange.coinbase.com"`
+	CoinbaseProducts     []string `envconfig:"COINBASE_PRODUCTS" default:"BTC-USDT,ETH-USDT"`
+
+	KrakenWebSocketURL string   `envconfig:"KRAKEN_WEBSOCKET_URL" default:"wss://ws.kraken.com/v2"`
+	KrakenPairs        []string `envconfig:"KRAKEN_PAIRS" default:"BTC/USDT,ETH/USDT"`
+
+	OkxWebSocketURL string   `envconfig:"OKX_WEBSOCKET_URL" default:"wss://ws.okx.com:8443/ws/v5/public"`
+	OkxInstruments  []string `envconfig:"OKX_INSTRUMENTS" default:"BTC-USDT,ETH-USDT"`
+
+	// RetentionInterval is how often old data is rolled up and deleted
+	RetentionInterval time.Duration `envconfig:"RETENTION_INTERVAL" default:"1h"`
+	// RawRetention keeps raw prices for this long before they only live in candles
+	RawRetention time.Duration `envconfig:"RAW_RETENTION" default:"168h"`
+	// MinuteCandleRetention keeps the candles shorter than an hour for this long
+	MinuteCandleRetention time.Duration `envconfig:"MINUTE_CANDLE_RETENTION" default:"720h"`
+	// HourlyCandleRetention keeps the candles shorter than a day for this long
+	HourlyCandleRetention time.Duration `envconfig:"HOURLY_CANDLE_RETENTION" default:"8760h"`
+	// DailyCandleRetention keeps the daily candles for this long
+	DailyCandleRetention time.Duration `envconfig:"DAILY_CANDLE_RETENTION" default:"0"`
+	// VacuumInterval is how often the database file is rebuilt to release deleted pages
+	VacuumInterval time.Duration `envconfig:"VACUUM_INTERVAL" default:"24h"`
+}
+
+// Validate checks that the raw prices are deleted before the candles they are rolled up into.
+// A raw price outliving its candles would be rolled up into them again by the archive, counted twice.
+// RECONNECT_JITTER must be a fraction, see validateJitter
+func (c PriceTrackerConfig) Validate() error {
+	err := validateJitter("RECONNECT_JITTER", c.ReconnectJitter)
+	if err != nil {
+		return err
+	}
+
+	for _, tier := range []struct {
+		name      string
+		retention time.Duration
+	}{
+		{"MINUTE_CANDLE_RETENTION", c.MinuteCandleRetention},
+		{"HOURLY_CANDLE_RETENTION", c.HourlyCandleRetention},
+		{"DAILY_CANDLE_RETENTION", c.DailyCandleRetention},
+	} {
+		// A zero retention keeps the data forever
+		if tier.retention > 0 && (c.RawRetention == 0 || c.RawRetention > tier.retention) {
+			return fmt.Errorf("RAW_RETENTION of %s must be set and at most %s of %s", c.RawRetention, tier.name, tier.retention)
+		}
+	}
+
+	return nil
+}
+
+// validateJitter checks that a jitter is a fraction of the wait it is drawn from, over 1 the wait would be negative
+func validateJitter(name string, jitter float64) error {
+	if jitter < 0 || jitter > 1 {
+		return fmt.Errorf("%s of %g must be between 0 and 1", name, jitter)
+	}
+
+	return nil
+}
+
+type IndexPriceConfig struct {
+	// IndexInterval is how often the index price is computed and stored
+	IndexInterval time.Duration `envconfig:"INDEX_INTERVAL" default:"5s"`
+	// IndexMaxAge excludes sources whose latest quote is older than this
+	IndexMaxAge time.Duration `envconfig:"INDEX_MAX_AGE" default:"30s"`
+	// IndexMaxDeviation excludes sources deviating from the median of all sources by more than this ratio
+	IndexMaxDeviation float64 `envconfig:"INDEX_MAX_DEVIATION" default:"0.02"`
+	// IndexMinSources is the number of accepted sources required to publish an index price
+	IndexMinSources int `envconfig:"INDEX_MIN_SOURCES" default:"1"`
+	// IndexSourceWeights weights each source in the median, unlisted sources weigh 1
+	IndexSourceWeights map[string]float64 `envconfig:"INDEX_SOURCE_WEIGHTS" default:"binance:1,coinbase:1,kraken:1,okx:1"`
+}
+
+type FreshnessConfig struct {
+	// PriceStaleAfter flags the latest price as stale once it was received that long ago,
+	// a stale binance price is fetched again from its REST API. 0 never flags it
+	PriceStaleAfter time.Duration `envconfig:"PRICE_STALE_AFTER" default:"1m"`
+	// PriceMaxAge answers 503 rather than a latest price received longer ago than this, 0 always serves it
+	PriceMaxAge time.Duration `envconfig:"PRICE_MAX_AGE" default:"10m"`
+}
+
+type StreamConfig struct {
+	// StreamHeartbeat is how often idle clients are sent a heartbeat, or websocket clients a ping,
+	// so proxies keep their connection open
+	StreamHeartbeat time.Duration `envconfig:"STREAM_HEARTBEAT" default:"15s"`
+	// StreamMaxSymbols bounds the symbols a client streams at once
+	StreamMaxSymbols int `envconfig:"STREAM_MAX_SYMBOLS" default:"20"`
+	// StreamMaxSubscriptions bounds the markets a websocket connection subscribes to
+	StreamMaxSubscriptions int `envconfig:"STREAM_MAX_SUBSCRIPTIONS" default:"50"`
+	// StreamQueueSize bounds the events waiting to be written to a websocket connection,
+	// further events are dropped until it catches up
+	StreamQueueSize int `envconfig:"STREAM_QUEUE_SIZE" default:"256"`
+	// StreamResumeLimit bounds the stored prices replayed to a client resuming after a disconnect
+	StreamResumeLimit int `envconfig:"STREAM_RESUME_LIMIT" default:"1000"`
+}
+
+type AlertConfig struct {
+	// AlertSampleInterval is the spacing of the prices kept for the windows of the rules,
+	// the moving averages are averages of the last price of each interval
+	AlertSampleInterval time.Duration `envconfig:"ALERT_SAMPLE_INTERVAL" default:"10s"`
+	// AlertMaxWindow bounds the window of a rule, and so the prices kept per market
+	AlertMaxWindow time.Duration `envconfig:"ALERT_MAX_WINDOW" default:"24h"`
+	// AlertHistoryLimit is the number of fired alerts returned when the request sets no limit, and at most
+	AlertHistoryLimit int `envconfig:"ALERT_HISTORY_LIMIT" default:"100"`
+	AlertHistoryMax   int `envconfig:"ALERT_HISTORY_MAX" default:"1000"`
+}
+
+type WebhookConfig struct {
+	// WebhookPollInterval is how often the outbox is read for the deliveries that are due
+	WebhookPollInterval time.Duration `envconfig:"WEBHOOK_POLL_INTERVAL" default:"1s"`
+	// WebhookBatchSize bounds the deliveries attempted per poll
+	WebhookBatchSize int `envconfig:"WEBHOOK_BATCH_SIZE" default:"100"`
+	// WebhookLease is how long the deliveries claimed by a poll are hidden from the other replicas,
+	// long enough to post them. A delivery left claimed by a crash is attempted again once it has passed
+	WebhookLease time.Duration `envconfig:"WEBHOOK_LEASE" default:"5m"`
+	// WebhookTimeout bounds each attempt, from the connection to the response
+	WebhookTimeout time.Duration `envconfig:"WEBHOOK_TIMEOUT" default:"10s"`
+	// WebhookMaxAttempts is the number of failed attempts after which a delivery is dead
+	WebhookMaxAttempts int `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"8"`
+	// WebhookRetryInitialDelay is the wait after the first failed attempt, doubled after each one
+	// up to WebhookRetryMaxDelay, with WebhookRetryJitter of it drawn at random
+	WebhookRetryInitialDelay time.Duration `envconfig:"WEBHOOK_RETRY_INITIAL_DELAY" default:"10s"`
+	WebhookRetryMaxDelay     time.Duration `envconfig:"WEBHOOK_RETRY_MAX_DELAY" default:"1h"`
+	WebhookRetryJitter       float64       `envconfig:"WEBHOOK_RETRY_JITTER" default:"0.2"`
+	// WebhookDeliveryLimit is the number of deliveries returned when the request sets no limit, and at most
+	WebhookDeliveryLimit int `envconfig:"WEBHOOK_DELIVERY_LIMIT" default:"100"`
+	WebhookDeliveryMax   int `envconfig:"WEBHOOK_DELIVERY_MAX" default:"1000"`
+}
+
+// Validate checks that WEBHOOK_RETRY_JITTER is a fraction, see validateJitter
+func (c WebhookConfig) Validate() error {
+	return validateJitter("WEBHOOK_RETRY_JITTER", c.WebhookRetryJitter)
+}
+
+type EmailConfig struct {
+	// EmailSMTPHost is the server the alerts are emailed through, no email is sent if it is empty
+	EmailSMTPHost string `envconfig:"EMAIL_SMTP_HOST"`
+	EmailSMTPPort int    `envconfig:"EMAIL_SMTP_PORT" default:"587"`
+	// EmailSMTPUsername and EmailSMTPPassword authenticate with PLAIN if set, which requires TLS but on localhost
+	EmailSMTPUsername string `envconfig:"EMAIL_SMTP_USERNAME"`
+	EmailSMTPPassword string `envconfig:"EMAIL_SMTP_PASSWORD"`
+	// EmailSMTPImplicitTLS connects with TLS from the start, as on port 465,
+	// otherwise the connection is upgraded with STARTTLS if the server offers it
+	EmailSMTPImplicitTLS bool `envconfig:"EMAIL_SMTP_IMPLICIT_TLS" default:"false"`
+	// EmailTimeout bounds the sending of each email, from the connection to the end of the session
+	EmailTimeout time.Duration `envconfig:"EMAIL_TIMEOUT" default:"10s"`
+	EmailFrom    string        `envconfig:"EMAIL_FROM" default:"Price Tracker <price-tracker@localhost>"`
+
+	// EmailDigestWindow is how long the alerts of a recipient are collected after the first one,
+	// then sent together in a single email
+	EmailDigestWindow time.Duration `envconfig:"EMAIL_DIGEST_WINDOW" default:"1m"`
+	// EmailDigestMaxAlerts bounds the alerts listed in an email, which is sent early once that many are collected,
+	// the further ones only being counted
+	EmailDigestMaxAlerts int `envconfig:"EMAIL_DIGEST_MAX_ALERTS" default:"50"`
+	// EmailRateLimit is the number of emails a recipient is sent per EmailRatePeriod at most, 0 for no limit.
+	// The alerts over it are collected into the next email allowed
+	EmailRateLimit  int           `envconfig:"EMAIL_RATE_LIMIT" default:"10"`
+	EmailRatePeriod time.Duration `envconfig:"EMAIL_RATE_PERIOD" default:"1h"`
+	// EmailRetryDelay is the wait before sending again an email that failed
+	EmailRetryDelay time.Duration `envconfig:"EMAIL_RETRY_DELAY" default:"30s"`
+	// EmailMaxAttempts is the number of failed attempts after which an email is dropped, at once if the server
+	// rejected it permanently
+	EmailMaxAttempts int `envconfig:"EMAIL_MAX_ATTEMPTS" default:"5"`
+	// EmailPollInterval is how often the collected alerts are checked for the emails that are due
+	EmailPollInterval time.Duration `envconfig:"EMAIL_POLL_INTERVAL" default:"5s"`
+	// EmailChartURL links each alert to its chart, {symbol}, {currency} and {source} being replaced by its market
+	EmailChartURL string `envconfig:"EMAIL_CHART_URL" default:"http://localhost:3000/?symbol={symbol}&currency={currency}"`
+}
+
+type AnomalyConfig struct {
+	// AnomalyWindow is the number of recent prices of each market the prices received are compared to
+	AnomalyWindow int `envconfig:"ANOMALY_WINDOW" default:"120"`
+	// AnomalyMinSamples is the number of prices a market needs before its prices are checked
+	AnomalyMinSamples int `envconfig:"ANOMALY_MIN_SAMPLES" default:"30"`
+	// AnomalyZScoreThreshold flags a price further from the mean of the window than that many standard deviations
+	AnomalyZScoreThreshold float64 `envconfig:"ANOMALY_ZSCORE_THRESHOLD" default:"6"`
+	// AnomalyJumpThreshold flags a price further from the previous one than that many standard deviations
+	// of the moves between the prices of the window
+	AnomalyJumpThreshold float64 `envconfig:"ANOMALY_JUMP_THRESHOLD" default:"10"`
+	// AnomalyFlatlineAfter flags a market whose price has not changed for that long, 0 disables it
+	AnomalyFlatlineAfter time.Duration `envconfig:"ANOMALY_FLATLINE_AFTER" default:"15m"`
+	// AnomalyResetAfter is the number of flagged prices in a row after which they are taken as the new level
+	// of the market, and the window starts again from them
+	AnomalyResetAfter int `envconfig:"ANOMALY_RESET_AFTER" default:"5"`
+	// AnomalyQuarantine keeps the prices flagged by zscore or jump out of the cache, the streams,
+	// the alerts and the database, they are only recorded as anomalies
+	AnomalyQuarantine bool `envconfig:"ANOMALY_QUARANTINE" default:"false"`
+	// AnomalyLimit is the number of anomalies returned when the request sets no limit, and at most
+	AnomalyLimit int `envconfig:"ANOMALY_LIMIT" default:"100"`
+	AnomalyMax   int `envconfig:"ANOMALY_MAX" default:"1000"`
+}
+
+type DatabaseConfig struct {
+	// DatabaseDriver selects the store, sqlite or postgres
+	DatabaseDriver string `envconfig:"DATABASE_DRIVER" default:"sqlite"`
+}
+
+type PostgresConfig struct {
+	PostgresURL string `envconfig:"POSTGRES_URL" required:"true"`
+	// PostgresTimescale turns the tables into TimescaleDB hypertables and aggregates the prices per minute
+	PostgresTimescale bool `envconfig:"POSTGRES_TIMESCALE" default:"false"`
 }
 
 type SqliteConfig struct {
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceTrackerConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     time.Duration
+		minute  time.Duration
+		hourly  time.Duration
+		daily   time.Duration
+		wantErr bool
+	}{
+		{name: "defaults", raw: 168 * time.Hour, minute: 720 * time.Hour, hourly: 8760 * time.Hour},
+		{name: "raw as long as minute candles", raw: 720 * time.Hour, minute: 720 * time.Hour},
+		{name: "everything kept forever"},
+		{name: "raw outliving minute candles", raw: 800 * time.Hour, minute: 720 * time.Hour, wantErr: true},
+		{name: "raw outliving daily candles", raw: 168 * time.Hour, daily: 24 * time.Hour, wantErr: true},
+		{name: "raw kept forever", minute: 720 * time.Hour, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := PriceTrackerConfig{
+				RawRetention:          tt.raw,
+				MinuteCandleRetention: tt.minute,
+				HourlyCandleRetention: tt.hourly,
+				DailyCandleRetention:  tt.daily,
+			}
+
+			// Act
+			err := cfg.Validate()
+
+			// Assert
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestValidateJitter(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     validator
+		wantErr bool
+	}{
+		{name: "reconnect default", cfg: PriceTrackerConfig{ReconnectJitter: 0.5}},
+		{name: "reconnect without jitter", cfg: PriceTrackerConfig{}},
+		{name: "reconnect over 1", cfg: PriceTrackerConfig{ReconnectJitter: 1.5}, wantErr: true},
+		{name: "webhook whole wait", cfg: WebhookConfig{WebhookRetryJitter: 1}},
+		{name: "webhook negative", cfg: WebhookConfig{WebhookRetryJitter: -0.1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := tt.cfg.Validate()
+
+			// Assert
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
@@ -3,3 +3,20 @@ package constant
 const (
 	USDT = "USDT"
 )
+
+// Sources of price data
+const (
+	SourceBinance  = "binance"
+	SourceCoinbase = "coinbase"
+	SourceKraken   = "kraken"
+	SourceOkx      = "okx"
+
+	// SourceIndex is the composite price computed across the other sources
+	SourceIndex = "index"
+)
+
+// Database drivers
+const (
+	DriverSqlite   = "sqlite"
+	DriverPostgres = "postgres"
+)
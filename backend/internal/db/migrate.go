@@ -0,0 +1,100 @@
+package db
+
+import (
+	"backend/internal/db/migrations"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+	"log/slog"
+	"time"
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer version of the application
+var ErrSchemaTooNew = errors.New("database schema is newer than this version")
+
+var (
+	// lockPollInterval is the wait between the attempts to take the lock of the migrations
+	lockPollInterval = time.Second
+	// lockTimeout bounds the wait for the lock, a replica that died while migrating leaves it held
+	// until its row is deleted from schema_migration_locks
+	lockTimeout = 2 * time.Minute
+)
+
+// NewMigrator returns the migrator of the schema, applied migrations are recorded in schema_migrations
+func NewMigrator(db *bun.DB) *migrate.Migrator {
+	return migrate.NewMigrator(db, migrations.Migrations,
+		migrate.WithTableName("schema_migrations"),
+		migrate.WithLocksTableName("schema_migration_locks"),
+		migrate.WithMarkAppliedOnSuccess(true),
+	)
+}
+
+// Migrate applies the pending migrations, it refuses to run against a newer schema
+func Migrate(ctx context.Context, db *bun.DB) error {
+	migrator := NewMigrator(db)
+
+	err := migrator.Init(ctx)
+	if err != nil {
+		return fmt.Errorf("init migrations: %w", err)
+	}
+
+	err = Lock(ctx, migrator)
+	if err != nil {
+		return err
+	}
+	defer migrator.Unlock(ctx)
+
+	// Checked under the lock, as the replica that held it may have migrated to a newer schema
+	err = CheckSchemaVersion(ctx, migrator)
+	if err != nil {
+		return err
+	}
+
+	group, err := migrator.Migrate(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if !group.IsZero() {
+		slog.Info("database migrated", "group", group.ID, "migrations", group.Migrations.String())
+	}
+
+	return nil
+}
+
+// Lock takes the lock of the migrations, waiting while another replica holds it up to lockTimeout
+func Lock(ctx context.Context, migrator *migrate.Migrator) error {
+	ctx, cancel := context.WithTimeout(ctx, lockTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := migrator.Lock(ctx)
+		if err == nil {
+			return nil
+		}
+
+		slog.Info("waiting for the lock of the migrations", "err", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("lock migrations: %w", errors.Join(ctx.Err(), err))
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckSchemaVersion returns ErrSchemaTooNew if the database has migrations this version does not know
+func CheckSchemaVersion(ctx context.Context, migrator *migrate.Migrator) error {
+	unknown, err := migrator.MissingMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("select applied migrations: %w", err)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: unknown migrations %s", ErrSchemaTooNew, unknown.String())
+	}
+
+	return nil
+}
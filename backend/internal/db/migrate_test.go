@@ -0,0 +1,163 @@
+package db
+
+import (
+	"backend/internal/config"
+	"backend/internal/db/migrations"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/migrate"
+)
+
+type MigrateTestSuite struct {
+	suite.Suite
+	db *bun.DB
+}
+
+func (s *MigrateTestSuite) SetupTest() {
+	db, err := OpenSqliteDB(config.SqliteConfig{
+		SqliteURL: filepath.Join(s.T().TempDir(), "price.db"),
+	})
+	s.Require().NoError(err)
+	s.db = db
+}
+
+func (s *MigrateTestSuite) TearDownTest() {
+	err := s.db.Close()
+	assert.NoError(s.T(), err)
+}
+
+func TestMigrateSuite(t *testing.T) {
+	suite.Run(t, new(MigrateTestSuite))
+}
+
+func (s *MigrateTestSuite) tableExists(table string) bool {
+	var count int
+	err := s.db.NewRaw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).
+		Scan(context.Background(), &count)
+	s.Require().NoError(err)
+	return count > 0
+}
+
+func (s *MigrateTestSuite) TestMigrate_UpAndDown() {
+	ctx := context.Background()
+
+	// Act
+	err := Migrate(ctx, s.db)
+	s.Require().NoError(err)
+
+	// Assert
+	for _, table := range []string{"crypto_price", "crypto_candle", "data_gaps", "alert_rules", "alert_events", "webhooks", "webhook_deliveries", "anomalies", "schema_migrations"} {
+		assert.True(s.T(), s.tableExists(table), table)
+	}
+
+	// Migrating again is a no-op
+	err = Migrate(ctx, s.db)
+	assert.NoError(s.T(), err)
+
+	// Act
+	group, err := NewMigrator(s.db).Rollback(ctx)
+	s.Require().NoError(err)
+
+	// Assert
+	assert.Len(s.T(), group.Migrations, len(migrations.Migrations.Sorted()))
+	for _, table := range []string{"crypto_price", "crypto_candle", "data_gaps", "alert_rules", "alert_events", "webhooks", "webhook_deliveries", "anomalies"} {
+		assert.False(s.T(), s.tableExists(table), table)
+	}
+}
+
+func (s *MigrateTestSuite) TestMigrate_KeepsLegacyData() {
+	ctx := context.Background()
+
+	// Arrange, the schema created before source was tracked
+	_, err := s.db.ExecContext(ctx,
+		"CREATE TABLE crypto_price (timestamp TIMESTAMP, symbol VARCHAR, currency VARCHAR, price FLOAT)")
+	s.Require().NoError(err)
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_, err = s.db.ExecContext(ctx,
+		"INSERT INTO crypto_price (timestamp, symbol, currency, price) VALUES (?, 'BTC', 'USDT', 60000.25)", timestamp)
+	s.Require().NoError(err)
+
+	// Act
+	err = Migrate(ctx, s.db)
+	s.Require().NoError(err)
+
+	// Assert
+	var rows []struct {
+		Symbol    string `bun:"symbol"`
+		Source    string `bun:"source"`
+		Price     string `bun:"price"`
+		PriceType string `bun:"price_type"`
+	}
+	err = s.db.NewRaw("SELECT symbol, source, price, typeof(price) AS price_type FROM crypto_price").Scan(ctx, &rows)
+	s.Require().NoError(err)
+	s.Require().Len(rows, 1)
+	assert.Equal(s.T(), "BTC", rows[0].Symbol)
+	assert.Equal(s.T(), "binance", rows[0].Source)
+	// Prices are exact decimals stored as text
+	assert.Equal(s.T(), "60000.25", rows[0].Price)
+	assert.Equal(s.T(), "text", rows[0].PriceType)
+}
+
+func (s *MigrateTestSuite) TestMigrate_RefusesNewerSchema() {
+	ctx := context.Background()
+
+	// Arrange, a migration applied by a newer version
+	err := Migrate(ctx, s.db)
+	s.Require().NoError(err)
+	err = NewMigrator(s.db).MarkApplied(ctx, &migrate.Migration{Name: "99990101000000", GroupID: 2})
+	s.Require().NoError(err)
+
+	// Act
+	err = Migrate(ctx, s.db)
+
+	// Assert
+	assert.ErrorIs(s.T(), err, ErrSchemaTooNew)
+}
+
+func (s *MigrateTestSuite) TestMigrate_WaitsForLock() {
+	ctx := context.Background()
+	poll := lockPollInterval
+	lockPollInterval = 10 * time.Millisecond
+	s.T().Cleanup(func() { lockPollInterval = poll })
+
+	// Arrange, another replica migrating
+	migrator := NewMigrator(s.db)
+	s.Require().NoError(migrator.Init(ctx))
+	s.Require().NoError(migrator.Lock(ctx))
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(s.T(), migrator.Unlock(ctx))
+	}()
+
+	// Act
+	err := Migrate(ctx, s.db)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), s.tableExists("crypto_price"))
+}
+
+func (s *MigrateTestSuite) TestMigrate_LockTimeout() {
+	ctx := context.Background()
+	poll, timeout := lockPollInterval, lockTimeout
+	lockPollInterval, lockTimeout = 10*time.Millisecond, 50*time.Millisecond
+	s.T().Cleanup(func() { lockPollInterval, lockTimeout = poll, timeout })
+
+	// Arrange, a replica that died while migrating
+	migrator := NewMigrator(s.db)
+	s.Require().NoError(migrator.Init(ctx))
+	s.Require().NoError(migrator.Lock(ctx))
+
+	// Act
+	err := Migrate(ctx, s.db)
+
+	// Assert
+	assert.ErrorIs(s.T(), err, context.DeadlineExceeded)
+	assert.False(s.T(), s.tableExists("crypto_price"))
+}
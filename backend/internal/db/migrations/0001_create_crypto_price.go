@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS crypto_price (
+			timestamp `+timestampType(db)+`,
+			symbol VARCHAR,
+			currency VARCHAR,
+			price DOUBLE PRECISION
+		)`)
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		_, err = db.ExecContext(ctx,
+			"CREATE INDEX IF NOT EXISTS idx_crypto_price_symbol_currency_timestamp ON crypto_price (symbol, currency, timestamp)")
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS crypto_price")
+		if err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+
+		return nil
+	})
+}
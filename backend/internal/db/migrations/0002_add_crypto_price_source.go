@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+// Prices stored before the source column was added all came from Binance
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			exists, err := columnExists(ctx, tx, "crypto_price", "source")
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				_, err = tx.ExecContext(ctx, "ALTER TABLE crypto_price ADD COLUMN source VARCHAR")
+				if err != nil {
+					return fmt.Errorf("add column: %w", err)
+				}
+
+				_, err = tx.ExecContext(ctx, "UPDATE crypto_price SET source = 'binance' WHERE source IS NULL")
+				if err != nil {
+					return fmt.Errorf("update source: %w", err)
+				}
+			}
+
+			_, err = tx.ExecContext(ctx,
+				"CREATE INDEX IF NOT EXISTS idx_crypto_price_symbol_source_timestamp ON crypto_price (symbol, source, timestamp)")
+			if err != nil {
+				return fmt.Errorf("create index: %w", err)
+			}
+
+			return nil
+		})
+	}, func(ctx context.Context, db *bun.DB) error {
+		return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			_, err := tx.ExecContext(ctx, "DROP INDEX IF EXISTS idx_crypto_price_symbol_source_timestamp")
+			if err != nil {
+				return fmt.Errorf("drop index: %w", err)
+			}
+
+			_, err = tx.ExecContext(ctx, "ALTER TABLE crypto_price DROP COLUMN source")
+			if err != nil {
+				return fmt.Errorf("drop column: %w", err)
+			}
+
+			return nil
+		})
+	})
+}
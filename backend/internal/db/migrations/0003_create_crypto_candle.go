@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		timestamp := timestampType(db)
+
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS crypto_candle (
+			symbol VARCHAR NOT NULL,
+			currency VARCHAR NOT NULL,
+			source VARCHAR NOT NULL,
+			resolution VARCHAR NOT NULL,
+			open_time `+timestamp+` NOT NULL,
+			open DOUBLE PRECISION,
+			high DOUBLE PRECISION,
+			low DOUBLE PRECISION,
+			close DOUBLE PRECISION,
+			count BIGINT,
+			first_tick `+timestamp+`,
+			last_tick `+timestamp+`,
+			PRIMARY KEY (symbol, currency, source, resolution, open_time)
+		)`)
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS crypto_candle")
+		if err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+
+		return nil
+	})
+}
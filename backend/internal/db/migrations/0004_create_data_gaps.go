@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		timestamp := timestampType(db)
+
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS data_gaps (
+			id `+serialType(db)+`,
+			symbol VARCHAR,
+			currency VARCHAR,
+			source VARCHAR,
+			start_time `+timestamp+`,
+			end_time `+timestamp+`,
+			status VARCHAR,
+			filled BIGINT,
+			attempts BIGINT,
+			error VARCHAR,
+			detected_at `+timestamp+`,
+			repaired_at `+timestamp+`
+		)`)
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		// A gap found by overlapping scans is recorded once
+		_, err = db.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS idx_data_gaps_symbol_currency_source_start_time "+
+			"ON data_gaps (symbol, currency, source, start_time)")
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS data_gaps")
+		if err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,126 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+)
+
+// Prices become exact decimals, stored as TEXT in SQLite which has no exact numeric type,
+// and as NUMERIC in Postgres. The existing prices keep the digits their float had
+
+// The columns of the rebuilt SQLite tables, with the prices as text or as floats
+const (
+	cryptoPriceText0005  = `timestamp TIMESTAMP, symbol VARCHAR, currency VARCHAR, source VARCHAR, price TEXT`
+	cryptoPriceFloat0005 = `timestamp TIMESTAMP, symbol VARCHAR, currency VARCHAR, source VARCHAR, price DOUBLE PRECISION`
+	cryptoCandleText0005 = `symbol VARCHAR NOT NULL, currency VARCHAR NOT NULL, source VARCHAR NOT NULL,
+		resolution VARCHAR NOT NULL, open_time TIMESTAMP NOT NULL, open TEXT, high TEXT, low TEXT, close TEXT,
+		count INTEGER, first_tick TIMESTAMP, last_tick TIMESTAMP, PRIMARY KEY (symbol, currency, source, resolution, open_time)`
+	cryptoCandleFloat0005 = `symbol VARCHAR NOT NULL, currency VARCHAR NOT NULL, source VARCHAR NOT NULL,
+		resolution VARCHAR NOT NULL, open_time TIMESTAMP NOT NULL, open DOUBLE PRECISION, high DOUBLE PRECISION,
+		low DOUBLE PRECISION, close DOUBLE PRECISION, count INTEGER, first_tick TIMESTAMP, last_tick TIMESTAMP,
+		PRIMARY KEY (symbol, currency, source, resolution, open_time)`
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if tx.Dialect().Name() == dialect.PG {
+				return alterPostgresPriceType(ctx, tx, "NUMERIC")
+			}
+
+			err := rebuildSqliteTable(ctx, tx, "crypto_price", cryptoPriceText0005,
+				"timestamp, symbol, currency, source, CAST(price AS TEXT)")
+			if err != nil {
+				return err
+			}
+
+			return rebuildSqliteTable(ctx, tx, "crypto_candle", cryptoCandleText0005,
+				"symbol, currency, source, resolution, open_time, CAST(open AS TEXT), CAST(high AS TEXT), "+
+					"CAST(low AS TEXT), CAST(close AS TEXT), count, first_tick, last_tick")
+		})
+	}, func(ctx context.Context, db *bun.DB) error {
+		return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if tx.Dialect().Name() == dialect.PG {
+				return alterPostgresPriceType(ctx, tx, "DOUBLE PRECISION")
+			}
+
+			err := rebuildSqliteTable(ctx, tx, "crypto_price", cryptoPriceFloat0005,
+				"timestamp, symbol, currency, source, CAST(price AS REAL)")
+			if err != nil {
+				return err
+			}
+
+			return rebuildSqliteTable(ctx, tx, "crypto_candle", cryptoCandleFloat0005,
+				"symbol, currency, source, resolution, open_time, CAST(open AS REAL), CAST(high AS REAL), "+
+					"CAST(low AS REAL), CAST(close AS REAL), count, first_tick, last_tick")
+		})
+	})
+}
+
+// rebuildSqliteTable replaces a table by a table of the columns, filled with the selected rows of the old table,
+// since SQLite cannot change the type of a column. The indexes of the old table are recreated
+func rebuildSqliteTable(ctx context.Context, tx bun.Tx, table string, columns string, selectExpr string) error {
+	rebuilt := table + "_0005"
+
+	var indexes []string
+	err := tx.NewRaw("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", table).
+		Scan(ctx, &indexes)
+	if err != nil {
+		return fmt.Errorf("select indexes of %s: %w", table, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "CREATE TABLE ? ("+columns+")", bun.Ident(rebuilt))
+	if err != nil {
+		return fmt.Errorf("create table: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO ? SELECT "+selectExpr+" FROM ?", bun.Ident(rebuilt), bun.Ident(table))
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", table, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE ?", bun.Ident(table))
+	if err != nil {
+		return fmt.Errorf("drop table: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "ALTER TABLE ? RENAME TO ?", bun.Ident(rebuilt), bun.Ident(table))
+	if err != nil {
+		return fmt.Errorf("rename table: %w", err)
+	}
+
+	for _, index := range indexes {
+		_, err = tx.ExecContext(ctx, index)
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// alterPostgresPriceType changes the type of the price columns. The TimescaleDB aggregate reading them
+// is dropped, it is recreated on startup
+func alterPostgresPriceType(ctx context.Context, tx bun.Tx, sqlType string) error {
+	_, err := tx.ExecContext(ctx, "DROP MATERIALIZED VIEW IF EXISTS crypto_price_1m")
+	if err != nil {
+		return fmt.Errorf("drop aggregate: %w", err)
+	}
+
+	for table, columns := range map[string][]string{
+		"crypto_price":  {"price"},
+		"crypto_candle": {"open", "high", "low", "close"},
+	} {
+		for _, column := range columns {
+			_, err = tx.ExecContext(ctx, "ALTER TABLE ? ALTER COLUMN ? TYPE "+sqlType+" USING ?::"+sqlType,
+				bun.Ident(table), bun.Ident(column), bun.Ident(column))
+			if err != nil {
+				return fmt.Errorf("alter %s.%s: %w", table, column, err)
+			}
+		}
+	}
+
+	return nil
+}
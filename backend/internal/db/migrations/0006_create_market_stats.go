@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		decimal := decimalType(db)
+
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS market_stats (
+			symbol VARCHAR NOT NULL,
+			currency VARCHAR NOT NULL,
+			source VARCHAR NOT NULL,
+			timestamp `+timestampType(db)+`,
+			bid_price `+decimal+`,
+			bid_quantity `+decimal+`,
+			ask_price `+decimal+`,
+			ask_quantity `+decimal+`,
+			last_quantity `+decimal+`,
+			volume `+decimal+`,
+			quote_volume `+decimal+`,
+			high `+decimal+`,
+			low `+decimal+`,
+			price_change_percent `+decimal+`,
+			PRIMARY KEY (symbol, currency, source)
+		)`)
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS market_stats")
+		if err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		decimal := decimalType(db)
+		timestamp := timestampType(db)
+
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS alert_rules (
+			id `+serialType(db)+`,
+			name VARCHAR NOT NULL,
+			symbol VARCHAR NOT NULL,
+			currency VARCHAR NOT NULL,
+			source VARCHAR NOT NULL,
+			kind VARCHAR NOT NULL,
+			threshold `+decimal+`,
+			window_duration BIGINT NOT NULL,
+			hysteresis `+decimal+`,
+			cooldown BIGINT NOT NULL,
+			enabled BOOLEAN NOT NULL,
+			created_at `+timestamp+`,
+			updated_at `+timestamp+`,
+			armed BOOLEAN NOT NULL,
+			side INTEGER NOT NULL,
+			last_fired_at `+timestamp+`
+		)`)
+		if err != nil {
+			return fmt.Errorf("create rules table: %w", err)
+		}
+
+		_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS alert_events (
+			id `+serialType(db)+`,
+			rule_id BIGINT NOT NULL,
+			name VARCHAR NOT NULL,
+			symbol VARCHAR NOT NULL,
+			currency VARCHAR NOT NULL,
+			source VARCHAR NOT NULL,
+			kind VARCHAR NOT NULL,
+			price `+decimal+`,
+			reference `+decimal+`,
+			message VARCHAR NOT NULL,
+			fired_at `+timestamp+`
+		)`)
+		if err != nil {
+			return fmt.Errorf("create events table: %w", err)
+		}
+
+		// The history is read newest first, of every rule or of one
+		_, err = db.ExecContext(ctx,
+			"CREATE INDEX IF NOT EXISTS idx_alert_events_rule_id_fired_at ON alert_events (rule_id, fired_at)")
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		for _, table := range []string{"alert_events", "alert_rules"} {
+			_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table)
+			if err != nil {
+				return fmt.Errorf("drop table %s: %w", table, err)
+			}
+		}
+
+		return nil
+	})
+}
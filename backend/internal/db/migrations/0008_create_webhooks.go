@@ -0,0 +1,62 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		timestamp := timestampType(db)
+
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS webhooks (
+			id `+serialType(db)+`,
+			name VARCHAR NOT NULL,
+			url VARCHAR NOT NULL,
+			format VARCHAR NOT NULL,
+			secret VARCHAR NOT NULL,
+			enabled BOOLEAN NOT NULL,
+			created_at `+timestamp+`,
+			updated_at `+timestamp+`
+		)`)
+		if err != nil {
+			return fmt.Errorf("create webhooks table: %w", err)
+		}
+
+		_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id `+serialType(db)+`,
+			webhook_id BIGINT NOT NULL,
+			event_type VARCHAR NOT NULL,
+			payload TEXT NOT NULL,
+			status VARCHAR NOT NULL,
+			attempts INTEGER NOT NULL,
+			next_attempt_at `+timestamp+`,
+			last_status_code INTEGER NOT NULL,
+			last_error VARCHAR NOT NULL,
+			created_at `+timestamp+`,
+			delivered_at `+timestamp+`
+		)`)
+		if err != nil {
+			return fmt.Errorf("create deliveries table: %w", err)
+		}
+
+		// The outbox is polled for the pending deliveries that are due
+		_, err = db.ExecContext(ctx,
+			"CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status_next_attempt_at ON webhook_deliveries (status, next_attempt_at)")
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		for _, table := range []string{"webhook_deliveries", "webhooks"} {
+			_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+table)
+			if err != nil {
+				return fmt.Errorf("drop table %s: %w", table, err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+// The rules created before email notifications notify no one by email
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		return db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			exists, err := columnExists(ctx, tx, "alert_rules", "email")
+			if err != nil {
+				return err
+			}
+			if exists {
+				return nil
+			}
+
+			_, err = tx.ExecContext(ctx, "ALTER TABLE alert_rules ADD COLUMN email VARCHAR NOT NULL DEFAULT ''")
+			if err != nil {
+				return fmt.Errorf("add column: %w", err)
+			}
+
+			return nil
+		})
+	}, func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, "ALTER TABLE alert_rules DROP COLUMN email")
+		if err != nil {
+			return fmt.Errorf("drop column: %w", err)
+		}
+
+		return nil
+	})
+}
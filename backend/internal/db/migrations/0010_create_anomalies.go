@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+)
+
+func init() {
+	Migrations.MustRegister(func(ctx context.Context, db *bun.DB) error {
+		decimal := decimalType(db)
+
+		_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS anomalies (
+			id `+serialType(db)+`,
+			timestamp `+timestampType(db)+`,
+			symbol VARCHAR NOT NULL,
+			currency VARCHAR NOT NULL,
+			source VARCHAR NOT NULL,
+			kind VARCHAR NOT NULL,
+			price `+decimal+`,
+			reference `+decimal+`,
+			score DOUBLE PRECISION NOT NULL,
+			message VARCHAR NOT NULL,
+			quarantined BOOLEAN NOT NULL
+		)`)
+		if err != nil {
+			return fmt.Errorf("create table: %w", err)
+		}
+
+		// The anomalies are read newest first, of every market or of a symbol
+		_, err = db.ExecContext(ctx,
+			"CREATE INDEX IF NOT EXISTS idx_anomalies_symbol_timestamp ON anomalies (symbol, timestamp)")
+		if err != nil {
+			return fmt.Errorf("create index: %w", err)
+		}
+
+		return nil
+	}, func(ctx context.Context, db *bun.DB) error {
+		_, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS anomalies")
+		if err != nil {
+			return fmt.Errorf("drop table: %w", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,64 @@
+// Package migrations holds the numbered schema migrations, applied in order of their file name.
+// A migration describes the tables in SQL as they were at its version, never with the models,
+// so that it keeps producing the same schema as the models evolve
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+	"github.com/uptrace/bun/migrate"
+)
+
+var Migrations = migrate.NewMigrations()
+
+// columnExists returns true if the table has the column, databases created before the migrations
+// may already be at any version of the schema
+func columnExists(ctx context.Context, db bun.IDB, table, column string) (bool, error) {
+	var query string
+	switch db.Dialect().Name() {
+	case dialect.SQLite:
+		query = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+	case dialect.PG:
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?"
+	default:
+		return false, fmt.Errorf("unsupported dialect %s", db.Dialect().Name())
+	}
+
+	var count int
+	err := db.NewRaw(query, table, column).Scan(ctx, &count)
+	if err != nil {
+		return false, fmt.Errorf("select columns: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// decimalType returns the column type of exact decimals, SQLite has no exact numeric type
+// and keeps them as text
+func decimalType(db bun.IDB) string {
+	if db.Dialect().Name() == dialect.PG {
+		return "NUMERIC"
+	}
+
+	return "TEXT"
+}
+
+// timestampType returns the column type bun gives to time.Time
+func timestampType(db bun.IDB) string {
+	if db.Dialect().Name() == dialect.PG {
+		return "TIMESTAMPTZ"
+	}
+
+	return "TIMESTAMP"
+}
+
+// serialType returns the column definition of an autoincremented primary key
+func serialType(db bun.IDB) string {
+	if db.Dialect().Name() == dialect.PG {
+		return "BIGSERIAL PRIMARY KEY"
+	}
+
+	return "INTEGER PRIMARY KEY AUTOINCREMENT"
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"backend/internal/config"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// NewPostgresDB connects to the database, migrates its schema and sets up TimescaleDB if enabled
+func NewPostgresDB(cfg config.PostgresConfig) (*bun.DB, error) {
+	db, err := OpenPostgresDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Migrate(context.Background(), db)
+	if err != nil {
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+
+	if cfg.PostgresTimescale {
+		err = setHypertables(db)
+		if err != nil {
+			return nil, fmt.Errorf("init hypertables: %w", err)
+		}
+	}
+
+	return db, nil
+}
+
+// OpenPostgresDB connects to the database without touching its schema
+func OpenPostgresDB(cfg config.PostgresConfig) (*bun.DB, error) {
+	sqldb := sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN(cfg.PostgresURL)))
+	db := bun.NewDB(sqldb, pgdialect.New())
+
+	err := db.Ping()
+	if err != nil {
+		return nil, fmt.Errorf("ping: %w", err)
+	}
+
+	return db, nil
+}
+
+// setHypertables partitions the prices and candles by time with TimescaleDB
+// and aggregates the last price of every minute, refreshed in the background
+func setHypertables(db *bun.DB) error {
+	for _, query := range []string{
+		`CREATE EXTENSION IF NOT EXISTS timescaledb;`,
+		`SELECT create_hypertable('crypto_price', 'timestamp',
+			chunk_time_interval => INTERVAL '1 day', if_not_exists => TRUE, migrate_data => TRUE);`,
+		`SELECT create_hypertable('crypto_candle', 'open_time',
+			chunk_time_interval => INTERVAL '30 days', if_not_exists => TRUE, migrate_data => TRUE);`,
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS crypto_price_1m
+			WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+			SELECT time_bucket(INTERVAL '1 minute', timestamp) AS bucket, symbol, currency, source,
+				last(price, timestamp) AS price, max(timestamp) AS last_tick
+			FROM crypto_price
+			GROUP BY bucket, symbol, currency, source
+			WITH NO DATA;`,
+		`SELECT add_continuous_aggregate_policy('crypto_price_1m',
+			start_offset => INTERVAL '1 day', end_offset => INTERVAL '1 minute',
+			schedule_interval => INTERVAL '1 minute', if_not_exists => TRUE);`,
+	} {
+		_, err := db.ExecContext(context.Background(), query)
+		if err != nil {
+			return fmt.Errorf("exec %q: %w", query, err)
+		}
+	}
+
+	return nil
+}
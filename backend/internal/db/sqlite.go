@@ -2,7 +2,6 @@ package db
 
 import (
 	"backend/internal/config"
-	"backend/price-tracker/models"
 	"context"
 	"database/sql"
 	"fmt"
@@ -11,26 +10,32 @@ import (
 	"github.com/uptrace/bun/driver/sqliteshim"
 )
 
+// NewSqliteDB connects to the database and migrates its schema
 func NewSqliteDB(cfg config.SqliteConfig) (*bun.DB, error) {
-	sqldb, err := sql.Open(sqliteshim.ShimName, cfg.SqliteURL)
+	db, err := OpenSqliteDB(cfg)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	db := bun.NewDB(sqldb, sqlitedialect.New())
 
-	err = db.Ping()
+	err = Migrate(context.Background(), db)
 	if err != nil {
-		return nil, fmt.Errorf("ping: %w", err)
+		return nil, fmt.Errorf("init schema: %w", err)
 	}
 
-	err = createNewTableIfNotExist(db)
+	return db, nil
+}
+
+// OpenSqliteDB connects to the database without touching its schema
+func OpenSqliteDB(cfg config.SqliteConfig) (*bun.DB, error) {
+	sqldb, err := sql.Open(sqliteshim.ShimName, cfg.SqliteURL)
 	if err != nil {
-		return nil, fmt.Errorf("init table: %w", err)
+		panic(err)
 	}
+	db := bun.NewDB(sqldb, sqlitedialect.New())
 
-	err = setIndexes(db)
+	err = db.Ping()
 	if err != nil {
-		return nil, fmt.Errorf("init indexes: %w", err)
+		return nil, fmt.Errorf("ping: %w", err)
 	}
 
 	err = setMaxDbSize(db)
@@ -38,37 +43,26 @@ func NewSqliteDB(cfg config.SqliteConfig) (*bun.DB, error) {
 		return nil, fmt.Errorf("init max db size: %w", err)
 	}
 
-	return db, nil
-}
-
-func createNewTableIfNotExist(db *bun.DB) error {
-	_, err := db.NewCreateTable().
-		Model(&models.PriceDatum{}).
-		IfNotExists().
-		Exec(context.Background())
+	err = setJournalMode(db)
 	if err != nil {
-		return fmt.Errorf("create table: %w", err)
+		return nil, fmt.Errorf("init journal mode: %w", err)
 	}
 
-	return nil
+	return db, nil
 }
 
-func setIndexes(db *bun.DB) error {
-	_, err := db.NewCreateIndex().
-		Model(&models.PriceDatum{}).
-		Index("idx_crypto_price_symbol_currency_timestamp").
-		Column("symbol", "currency", "timestamp").
-		IfNotExists().
-		Exec(context.Background())
+func setMaxDbSize(db *bun.DB) error {
+	_, err := db.Exec("PRAGMA max_page_count = 262144;")
 	if err != nil {
-		return fmt.Errorf("create index: %w", err)
+		return err
 	}
-
 	return nil
 }
 
-func setMaxDbSize(db *bun.DB) error {
-	_, err := db.Exec("PRAGMA max_page_count = 262144;")
+// setJournalMode switches to write-ahead logging so that reads do not wait for writes,
+// the log is checkpointed by the retention job
+func setJournalMode(db *bun.DB) error {
+	_, err := db.Exec("PRAGMA journal_mode = WAL;")
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,10 @@
 package gin_test_setup
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
+	"io"
 	"net/http/httptest"
 )
 
@@ -48,22 +51,22 @@ func NewGinTestContext(method, path string) GinTestContext {
 			},
 		}
 */
-//func (c *GinTestContext) CustomQueryParams(params gin.Params) {
-//	c.Params = params
-//}
-//
-//func (c *GinTestContext) CustomBody(content any) {
-//	jsonBytes, err := json.Marshal(content)
-//	if err != nil {
-//		panic(err)
-//	}
-//
-//	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonBytes))
-//}
-//
-//func (c *GinTestContext) GetResponseCode() int {
-//	return c.w.Code
-//}
+func (c *GinTestContext) CustomQueryParams(params gin.Params) {
+	c.Params = params
+}
+
+func (c *GinTestContext) CustomBody(content any) {
+	jsonBytes, err := json.Marshal(content)
+	if err != nil {
+		panic(err)
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(jsonBytes))
+}
+
+func (c *GinTestContext) GetResponseCode() int {
+	return c.w.Code
+}
 
 func (c *GinTestContext) GetResponseBody() string {
 	return c.w.Body.String()
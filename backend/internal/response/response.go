@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
 type CustomResponse struct {
@@ -18,6 +19,9 @@ type ResponseData struct {
 type Meta struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// AgeMs and Stale tell how long ago the data was received and whether it is stale, only set on live data
+	AgeMs *int64 `json:"age_ms,omitempty"`
+	Stale *bool  `json:"stale,omitempty"`
 }
 
 func (c *CustomResponse) Success(data interface{}, ctx *gin.Context) {
@@ -34,6 +38,24 @@ func (c *CustomResponse) Success(data interface{}, ctx *gin.Context) {
 	c.logging(res, slog.LevelInfo, ctx.Request)
 }
 
+// SuccessWithAge answers the data along with how long ago it was received and whether it is stale
+func (c *CustomResponse) SuccessWithAge(data interface{}, age time.Duration, stale bool, ctx *gin.Context) {
+	ageMs := age.Milliseconds()
+	res := ResponseData{
+		Meta: Meta{
+			Code:    http.StatusOK,
+			Message: "ok",
+			AgeMs:   &ageMs,
+			Stale:   &stale,
+		},
+		Data: data,
+	}
+
+	ctx.JSON(http.StatusOK, res)
+
+	c.logging(res, slog.LevelInfo, ctx.Request)
+}
+
 func (c *CustomResponse) BadRequest(err error, ctx *gin.Context) {
 	res := ResponseData{
 		Meta: Meta{
@@ -60,6 +82,19 @@ func (c *CustomResponse) InternalServerError(err error, ctx *gin.Context) {
 	c.logging(res, slog.LevelError, ctx.Request)
 }
 
+func (c *CustomResponse) NotFound(err error, ctx *gin.Context) {
+	res := ResponseData{
+		Meta: Meta{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		},
+	}
+
+	ctx.JSON(http.StatusNotFound, res)
+
+	c.logging(res, slog.LevelError, ctx.Request)
+}
+
 func (c *CustomResponse) Unauthorized(err error, ctx *gin.Context) {
 	res := ResponseData{
 		Meta: Meta{
@@ -73,6 +108,24 @@ func (c *CustomResponse) Unauthorized(err error, ctx *gin.Context) {
 	c.logging(res, slog.LevelError, ctx.Request)
 }
 
+// ServiceUnavailableWithAge answers that the data is too old to be served, along with how long ago it was received
+func (c *CustomResponse) ServiceUnavailableWithAge(err error, age time.Duration, ctx *gin.Context) {
+	ageMs := age.Milliseconds()
+	stale := true
+	res := ResponseData{
+		Meta: Meta{
+			Code:    http.StatusServiceUnavailable,
+			Message: err.Error(),
+			AgeMs:   &ageMs,
+			Stale:   &stale,
+		},
+	}
+
+	ctx.JSON(http.StatusServiceUnavailable, res)
+
+	c.logging(res, slog.LevelError, ctx.Request)
+}
+
 func (c *CustomResponse) logging(res ResponseData, logLevel slog.Level, r *http.Request) {
 	body, _ := io.ReadAll(r.Body)
 	slog.LogAttrs(
@@ -0,0 +1,213 @@
+package smtp_test_setup
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// SMTPTestServer is an SMTP server on a local port, keeping the messages it accepts
+type SMTPTestServer struct {
+	listener net.Listener
+	messages chan ReceivedMessage
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	reject string
+}
+
+// ReceivedMessage is a message accepted by the server, as sent by the client
+type ReceivedMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Usage
+/*
+	server, err := NewSMTPTestServer()
+	defer server.Close()
+
+	sender := mail.NewSender(config.EmailConfig{EmailSMTPHost: server.Host(), EmailSMTPPort: server.Port()})
+	sender.Send(ctx, msg)
+
+	received := <-server.Messages()
+*/
+func NewSMTPTestServer() (*SMTPTestServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SMTPTestServer{
+		listener: listener,
+		messages: make(chan ReceivedMessage, 100),
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(conn)
+			}()
+		}
+	}()
+
+	return s, nil
+}
+
+func (s *SMTPTestServer) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+func (s *SMTPTestServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Messages returns the messages accepted, in order
+func (s *SMTPTestServer) Messages() <-chan ReceivedMessage {
+	return s.messages
+}
+
+// Reject answers the end of the next messages with the reply, such as "451 try again later",
+// instead of accepting them. An empty reply accepts them again
+func (s *SMTPTestServer) Reject(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reject = reply
+}
+
+// Close stops listening and waits for the sessions in progress to end
+func (s *SMTPTestServer) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+// serve speaks enough SMTP for a client sending a message per session
+func (s *SMTPTestServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+
+	_ = text.PrintfLine("220 localhost ESMTP test")
+
+	var msg ReceivedMessage
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250 localhost")
+		case "MAIL":
+			msg = ReceivedMessage{From: address(arg)}
+			_ = text.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			_ = text.PrintfLine("250 OK")
+		case "DATA":
+			_ = text.PrintfLine("354 end data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(text.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+
+			s.mu.Lock()
+			reject := s.reject
+			s.mu.Unlock()
+			if reject != "" {
+				_ = text.PrintfLine("%s", reject)
+				continue
+			}
+
+			s.messages <- msg
+			_ = text.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			_ = text.PrintfLine("250 OK")
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		default:
+			_ = text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// address returns the address of a MAIL FROM:<address> or RCPT TO:<address> argument
+func address(arg string) string {
+	_, path, _ := strings.Cut(arg, ":")
+	path, _, _ = strings.Cut(strings.TrimSpace(path), " ")
+	return strings.Trim(path, "<>")
+}
+
+// Header returns the header of the message
+func (m ReceivedMessage) Header() (mail.Header, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.Header, nil
+}
+
+// Bodies returns the decoded parts of a multipart message by content type, such as text/plain and text/html,
+// or its body by the content type of the message
+func (m ReceivedMessage) Bodies() (map[string]string, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(m.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	bodies := make(map[string]string)
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(msg.Body)
+		if err != nil {
+			return nil, err
+		}
+		bodies[mediaType] = string(body)
+		return bodies, nil
+	}
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return bodies, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		bodies[partType] = string(body)
+	}
+}
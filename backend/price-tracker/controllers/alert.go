@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+type AlertController interface {
+	// GetRules handles requests to list the alert rules
+	GetRules(ctx *gin.Context)
+	// GetRule handles requests to get an alert rule by ID
+	GetRule(ctx *gin.Context)
+	// CreateRule handles requests to add an alert rule
+	CreateRule(ctx *gin.Context)
+	// UpdateRule handles requests to replace an alert rule
+	UpdateRule(ctx *gin.Context)
+	// DeleteRule handles requests to delete an alert rule
+	DeleteRule(ctx *gin.Context)
+	// GetHistory handles requests to get the fired alerts
+	GetHistory(ctx *gin.Context)
+}
+
+type AlertControllerImpl struct {
+	alertService services.AlertService
+	cfg          config.AlertConfig
+	httpResponse response.CustomResponse
+}
+
+func NewAlertController(alertService services.AlertService, cfg config.AlertConfig) AlertController {
+	return &AlertControllerImpl{
+		alertService: alertService,
+		cfg:          cfg,
+	}
+}
+
+func (a *AlertControllerImpl) GetRules(ctx *gin.Context) {
+	res, err := a.alertService.GetRules(ctx.Request.Context())
+	if err != nil {
+		a.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(res, ctx)
+}
+
+func (a *AlertControllerImpl) GetRule(ctx *gin.Context) {
+	// Verify the request
+	id, ok := a.ruleID(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	res, err := a.alertService.GetRule(ctx.Request.Context(), id)
+	if err != nil {
+		a.serviceError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(res, ctx)
+}
+
+// CreateRule handles requests to add an alert rule, enabled unless the body says otherwise
+func (a *AlertControllerImpl) CreateRule(ctx *gin.Context) {
+	// Verify the request
+	rule, ok := a.bindRule(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	res, err := a.alertService.CreateRule(ctx.Request.Context(), rule)
+	if err != nil {
+		a.serviceError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(res, ctx)
+}
+
+// UpdateRule handles requests to replace an alert rule by the body, which arms it again
+func (a *AlertControllerImpl) UpdateRule(ctx *gin.Context) {
+	// Verify the request
+	id, ok := a.ruleID(ctx)
+	if !ok {
+		return
+	}
+	rule, ok := a.bindRule(ctx)
+	if !ok {
+		return
+	}
+	rule.ID = id
+
+	// Process the request
+	res, err := a.alertService.UpdateRule(ctx.Request.Context(), rule)
+	if err != nil {
+		a.serviceError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(res, ctx)
+}
+
+func (a *AlertControllerImpl) DeleteRule(ctx *gin.Context) {
+	// Verify the request
+	id, ok := a.ruleID(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	err := a.alertService.DeleteRule(ctx.Request.Context(), id)
+	if err != nil {
+		a.serviceError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(nil, ctx)
+}
+
+// GetHistory handles requests to get the latest fired alerts, newest first, of a rule if rule_id is given
+func (a *AlertControllerImpl) GetHistory(ctx *gin.Context) {
+	// Verify the request
+	req := models.AlertEventRequest{Limit: a.cfg.AlertHistoryLimit}
+
+	if ruleID := ctx.Query("rule_id"); ruleID != "" {
+		id, err := strconv.ParseInt(ruleID, 10, 64)
+		if err != nil || id <= 0 {
+			a.httpResponse.BadRequest(fmt.Errorf("invalid rule_id value '%s'", ruleID), ctx)
+			return
+		}
+		req.RuleID = id
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > a.cfg.AlertHistoryMax {
+			a.httpResponse.BadRequest(
+				fmt.Errorf("invalid limit value '%s', must be between 1 and %d", limit, a.cfg.AlertHistoryMax), ctx)
+			return
+		}
+		req.Limit = n
+	}
+
+	// Process the request
+	res, err := a.alertService.GetEvents(ctx.Request.Context(), req)
+	if err != nil {
+		a.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(res, ctx)
+}
+
+// ruleID reads the ID of the path, it answers a bad request and returns false if it is not one
+func (a *AlertControllerImpl) ruleID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		a.httpResponse.BadRequest(fmt.Errorf("invalid rule id '%s'", ctx.Param("id")), ctx)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// bindRule reads the rule of the body, it answers a bad request and returns false if it is not one
+func (a *AlertControllerImpl) bindRule(ctx *gin.Context) (models.AlertRule, bool) {
+	rule := models.AlertRule{Enabled: true}
+	err := ctx.ShouldBindJSON(&rule)
+	if err != nil {
+		a.httpResponse.BadRequest(fmt.Errorf("invalid rule: %w", err), ctx)
+		return models.AlertRule{}, false
+	}
+
+	return rule, true
+}
+
+// serviceError answers a rule that is not valid by a bad request and a missing one by not found
+func (a *AlertControllerImpl) serviceError(err error, ctx *gin.Context) {
+	switch {
+	case errors.Is(err, services.ErrInvalidAlertRule):
+		a.httpResponse.BadRequest(err, ctx)
+	case errors.Is(err, repositories.ErrNotFound):
+		a.httpResponse.NotFound(errors.New("alert rule not found"), ctx)
+	default:
+		a.httpResponse.InternalServerError(err, ctx)
+	}
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAlertService implements services.AlertService
+type MockAlertService struct {
+	mock.Mock
+}
+
+func (m *MockAlertService) Load(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetRules(ctx context.Context) ([]models.AlertRule, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.AlertRule), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAlertService) GetRule(ctx context.Context, id int64) (*models.AlertRule, error) {
+	args := m.Called(id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.AlertRule), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAlertService) CreateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	args := m.Called(rule)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.AlertRule), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAlertService) UpdateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	args := m.Called(rule)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.AlertRule), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAlertService) DeleteRule(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAlertService) GetEvents(ctx context.Context, req models.AlertEventRequest) ([]models.AlertEvent, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.AlertEvent), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAlertService) Evaluate(tick models.PriceTick) {
+	m.Called(tick)
+}
+
+var alertCfg = config.AlertConfig{
+	AlertHistoryLimit: 100,
+	AlertHistoryMax:   1000,
+}
+
+func TestAlertControllerImpl_CreateRule(t *testing.T) {
+	rule := models.AlertRule{
+		Symbol:    "BTC",
+		Kind:      models.AlertKindPercentChange,
+		Threshold: decimal.NewFromInt(5),
+		Window:    models.Duration(time.Hour),
+		Enabled:   true,
+	}
+	created := rule
+	created.ID = 1
+	created.Currency = "USDT"
+
+	tests := []struct {
+		name       string
+		body       any
+		mockRule   *models.AlertRule
+		mockErr    error
+		wantStatus int
+		wantRule   *models.AlertRule
+	}{
+		{
+			name:       "created, enabled by default",
+			body:       map[string]any{"symbol": "BTC", "kind": "percent_change", "threshold": "5", "window": "1h"},
+			mockRule:   &rule,
+			wantStatus: http.StatusOK,
+			wantRule:   &created,
+		},
+		{
+			name:       "window not a duration",
+			body:       map[string]any{"symbol": "BTC", "kind": "percent_change", "threshold": "5", "window": "an hour"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "rule not valid",
+			body:       map[string]any{"symbol": "BTC", "kind": "percent_change", "threshold": "5", "window": "1h"},
+			mockRule:   &rule,
+			mockErr:    fmt.Errorf("%w: window must be at least 10s", services.ErrInvalidAlertRule),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "database error",
+			body:       map[string]any{"symbol": "BTC", "kind": "percent_change", "threshold": "5", "window": "1h"},
+			mockRule:   &rule,
+			mockErr:    errors.New("database is locked"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockAlertService)
+			if tt.mockRule != nil {
+				if tt.mockErr != nil {
+					mockService.On("CreateRule", *tt.mockRule).Return(nil, tt.mockErr)
+				} else {
+					mockService.On("CreateRule", *tt.mockRule).Return(&created, nil)
+				}
+			}
+			controller := NewAlertController(mockService, alertCfg)
+			ctx := gin_test_setup.NewGinTestContext("POST", "/alerts/rules")
+			ctx.CustomBody(tt.body)
+
+			// Act
+			controller.CreateRule(ctx.Context)
+
+			// Assert
+			var res struct {
+				Meta response.Meta    `json:"meta"`
+				Data models.AlertRule `json:"data"`
+			}
+			err := json.Unmarshal([]byte(ctx.GetResponseBody()), &res)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, ctx.GetResponseCode())
+			assert.Equal(t, tt.wantStatus, res.Meta.Code)
+			if tt.wantRule != nil {
+				assert.Equal(t, tt.wantRule.ID, res.Data.ID)
+				assert.Equal(t, tt.wantRule.Window, res.Data.Window)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAlertControllerImpl_UpdateRule(t *testing.T) {
+	// Arrange
+	rule := models.AlertRule{
+		ID:        4,
+		Symbol:    "ETH",
+		Kind:      models.AlertKindBelow,
+		Threshold: decimal.NewFromInt(1000),
+		Cooldown:  models.Duration(30 * time.Minute),
+	}
+	mockService := new(MockAlertService)
+	mockService.On("UpdateRule", rule).Return(nil, repositories.ErrNotFound)
+	controller := NewAlertController(mockService, alertCfg)
+	ctx := gin_test_setup.NewGinTestContext("PUT", "/alerts/rules/4")
+	ctx.CustomQueryParams(gin.Params{{Key: "id", Value: "4"}})
+	ctx.CustomBody(map[string]any{"symbol": "ETH", "kind": "below", "threshold": "1000", "cooldown": "30m", "enabled": false})
+
+	// Act
+	controller.UpdateRule(ctx.Context)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, ctx.GetResponseCode())
+	mockService.AssertExpectations(t)
+}
+
+func TestAlertControllerImpl_GetRule(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		mockRule   *models.AlertRule
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "found", id: "2", mockRule: &models.AlertRule{ID: 2}, wantStatus: http.StatusOK},
+		{name: "not found", id: "3", mockErr: repositories.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "invalid id", id: "abc", wantStatus: http.StatusBadRequest},
+		{name: "zero id", id: "0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockAlertService)
+			if tt.mockRule != nil || tt.mockErr != nil {
+				id, _ := strconv.ParseInt(tt.id, 10, 64)
+				mockService.On("GetRule", id).Return(tt.mockRule, tt.mockErr)
+			}
+			controller := NewAlertController(mockService, alertCfg)
+			ctx := gin_test_setup.NewGinTestContext("GET", "/alerts/rules/"+tt.id)
+			ctx.CustomQueryParams(gin.Params{{Key: "id", Value: tt.id}})
+
+			// Act
+			controller.GetRule(ctx.Context)
+
+			// Assert
+			assert.Equal(t, tt.wantStatus, ctx.GetResponseCode())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAlertControllerImpl_DeleteRule(t *testing.T) {
+	// Arrange
+	mockService := new(MockAlertService)
+	mockService.On("DeleteRule", int64(5)).Return(nil)
+	controller := NewAlertController(mockService, alertCfg)
+	ctx := gin_test_setup.NewGinTestContext("DELETE", "/alerts/rules/5")
+	ctx.CustomQueryParams(gin.Params{{Key: "id", Value: "5"}})
+
+	// Act
+	controller.DeleteRule(ctx.Context)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, ctx.GetResponseCode())
+	mockService.AssertExpectations(t)
+}
+
+func TestAlertControllerImpl_GetHistory(t *testing.T) {
+	event := models.AlertEvent{
+		ID:        1,
+		RuleID:    2,
+		Symbol:    "BTC",
+		Price:     decimal.NewFromInt(101),
+		Reference: decimal.NewFromInt(100),
+		Message:   "BTC/USDT rose above 100 at 101 on binance",
+		FiredAt:   time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		mockReq    *models.AlertEventRequest
+		wantStatus int
+	}{
+		{
+			name:       "default limit",
+			query:      "",
+			mockReq:    &models.AlertEventRequest{Limit: 100},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "of a rule",
+			query:      "?rule_id=2&limit=10",
+			mockReq:    &models.AlertEventRequest{RuleID: 2, Limit: 10},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid rule_id",
+			query:      "?rule_id=x",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "limit too high",
+			query:      "?limit=5000",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockAlertService)
+			if tt.mockReq != nil {
+				mockService.On("GetEvents", *tt.mockReq).Return([]models.AlertEvent{event}, nil)
+			}
+			controller := NewAlertController(mockService, alertCfg)
+			ctx := gin_test_setup.NewGinTestContext("GET", "/alerts/history"+tt.query)
+
+			// Act
+			controller.GetHistory(ctx.Context)
+
+			// Assert
+			var res struct {
+				Meta response.Meta       `json:"meta"`
+				Data []models.AlertEvent `json:"data"`
+			}
+			err := json.Unmarshal([]byte(ctx.GetResponseBody()), &res)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, res.Meta.Code)
+			if tt.mockReq != nil {
+				assert.Equal(t, []models.AlertEvent{event}, res.Data)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
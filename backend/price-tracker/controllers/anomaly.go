@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+)
+
+type AnomalyController interface {
+	// GetAnomalies handles requests to get the anomalies detected in the prices received
+	GetAnomalies(ctx *gin.Context)
+}
+
+type AnomalyControllerImpl struct {
+	anomalyService services.AnomalyService
+	cfg            config.AnomalyConfig
+	httpResponse   response.CustomResponse
+}
+
+func NewAnomalyController(anomalyService services.AnomalyService, cfg config.AnomalyConfig) AnomalyController {
+	return &AnomalyControllerImpl{
+		anomalyService: anomalyService,
+		cfg:            cfg,
+	}
+}
+
+// GetAnomalies handles requests to get the latest anomalies, newest first,
+// filtered by symbol, currency and source if given
+func (a *AnomalyControllerImpl) GetAnomalies(ctx *gin.Context) {
+	// Verify the request
+	req := models.AnomalyRequest{
+		Symbol:   strings.ToUpper(strings.TrimSpace(ctx.Query("symbol"))),
+		Currency: strings.ToUpper(strings.TrimSpace(ctx.Query("currency"))),
+		Source:   strings.ToLower(strings.TrimSpace(ctx.Query("source"))),
+		Limit:    a.cfg.AnomalyLimit,
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > a.cfg.AnomalyMax {
+			a.httpResponse.BadRequest(
+				fmt.Errorf("invalid limit value '%s', must be between 1 and %d", limit, a.cfg.AnomalyMax), ctx)
+			return
+		}
+		req.Limit = n
+	}
+
+	// Process the request
+	res, err := a.anomalyService.GetAnomalies(ctx.Request.Context(), req)
+	if err != nil {
+		a.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	a.httpResponse.Success(res, ctx)
+}
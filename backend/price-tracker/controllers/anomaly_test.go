@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAnomalyService implements services.AnomalyService
+type MockAnomalyService struct {
+	mock.Mock
+}
+
+func (m *MockAnomalyService) Publish(tick models.PriceTick) {
+	m.Called(tick)
+}
+
+func (m *MockAnomalyService) SaveAnomalies(anomalies []models.Anomaly) {
+	m.Called(anomalies)
+}
+
+func (m *MockAnomalyService) GetAnomalies(ctx context.Context, req models.AnomalyRequest) ([]models.Anomaly, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.Anomaly), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestAnomalyControllerImpl_GetAnomalies(t *testing.T) {
+	cfg := config.AnomalyConfig{AnomalyLimit: 100, AnomalyMax: 1000}
+	anomaly := models.Anomaly{
+		ID:        1,
+		Timestamp: time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC),
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "binance",
+		Kind:      models.AnomalyKindJump,
+		Price:     decimal.NewFromInt(150),
+		Reference: decimal.NewFromInt(100),
+		Score:     249,
+		Message:   "BTC/USDT jumped from 100 to 150 on binance, 249.0 times its usual move",
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		mockReq    *models.AnomalyRequest
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "default limit",
+			query:      "",
+			mockReq:    &models.AnomalyRequest{Limit: 100},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "of a market",
+			query:      "?symbol=btc&currency=usdt&source=Binance&limit=10",
+			mockReq:    &models.AnomalyRequest{Symbol: "BTC", Currency: "USDT", Source: "binance", Limit: 10},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "limit too high",
+			query:      "?symbol=BTC&limit=5000",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "database error",
+			query:      "?symbol=BTC",
+			mockReq:    &models.AnomalyRequest{Symbol: "BTC", Limit: 100},
+			mockErr:    errors.New("database is locked"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockAnomalyService)
+			if tt.mockReq != nil {
+				if tt.mockErr != nil {
+					mockService.On("GetAnomalies", *tt.mockReq).Return(nil, tt.mockErr)
+				} else {
+					mockService.On("GetAnomalies", *tt.mockReq).Return([]models.Anomaly{anomaly}, nil)
+				}
+			}
+			controller := NewAnomalyController(mockService, cfg)
+			ctx := gin_test_setup.NewGinTestContext("GET", "/anomalies"+tt.query)
+
+			// Act
+			controller.GetAnomalies(ctx.Context)
+
+			// Assert
+			var res struct {
+				Meta response.Meta    `json:"meta"`
+				Data []models.Anomaly `json:"data"`
+			}
+			err := json.Unmarshal([]byte(ctx.GetResponseBody()), &res)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, ctx.GetResponseCode())
+			assert.Equal(t, tt.wantStatus, res.Meta.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, []models.Anomaly{anomaly}, res.Data)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
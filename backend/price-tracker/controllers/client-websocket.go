@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services"
+	"backend/price-tracker/services/stream"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// clientWriteWait bounds the time to write a message to a websocket client
+	clientWriteWait = 10 * time.Second
+	// clientMaxRequestSize bounds the size of a request from a websocket client
+	clientMaxRequestSize = 4096
+)
+
+type ClientWebsocketController interface {
+	// Serve handles requests to open a websocket streaming the events of the markets the client subscribes to
+	Serve(ctx *gin.Context)
+}
+
+type ClientWebsocketControllerImpl struct {
+	priceTrackingService services.PriceTrackingService
+	hub                  stream.Hub
+	cfg                  config.StreamConfig
+	upgrader             websocket.Upgrader
+}
+
+func NewClientWebsocketController(
+	priceTrackingService services.PriceTrackingService,
+	hub stream.Hub,
+	cfg config.StreamConfig,
+) ClientWebsocketController {
+	return &ClientWebsocketControllerImpl{
+		priceTrackingService: priceTrackingService,
+		hub:                  hub,
+		cfg:                  cfg,
+		upgrader: websocket.Upgrader{
+			// Only public market data is served, pages of any origin may connect
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		},
+	}
+}
+
+// Serve upgrades the request to a websocket, then answers the subscribe and unsubscribe requests of the client
+// while a separate goroutine writes the events of its subscriptions
+func (c *ClientWebsocketControllerImpl) Serve(ctx *gin.Context) {
+	conn, err := c.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		// The upgrader already replied with an error status
+		slog.Error("upgrade client websocket", "err", err)
+		return
+	}
+
+	client := stream.NewClient(c.cfg.StreamQueueSize)
+	c.hub.Register(client)
+	defer c.hub.Unregister(client)
+
+	go c.writeEvents(ctx.Request.Context(), conn, client)
+
+	err = c.readRequests(conn, client)
+	slog.Info("client websocket closed", "dropped", client.Dropped(), "err", err)
+}
+
+// readRequests answers the requests of the client until the connection fails or is closed
+func (c *ClientWebsocketControllerImpl) readRequests(conn *websocket.Conn, client *stream.Client) error {
+	conn.SetReadLimit(clientMaxRequestSize)
+
+	// Clients answer the pings of writeEvents, a client silent for two of them is gone
+	deadline := func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * c.cfg.StreamHeartbeat))
+	}
+	conn.SetPongHandler(deadline)
+
+	for {
+		err := deadline("")
+		if err != nil {
+			return fmt.Errorf("set read deadline: %w", err)
+		}
+
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+
+		var req models.ClientRequest
+		var res models.ClientEvent
+		if err := json.Unmarshal(message, &req); err != nil {
+			res = models.ClientEvent{Type: models.ClientEventError, Error: fmt.Sprintf("invalid request: %s", err)}
+		} else {
+			res = c.handleRequest(client, req)
+		}
+
+		body, err := json.Marshal(res)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+
+		client.Send(body)
+	}
+}
+
+// handleRequest updates the subscriptions of the client and returns the response to the request
+func (c *ClientWebsocketControllerImpl) handleRequest(client *stream.Client, req models.ClientRequest) models.ClientEvent {
+	markets, err := c.getMarkets(req)
+	if err != nil {
+		return models.ClientEvent{Type: models.ClientEventError, ID: req.ID, Error: err.Error()}
+	}
+
+	switch req.Op {
+	case models.ClientOpSubscribe:
+		subscriptions, err := c.hub.Subscribe(client, markets)
+		if errors.Is(err, stream.ErrTooManySubscriptions) {
+			return models.ClientEvent{
+				Type:          models.ClientEventError,
+				ID:            req.ID,
+				Subscriptions: subscriptions,
+				Error:         fmt.Sprintf("%s, at most %d per connection", err, c.cfg.StreamMaxSubscriptions),
+			}
+		}
+
+		return models.ClientEvent{Type: models.ClientEventSubscribed, ID: req.ID, Subscriptions: subscriptions}
+	case models.ClientOpUnsubscribe:
+		subscriptions := c.hub.Unsubscribe(client, markets)
+
+		return models.ClientEvent{Type: models.ClientEventUnsubscribed, ID: req.ID, Subscriptions: subscriptions}
+	default:
+		return models.ClientEvent{Type: models.ClientEventError, ID: req.ID, Error: fmt.Sprintf("invalid op value '%s'", req.Op)}
+	}
+}
+
+// getMarkets returns the markets of the symbols of a request, such as BTC/USDT, if all of them exist
+func (c *ClientWebsocketControllerImpl) getMarkets(req models.ClientRequest) ([]string, error) {
+	if len(req.Symbols) == 0 {
+		return nil, errors.New("missing symbols value")
+	}
+
+	currency := strings.ToUpper(req.Currency)
+	if currency == "" {
+		currency = constant.USDT
+	}
+
+	markets := make([]string, 0, len(req.Symbols))
+	for _, symbol := range req.Symbols {
+		symbol = strings.ToUpper(symbol)
+		if !c.priceTrackingService.IsSymbolValid(symbol, currency) {
+			return nil, fmt.Errorf("invalid symbol value '%s' for currency '%s'", symbol, currency)
+		}
+
+		markets = append(markets, models.CacheKey("", symbol, currency))
+	}
+
+	return markets, nil
+}
+
+// writeEvents writes the queued events and the pings to the client, until the client is unregistered,
+// the connection fails or the server shuts down
+func (c *ClientWebsocketControllerImpl) writeEvents(ctx context.Context, conn *websocket.Conn, client *stream.Client) {
+	ping := time.NewTicker(c.cfg.StreamHeartbeat)
+	defer ping.Stop()
+	// Closing the connection also ends readRequests
+	defer conn.Close()
+
+	for {
+		select {
+		case message, ok := <-client.Queue():
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(clientWriteWait))
+				return
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(clientWriteWait))
+			err := conn.WriteMessage(websocket.TextMessage, message)
+			if err != nil {
+				return
+			}
+		case <-ctx.Done():
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(clientWriteWait))
+			return
+		case <-ping.C:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(clientWriteWait))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
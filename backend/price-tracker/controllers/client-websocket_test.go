@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/stream"
+	"context"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientWebsocketTestSuite struct {
+	suite.Suite
+	hub    stream.Hub
+	server *httptest.Server
+	conn   *websocket.Conn
+	// shutdown cancels the context of the requests, as EndOnShutdown does when the server shuts down
+	shutdown context.CancelFunc
+}
+
+func (s *ClientWebsocketTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+
+	mockPriceTrackingService := new(MockPriceTrackingService)
+	mockPriceTrackingService.On("IsSymbolValid", "NOPE", mock.Anything).Return(false)
+	mockPriceTrackingService.On("IsSymbolValid", mock.Anything, "USDT").Return(true)
+
+	s.hub = stream.NewHub(2)
+	controller := NewClientWebsocketController(mockPriceTrackingService, s.hub, config.StreamConfig{
+		StreamHeartbeat:        time.Minute,
+		StreamMaxSubscriptions: 2,
+		StreamQueueSize:        16,
+	})
+
+	r := gin.New()
+	r.GET("/ws", controller.Serve)
+	var ctx context.Context
+	ctx, s.shutdown = context.WithCancel(context.Background())
+	s.server = httptest.NewUnstartedServer(r)
+	s.server.Config.BaseContext = func(net.Listener) context.Context {
+		return ctx
+	}
+	s.server.Start()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.server.URL, "http")+"/ws", nil)
+	s.Require().NoError(err)
+	s.conn = conn
+}
+
+func (s *ClientWebsocketTestSuite) TearDownTest() {
+	s.shutdown()
+	s.conn.Close()
+	s.server.Close()
+}
+
+func TestClientWebsocketSuite(t *testing.T) {
+	suite.Run(t, new(ClientWebsocketTestSuite))
+}
+
+// request sends a request and returns the response of the server
+func (s *ClientWebsocketTestSuite) request(req models.ClientRequest) models.ClientEvent {
+	s.Require().NoError(s.conn.WriteJSON(req))
+
+	return s.readEvent()
+}
+
+func (s *ClientWebsocketTestSuite) readEvent() models.ClientEvent {
+	s.Require().NoError(s.conn.SetReadDeadline(time.Now().Add(time.Second)))
+
+	var event models.ClientEvent
+	s.Require().NoError(s.conn.ReadJSON(&event))
+
+	return event
+}
+
+func (s *ClientWebsocketTestSuite) TestSubscribe() {
+	// Act
+	res := s.request(models.ClientRequest{ID: 1, Op: models.ClientOpSubscribe, Symbols: []string{"btc"}})
+
+	s.hub.Publish([]models.PriceDatum{
+		{Timestamp: time.UnixMilli(1700000000000).UTC(), Symbol: "ETH", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("10")},
+		{Timestamp: time.UnixMilli(1700000000000).UTC(), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")},
+	})
+	price := s.readEvent()
+	candle := s.readEvent()
+
+	// Assert
+	s.Equal(models.ClientEvent{Type: models.ClientEventSubscribed, ID: 1, Subscriptions: []string{"BTC/USDT"}}, res)
+
+	s.Equal(models.ClientEventPrice, price.Type)
+	data, _ := json.Marshal(price.Data)
+	s.JSONEq(`{"timestamp":"2023-11-14T22:13:20Z","symbol":"BTC","currency":"USDT","source":"binance","price":"100"}`, string(data))
+
+	s.Equal(models.ClientEventCandle, candle.Type)
+	data, _ = json.Marshal(candle.Data)
+	var published models.Candle
+	s.Require().NoError(json.Unmarshal(data, &published))
+	s.Equal("1m", published.Resolution)
+	s.Equal("100", published.Close.String())
+}
+
+func (s *ClientWebsocketTestSuite) TestUnsubscribe() {
+	// Act
+	s.request(models.ClientRequest{Op: models.ClientOpSubscribe, Symbols: []string{"BTC", "ETH"}})
+	res := s.request(models.ClientRequest{Op: models.ClientOpUnsubscribe, Symbols: []string{"BTC"}})
+
+	s.hub.PublishEvent("BTC", "USDT", models.ClientEvent{Type: models.ClientEventAlert, Data: "btc"})
+	s.hub.PublishEvent("ETH", "USDT", models.ClientEvent{Type: models.ClientEventAlert, Data: "eth"})
+	alert := s.readEvent()
+
+	// Assert
+	s.Equal(models.ClientEvent{Type: models.ClientEventUnsubscribed, Subscriptions: []string{"ETH/USDT"}}, res)
+	s.Equal(models.ClientEvent{Type: models.ClientEventAlert, Data: "eth"}, alert)
+}
+
+func (s *ClientWebsocketTestSuite) TestInvalidRequests() {
+	tests := []struct {
+		name    string
+		req     models.ClientRequest
+		wantErr string
+	}{
+		{
+			name:    "invalid op",
+			req:     models.ClientRequest{Op: "publish", Symbols: []string{"BTC"}},
+			wantErr: "invalid op value 'publish'",
+		},
+		{
+			name:    "missing symbols",
+			req:     models.ClientRequest{Op: models.ClientOpSubscribe},
+			wantErr: "missing symbols value",
+		},
+		{
+			name:    "invalid symbol",
+			req:     models.ClientRequest{Op: models.ClientOpSubscribe, Symbols: []string{"NOPE"}},
+			wantErr: "invalid symbol value 'NOPE' for currency 'USDT'",
+		},
+		{
+			name:    "too many subscriptions",
+			req:     models.ClientRequest{Op: models.ClientOpSubscribe, Symbols: []string{"BTC", "ETH", "SOL"}},
+			wantErr: "too many subscriptions, at most 2 per connection",
+		},
+	}
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			res := s.request(tt.req)
+
+			s.Equal(models.ClientEventError, res.Type)
+			s.Equal(tt.wantErr, res.Error)
+		})
+	}
+
+	// Malformed requests leave the connection open
+	s.Require().NoError(s.conn.WriteMessage(websocket.TextMessage, []byte("{")))
+	s.Equal(models.ClientEventError, s.readEvent().Type)
+	s.Equal(models.ClientEventSubscribed, s.request(models.ClientRequest{Op: models.ClientOpSubscribe, Symbols: []string{"BTC"}}).Type)
+}
+
+func (s *ClientWebsocketTestSuite) TestShutdown() {
+	// Act
+	s.request(models.ClientRequest{Op: models.ClientOpSubscribe, Symbols: []string{"BTC"}})
+	s.shutdown()
+
+	s.Require().NoError(s.conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, _, err := s.conn.ReadMessage()
+
+	// Assert
+	s.True(websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+type ConversionController interface {
+	// Convert handles requests to convert an amount from an asset to another
+	Convert(ctx *gin.Context)
+}
+
+type ConversionControllerImpl struct {
+	conversionService services.ConversionService
+	httpResponse      response.CustomResponse
+}
+
+func NewConversionController(conversionService services.ConversionService) ConversionController {
+	return &ConversionControllerImpl{
+		conversionService: conversionService,
+	}
+}
+
+// Convert handles requests to convert an amount from an asset to another
+func (c *ConversionControllerImpl) Convert(ctx *gin.Context) {
+	// Verify the request
+	from := strings.ToUpper(ctx.Query("from"))
+	to := strings.ToUpper(ctx.Query("to"))
+	if from == "" || to == "" {
+		c.httpResponse.BadRequest(fmt.Errorf("missing from '%s' or to '%s' value", from, to), ctx)
+		return
+	}
+
+	amount, err := decimal.NewFromString(ctx.DefaultQuery("amount", "1"))
+	if err != nil || !amount.IsPositive() {
+		c.httpResponse.BadRequest(fmt.Errorf("invalid amount value '%s'", ctx.Query("amount")), ctx)
+		return
+	}
+
+	// Process the request
+	req := models.ConversionRequest{
+		From:   from,
+		To:     to,
+		Amount: amount,
+	}
+
+	res, err := c.conversionService.Convert(req)
+	if errors.Is(err, services.ErrNoConversionPath) {
+		c.httpResponse.BadRequest(err, ctx)
+		return
+	}
+	if err != nil {
+		c.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	c.httpResponse.Success(res, ctx)
+}
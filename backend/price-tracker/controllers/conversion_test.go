@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockConversionService implements services.ConversionService
+type MockConversionService struct {
+	mock.Mock
+}
+
+func (m *MockConversionService) Convert(req models.ConversionRequest) (*models.Conversion, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Conversion), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestConversionControllerImpl_Convert(t *testing.T) {
+	type TestResponseData struct {
+		Meta response.Meta      `json:"meta"`
+		Data *models.Conversion `json:"data"`
+	}
+	type want struct {
+		res     TestResponseData
+		mockErr error
+	}
+	tests := []struct {
+		name string
+		path string
+		want want
+	}{
+		{
+			name: "missing to",
+			path: "/?from=ETH",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "missing from 'ETH' or to '' value",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid amount",
+			path: "/?from=ETH&to=EUR&amount=-1",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "invalid amount value '-1'",
+					},
+				},
+			},
+		},
+		{
+			name: "no path",
+			path: "/?from=ETH&to=JPY",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "no conversion path from 'ETH' to 'JPY'",
+					},
+				},
+				mockErr: fmt.Errorf("%w from 'ETH' to 'JPY'", services.ErrNoConversionPath),
+			},
+		},
+		{
+			name: "internal error",
+			path: "/?from=ETH&to=EUR",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    500,
+						Message: "error",
+					},
+				},
+				mockErr: errors.New("error"),
+			},
+		},
+		{
+			name: "success",
+			path: "/?from=eth&to=eur&amount=2.5",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: &models.Conversion{
+						From:   "ETH",
+						To:     "EUR",
+						Amount: decimal.RequireFromString("2.5"),
+						Rate:   decimal.RequireFromString("1600"),
+						Result: decimal.RequireFromString("4000"),
+						Path:   []string{"ETH", "USDT", "EUR"},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gin_test_setup.NewGinTestContext("GET", tt.path)
+
+			mockConversionService := &MockConversionService{}
+
+			c := &ConversionControllerImpl{
+				conversionService: mockConversionService,
+			}
+
+			mockConversionService.On("Convert", mock.Anything).Return(tt.want.res.Data, tt.want.mockErr)
+
+			c.Convert(ctx.Context)
+
+			var get TestResponseData
+			json.Unmarshal([]byte(ctx.GetResponseBody()), &get)
+
+			assert.Equal(t, tt.want.res.Meta, get.Meta)
+			assert.Equal(t, tt.want.res.Data, get.Data)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"backend/price-tracker/services"
+	"context"
+	"time"
+)
+
+type EmailWorker interface {
+	// Run sends the due emails every interval, until ctx is done
+	Run(ctx context.Context)
+}
+
+type EmailWorkerImpl struct {
+	emailService services.EmailService
+	interval     time.Duration
+}
+
+func NewEmailWorker(emailService services.EmailService, interval time.Duration) EmailWorker {
+	return &EmailWorkerImpl{
+		emailService: emailService,
+		interval:     interval,
+	}
+}
+
+// Run sends the due emails every interval, until ctx is done.
+// The alerts collected meanwhile are flushed on shutdown once the alerts bus is drained
+func (w *EmailWorkerImpl) Run(ctx context.Context) {
+	runPeriodically(ctx, w.interval, periodicTask{name: "send emails", run: w.emailService.SendDue})
+}
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"backend/internal/response"
+	"backend/price-tracker/services/bus"
+	"github.com/gin-gonic/gin"
+)
+
+type EventBusController interface {
+	// GetStats handles requests to get the counters of the event bus subscribers
+	GetStats(ctx *gin.Context)
+}
+
+type EventBusControllerImpl struct {
+	buses        []bus.StatsReporter
+	httpResponse response.CustomResponse
+}
+
+func NewEventBusController(buses ...bus.StatsReporter) EventBusController {
+	return &EventBusControllerImpl{
+		buses: buses,
+	}
+}
+
+// GetStats handles requests to get the queued, delivered and dropped events of every subscriber of every bus
+func (e *EventBusControllerImpl) GetStats(ctx *gin.Context) {
+	res := make([]bus.SubscriberStats, 0)
+	for _, b := range e.buses {
+		res = append(res, b.Stats()...)
+	}
+
+	e.httpResponse.Success(res, ctx)
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/services/bus"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBusControllerImpl_GetStats(t *testing.T) {
+	// Arrange
+	type TestResponseData struct {
+		Meta response.Meta         `json:"meta"`
+		Data []bus.SubscriberStats `json:"data"`
+	}
+	prices := bus.New[int]("prices")
+	prices.Subscribe("cache", 4, func(int) {})
+	stats := bus.New[string]("market_stats")
+	stats.Subscribe("database", 8, func(string) {})
+
+	ctx := gin_test_setup.NewGinTestContext("GET", "/admin/events")
+	e := NewEventBusController(prices, stats)
+
+	// Act
+	e.GetStats(ctx.Context)
+
+	// Assert
+	var get TestResponseData
+	json.Unmarshal([]byte(ctx.GetResponseBody()), &get)
+
+	assert.Equal(t, response.Meta{Code: 200, Message: "ok"}, get.Meta)
+	assert.Equal(t, []bus.SubscriberStats{
+		{Bus: "prices", Subscriber: "cache", Capacity: 4},
+		{Bus: "market_stats", Subscriber: "database", Capacity: 8},
+	}, get.Data)
+}
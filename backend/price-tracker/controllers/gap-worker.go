@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"backend/price-tracker/services"
+	"context"
+	"time"
+)
+
+type GapWorker interface {
+	// Run detects and repairs the gaps every interval, until ctx is done
+	Run(ctx context.Context)
+}
+
+type GapWorkerImpl struct {
+	gapService services.GapService
+	interval   time.Duration
+}
+
+func NewGapWorker(gapService services.GapService, interval time.Duration) GapWorker {
+	return &GapWorkerImpl{
+		gapService: gapService,
+		interval:   interval,
+	}
+}
+
+// Run detects and repairs the gaps every interval, until ctx is done
+func (g *GapWorkerImpl) Run(ctx context.Context) {
+	runPeriodically(ctx, g.interval,
+		periodicTask{name: "detect gaps", run: g.gapService.DetectGaps},
+		periodicTask{name: "repair gaps", run: g.gapService.RepairGaps},
+	)
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services"
+	"fmt"
+	"github.com/gin-gonic/gin"
+)
+
+type GapController interface {
+	// GetGaps handles requests to get the recorded gaps of the price series
+	GetGaps(ctx *gin.Context)
+}
+
+type GapControllerImpl struct {
+	gapService   services.GapService
+	httpResponse response.CustomResponse
+}
+
+func NewGapController(gapService services.GapService) GapController {
+	return &GapControllerImpl{
+		gapService: gapService,
+	}
+}
+
+// GetGaps handles requests to get the recorded gaps of the price series, filtered by status if given
+func (g *GapControllerImpl) GetGaps(ctx *gin.Context) {
+	// Verify the request
+	status := ctx.Query("status")
+	switch status {
+	case "", models.DataGapStatusOpen, models.DataGapStatusRepaired, models.DataGapStatusFailed:
+	default:
+		g.httpResponse.BadRequest(fmt.Errorf("invalid status value '%s'", status), ctx)
+		return
+	}
+
+	// Process the request
+	res, err := g.gapService.GetGaps(ctx.Request.Context(), status)
+	if err != nil {
+		g.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	g.httpResponse.Success(res, ctx)
+}
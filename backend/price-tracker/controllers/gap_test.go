@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockGapService implements services.GapService
+type MockGapService struct {
+	mock.Mock
+}
+
+func (m *MockGapService) DetectGaps(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockGapService) RepairGaps(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockGapService) GetGaps(ctx context.Context, status string) ([]models.DataGap, error) {
+	args := m.Called(status)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.DataGap), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func TestGapControllerImpl_GetGaps(t *testing.T) {
+	type TestResponseData struct {
+		Meta response.Meta    `json:"meta"`
+		Data []models.DataGap `json:"data"`
+	}
+	type want struct {
+		res     TestResponseData
+		status  string
+		mockErr error
+	}
+	gap := models.DataGap{
+		ID:         1,
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "binance",
+		StartTime:  time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+		EndTime:    time.Date(2025, 3, 1, 9, 10, 0, 0, time.UTC),
+		Status:     models.DataGapStatusOpen,
+		DetectedAt: time.Date(2025, 3, 1, 9, 15, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name string
+		path string
+		want want
+	}{
+		{
+			name: "invalid status",
+			path: "/?status=closed",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "invalid status value 'closed'",
+					},
+				},
+			},
+		},
+		{
+			name: "all gaps",
+			path: "/",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: []models.DataGap{gap},
+				},
+			},
+		},
+		{
+			name: "open gaps",
+			path: "/?status=open",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: []models.DataGap{gap},
+				},
+				status: "open",
+			},
+		},
+		{
+			name: "internal error",
+			path: "/?status=failed",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    500,
+						Message: "error",
+					},
+				},
+				status:  "failed",
+				mockErr: errors.New("error"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gin_test_setup.NewGinTestContext("GET", tt.path)
+
+			mockGapService := &MockGapService{}
+			mockGapService.On("GetGaps", tt.want.status).Return(tt.want.res.Data, tt.want.mockErr)
+
+			g := &GapControllerImpl{
+				gapService: mockGapService,
+			}
+
+			g.GetGaps(ctx.Context)
+
+			var get TestResponseData
+			json.Unmarshal([]byte(ctx.GetResponseBody()), &get)
+
+			assert.Equal(t, tt.want.res.Meta, get.Meta)
+			assert.Equal(t, tt.want.res.Data, get.Data)
+		})
+	}
+}
+
+func TestGapWorkerImpl_Run(t *testing.T) {
+	called := make(chan struct{}, 1)
+	mockGapService := &MockGapService{}
+	mockGapService.On("DetectGaps").Return(nil)
+	mockGapService.On("RepairGaps").Run(func(args mock.Arguments) {
+		select {
+		case called <- struct{}{}:
+		default:
+		}
+	}).Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		NewGapWorker(mockGapService, 10*time.Millisecond).Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-called:
+	case <-time.After(time.Second):
+		t.Fatal("RepairGaps was not called")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+	mockGapService.AssertCalled(t, "DetectGaps")
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/reconnect"
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController interface {
+	// GetHealth handles requests to get the status of the exchange workers
+	GetHealth(ctx *gin.Context)
+}
+
+type HealthControllerImpl struct {
+	workers      []PriceTrackingWorker
+	httpResponse response.CustomResponse
+}
+
+func NewHealthController(workers ...PriceTrackingWorker) HealthController {
+	return &HealthControllerImpl{
+		workers: workers,
+	}
+}
+
+// GetHealth handles requests to get the connection and circuit breaker of every exchange worker.
+// The service is degraded while a circuit is not closed, it still answers 200 as the API keeps serving
+func (h *HealthControllerImpl) GetHealth(ctx *gin.Context) {
+	res := models.Health{
+		Status:  models.HealthStatusOk,
+		Workers: make([]models.WorkerStatus, 0, len(h.workers)),
+	}
+	for _, worker := range h.workers {
+		status := worker.Status()
+		if status.Circuit != string(reconnect.StateClosed) {
+			res.Status = models.HealthStatusDegraded
+		}
+
+		res.Workers = append(res.Workers, status)
+	}
+
+	h.httpResponse.Success(res, ctx)
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/reconnect"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthControllerImpl_GetHealth(t *testing.T) {
+	type TestResponseData struct {
+		Meta response.Meta `json:"meta"`
+		Data models.Health `json:"data"`
+	}
+	cfg := config.PriceTrackerConfig{ReconnectInitialDelay: time.Second, ReconnectMaxDelay: time.Minute}
+
+	tests := []struct {
+		name       string
+		trip       bool
+		wantStatus string
+	}{
+		{name: "ok", wantStatus: models.HealthStatusOk},
+		{name: "degraded while a circuit is open", trip: true, wantStatus: models.HealthStatusDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			binance := reconnect.NewBreaker("binance", cfg)
+			if tt.trip {
+				binance.Trip(errors.New("http status 418"), time.Hour)
+			}
+			h := NewHealthController(
+				NewPriceTrackingWorker("binance", new(MockWebSocketFetcher), binance, cfg),
+				NewPriceTrackingWorker("kraken", new(MockWebSocketFetcher), reconnect.NewBreaker("kraken", cfg), cfg),
+			)
+			ctx := gin_test_setup.NewGinTestContext("GET", "/health")
+
+			// Act
+			h.GetHealth(ctx.Context)
+
+			// Assert
+			var get TestResponseData
+			json.Unmarshal([]byte(ctx.GetResponseBody()), &get)
+
+			assert.Equal(t, response.Meta{Code: 200, Message: "ok"}, get.Meta)
+			assert.Equal(t, tt.wantStatus, get.Data.Status)
+			assert.Len(t, get.Data.Workers, 2)
+			assert.Equal(t, "binance", get.Data.Workers[0].Source)
+			assert.Equal(t, models.WorkerConnectionWaiting, get.Data.Workers[0].Connection)
+		})
+	}
+}
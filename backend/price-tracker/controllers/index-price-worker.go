@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"backend/price-tracker/services"
+	"context"
+	"time"
+)
+
+type IndexPriceWorker interface {
+	// Run updates the index prices every interval, until ctx is done
+	Run(ctx context.Context)
+}
+
+type IndexPriceWorkerImpl struct {
+	indexPriceService services.IndexPriceService
+	interval          time.Duration
+}
+
+func NewIndexPriceWorker(indexPriceService services.IndexPriceService, interval time.Duration) IndexPriceWorker {
+	return &IndexPriceWorkerImpl{
+		indexPriceService: indexPriceService,
+		interval:          interval,
+	}
+}
+
+// Run updates the index prices every interval, until ctx is done
+func (i *IndexPriceWorkerImpl) Run(ctx context.Context) {
+	runPeriodically(ctx, i.interval, periodicTask{name: "update index prices", run: i.indexPriceService.UpdateIndexPrices})
+}
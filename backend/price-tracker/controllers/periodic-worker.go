@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// periodicTask is a step of a periodic worker, its errors are logged under its name
+type periodicTask struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// runPeriodically runs the tasks in order every interval, until ctx is done.
+// A task that fails is logged and does not keep the next ones from running
+func runPeriodically(ctx context.Context, interval time.Duration, tasks ...periodicTask) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, task := range tasks {
+			err := task.run(ctx)
+			if err != nil {
+				slog.Error(task.name, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"backend/price-tracker/models"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIndexPriceService implements services.IndexPriceService
+type MockIndexPriceService struct {
+	mock.Mock
+}
+
+func (m *MockIndexPriceService) UpdateIndexPrices(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// MockRetentionService implements services.RetentionService
+type MockRetentionService struct {
+	mock.Mock
+}
+
+func (m *MockRetentionService) EnforceRetention(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// MockEmailService implements services.EmailService
+type MockEmailService struct {
+	mock.Mock
+}
+
+func (m *MockEmailService) Add(event models.AlertEvent) {
+	m.Called(event)
+}
+
+func (m *MockEmailService) SendDue(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockEmailService) Flush(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func TestRunPeriodically(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran []string
+	tasks := []periodicTask{
+		{name: "first", run: func(ctx context.Context) error {
+			ran = append(ran, "first")
+			return errors.New("failed")
+		}},
+		{name: "second", run: func(ctx context.Context) error {
+			ran = append(ran, "second")
+			// Stop after the second round
+			if len(ran) == 4 {
+				cancel()
+			}
+			return nil
+		}},
+	}
+
+	// Act
+	stopped := make(chan struct{})
+	go func() {
+		runPeriodically(ctx, time.Millisecond, tasks...)
+		close(stopped)
+	}()
+
+	// Assert, the failure of the first task does not skip the second
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+	assert.Equal(t, []string{"first", "second", "first", "second"}, ran)
+}
+
+func TestPeriodicWorkers_Run(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	indexPriceService := &MockIndexPriceService{}
+	retentionService := &MockRetentionService{}
+	webhookService := &MockWebhookService{}
+	emailService := &MockEmailService{}
+	gapService := &MockGapService{}
+
+	tests := []struct {
+		name   string
+		mock   *mock.Mock
+		method string
+		run    func(ctx context.Context)
+	}{
+		{
+			name:   "index price",
+			mock:   &indexPriceService.Mock,
+			method: "UpdateIndexPrices",
+			run:    NewIndexPriceWorker(indexPriceService, interval).Run,
+		},
+		{
+			name:   "retention",
+			mock:   &retentionService.Mock,
+			method: "EnforceRetention",
+			run:    NewRetentionWorker(retentionService, interval).Run,
+		},
+		{
+			name:   "webhook",
+			mock:   &webhookService.Mock,
+			method: "DeliverDue",
+			run:    NewWebhookWorker(webhookService, interval).Run,
+		},
+		{
+			name:   "email",
+			mock:   &emailService.Mock,
+			method: "SendDue",
+			run:    NewEmailWorker(emailService, interval).Run,
+		},
+		{
+			name:   "gap",
+			mock:   &gapService.Mock,
+			method: "RepairGaps",
+			run:    NewGapWorker(gapService, interval).Run,
+		},
+	}
+	gapService.On("DetectGaps").Return(nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			called := make(chan struct{}, 1)
+			tt.mock.On(tt.method).Run(func(args mock.Arguments) {
+				select {
+				case called <- struct{}{}:
+				default:
+				}
+			}).Return(nil)
+			ctx, cancel := context.WithCancel(context.Background())
+
+			// Act
+			stopped := make(chan struct{})
+			go func() {
+				tt.run(ctx)
+				close(stopped)
+			}()
+
+			// Assert, the task runs every interval until ctx is done
+			select {
+			case <-called:
+			case <-time.After(time.Second):
+				t.Fatalf("%s was not called", tt.method)
+			}
+			cancel()
+			select {
+			case <-stopped:
+			case <-time.After(time.Second):
+				t.Fatal("worker did not stop")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/internal/constant"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PriceStreamController interface {
+	// StreamPrices handles requests to stream the prices of symbols as Server-Sent Events
+	StreamPrices(ctx *gin.Context)
+}
+
+type PriceStreamControllerImpl struct {
+	priceTrackingService services.PriceTrackingService
+	priceStreamService   services.PriceStreamService
+	cfg                  config.StreamConfig
+	httpResponse         response.CustomResponse
+}
+
+func NewPriceStreamController(
+	priceTrackingService services.PriceTrackingService,
+	priceStreamService services.PriceStreamService,
+	cfg config.StreamConfig,
+) PriceStreamController {
+	return &PriceStreamControllerImpl{
+		priceTrackingService: priceTrackingService,
+		priceStreamService:   priceStreamService,
+		cfg:                  cfg,
+	}
+}
+
+// StreamPrices handles requests to stream the prices of symbols as Server-Sent Events.
+// Each price is a "price" event whose id is its unix time in milliseconds. A client reconnecting
+// with Last-Event-ID is first sent the stored prices since that time, those at that very time included
+func (p *PriceStreamControllerImpl) StreamPrices(ctx *gin.Context) {
+	// Verify the request
+	req, ok := p.getStreamRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if lastEventID := ctx.GetHeader("Last-Event-ID"); lastEventID != "" {
+		millis, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			p.httpResponse.BadRequest(fmt.Errorf("invalid Last-Event-ID value '%s'", lastEventID), ctx)
+			return
+		}
+
+		since = time.UnixMilli(millis).UTC()
+	}
+
+	// Process the request, subscribing before reading the missed prices so none is lost in between
+	sub := p.priceStreamService.Subscribe(req)
+	defer p.priceStreamService.Unsubscribe(sub)
+
+	var missed []models.PriceDatum
+	if !since.IsZero() {
+		req.Since = since
+
+		var err error
+		missed, err = p.priceStreamService.GetMissedPrices(ctx.Request.Context(), req)
+		if err != nil {
+			p.httpResponse.InternalServerError(err, ctx)
+			return
+		}
+	}
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Keep reverse proxies such as nginx from buffering the events
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	err := writePriceEvents(ctx.Writer, missed)
+	if err != nil {
+		return
+	}
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(p.cfg.StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			slog.Info("price stream closed", "symbols", req.Symbols, "dropped", sub.Dropped())
+			return
+		case <-sub.Ready():
+			err = writePriceEvents(ctx.Writer, sub.Next())
+		case <-heartbeat.C:
+			_, err = io.WriteString(ctx.Writer, ": heartbeat\n\n")
+		}
+		if err != nil {
+			slog.Info("price stream closed", "symbols", req.Symbols, "dropped", sub.Dropped(), "err", err)
+			return
+		}
+
+		ctx.Writer.Flush()
+	}
+}
+
+// getStreamRequest returns the symbols, currency and source to stream from query params,
+// symbols are comma separated and currency defaults to USDT
+func (p *PriceStreamControllerImpl) getStreamRequest(ctx *gin.Context) (models.PriceStreamRequest, bool) {
+	currency := strings.ToUpper(ctx.DefaultQuery("currency", constant.USDT))
+
+	var symbols []string
+	for _, symbol := range strings.Split(ctx.Query("symbols"), ",") {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" {
+			continue
+		}
+
+		if !p.priceTrackingService.IsSymbolValid(symbol, currency) {
+			p.httpResponse.BadRequest(fmt.Errorf("invalid symbol value '%s' for currency '%s'", symbol, currency), ctx)
+			return models.PriceStreamRequest{}, false
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	if len(symbols) == 0 {
+		p.httpResponse.BadRequest(errors.New("missing symbols value"), ctx)
+		return models.PriceStreamRequest{}, false
+	}
+	if len(symbols) > p.cfg.StreamMaxSymbols {
+		p.httpResponse.BadRequest(fmt.Errorf("too many symbols, at most %d can be streamed", p.cfg.StreamMaxSymbols), ctx)
+		return models.PriceStreamRequest{}, false
+	}
+
+	return models.PriceStreamRequest{
+		Symbols:  symbols,
+		Currency: currency,
+		Source:   ctx.Query("source"),
+	}, true
+}
+
+// writePriceEvents writes each price as a "price" event identified by its unix time in milliseconds
+func writePriceEvents(w io.Writer, data []models.PriceDatum) error {
+	for _, datum := range data {
+		body, err := json.Marshal(datum)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+
+		_, err = fmt.Fprintf(w, "id: %d\nevent: price\ndata: %s\n\n", datum.Timestamp.UnixMilli(), body)
+		if err != nil {
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	repomock "backend/price-tracker/repositories/mock"
+	"backend/price-tracker/services"
+	"backend/price-tracker/services/stream"
+	"bufio"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newPriceStreamServer(t *testing.T, broadcaster stream.PriceBroadcaster, repository *repomock.MockRepository) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	mockPriceTrackingService := new(MockPriceTrackingService)
+	mockPriceTrackingService.On("IsSymbolValid", "BTC", "USDT").Return(true)
+	mockPriceTrackingService.On("IsSymbolValid", mock.Anything, mock.Anything).Return(false)
+
+	cfg := config.StreamConfig{StreamHeartbeat: 50 * time.Millisecond, StreamMaxSymbols: 2, StreamResumeLimit: 10}
+	controller := NewPriceStreamController(
+		mockPriceTrackingService, services.NewPriceStreamService(broadcaster, repository, cfg), cfg)
+
+	r := gin.New()
+	r.GET("/price/stream", controller.StreamPrices)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// readEvent returns the next event or comment of the stream, without its trailing blank line
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestPriceStreamControllerImpl_StreamPrices(t *testing.T) {
+	// Arrange
+	broadcaster := stream.NewPriceBroadcaster()
+	mockRepo := new(repomock.MockRepository)
+	missed := []models.PriceDatum{
+		{Timestamp: time.UnixMilli(1700000000000).UTC(), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")},
+	}
+	mockRepo.On("GetPricesSince", models.PriceStreamRequest{
+		Symbols:  []string{"BTC"},
+		Currency: "USDT",
+		Since:    time.UnixMilli(1700000000000).UTC(),
+		Limit:    10,
+	}).Return(missed, nil)
+	server := newPriceStreamServer(t, broadcaster, mockRepo)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/price/stream?symbols=btc", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1700000000000")
+
+	// Act
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	assert.Equal(t,
+		`id: 1700000000000`+"\n"+`event: price`+"\n"+`data: {"timestamp":"2023-11-14T22:13:20Z","symbol":"BTC","currency":"USDT","source":"binance","price":"100"}`,
+		readEvent(t, reader))
+
+	broadcaster.Publish([]models.PriceDatum{
+		{Timestamp: time.UnixMilli(1700000001000).UTC(), Symbol: "ETH", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("10")},
+		{Timestamp: time.UnixMilli(1700000001000).UTC(), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("101")},
+	})
+	event := readEvent(t, reader)
+	assert.True(t, strings.HasPrefix(event, "id: 1700000001000\nevent: price\n"), event)
+	assert.Contains(t, event, `"price":"101"`)
+
+	assert.Equal(t, ": heartbeat", readEvent(t, reader))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPriceStreamControllerImpl_StreamPrices_BadRequest(t *testing.T) {
+	server := newPriceStreamServer(t, stream.NewPriceBroadcaster(), new(repomock.MockRepository))
+
+	tests := []struct {
+		name        string
+		query       string
+		lastEventID string
+	}{
+		{name: "missing symbols", query: ""},
+		{name: "invalid symbol", query: "?symbols=BTC,NOPE"},
+		{name: "too many symbols", query: "?symbols=BTC,BTC,BTC"},
+		{name: "invalid last event id", query: "?symbols=BTC", lastEventID: "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/price/stream"+tt.query, nil)
+			require.NoError(t, err)
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		})
+	}
+}
@@ -1,22 +1,36 @@
 package controllers
 
 import (
+	"backend/internal/constant"
 	"backend/internal/response"
 	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
 	"backend/price-tracker/services"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxCandles bounds the number of candles returned by a single request
+	maxCandles = 1500
+	// defaultHistoryPoints and maxHistoryPoints bound the number of prices returned by a history request
+	defaultHistoryPoints = 30
+	maxHistoryPoints     = 1000
 )
 
 type PriceTrackerController interface {
 	// GetLatestPrice handles requests to get the latest price of an symbol
 	GetLatestPrice(ctx *gin.Context)
-	// GetPriceHistory handles requests to get the price history
-	// current supports only 24h interval
+	// GetPriceHistory handles requests to get the price history of a preset interval or a from/to range
 	GetPriceHistory(ctx *gin.Context)
 	// GetCryptoList handles requests to get list of crypto
 	GetCryptoList(ctx *gin.Context)
+	// GetCandles handles requests to get the candles of a symbol
+	GetCandles(ctx *gin.Context)
 }
 
 type PriceTrackerControllerImpl struct {
@@ -30,47 +44,71 @@ func NewPriceTrackerController(priceTrackingService services.PriceTrackingServic
 	}
 }
 
-// GetLatestPrice handles requests to get the latest price of an symbol
+// GetLatestPrice handles requests to get the latest price of an symbol, the meta tells how long ago it was received
+// and whether it is stale. A price older than the max age is answered by 503
 func (p *PriceTrackerControllerImpl) GetLatestPrice(ctx *gin.Context) {
 	// Verify the request
-	symbol, ok := p.getSymbol(ctx)
+	symbol, currency, ok := p.getSymbol(ctx)
 	if !ok {
 		return
 	}
 
 	// Process the request
 	req := models.PriceDatum{
-		Symbol: symbol,
+		Symbol:   symbol,
+		Currency: currency,
+		Source:   ctx.Query("source"),
 	}
 
-	res, err := p.priceTrackingService.GetLatestPrice(req)
+	res, freshness, err := p.priceTrackingService.GetLatestPrice(ctx.Request.Context(), req)
+	if errors.Is(err, services.ErrStalePrice) {
+		p.httpResponse.ServiceUnavailableWithAge(err, freshness.Age, ctx)
+		return
+	}
 	if err != nil {
 		p.httpResponse.InternalServerError(err, ctx)
 		return
 	}
 
-	p.httpResponse.Success(res, ctx)
+	p.httpResponse.SuccessWithAge(res, freshness.Age, freshness.Stale, ctx)
 }
 
-// GetPriceHistory handles requests to get the price history
-// current supports only 24h interval
+// GetPriceHistory handles requests to get the price history of a preset interval or a from/to range,
+// downsampled to a number of points or a step. It defaults to the last 24h of the prices of the busiest source
 func (p *PriceTrackerControllerImpl) GetPriceHistory(ctx *gin.Context) {
 	// Verify the request
-	symbol, ok := p.getSymbol(ctx)
+	symbol, currency, ok := p.getSymbol(ctx)
 	if !ok {
 		return
 	}
 
-	if _, ok = p.getInterval(ctx); !ok {
+	from, to, ok := p.getRange(ctx)
+	if !ok {
+		return
+	}
+
+	points, step, ok := p.getResolution(ctx, from, to)
+	if !ok {
 		return
 	}
 
 	// Process the request
-	req := models.PriceDatum{
-		Symbol: symbol,
+	req := models.PriceHistoryRequest{
+		Symbol:    symbol,
+		Currency:  currency,
+		Source:    ctx.Query("source"),
+		From:      from,
+		To:        to,
+		Points:    points,
+		Step:      step,
+		MaxPoints: maxHistoryPoints,
 	}
 
-	res, err := p.priceTrackingService.GetPriceOfTheLast24h(req)
+	res, err := p.priceTrackingService.GetPriceHistory(ctx.Request.Context(), req)
+	if errors.Is(err, repositories.ErrTooManyPoints) {
+		p.httpResponse.BadRequest(fmt.Errorf("range exceeds %d points", maxHistoryPoints), ctx)
+		return
+	}
 	if err != nil {
 		p.httpResponse.InternalServerError(err, ctx)
 		return
@@ -79,35 +117,150 @@ func (p *PriceTrackerControllerImpl) GetPriceHistory(ctx *gin.Context) {
 	p.httpResponse.Success(res, ctx)
 }
 
-// getSymbol returns symbol value from query params if exist
-func (p *PriceTrackerControllerImpl) getSymbol(ctx *gin.Context) (string, bool) {
+// GetCandles handles requests to get the candles of a symbol at a resolution,
+// the range defaults to the last maxCandles candles of the busiest source
+func (p *PriceTrackerControllerImpl) GetCandles(ctx *gin.Context) {
+	// Verify the request
+	symbol, currency, ok := p.getSymbol(ctx)
+	if !ok {
+		return
+	}
+
+	resolution, found := models.ParseCandleResolution(ctx.DefaultQuery("resolution", "1m"))
+	if !found {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid resolution value '%s'", ctx.Query("resolution")), ctx)
+		return
+	}
+
+	to, err := parseTime(ctx.Query("to"), time.Now())
+	if err != nil {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid to value: %w", err), ctx)
+		return
+	}
+
+	from, err := parseTime(ctx.Query("from"), to.Add(-(maxCandles-1)*resolution.Duration))
+	if err != nil {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid from value: %w", err), ctx)
+		return
+	}
+
+	if !from.Before(to) {
+		p.httpResponse.BadRequest(errors.New("from must be before to"), ctx)
+		return
+	}
+
+	// The candle open at from is returned, the range starts at its open
+	if to.Sub(from.Truncate(resolution.Duration)) > maxCandles*resolution.Duration {
+		p.httpResponse.BadRequest(fmt.Errorf("range exceeds %d candles", maxCandles), ctx)
+		return
+	}
+
+	// Process the request
+	req := models.CandleRequest{
+		Symbol:     symbol,
+		Currency:   currency,
+		Source:     ctx.Query("source"),
+		Resolution: resolution,
+		From:       from,
+		To:         to,
+	}
+
+	res, err := p.priceTrackingService.GetCandles(ctx.Request.Context(), req)
+	if err != nil {
+		p.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	p.httpResponse.Success(res, ctx)
+}
+
+// getSymbol returns symbol and currency values from query params if the pair exist,
+// currency defaults to USDT
+func (p *PriceTrackerControllerImpl) getSymbol(ctx *gin.Context) (string, string, bool) {
 	symbol := ctx.Query("symbol")
 	if symbol == "" {
 		p.httpResponse.BadRequest(fmt.Errorf("missing symbol value '%s'", symbol), ctx)
-		return "", false
+		return "", "", false
+	}
+
+	currency := strings.ToUpper(ctx.DefaultQuery("currency", constant.USDT))
+
+	if !p.priceTrackingService.IsSymbolValid(symbol, currency) {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid symbol value '%s' for currency '%s'", symbol, currency), ctx)
+		return "", "", false
+	}
+
+	return symbol, currency, true
+}
+
+// getRange returns the history range from the from/to query params,
+// or else from the interval preset ending now. A zero from means all stored prices
+func (p *PriceTrackerControllerImpl) getRange(ctx *gin.Context) (time.Time, time.Time, bool) {
+	to, err := parseTime(ctx.Query("to"), time.Now().UTC())
+	if err != nil {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid to value: %w", err), ctx)
+		return time.Time{}, time.Time{}, false
+	}
+
+	if ctx.Query("from") == "" {
+		interval := ctx.DefaultQuery("interval", "24h")
+		duration, found := models.PriceHistoryPresets[interval]
+		if !found {
+			p.httpResponse.BadRequest(fmt.Errorf("invalid interval value '%s', supported values are 1h, 24h, 7d, 30d, 1y and all", interval), ctx)
+			return time.Time{}, time.Time{}, false
+		}
+		if duration == 0 {
+			return time.Time{}, to, true
+		}
+
+		return to.Add(-duration), to, true
+	}
+
+	from, err := parseTime(ctx.Query("from"), time.Time{})
+	if err != nil {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid from value: %w", err), ctx)
+		return time.Time{}, time.Time{}, false
 	}
 
-	if !p.priceTrackingService.IsSymbolValid(symbol) {
-		p.httpResponse.BadRequest(fmt.Errorf("invalid symbol value '%s'", symbol), ctx)
-		return "", false
+	if !from.Before(to) {
+		p.httpResponse.BadRequest(errors.New("from must be before to"), ctx)
+		return time.Time{}, time.Time{}, false
 	}
 
-	return symbol, true
+	return from, to, true
 }
 
-// getInterval returns interval value from query params if exist
-func (p *PriceTrackerControllerImpl) getInterval(ctx *gin.Context) (string, bool) {
-	interval := ctx.Query("interval")
-	if interval == "" {
-		p.httpResponse.BadRequest(errors.New("interval only support '24' hours"), ctx)
-		return "", false
+// getResolution returns the number of points, or the step between prices if given,
+// of the history in the range
+func (p *PriceTrackerControllerImpl) getResolution(ctx *gin.Context, from, to time.Time) (int, time.Duration, bool) {
+	if value := ctx.Query("step"); value != "" {
+		step, err := time.ParseDuration(value)
+		if err != nil || step <= 0 {
+			p.httpResponse.BadRequest(fmt.Errorf("invalid step value '%s'", value), ctx)
+			return 0, 0, false
+		}
+
+		// Ranges over all prices are checked against MaxPoints by the repository, once it found the first price
+		if !from.IsZero() && to.Sub(from)/step > maxHistoryPoints {
+			p.httpResponse.BadRequest(fmt.Errorf("range exceeds %d points", maxHistoryPoints), ctx)
+			return 0, 0, false
+		}
+
+		return 0, step, true
+	}
+
+	value := ctx.DefaultQuery("points", strconv.Itoa(defaultHistoryPoints))
+	points, err := strconv.Atoi(value)
+	if err != nil || points <= 0 || points > maxHistoryPoints {
+		p.httpResponse.BadRequest(fmt.Errorf("invalid points value '%s', must be between 1 and %d", value, maxHistoryPoints), ctx)
+		return 0, 0, false
 	}
 
-	return interval, true
+	return points, 0, true
 }
 
 func (p *PriceTrackerControllerImpl) GetCryptoList(ctx *gin.Context) {
-	list, err := p.priceTrackingService.GetCryptoList()
+	list, err := p.priceTrackingService.GetCryptoList(ctx.Request.Context())
 	if err != nil {
 		p.httpResponse.InternalServerError(err, ctx)
 		return
@@ -115,3 +268,21 @@ func (p *PriceTrackerControllerImpl) GetCryptoList(ctx *gin.Context) {
 
 	p.httpResponse.Success(list, ctx)
 }
+
+// parseTime parses a time given as unix seconds or RFC3339, an empty value returns fallback
+func parseTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' is neither unix seconds nor RFC3339", value)
+	}
+
+	return t.UTC(), nil
+}
@@ -4,9 +4,15 @@ import (
 	gin_test_setup "backend/internal/gin-test-setup"
 	"backend/internal/response"
 	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,25 +23,25 @@ type MockPriceTrackingService struct {
 	mock.Mock
 }
 
-func (m *MockPriceTrackingService) GetCryptoList() ([]models.PriceDatum, error) {
+func (m *MockPriceTrackingService) GetCryptoList(ctx context.Context) ([]models.PriceDatum, error) {
 	args := m.Called()
 	return args.Get(0).([]models.PriceDatum), args.Error(1)
 }
 
-func (m *MockPriceTrackingService) IsSymbolValid(symbol string) bool {
-	args := m.Called(symbol)
+func (m *MockPriceTrackingService) IsSymbolValid(symbol, currency string) bool {
+	args := m.Called(symbol, currency)
 	return args.Bool(0)
 }
 
-func (m *MockPriceTrackingService) GetLatestPrice(req models.PriceDatum) (*models.PriceDatum, error) {
+func (m *MockPriceTrackingService) GetLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, models.Freshness, error) {
 	args := m.Called(req)
 	if args.Get(0) != nil {
-		return args.Get(0).(*models.PriceDatum), args.Error(1)
+		return args.Get(0).(*models.PriceDatum), args.Get(1).(models.Freshness), args.Error(2)
 	}
-	return nil, args.Error(1)
+	return nil, args.Get(1).(models.Freshness), args.Error(2)
 }
 
-func (m *MockPriceTrackingService) GetPriceOfTheLast24h(req models.PriceDatum) ([]models.PriceDatum, error) {
+func (m *MockPriceTrackingService) GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error) {
 	args := m.Called(req)
 	if args.Get(0) != nil {
 		return args.Get(0).([]models.PriceDatum), args.Error(1)
@@ -43,6 +49,14 @@ func (m *MockPriceTrackingService) GetPriceOfTheLast24h(req models.PriceDatum) (
 	return nil, args.Error(1)
 }
 
+func (m *MockPriceTrackingService) GetCandles(ctx context.Context, req models.CandleRequest) ([]models.Candle, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.Candle), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func TestPriceTrackerControllerImpl_GetLatestPrice(t *testing.T) {
 	type TestResponseData struct {
 		Meta response.Meta      `json:"meta"`
@@ -53,9 +67,12 @@ func TestPriceTrackerControllerImpl_GetLatestPrice(t *testing.T) {
 		valid bool
 	}
 	type want struct {
-		res     TestResponseData
-		mockErr error
+		res       TestResponseData
+		freshness models.Freshness
+		mockErr   error
 	}
+	ageMs := func(ms int64) *int64 { return &ms }
+	stale := func(stale bool) *bool { return &stale }
 	tests := []struct {
 		name string
 		args args
@@ -87,7 +104,7 @@ func TestPriceTrackerControllerImpl_GetLatestPrice(t *testing.T) {
 				res: TestResponseData{
 					Meta: response.Meta{
 						Code:    400,
-						Message: "invalid symbol value 'HUHU'",
+						Message: "invalid symbol value 'HUHU' for currency 'USD'",
 					},
 					Data: nil,
 				},
@@ -104,13 +121,58 @@ func TestPriceTrackerControllerImpl_GetLatestPrice(t *testing.T) {
 					Meta: response.Meta{
 						Code:    200,
 						Message: "ok",
+						AgeMs:   ageMs(1500),
+						Stale:   stale(false),
+					},
+					Data: &models.PriceDatum{
+						Symbol:   "BTC",
+						Currency: "USDT",
+						Price:    decimal.RequireFromString("1"),
+					},
+				},
+				freshness: models.Freshness{Age: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name: "stale",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+						AgeMs:   ageMs(90000),
+						Stale:   stale(true),
 					},
 					Data: &models.PriceDatum{
 						Symbol:   "BTC",
 						Currency: "USDT",
-						Price:    1,
+						Price:    decimal.RequireFromString("1"),
 					},
 				},
+				freshness: models.Freshness{Age: 90 * time.Second, Stale: true},
+			},
+		},
+		{
+			name: "too old",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    503,
+						Message: "stale price, the latest BTC/USDT was received 1h0m0s ago",
+						AgeMs:   ageMs(3600000),
+						Stale:   stale(true),
+					},
+				},
+				freshness: models.Freshness{Age: time.Hour, Stale: true},
+				mockErr:   fmt.Errorf("%w, the latest BTC/USDT was received 1h0m0s ago", services.ErrStalePrice),
 			},
 		},
 		{
@@ -142,13 +204,14 @@ func TestPriceTrackerControllerImpl_GetLatestPrice(t *testing.T) {
 				priceTrackingService: mockPriceTrackingService,
 			}
 
-			mockPriceTrackingService.On("GetLatestPrice", mock.Anything).Return(tt.want.res.Data, tt.want.mockErr)
-			mockPriceTrackingService.On("IsSymbolValid", mock.Anything).Return(tt.args.valid)
+			mockPriceTrackingService.On("GetLatestPrice", mock.Anything).Return(tt.want.res.Data, tt.want.freshness, tt.want.mockErr)
+			mockPriceTrackingService.On("IsSymbolValid", mock.Anything, mock.Anything).Return(tt.args.valid)
 			p.GetLatestPrice(ctx.Context)
 
 			var get TestResponseData
 			json.Unmarshal([]byte(ctx.GetResponseBody()), &get)
 
+			assert.Equal(t, tt.want.res.Meta.Code, ctx.GetResponseCode())
 			assert.Equal(t, tt.want.res.Meta, get.Meta)
 			assert.Equal(t, tt.want.res.Data, get.Data)
 
@@ -156,6 +219,44 @@ func TestPriceTrackerControllerImpl_GetLatestPrice(t *testing.T) {
 	}
 }
 
+func TestPriceTrackerControllerImpl_GetLatestPrice_Currency(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		currency string
+	}{
+		{
+			name:     "default currency",
+			path:     "/?symbol=BTC",
+			currency: "USDT",
+		},
+		{
+			name:     "given currency",
+			path:     "/?symbol=BTC&currency=eur",
+			currency: "EUR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gin_test_setup.NewGinTestContext("GET", tt.path)
+
+			mockPriceTrackingService := &MockPriceTrackingService{}
+
+			p := &PriceTrackerControllerImpl{
+				priceTrackingService: mockPriceTrackingService,
+			}
+
+			req := models.PriceDatum{Symbol: "BTC", Currency: tt.currency}
+			mockPriceTrackingService.On("IsSymbolValid", "BTC", tt.currency).Return(true)
+			mockPriceTrackingService.On("GetLatestPrice", req).Return(&req, models.Freshness{}, nil)
+
+			p.GetLatestPrice(ctx.Context)
+
+			mockPriceTrackingService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestNewPriceTrackerController(t *testing.T) {
 	mockPriceTrackingService := &MockPriceTrackingService{}
 	NewPriceTrackerController(mockPriceTrackingService)
@@ -205,28 +306,115 @@ func TestPriceTrackerControllerImpl_GetPriceHistory(t *testing.T) {
 				res: TestResponseData{
 					Meta: response.Meta{
 						Code:    400,
-						Message: "invalid symbol value 'AAA'",
+						Message: "invalid symbol value 'AAA' for currency 'USD'",
+					},
+					Data: nil,
+				},
+			},
+		},
+		{
+			name: "wrong interval",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT&interval=2w",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "invalid interval value '2w', supported values are 1h, 24h, 7d, 30d, 1y and all",
+					},
+					Data: nil,
+				},
+			},
+		},
+		{
+			name: "from after to",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT&from=1700003600&to=1700000000",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "from must be before to",
+					},
+					Data: nil,
+				},
+			},
+		},
+		{
+			name: "invalid points",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT&interval=7d&points=5000",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "invalid points value '5000', must be between 1 and 1000",
 					},
 					Data: nil,
 				},
 			},
 		},
 		{
-			name: "wrong/missing interval",
+			name: "step too small for range",
 			args: args{
-				path:  "/?symbol=BTC&currency=USDT&interval=",
+				path:  "/?symbol=BTC&currency=USDT&interval=1y&step=1m",
 				valid: true,
 			},
 			want: want{
 				res: TestResponseData{
 					Meta: response.Meta{
 						Code:    400,
-						Message: "interval only support '24' hours",
+						Message: "range exceeds 1000 points",
 					},
 					Data: nil,
 				},
 			},
 		},
+		{
+			name: "step too small for all prices",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT&interval=all&step=1s",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "range exceeds 1000 points",
+					},
+					Data: nil,
+				},
+				mockErr: fmt.Errorf("%w, 1s from 2024-01-01 00:00:00 +0000 UTC exceeds 1000", repositories.ErrTooManyPoints),
+			},
+		},
+		{
+			name: "success with range and step",
+			args: args{
+				path:  "/?symbol=BTC&currency=USDT&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&step=1h",
+				valid: true,
+			},
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: []models.PriceDatum{
+						{
+							Symbol:   "BTC",
+							Currency: "USDT",
+							Price:    decimal.RequireFromString("1"),
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "success",
 			args: args{
@@ -243,7 +431,7 @@ func TestPriceTrackerControllerImpl_GetPriceHistory(t *testing.T) {
 						{
 							Symbol:   "BTC",
 							Currency: "USDT",
-							Price:    1,
+							Price:    decimal.RequireFromString("1"),
 						},
 					},
 				},
@@ -277,8 +465,8 @@ func TestPriceTrackerControllerImpl_GetPriceHistory(t *testing.T) {
 				priceTrackingService: mockPriceTrackingService,
 			}
 
-			mockPriceTrackingService.On("GetPriceOfTheLast24h", mock.Anything).Return(tt.want.res.Data, tt.want.mockErr)
-			mockPriceTrackingService.On("IsSymbolValid", mock.Anything).Return(tt.args.valid)
+			mockPriceTrackingService.On("GetPriceHistory", mock.Anything).Return(tt.want.res.Data, tt.want.mockErr)
+			mockPriceTrackingService.On("IsSymbolValid", mock.Anything, mock.Anything).Return(tt.args.valid)
 
 			p.GetPriceHistory(ctx.Context)
 
@@ -291,6 +479,52 @@ func TestPriceTrackerControllerImpl_GetPriceHistory(t *testing.T) {
 	}
 }
 
+func TestPriceTrackerControllerImpl_GetPriceHistory_Source(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		source string
+	}{
+		{
+			name:   "any source by default",
+			path:   "/?symbol=BTC&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&points=24",
+			source: "",
+		},
+		{
+			name:   "index",
+			path:   "/?symbol=BTC&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&points=24&source=index",
+			source: "index",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gin_test_setup.NewGinTestContext("GET", tt.path)
+
+			mockPriceTrackingService := &MockPriceTrackingService{}
+
+			p := &PriceTrackerControllerImpl{
+				priceTrackingService: mockPriceTrackingService,
+			}
+
+			req := models.PriceHistoryRequest{
+				Symbol:    "BTC",
+				Currency:  "USDT",
+				Source:    tt.source,
+				From:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				To:        time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+				Points:    24,
+				MaxPoints: maxHistoryPoints,
+			}
+			mockPriceTrackingService.On("IsSymbolValid", "BTC", "USDT").Return(true)
+			mockPriceTrackingService.On("GetPriceHistory", req).Return([]models.PriceDatum{}, nil)
+
+			p.GetPriceHistory(ctx.Context)
+
+			mockPriceTrackingService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestPriceTrackerControllerImpl_GetCryptoList(t *testing.T) {
 	type TestResponseData struct {
 		Meta response.Meta       `json:"meta"`
@@ -373,3 +607,195 @@ func TestPriceTrackerControllerImpl_GetCryptoList(t *testing.T) {
 		})
 	}
 }
+
+func TestPriceTrackerControllerImpl_GetCandles(t *testing.T) {
+	type TestResponseData struct {
+		Meta response.Meta   `json:"meta"`
+		Data []models.Candle `json:"data"`
+	}
+	type want struct {
+		res     TestResponseData
+		req     *models.CandleRequest
+		mockErr error
+	}
+	minute, _ := models.ParseCandleResolution("1m")
+	hour, _ := models.ParseCandleResolution("1h")
+	tests := []struct {
+		name string
+		path string
+		want want
+	}{
+		{
+			name: "invalid resolution",
+			path: "/?symbol=BTC&resolution=2h",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "invalid resolution value '2h'",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid from",
+			path: "/?symbol=BTC&resolution=1h&from=yesterday",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "invalid from value: 'yesterday' is neither unix seconds nor RFC3339",
+					},
+				},
+			},
+		},
+		{
+			name: "from after to",
+			path: "/?symbol=BTC&resolution=1h&from=1740830400&to=1740826800",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "from must be before to",
+					},
+				},
+			},
+		},
+		{
+			name: "too many candles",
+			path: "/?symbol=BTC&resolution=1m&from=2025-01-01T00:00:00Z&to=2025-03-01T00:00:00Z",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "range exceeds 1500 candles",
+					},
+				},
+			},
+		},
+		{
+			name: "too many candles from the open of the first",
+			path: "/?symbol=BTC&resolution=1m&from=2025-01-01T00:00:30Z&to=2025-01-02T01:00:01Z",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    400,
+						Message: "range exceeds 1500 candles",
+					},
+				},
+			},
+		},
+		{
+			name: "most candles",
+			path: "/?symbol=BTC&resolution=1m&from=2025-01-01T00:00:30Z&to=2025-01-02T01:00:00Z",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: []models.Candle{},
+				},
+				req: &models.CandleRequest{
+					Symbol:     "BTC",
+					Currency:   "USDT",
+					Resolution: minute,
+					From:       time.Date(2025, 1, 1, 0, 0, 30, 0, time.UTC),
+					To:         time.Date(2025, 1, 2, 1, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "success",
+			path: "/?symbol=BTC&currency=EUR&source=kraken&resolution=1h&from=1740819600&to=2025-03-01T10:00:00Z",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: []models.Candle{
+						{
+							Symbol:     "BTC",
+							Currency:   "EUR",
+							Source:     "kraken",
+							Resolution: "1h",
+							Open:       decimal.RequireFromString("1"),
+							High:       decimal.RequireFromString("2"),
+							Low:        decimal.RequireFromString("1"),
+							Close:      decimal.RequireFromString("2"),
+							Count:      3,
+						},
+					},
+				},
+				req: &models.CandleRequest{
+					Symbol:     "BTC",
+					Currency:   "EUR",
+					Source:     "kraken",
+					Resolution: hour,
+					From:       time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+					To:         time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "any source by default",
+			path: "/?symbol=BTC&resolution=1h&from=1740819600&to=2025-03-01T10:00:00Z",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    200,
+						Message: "ok",
+					},
+					Data: []models.Candle{},
+				},
+				req: &models.CandleRequest{
+					Symbol:     "BTC",
+					Currency:   "USDT",
+					Resolution: hour,
+					From:       time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC),
+					To:         time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name: "internal error",
+			path: "/?symbol=BTC&resolution=1h",
+			want: want{
+				res: TestResponseData{
+					Meta: response.Meta{
+						Code:    500,
+						Message: "error",
+					},
+				},
+				mockErr: errors.New("error"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := gin_test_setup.NewGinTestContext("GET", tt.path)
+
+			mockPriceTrackingService := &MockPriceTrackingService{}
+
+			p := &PriceTrackerControllerImpl{
+				priceTrackingService: mockPriceTrackingService,
+			}
+
+			var req any = mock.Anything
+			if tt.want.req != nil {
+				req = *tt.want.req
+			}
+			mockPriceTrackingService.On("GetCandles", req).Return(tt.want.res.Data, tt.want.mockErr)
+			mockPriceTrackingService.On("IsSymbolValid", mock.Anything, mock.Anything).Return(true)
+
+			p.GetCandles(ctx.Context)
+
+			var get TestResponseData
+			json.Unmarshal([]byte(ctx.GetResponseBody()), &get)
+
+			assert.Equal(t, tt.want.res.Meta, get.Meta)
+			assert.Equal(t, tt.want.res.Data, get.Data)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"backend/price-tracker/services"
+	"context"
+	"time"
+)
+
+type RetentionWorker interface {
+	// Run enforces the retention every interval, until ctx is done
+	Run(ctx context.Context)
+}
+
+type RetentionWorkerImpl struct {
+	retentionService services.RetentionService
+	interval         time.Duration
+}
+
+func NewRetentionWorker(retentionService services.RetentionService, interval time.Duration) RetentionWorker {
+	return &RetentionWorkerImpl{
+		retentionService: retentionService,
+		interval:         interval,
+	}
+}
+
+// Run enforces the retention every interval, until ctx is done
+func (r *RetentionWorkerImpl) Run(ctx context.Context) {
+	runPeriodically(ctx, r.interval, periodicTask{name: "enforce retention", run: r.retentionService.EnforceRetention})
+}
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+)
+
+// EndOnShutdown cancels the context of the requests it handles once shutdown is done.
+// It ends the streams, which would otherwise keep the server from shutting down,
+// while the other requests in flight are left to complete
+func EndOnShutdown(shutdown context.Context) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		reqCtx, cancel := context.WithCancel(ctx.Request.Context())
+		defer cancel()
+
+		stop := context.AfterFunc(shutdown, cancel)
+		defer stop()
+
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
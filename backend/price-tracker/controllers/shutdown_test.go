@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndOnShutdown(t *testing.T) {
+	// Arrange
+	gin.SetMode(gin.TestMode)
+	shutdown, cancel := context.WithCancel(context.Background())
+
+	r := gin.New()
+	r.GET("/stream", EndOnShutdown(shutdown), func(ctx *gin.Context) {
+		<-ctx.Request.Context().Done()
+		ctx.Status(http.StatusNoContent)
+	})
+
+	// Act
+	time.AfterFunc(10*time.Millisecond, cancel)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"backend/price-tracker/services/symbols"
+	"context"
+	"time"
+)
+
+type SymbolWorker interface {
+	// Run loads the markets metadata again every interval, until ctx is done
+	Run(ctx context.Context)
+}
+
+type SymbolWorkerImpl struct {
+	registry symbols.SymbolRegistry
+	interval time.Duration
+}
+
+func NewSymbolWorker(registry symbols.SymbolRegistry, interval time.Duration) SymbolWorker {
+	return &SymbolWorkerImpl{
+		registry: registry,
+		interval: interval,
+	}
+}
+
+// Run loads the markets metadata again every interval, until ctx is done.
+// A load failed on startup is retried, the symbols being split by quote asset meanwhile
+func (s *SymbolWorkerImpl) Run(ctx context.Context) {
+	runPeriodically(ctx, s.interval, periodicTask{name: "load binance exchange info", run: s.registry.Load})
+}
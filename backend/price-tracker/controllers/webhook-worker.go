@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"backend/price-tracker/services"
+	"context"
+	"time"
+)
+
+type WebhookWorker interface {
+	// Run posts the due deliveries of the outbox every interval, until ctx is done
+	Run(ctx context.Context)
+}
+
+type WebhookWorkerImpl struct {
+	webhookService services.WebhookService
+	interval       time.Duration
+}
+
+func NewWebhookWorker(webhookService services.WebhookService, interval time.Duration) WebhookWorker {
+	return &WebhookWorkerImpl{
+		webhookService: webhookService,
+		interval:       interval,
+	}
+}
+
+// Run posts the due deliveries of the outbox every interval, until ctx is done.
+// Those left pending on shutdown are posted after the restart
+func (w *WebhookWorkerImpl) Run(ctx context.Context) {
+	runPeriodically(ctx, w.interval, periodicTask{name: "deliver webhooks", run: w.webhookService.DeliverDue})
+}
@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+type WebhookController interface {
+	// GetWebhooks handles requests to list the webhooks
+	GetWebhooks(ctx *gin.Context)
+	// GetWebhook handles requests to get a webhook by ID
+	GetWebhook(ctx *gin.Context)
+	// CreateWebhook handles requests to add a webhook
+	CreateWebhook(ctx *gin.Context)
+	// UpdateWebhook handles requests to replace a webhook
+	UpdateWebhook(ctx *gin.Context)
+	// DeleteWebhook handles requests to delete a webhook
+	DeleteWebhook(ctx *gin.Context)
+	// GetDeliveries handles requests to get the deliveries of the outbox
+	GetDeliveries(ctx *gin.Context)
+	// RetryDelivery handles requests to retry a dead delivery
+	RetryDelivery(ctx *gin.Context)
+}
+
+type WebhookControllerImpl struct {
+	webhookService services.WebhookService
+	cfg            config.WebhookConfig
+	httpResponse   response.CustomResponse
+}
+
+func NewWebhookController(webhookService services.WebhookService, cfg config.WebhookConfig) WebhookController {
+	return &WebhookControllerImpl{
+		webhookService: webhookService,
+		cfg:            cfg,
+	}
+}
+
+func (w *WebhookControllerImpl) GetWebhooks(ctx *gin.Context) {
+	res, err := w.webhookService.GetWebhooks(ctx.Request.Context())
+	if err != nil {
+		w.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(res, ctx)
+}
+
+func (w *WebhookControllerImpl) GetWebhook(ctx *gin.Context) {
+	// Verify the request
+	id, ok := w.pathID(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	res, err := w.webhookService.GetWebhook(ctx.Request.Context(), id)
+	if err != nil {
+		w.serviceError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(res, ctx)
+}
+
+// CreateWebhook handles requests to add a webhook, enabled unless the body says otherwise.
+// The response carries its secret, which is not returned afterwards
+func (w *WebhookControllerImpl) CreateWebhook(ctx *gin.Context) {
+	// Verify the request
+	webhook, ok := w.bindWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	res, err := w.webhookService.CreateWebhook(ctx.Request.Context(), webhook)
+	if err != nil {
+		w.serviceError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(res, ctx)
+}
+
+// UpdateWebhook handles requests to replace a webhook by the body, its secret is kept unless the body has one
+func (w *WebhookControllerImpl) UpdateWebhook(ctx *gin.Context) {
+	// Verify the request
+	id, ok := w.pathID(ctx)
+	if !ok {
+		return
+	}
+	webhook, ok := w.bindWebhook(ctx)
+	if !ok {
+		return
+	}
+	webhook.ID = id
+
+	// Process the request
+	res, err := w.webhookService.UpdateWebhook(ctx.Request.Context(), webhook)
+	if err != nil {
+		w.serviceError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(res, ctx)
+}
+
+func (w *WebhookControllerImpl) DeleteWebhook(ctx *gin.Context) {
+	// Verify the request
+	id, ok := w.pathID(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	err := w.webhookService.DeleteWebhook(ctx.Request.Context(), id)
+	if err != nil {
+		w.serviceError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(nil, ctx)
+}
+
+// GetDeliveries handles requests to get the latest deliveries, newest first, filtered by status and webhook_id
+// if given. The dead ones are the dead-letter queue
+func (w *WebhookControllerImpl) GetDeliveries(ctx *gin.Context) {
+	// Verify the request
+	req := models.WebhookDeliveryRequest{
+		Status: ctx.Query("status"),
+		Limit:  w.cfg.WebhookDeliveryLimit,
+	}
+	switch req.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+	default:
+		w.httpResponse.BadRequest(fmt.Errorf("invalid status value '%s'", req.Status), ctx)
+		return
+	}
+
+	if webhookID := ctx.Query("webhook_id"); webhookID != "" {
+		id, err := strconv.ParseInt(webhookID, 10, 64)
+		if err != nil || id <= 0 {
+			w.httpResponse.BadRequest(fmt.Errorf("invalid webhook_id value '%s'", webhookID), ctx)
+			return
+		}
+		req.WebhookID = id
+	}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 || n > w.cfg.WebhookDeliveryMax {
+			w.httpResponse.BadRequest(
+				fmt.Errorf("invalid limit value '%s', must be between 1 and %d", limit, w.cfg.WebhookDeliveryMax), ctx)
+			return
+		}
+		req.Limit = n
+	}
+
+	// Process the request
+	res, err := w.webhookService.GetDeliveries(ctx.Request.Context(), req)
+	if err != nil {
+		w.httpResponse.InternalServerError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(res, ctx)
+}
+
+// RetryDelivery handles requests to send a dead delivery back to the outbox
+func (w *WebhookControllerImpl) RetryDelivery(ctx *gin.Context) {
+	// Verify the request
+	id, ok := w.pathID(ctx)
+	if !ok {
+		return
+	}
+
+	// Process the request
+	res, err := w.webhookService.RetryDelivery(ctx.Request.Context(), id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		w.httpResponse.NotFound(errors.New("delivery not found"), ctx)
+		return
+	}
+	if err != nil {
+		w.serviceError(err, ctx)
+		return
+	}
+
+	w.httpResponse.Success(res, ctx)
+}
+
+// pathID reads the ID of the path, it answers a bad request and returns false if it is not one
+func (w *WebhookControllerImpl) pathID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		w.httpResponse.BadRequest(fmt.Errorf("invalid id '%s'", ctx.Param("id")), ctx)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// bindWebhook reads the webhook of the body, it answers a bad request and returns false if it is not one
+func (w *WebhookControllerImpl) bindWebhook(ctx *gin.Context) (models.Webhook, bool) {
+	webhook := models.Webhook{Enabled: true}
+	err := ctx.ShouldBindJSON(&webhook)
+	if err != nil {
+		w.httpResponse.BadRequest(fmt.Errorf("invalid webhook: %w", err), ctx)
+		return models.Webhook{}, false
+	}
+
+	return webhook, true
+}
+
+// serviceError answers a webhook that is not valid or a delivery that cannot be retried by a bad request,
+// and a missing webhook by not found
+func (w *WebhookControllerImpl) serviceError(err error, ctx *gin.Context) {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook), errors.Is(err, services.ErrDeliveryNotDead):
+		w.httpResponse.BadRequest(err, ctx)
+	case errors.Is(err, repositories.ErrNotFound):
+		w.httpResponse.NotFound(errors.New("webhook not found"), ctx)
+	default:
+		w.httpResponse.InternalServerError(err, ctx)
+	}
+}
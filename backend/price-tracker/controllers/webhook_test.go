@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"backend/internal/config"
+	gin_test_setup "backend/internal/gin-test-setup"
+	"backend/internal/response"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWebhookService implements services.WebhookService
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.Webhook), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	args := m.Called(id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Webhook), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	args := m.Called(webhook)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Webhook), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) UpdateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	args := m.Called(webhook)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.Webhook), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) Enqueue(ctx context.Context, event models.WebhookEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) DeliverDue(ctx context.Context) error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetDeliveries(ctx context.Context, req models.WebhookDeliveryRequest) ([]models.WebhookDelivery, error) {
+	args := m.Called(req)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) RetryDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	args := m.Called(id)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+var webhookCfg = config.WebhookConfig{
+	WebhookDeliveryLimit: 100,
+	WebhookDeliveryMax:   1000,
+}
+
+func TestWebhookControllerImpl_CreateWebhook(t *testing.T) {
+	webhook := models.Webhook{URL: "https://hooks.slack.com/services/T0/B0/X", Format: "slack", Enabled: true}
+	created := webhook
+	created.ID = 1
+	created.Secret = "generated"
+
+	tests := []struct {
+		name       string
+		body       any
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "created with its secret",
+			body:       map[string]any{"url": webhook.URL, "format": "slack"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not valid",
+			body:       map[string]any{"url": webhook.URL, "format": "slack"},
+			mockErr:    fmt.Errorf("%w: url must be an absolute http or https URL", services.ErrInvalidWebhook),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not json",
+			body:       "https://hooks.slack.com",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockWebhookService)
+			if tt.mockErr != nil {
+				mockService.On("CreateWebhook", webhook).Return(nil, tt.mockErr)
+			} else {
+				mockService.On("CreateWebhook", webhook).Return(&created, nil)
+			}
+			controller := NewWebhookController(mockService, webhookCfg)
+			ctx := gin_test_setup.NewGinTestContext("POST", "/webhooks")
+			ctx.CustomBody(tt.body)
+
+			// Act
+			controller.CreateWebhook(ctx.Context)
+
+			// Assert
+			var res struct {
+				Meta response.Meta  `json:"meta"`
+				Data models.Webhook `json:"data"`
+			}
+			err := json.Unmarshal([]byte(ctx.GetResponseBody()), &res)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, ctx.GetResponseCode())
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "generated", res.Data.Secret)
+			}
+		})
+	}
+}
+
+func TestWebhookControllerImpl_GetDeliveries(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		mockReq    *models.WebhookDeliveryRequest
+		wantStatus int
+	}{
+		{
+			name:       "dead letters",
+			query:      "?status=dead&webhook_id=2",
+			mockReq:    &models.WebhookDeliveryRequest{Status: models.WebhookDeliveryDead, WebhookID: 2, Limit: 100},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "with limit",
+			query:      "?limit=5",
+			mockReq:    &models.WebhookDeliveryRequest{Limit: 5},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid status",
+			query:      "?status=lost",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid limit",
+			query:      "?limit=0",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockWebhookService)
+			if tt.mockReq != nil {
+				mockService.On("GetDeliveries", *tt.mockReq).Return([]models.WebhookDelivery{{ID: 1}}, nil)
+			}
+			controller := NewWebhookController(mockService, webhookCfg)
+			ctx := gin_test_setup.NewGinTestContext("GET", "/webhooks/deliveries"+tt.query)
+
+			// Act
+			controller.GetDeliveries(ctx.Context)
+
+			// Assert
+			assert.Equal(t, tt.wantStatus, ctx.GetResponseCode())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWebhookControllerImpl_RetryDelivery(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "retried", id: "1", wantStatus: http.StatusOK},
+		{name: "not found", id: "2", mockErr: repositories.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "not dead", id: "3", mockErr: services.ErrDeliveryNotDead, wantStatus: http.StatusBadRequest},
+		{name: "invalid id", id: "x", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockService := new(MockWebhookService)
+			if tt.mockErr != nil {
+				mockService.On("RetryDelivery", mock.Anything).Return(nil, tt.mockErr)
+			} else {
+				mockService.On("RetryDelivery", int64(1)).Return(&models.WebhookDelivery{ID: 1}, nil)
+			}
+			controller := NewWebhookController(mockService, webhookCfg)
+			ctx := gin_test_setup.NewGinTestContext("POST", "/webhooks/deliveries/"+tt.id+"/retry")
+			ctx.CustomQueryParams(gin.Params{{Key: "id", Value: tt.id}})
+
+			// Act
+			controller.RetryDelivery(ctx.Context)
+
+			// Assert
+			assert.Equal(t, tt.wantStatus, ctx.GetResponseCode())
+		})
+	}
+}
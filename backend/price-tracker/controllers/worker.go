@@ -1,43 +1,151 @@
 package controllers
 
 import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/reconnect"
 	"backend/price-tracker/services/ws"
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
+	"sync"
 	"time"
 )
 
 type PriceTrackingWorker interface {
-	// Run starts the worker and keeps retrying if errors occurs
-	Run()
+	// Run fetches the prices and keeps reconnecting if errors occurs, until ctx is done
+	Run(ctx context.Context)
+	// Status returns the connection of the worker and the state of its circuit breaker
+	Status() models.WorkerStatus
 }
 
 type PriceTrackingWorkerImpl struct {
-	ws ws.WebSocketFetcher
+	source  string
+	ws      ws.WebSocketFetcher
+	breaker reconnect.Breaker
+	cfg     config.PriceTrackerConfig
+	now     func() time.Time
+
+	mu         sync.Mutex
+	connection string
+	since      time.Time
 }
 
-func NewPriceTrackingWorker(ws ws.WebSocketFetcher) PriceTrackingWorker {
+func NewPriceTrackingWorker(
+	source string,
+	ws ws.WebSocketFetcher,
+	breaker reconnect.Breaker,
+	cfg config.PriceTrackerConfig,
+) PriceTrackingWorker {
 	return &PriceTrackingWorkerImpl{
-		ws: ws,
+		source:     source,
+		ws:         ws,
+		breaker:    breaker,
+		cfg:        cfg,
+		now:        time.Now,
+		connection: models.WorkerConnectionWaiting,
+		since:      time.Now().UTC(),
 	}
 }
 
-// Run starts the worker and keeps retrying if errors occurs
-func (p *PriceTrackingWorkerImpl) Run() {
-	go func() {
-		for {
-			time.Sleep(5 * time.Second)
+// Run fetches the prices and keeps reconnecting if errors occurs, until ctx is done.
+// The breaker paces the attempts, the connection is closed cleanly before it returns
+func (p *PriceTrackingWorkerImpl) Run(ctx context.Context) {
+	defer p.setConnection(models.WorkerConnectionStopped)
+
+	for {
+		if wait := p.breaker.Next(); wait > 0 {
+			p.setConnection(models.WorkerConnectionWaiting)
 
-			connection, err := p.ws.Connect()
-			if err != nil {
-				slog.Error("connect to websocket:", "error", err)
-				continue
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
 			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
 
-			err = p.ws.Fetch(connection)
-			if err != nil {
-				slog.Error("worker fetch:", "error", err)
-				continue
+		p.breaker.Attempt()
+		p.setConnection(models.WorkerConnectionConnecting)
+
+		connection, err := p.ws.Connect(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
 			}
+			slog.Error("connect to websocket:", "source", p.source, "error", err)
+			p.failure(err)
+			continue
+		}
+
+		// Only a connection dropping soon after it opened counts towards the circuit
+		p.setConnection(models.WorkerConnectionConnected)
+		stable := time.AfterFunc(p.cfg.ReconnectStableAfter, p.breaker.Success)
+
+		err = p.ws.Fetch(ctx, connection)
+		stable.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("connection closed")
 		}
-	}()
+		slog.Error("worker fetch:", "source", p.source, "error", err)
+		p.failure(err)
+	}
+}
+
+// failure records a failed connection, an exchange refusing it opens the circuit at once.
+// Binance answers 429 once rate limited and 418 once banned for ignoring it, both with Retry-After,
+// and refused credentials are not accepted by retrying soon either
+func (p *PriceTrackingWorkerImpl) failure(err error) {
+	var handshakeErr *ws.HandshakeError
+	if errors.As(err, &handshakeErr) {
+		switch handshakeErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusTeapot, http.StatusUnauthorized, http.StatusForbidden:
+			wait := handshakeErr.RetryAfter
+			if wait <= 0 {
+				wait = p.cfg.ReconnectMaxDelay
+			}
+
+			p.breaker.Trip(err, wait)
+			return
+		}
+	}
+
+	p.breaker.Failure(err)
+}
+
+func (p *PriceTrackingWorkerImpl) Status() models.WorkerStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	circuit := p.breaker.Status()
+
+	return models.WorkerStatus{
+		Source:     p.source,
+		Connection: p.connection,
+		Since:      p.since,
+		Circuit:    string(circuit.State),
+		Failures:   circuit.Failures,
+		LastError:  circuit.LastError,
+		RetryAt:    circuit.RetryAt,
+	}
+}
+
+// setConnection records the connection of the worker and logs its transitions
+func (p *PriceTrackingWorkerImpl) setConnection(connection string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.connection == connection {
+		return
+	}
+
+	slog.Info("worker status changed", "source", p.source, "from", p.connection, "to", connection)
+	p.connection = connection
+	p.since = p.now().UTC()
 }
@@ -1,11 +1,123 @@
 package controllers
 
 import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/reconnect"
 	"backend/price-tracker/services/ws"
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"net/http"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
-func TestNewPriceTrackingWorker(t *testing.T) {
-	b := ws.BinanceWebsocketImpl{}
-	NewPriceTrackingWorker(&b)
+// MockWebSocketFetcher implements ws.WebSocketFetcher
+type MockWebSocketFetcher struct {
+	mock.Mock
+}
+
+func (m *MockWebSocketFetcher) Connect(ctx context.Context) (*websocket.Conn, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*websocket.Conn), args.Error(1)
+}
+
+func (m *MockWebSocketFetcher) Fetch(ctx context.Context, conn *websocket.Conn) error {
+	args := m.Called(conn)
+	return args.Error(0)
+}
+
+var testWorkerConfig = config.PriceTrackerConfig{
+	ReconnectInitialDelay: time.Millisecond,
+	ReconnectMaxDelay:     10 * time.Second,
+	ReconnectStableAfter:  time.Minute,
+	BreakerThreshold:      2,
+	BreakerCooldown:       time.Hour,
+}
+
+func TestPriceTrackingWorkerImpl_Run(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int
+		wantRetry time.Duration
+	}{
+		{
+			name:      "transient errors open the circuit at the threshold",
+			err:       errors.New("dial: connection refused"),
+			wantCalls: 2,
+			wantRetry: time.Hour,
+		},
+		{
+			name:      "rate limit opens the circuit at once for retry after",
+			err:       &ws.HandshakeError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second},
+			wantCalls: 1,
+			wantRetry: 30 * time.Second,
+		},
+		{
+			name:      "ban opens the circuit at once for the max delay without retry after",
+			err:       &ws.HandshakeError{StatusCode: http.StatusTeapot},
+			wantCalls: 1,
+			wantRetry: 10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			fetcher := new(MockWebSocketFetcher)
+			fetcher.On("Connect").Return(nil, tt.err)
+			worker := NewPriceTrackingWorker("binance", fetcher, reconnect.NewBreaker("binance", testWorkerConfig), testWorkerConfig)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			stopped := make(chan struct{})
+			start := time.Now()
+
+			// Act
+			go func() {
+				worker.Run(ctx)
+				close(stopped)
+			}()
+
+			// Assert
+			assert.Eventually(t, func() bool {
+				return worker.Status().Circuit == string(reconnect.StateOpen)
+			}, time.Second, time.Millisecond)
+			status := worker.Status()
+			cancel()
+			<-stopped
+
+			assert.Equal(t, tt.err.Error(), status.LastError)
+			assert.WithinRange(t, status.RetryAt, start.Add(tt.wantRetry), time.Now().Add(tt.wantRetry))
+			assert.Equal(t, models.WorkerConnectionStopped, worker.Status().Connection)
+			fetcher.AssertNumberOfCalls(t, "Connect", tt.wantCalls)
+		})
+	}
+}
+
+func TestPriceTrackingWorkerImpl_Run_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fetcher := new(MockWebSocketFetcher)
+	worker := NewPriceTrackingWorker("binance", fetcher, reconnect.NewBreaker("binance", testWorkerConfig), testWorkerConfig)
+
+	stopped := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+	fetcher.AssertNotCalled(t, "Connect")
 }
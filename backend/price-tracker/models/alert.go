@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	// AlertKindAbove fires when the price reaches the threshold from below, AlertKindBelow from above
+	AlertKindAbove = "above"
+	AlertKindBelow = "below"
+	// AlertKindPercentChange fires when the price changed by the threshold percent over the window,
+	// a negative threshold watching for a fall
+	AlertKindPercentChange = "percent_change"
+	// AlertKindMACross fires when the price crosses its moving average over the window, either way
+	AlertKindMACross = "ma_cross"
+)
+
+// Duration is a time.Duration written as a string such as 15m in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("duration must be a string such as 15m: %w", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// AlertRule is a condition on the price of a market, from a source or the index if Source is empty,
+// evaluated on every price received
+type AlertRule struct {
+	bun.BaseModel `json:"-" bun:"table:alert_rules"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Name          string `json:"name" bun:"name"`
+	Symbol        string `json:"symbol" bun:"symbol"`
+	Currency      string `json:"currency" bun:"currency"`
+	Source        string `json:"source,omitempty" bun:"source"`
+	Kind          string `json:"kind" bun:"kind"`
+	// Threshold is a price for above and below, a percent for percent_change and unused by ma_cross
+	Threshold decimal.Decimal `json:"threshold" bun:"threshold"`
+	// Window is the period of the change or of the moving average
+	Window Duration `json:"window,omitzero" bun:"window_duration"`
+	// Hysteresis is the percent of the level, the threshold or the moving average,
+	// the price must move back past before the rule fires again
+	Hysteresis decimal.Decimal `json:"hysteresis" bun:"hysteresis"`
+	// Cooldown is the least time between two alerts of the rule
+	Cooldown Duration `json:"cooldown,omitzero" bun:"cooldown"`
+	// Email is the address its alerts are emailed to, none if empty
+	Email     string    `json:"email,omitempty" bun:"email"`
+	Enabled   bool      `json:"enabled" bun:"enabled"`
+	CreatedAt time.Time `json:"created_at" bun:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bun:"updated_at"`
+
+	// Armed is false once the rule fired, until the price moved back past the hysteresis
+	Armed bool `json:"armed" bun:"armed"`
+	// Side is the side of the moving average the price was last seen on, 1 above, -1 below and 0 unknown
+	Side        int       `json:"side,omitempty" bun:"side"`
+	LastFiredAt time.Time `json:"last_fired_at,omitzero" bun:"last_fired_at"`
+}
+
+// AlertEvent is the record of a rule firing
+type AlertEvent struct {
+	bun.BaseModel `json:"-" bun:"table:alert_events"`
+	ID            int64           `json:"id" bun:"id,pk,autoincrement"`
+	RuleID        int64           `json:"rule_id" bun:"rule_id"`
+	Name          string          `json:"name" bun:"name"`
+	Symbol        string          `json:"symbol" bun:"symbol"`
+	Currency      string          `json:"currency" bun:"currency"`
+	Source        string          `json:"source" bun:"source"`
+	Kind          string          `json:"kind" bun:"kind"`
+	Price         decimal.Decimal `json:"price" bun:"price"`
+	// Reference is the level the price was compared to, the threshold, the price a window ago or the moving average
+	Reference decimal.Decimal `json:"reference" bun:"reference"`
+	Message   string          `json:"message" bun:"message"`
+	FiredAt   time.Time       `json:"fired_at" bun:"fired_at"`
+
+	// Email is the address of the rule when it fired, neither stored nor returned
+	Email string `json:"-" bun:"-"`
+}
+
+// Title returns the name of the rule, or the market if it has none
+func (a AlertEvent) Title() string {
+	if a.Name != "" {
+		return a.Name
+	}
+
+	return "Alert on " + a.Symbol + "/" + a.Currency
+}
+
+// Change returns the percent change of the price from the reference, zero if there is none
+func (a AlertEvent) Change() decimal.Decimal {
+	if a.Reference.IsZero() {
+		return decimal.Zero
+	}
+
+	return a.Price.Sub(a.Reference).Div(a.Reference).Mul(decimal.NewFromInt(100))
+}
+
+// AlertEventRequest asks for the latest fired alerts, of a rule if RuleID is set
+type AlertEventRequest struct {
+	RuleID int64
+	Limit  int
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	// AnomalyKindZScore is a price too far from the mean of the recent prices of its market,
+	// AnomalyKindJump a price too far from the previous one given the usual moves between prices
+	AnomalyKindZScore = "zscore"
+	AnomalyKindJump   = "jump"
+	// AnomalyKindFlatline is a market whose price has not changed for too long while prices keep arriving
+	AnomalyKindFlatline = "flatline"
+)
+
+// Anomaly is a price flagged by the detection on ingestion
+type Anomaly struct {
+	bun.BaseModel `json:"-" bun:"table:anomalies"`
+	ID            int64           `json:"id" bun:"id,pk,autoincrement"`
+	Timestamp     time.Time       `json:"timestamp" bun:"timestamp"`
+	Symbol        string          `json:"symbol" bun:"symbol"`
+	Currency      string          `json:"currency" bun:"currency"`
+	Source        string          `json:"source" bun:"source"`
+	Kind          string          `json:"kind" bun:"kind"`
+	Price         decimal.Decimal `json:"price" bun:"price"`
+	// Reference is what the price was compared to, the mean for zscore, the previous price for jump
+	// and the unchanged price for flatline
+	Reference decimal.Decimal `json:"reference" bun:"reference"`
+	// Score is the number of standard deviations from the reference for zscore and jump,
+	// and the seconds the price was unchanged for flatline
+	Score   float64 `json:"score" bun:"score"`
+	Message string  `json:"message" bun:"message"`
+	// Quarantined is true if the price was kept out of the prices received
+	Quarantined bool `json:"quarantined" bun:"quarantined"`
+}
+
+// AnomalyRequest asks for the latest anomalies, of a market if Symbol, Currency or Source is set
+type AnomalyRequest struct {
+	Symbol   string
+	Currency string
+	Source   string
+	Limit    int
+}
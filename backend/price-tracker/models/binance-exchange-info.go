@@ -0,0 +1,56 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+const (
+	BinanceSymbolStatusTrading = "TRADING"
+	BinanceFilterPrice         = "PRICE_FILTER"
+)
+
+// BinanceExchangeInfo is the response of Binance's /api/v3/exchangeInfo
+type BinanceExchangeInfo struct {
+	Symbols []BinanceSymbolInfo `json:"symbols"`
+}
+
+type BinanceSymbolInfo struct {
+	Symbol     string                `json:"symbol"`
+	Status     string                `json:"status"`
+	BaseAsset  string                `json:"baseAsset"`
+	QuoteAsset string                `json:"quoteAsset"`
+	Filters    []BinanceSymbolFilter `json:"filters"`
+}
+
+// BinanceSymbolFilter is a trading rule of a symbol, only the price filter is read
+type BinanceSymbolFilter struct {
+	FilterType string `json:"filterType"`
+	TickSize   string `json:"tickSize"`
+}
+
+// TickSize returns the price increment of the symbol without trailing zeros, e.g. 0.01000000 -> 0.01,
+// or zero if the symbol has no price filter
+func (b *BinanceSymbolInfo) TickSize() decimal.Decimal {
+	for _, filter := range b.Filters {
+		if filter.FilterType != BinanceFilterPrice {
+			continue
+		}
+
+		tickSize, err := decimal.NewFromString(filter.TickSize)
+		if err != nil {
+			return decimal.Decimal{}
+		}
+
+		// Drop the trailing zeros of the exponent
+		return decimal.RequireFromString(tickSize.String())
+	}
+
+	return decimal.Decimal{}
+}
+
+// SymbolPair is a market of an exchange split into its base and quote asset
+type SymbolPair struct {
+	Symbol string `json:"symbol"`
+	Base   string `json:"base"`
+	Quote  string `json:"quote"`
+	// TickSize is the price increment of the market, zero when unknown
+	TickSize decimal.Decimal `json:"tick_size,omitzero"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"backend/internal/constant"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// BinanceKline is an item of /api/v3/klines, sent as an array
+// [openTime, open, high, low, close, volume, closeTime, ...]
+type BinanceKline struct {
+	OpenTime  int64
+	Open      string
+	High      string
+	Low       string
+	Close     string
+	Volume    string
+	CloseTime int64
+}
+
+func (b *BinanceKline) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) < 7 {
+		return fmt.Errorf("kline has %d fields, expected at least 7", len(fields))
+	}
+
+	for i, dest := range []any{&b.OpenTime, &b.Open, &b.High, &b.Low, &b.Close, &b.Volume, &b.CloseTime} {
+		if err := json.Unmarshal(fields[i], dest); err != nil {
+			return fmt.Errorf("kline field %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// ToPriceData returns the open, high, low and close prices of the kline as ticks spread over its period,
+// high before low on a falling kline and low before high on a rising one, so the candles rolled up
+// from them match the kline
+func (b *BinanceKline) ToPriceData(pair SymbolPair) ([]PriceDatum, error) {
+	var prices [4]decimal.Decimal
+	for i, value := range []string{b.Open, b.High, b.Low, b.Close} {
+		price, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse price: %w", err)
+		}
+		prices[i] = price
+	}
+
+	open, high, low, closePrice := prices[0], prices[1], prices[2], prices[3]
+	path := []decimal.Decimal{open, low, high, closePrice}
+	if closePrice.LessThan(open) {
+		path = []decimal.Decimal{open, high, low, closePrice}
+	}
+
+	openTime := time.UnixMilli(b.OpenTime).UTC()
+	period := time.UnixMilli(b.CloseTime).UTC().Sub(openTime)
+
+	data := make([]PriceDatum, 0, len(path))
+	for i, price := range path {
+		data = append(data, PriceDatum{
+			Timestamp: openTime.Add(period * time.Duration(i) / time.Duration(len(path)-1)),
+			Symbol:    pair.Base,
+			Currency:  pair.Quote,
+			Source:    constant.SourceBinance,
+			Price:     RoundToTick(price, pair.TickSize),
+			TickSize:  pair.TickSize,
+		})
+	}
+
+	return data, nil
+}
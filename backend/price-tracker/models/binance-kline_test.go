@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinanceKline_ToPriceData(t *testing.T) {
+	pair := SymbolPair{Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"}
+	openTime := time.UnixMilli(1700000040000).UTC()
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "rising",
+			raw:  `[1700000040000,"100.0","110.0","95.0","105.0","12.5",1700000099999,"1300.0",10,"6.0","630.0","0"]`,
+			want: []string{"100", "95", "110", "105"},
+		},
+		{
+			name: "falling",
+			raw:  `[1700000040000,"100.0","110.0","95.0","97.0","12.5",1700000099999,"1300.0",10,"6.0","630.0","0"]`,
+			want: []string{"100", "110", "95", "97"},
+		},
+		{
+			name:    "invalid price",
+			raw:     `[1700000040000,"abc","110.0","95.0","97.0","12.5",1700000099999]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kline BinanceKline
+			err := json.Unmarshal([]byte(tt.raw), &kline)
+			assert.NoError(t, err)
+
+			got, err := kline.ToPriceData(pair)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, got, len(tt.want))
+			for i, datum := range got {
+				assert.Equal(t, tt.want[i], datum.Price.String())
+				assert.Equal(t, "BTC", datum.Symbol)
+				assert.Equal(t, "USDT", datum.Currency)
+				assert.Equal(t, "binance", datum.Source)
+				assert.Equal(t, openTime.Truncate(time.Minute), datum.Timestamp.Truncate(time.Minute))
+			}
+			assert.Equal(t, openTime, got[0].Timestamp)
+			assert.Equal(t, time.UnixMilli(1700000099999).UTC(), got[3].Timestamp)
+
+			candles := NewCandlesFromPriceData(got)
+			assert.Equal(t, tt.want[0], candles[0].Open.String())
+			assert.Equal(t, "110", candles[0].High.String())
+			assert.Equal(t, "95", candles[0].Low.String())
+			assert.Equal(t, tt.want[3], candles[0].Close.String())
+		})
+	}
+}
+
+func TestBinanceKline_UnmarshalJSON_TooShort(t *testing.T) {
+	var kline BinanceKline
+	err := json.Unmarshal([]byte(`[1700000040000,"100.0"]`), &kline)
+	assert.Error(t, err)
+}
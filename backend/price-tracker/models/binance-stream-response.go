@@ -0,0 +1,176 @@
+package models
+
+import (
+	"backend/internal/constant"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+const (
+	BinanceMethodSubscribe = "SUBSCRIBE"
+
+	BinanceMiniTickerArrStream = "!miniTicker@arr"
+	binanceTradeStreamSuffix   = "@trade"
+	binanceTickerStreamSuffix  = "@ticker"
+)
+
+// BinanceStreamRequest is a control frame sent to Binance combined streams
+type BinanceStreamRequest struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+	ID     int64    `json:"id"`
+}
+
+// BinanceStreamMessage is any frame received from Binance combined streams.
+// Market data comes wrapped in Stream/Data, control responses carry ID and Result or Error
+type BinanceStreamMessage struct {
+	Stream string              `json:"stream"`
+	Data   json.RawMessage     `json:"data"`
+	ID     *int64              `json:"id"`
+	Result json.RawMessage     `json:"result"`
+	Error  *BinanceStreamError `json:"error"`
+}
+
+type BinanceStreamError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+func (b *BinanceStreamError) Error() string {
+	return fmt.Sprintf("code %d: %s", b.Code, b.Msg)
+}
+
+// IsControlResponse returns true if the message answers a SUBSCRIBE/UNSUBSCRIBE request
+func (b *BinanceStreamMessage) IsControlResponse() bool {
+	return b.Stream == "" && b.ID != nil
+}
+
+// BinanceMiniTicker is an item of the !miniTicker@arr stream
+type BinanceMiniTicker struct {
+	EventType   string `json:"e"`
+	EventTime   int64  `json:"E"`
+	Symbol      string `json:"s"`
+	Close       string `json:"c"`
+	Open        string `json:"o"`
+	High        string `json:"h"`
+	Low         string `json:"l"`
+	Volume      string `json:"v"`
+	QuoteVolume string `json:"q"`
+}
+
+func (b *BinanceMiniTicker) ToBinanceResult() BinanceResult {
+	return BinanceResult{
+		Symbol: b.Symbol,
+		Price:  b.Close,
+	}
+}
+
+func (b *BinanceMiniTicker) Time() time.Time {
+	return time.UnixMilli(b.EventTime).UTC()
+}
+
+// BinanceTrade is a message of the <symbol>@trade stream
+type BinanceTrade struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Symbol    string `json:"s"`
+	TradeID   int64  `json:"t"`
+	Price     string `json:"p"`
+	Quantity  string `json:"q"`
+	TradeTime int64  `json:"T"`
+}
+
+func (b *BinanceTrade) ToBinanceResult() BinanceResult {
+	return BinanceResult{
+		Symbol: b.Symbol,
+		Price:  b.Price,
+	}
+}
+
+func (b *BinanceTrade) Time() time.Time {
+	return time.UnixMilli(b.TradeTime).UTC()
+}
+
+// BinanceTicker is a message of the <symbol>@ticker stream, the rolling 24h statistics of a symbol
+// with its best bid and ask, pushed every second.
+// Keys differing only by case are all declared, encoding/json would match them case-insensitively otherwise
+type BinanceTicker struct {
+	EventType          string `json:"e"`
+	EventTime          int64  `json:"E"`
+	Symbol             string `json:"s"`
+	PriceChange        string `json:"p"`
+	PriceChangePercent string `json:"P"`
+	Close              string `json:"c"`
+	LastQuantity       string `json:"Q"`
+	BidPrice           string `json:"b"`
+	BidQuantity        string `json:"B"`
+	AskPrice           string `json:"a"`
+	AskQuantity        string `json:"A"`
+	Open               string `json:"o"`
+	High               string `json:"h"`
+	Low                string `json:"l"`
+	Volume             string `json:"v"`
+	QuoteVolume        string `json:"q"`
+	OpenTime           int64  `json:"O"`
+	CloseTime          int64  `json:"C"`
+	LastTradeID        int64  `json:"L"`
+}
+
+func (b *BinanceTicker) Time() time.Time {
+	return time.UnixMilli(b.EventTime).UTC()
+}
+
+// ToMarketStats returns the stats of the ticker for the market of pair
+func (b *BinanceTicker) ToMarketStats(pair SymbolPair) (MarketStats, error) {
+	var values [10]decimal.Decimal
+	for i, value := range []string{
+		b.BidPrice, b.BidQuantity, b.AskPrice, b.AskQuantity, b.LastQuantity,
+		b.Volume, b.QuoteVolume, b.High, b.Low, b.PriceChangePercent,
+	} {
+		parsed, err := decimal.NewFromString(value)
+		if err != nil {
+			return MarketStats{}, fmt.Errorf("parse decimal: %w", err)
+		}
+		values[i] = parsed
+	}
+
+	return MarketStats{
+		Symbol:             pair.Base,
+		Currency:           pair.Quote,
+		Source:             constant.SourceBinance,
+		Timestamp:          b.Time(),
+		BidPrice:           values[0],
+		BidQuantity:        values[1],
+		AskPrice:           values[2],
+		AskQuantity:        values[3],
+		LastQuantity:       values[4],
+		Volume:             values[5],
+		QuoteVolume:        values[6],
+		High:               values[7],
+		Low:                values[8],
+		PriceChangePercent: values[9],
+	}, nil
+}
+
+// BinanceTickerStream returns the 24h ticker stream name of a symbol, e.g. BTCUSDT -> btcusdt@ticker
+func BinanceTickerStream(symbol string) string {
+	return strings.ToLower(symbol) + binanceTickerStreamSuffix
+}
+
+// IsBinanceTickerStream returns true if the stream name is a <symbol>@ticker stream
+func IsBinanceTickerStream(stream string) bool {
+	return strings.HasSuffix(stream, binanceTickerStreamSuffix)
+}
+
+// BinanceTradeStream returns the trade stream name of a symbol, e.g. BTCUSDT -> btcusdt@trade
+func BinanceTradeStream(symbol string) string {
+	return strings.ToLower(symbol) + binanceTradeStreamSuffix
+}
+
+// IsBinanceTradeStream returns true if the stream name is a <symbol>@trade stream
+func IsBinanceTradeStream(stream string) bool {
+	return strings.HasSuffix(stream, binanceTradeStreamSuffix)
+}
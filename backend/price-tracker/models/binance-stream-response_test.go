@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinanceTicker_ToMarketStats(t *testing.T) {
+	pair := SymbolPair{Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"}
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			raw:  `{"e":"24hrTicker","E":1700000000000,"s":"BTCUSDT","p":"10.0","P":"1.50","c":"101.0","Q":"0.2","b":"100.9","B":"3.0","a":"101.1","A":"4.0","o":"91.0","h":"105.0","l":"90.0","v":"1000.0","q":"100000.0","O":1699913600000,"C":1700000000000,"L":42}`,
+		},
+		{
+			name:    "invalid bid",
+			raw:     `{"e":"24hrTicker","E":1700000000000,"s":"BTCUSDT","b":"abc"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ticker BinanceTicker
+			err := json.Unmarshal([]byte(tt.raw), &ticker)
+			assert.NoError(t, err)
+
+			stats, err := ticker.ToMarketStats(pair)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "BTC", stats.Symbol)
+			assert.Equal(t, "USDT", stats.Currency)
+			assert.Equal(t, time.UnixMilli(1700000000000).UTC(), stats.Timestamp)
+			assert.Equal(t, "100.9", stats.BidPrice.String())
+			assert.Equal(t, "101.1", stats.AskPrice.String())
+			assert.Equal(t, "0.2", stats.LastQuantity.String())
+			assert.Equal(t, "1.5", stats.PriceChangePercent.String())
+			// Upper case keys must not overwrite their lower case twins
+			assert.Equal(t, "91.0", ticker.Open)
+			assert.Equal(t, "101.0", ticker.Close)
+			assert.Equal(t, int64(42), ticker.LastTradeID)
+		})
+	}
+}
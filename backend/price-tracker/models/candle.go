@@ -0,0 +1,155 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+	"sort"
+	"time"
+)
+
+// CandleResolution is the period covered by a candle
+type CandleResolution struct {
+	Name     string
+	Duration time.Duration
+}
+
+// CandleResolutions lists the resolutions candles are rolled up at
+var CandleResolutions = []CandleResolution{
+	{Name: "1m", Duration: time.Minute},
+	{Name: "5m", Duration: 5 * time.Minute},
+	{Name: "15m", Duration: 15 * time.Minute},
+	{Name: "1h", Duration: time.Hour},
+	{Name: "4h", Duration: 4 * time.Hour},
+	{Name: "1d", Duration: 24 * time.Hour},
+}
+
+// ParseCandleResolution returns the resolution of a name such as 5m
+func ParseCandleResolution(name string) (CandleResolution, bool) {
+	for _, resolution := range CandleResolutions {
+		if resolution.Name == name {
+			return resolution, true
+		}
+	}
+
+	return CandleResolution{}, false
+}
+
+// CandleResolutionFor returns the coarsest resolution no longer than step, the finest for shorter steps.
+// Each step of a history then has a candle of its own, from the resolution kept the longest
+func CandleResolutionFor(step time.Duration) CandleResolution {
+	res := CandleResolutions[0]
+	for _, resolution := range CandleResolutions {
+		if resolution.Duration <= step {
+			res = resolution
+		}
+	}
+
+	return res
+}
+
+type Candle struct {
+	bun.BaseModel `json:"-" bun:"table:crypto_candle"`
+	Symbol        string          `json:"symbol" bun:"symbol,pk"`
+	Currency      string          `json:"currency" bun:"currency,pk"`
+	Source        string          `json:"source" bun:"source,pk"`
+	Resolution    string          `json:"resolution" bun:"resolution,pk"`
+	OpenTime      time.Time       `json:"open_time" bun:"open_time,pk"`
+	Open          decimal.Decimal `json:"open" bun:"open"`
+	High          decimal.Decimal `json:"high" bun:"high"`
+	Low           decimal.Decimal `json:"low" bun:"low"`
+	Close         decimal.Decimal `json:"close" bun:"close"`
+	Count         int64           `json:"count" bun:"count"`
+	// FirstTick and LastTick are the timestamps of the open and close prices,
+	// they let partial candles of the same period be merged in any order
+	FirstTick time.Time `json:"-" bun:"first_tick"`
+	LastTick  time.Time `json:"-" bun:"last_tick"`
+}
+
+type CandleRequest struct {
+	Symbol     string
+	Currency   string
+	Source     string
+	Resolution CandleResolution
+	From       time.Time
+	To         time.Time
+}
+
+type candleKey struct {
+	symbol     string
+	currency   string
+	source     string
+	resolution string
+	openTime   time.Time
+}
+
+// NewCandlesFromPriceData aggregates data into a candle per market, source and period at every resolution
+func NewCandlesFromPriceData(data []PriceDatum) []Candle {
+	sorted := append([]PriceDatum(nil), data...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Timestamp.Before(sorted[j].Timestamp)
+	})
+
+	var candles []Candle
+	index := make(map[candleKey]int)
+
+	for _, resolution := range CandleResolutions {
+		for _, datum := range sorted {
+			timestamp := datum.Timestamp.UTC()
+			key := candleKey{
+				symbol:     datum.Symbol,
+				currency:   datum.Currency,
+				source:     datum.Source,
+				resolution: resolution.Name,
+				openTime:   timestamp.Truncate(resolution.Duration),
+			}
+
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(candles)
+				candles = append(candles, Candle{
+					Symbol:     key.symbol,
+					Currency:   key.currency,
+					Source:     key.source,
+					Resolution: key.resolution,
+					OpenTime:   key.openTime,
+					Open:       datum.Price,
+					High:       datum.Price,
+					Low:        datum.Price,
+					Close:      datum.Price,
+					Count:      1,
+					FirstTick:  timestamp,
+					LastTick:   timestamp,
+				})
+				continue
+			}
+
+			candle := &candles[i]
+			candle.High = decimal.Max(candle.High, datum.Price)
+			candle.Low = decimal.Min(candle.Low, datum.Price)
+			candle.Close = datum.Price
+			candle.LastTick = timestamp
+			candle.Count++
+		}
+	}
+
+	return candles
+}
+
+// Merge returns the candle of the period covering both c and other, two partial candles of the same market,
+// resolution and open time. It mirrors the upsert of the repositories, so they can be merged in any order
+func (c Candle) Merge(other Candle) Candle {
+	merged := c
+	if other.FirstTick.Before(c.FirstTick) {
+		merged.Open = other.Open
+		merged.FirstTick = other.FirstTick
+	}
+	if !other.LastTick.Before(c.LastTick) {
+		merged.Close = other.Close
+		merged.LastTick = other.LastTick
+	}
+	merged.High = decimal.Max(c.High, other.High)
+	merged.Low = decimal.Min(c.Low, other.Low)
+	merged.Count = c.Count + other.Count
+
+	return merged
+}
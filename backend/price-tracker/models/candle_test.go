@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCandleResolution(t *testing.T) {
+	resolution, ok := ParseCandleResolution("4h")
+	assert.True(t, ok)
+	assert.Equal(t, 4*time.Hour, resolution.Duration)
+
+	_, ok = ParseCandleResolution("2h")
+	assert.False(t, ok)
+}
+
+func TestNewCandlesFromPriceData(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	data := []PriceDatum{
+		{Timestamp: start.Add(70 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(104)},
+		{Timestamp: start.Add(10 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(100)},
+		{Timestamp: start.Add(20 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(105)},
+		{Timestamp: start.Add(30 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(98)},
+		{Timestamp: start.Add(40 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(101)},
+		{Timestamp: start.Add(40 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "kraken", Price: decimal.NewFromInt(102)},
+	}
+
+	candles := NewCandlesFromPriceData(data)
+
+	// 1m: two binance candles and one kraken candle, other resolutions: one of each source
+	assert.Len(t, candles, 3+2*(len(CandleResolutions)-1))
+
+	assert.Equal(t, Candle{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "binance",
+		Resolution: "1m",
+		OpenTime:   start,
+		Open:       decimal.NewFromInt(100),
+		High:       decimal.NewFromInt(105),
+		Low:        decimal.NewFromInt(98),
+		Close:      decimal.NewFromInt(101),
+		Count:      4,
+		FirstTick:  start.Add(10 * time.Second),
+		LastTick:   start.Add(40 * time.Second),
+	}, candles[0])
+
+	for _, candle := range candles {
+		if candle.Source == "binance" && candle.Resolution == "1h" {
+			assert.Equal(t, start, candle.OpenTime)
+			assert.Equal(t, "100", candle.Open.String())
+			assert.Equal(t, "104", candle.Close.String())
+			assert.Equal(t, int64(5), candle.Count)
+		}
+	}
+}
+
+func TestCandle_Merge(t *testing.T) {
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	early := Candle{
+		Open: decimal.NewFromInt(100), High: decimal.NewFromInt(105), Low: decimal.NewFromInt(99), Close: decimal.NewFromInt(101),
+		Count: 3, FirstTick: start.Add(5 * time.Second), LastTick: start.Add(20 * time.Second),
+	}
+	late := Candle{
+		Open: decimal.NewFromInt(102), High: decimal.NewFromInt(103), Low: decimal.NewFromInt(97), Close: decimal.NewFromInt(98),
+		Count: 2, FirstTick: start.Add(30 * time.Second), LastTick: start.Add(50 * time.Second),
+	}
+
+	for _, merged := range []Candle{early.Merge(late), late.Merge(early)} {
+		assert.Equal(t, "100", merged.Open.String())
+		assert.Equal(t, "105", merged.High.String())
+		assert.Equal(t, "97", merged.Low.String())
+		assert.Equal(t, "98", merged.Close.String())
+		assert.Equal(t, int64(5), merged.Count)
+		assert.Equal(t, start.Add(5*time.Second), merged.FirstTick)
+		assert.Equal(t, start.Add(50*time.Second), merged.LastTick)
+	}
+}
+
+func TestCandleResolutionFor(t *testing.T) {
+	tests := []struct {
+		step time.Duration
+		want string
+	}{
+		{step: time.Second, want: "1m"},
+		{step: time.Minute, want: "1m"},
+		{step: 10 * time.Minute, want: "5m"},
+		{step: 48 * time.Minute, want: "15m"},
+		{step: 24 * time.Hour, want: "1d"},
+		{step: 7 * 24 * time.Hour, want: "1d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.step.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, CandleResolutionFor(tt.step).Name)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+const (
+	ClientOpSubscribe   = "subscribe"
+	ClientOpUnsubscribe = "unsubscribe"
+
+	ClientEventPrice  = "price"
+	ClientEventCandle = "candle"
+	ClientEventAlert  = "alert"
+	// ClientEventSubscribed and ClientEventUnsubscribed answer a request with the subscriptions of the connection
+	ClientEventSubscribed   = "subscribed"
+	ClientEventUnsubscribed = "unsubscribed"
+	ClientEventError        = "error"
+)
+
+// ClientRequest is a message sent by a client of the websocket API,
+// such as {"op":"subscribe","symbols":["BTC","ETH"]}. Currency defaults to USDT
+type ClientRequest struct {
+	ID       int64    `json:"id,omitempty"`
+	Op       string   `json:"op"`
+	Symbols  []string `json:"symbols"`
+	Currency string   `json:"currency"`
+}
+
+// ClientEvent is a message sent to a client of the websocket API, either an event of a subscribed market
+// or the response to a request, which carries the ID of the request
+type ClientEvent struct {
+	Type          string   `json:"type"`
+	ID            int64    `json:"id,omitempty"`
+	Data          any      `json:"data,omitempty"`
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"backend/internal/constant"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+const (
+	CoinbaseTypeSubscribe     = "subscribe"
+	CoinbaseTypeSubscriptions = "subscriptions"
+	CoinbaseTypeTicker        = "ticker"
+	CoinbaseTypeError         = "error"
+
+	CoinbaseChannelTicker = "ticker"
+)
+
+// CoinbaseSubscribeRequest subscribes to channels of Coinbase Exchange websocket feed
+type CoinbaseSubscribeRequest struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+}
+
+// CoinbaseMessage is any message received from Coinbase Exchange websocket feed
+type CoinbaseMessage struct {
+	Type      string    `json:"type"`
+	ProductID string    `json:"product_id"`
+	Price     string    `json:"price"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+	Reason    string    `json:"reason"`
+}
+
+// NewPriceDatumFromCoinbaseTicker converts a ticker message of product like BTC-USD into a datum
+func NewPriceDatumFromCoinbaseTicker(msg CoinbaseMessage) (PriceDatum, error) {
+	base, quote, ok := strings.Cut(msg.ProductID, "-")
+	if !ok {
+		return PriceDatum{}, fmt.Errorf("invalid product id '%s'", msg.ProductID)
+	}
+
+	price, err := decimal.NewFromString(msg.Price)
+	if err != nil {
+		return PriceDatum{}, fmt.Errorf("parse decimal: %w", err)
+	}
+
+	return PriceDatum{
+		Timestamp: msg.Time.UTC(),
+		Symbol:    base,
+		Currency:  quote,
+		Source:    constant.SourceCoinbase,
+		Price:     price,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewPriceDatumFromCoinbaseTicker(t *testing.T) {
+	timestamp := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		msg     CoinbaseMessage
+		want    PriceDatum
+		wantErr bool
+	}{
+		{
+			name: "success",
+			msg: CoinbaseMessage{
+				Type:      "ticker",
+				ProductID: "BTC-USD",
+				Price:     "123.45",
+				Time:      timestamp,
+			},
+			want: PriceDatum{
+				Timestamp: timestamp,
+				Symbol:    "BTC",
+				Currency:  "USD",
+				Source:    "coinbase",
+				Price:     decimal.RequireFromString("123.45"),
+			},
+		},
+		{
+			name: "invalid product id",
+			msg: CoinbaseMessage{
+				ProductID: "BTCUSD",
+				Price:     "123.45",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid price",
+			msg: CoinbaseMessage{
+				ProductID: "BTC-USD",
+				Price:     "abc",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPriceDatumFromCoinbaseTicker(tt.msg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPriceDatumFromCoinbaseTicker() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPriceDatumFromCoinbaseTicker() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
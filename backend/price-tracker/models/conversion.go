@@ -0,0 +1,38 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type ConversionRequest struct {
+	From   string
+	To     string
+	Amount decimal.Decimal
+}
+
+// Conversion is the result of converting an amount through one or more markets
+type Conversion struct {
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+	Rate   decimal.Decimal `json:"rate"`
+	Result decimal.Decimal `json:"result"`
+	// Path lists the assets traversed, e.g. ETH -> USDT -> EUR
+	Path []string        `json:"path"`
+	Legs []ConversionLeg `json:"legs"`
+	// Timestamp is the timestamp of the oldest leg
+	Timestamp time.Time `json:"timestamp,omitzero"`
+}
+
+// ConversionLeg is a single market used in a conversion.
+// Inverted legs go from the quote to the base asset of the market
+type ConversionLeg struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Market    string          `json:"market"`
+	Source    string          `json:"source,omitempty"`
+	Inverted  bool            `json:"inverted"`
+	Rate      decimal.Decimal `json:"rate"`
+	Timestamp time.Time       `json:"timestamp,omitzero"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	DataGapStatusOpen     = "open"
+	DataGapStatusRepaired = "repaired"
+	DataGapStatusFailed   = "failed"
+)
+
+// DataGap is a hole in the price series of a symbol, between the last price before it and the first after it
+type DataGap struct {
+	bun.BaseModel `json:"-" bun:"table:data_gaps"`
+	ID            int64     `json:"id" bun:"id,pk,autoincrement"`
+	Symbol        string    `json:"symbol" bun:"symbol"`
+	Currency      string    `json:"currency" bun:"currency"`
+	Source        string    `json:"source" bun:"source"`
+	StartTime     time.Time `json:"start_time" bun:"start_time"`
+	EndTime       time.Time `json:"end_time" bun:"end_time"`
+	Status        string    `json:"status" bun:"status"`
+	// Filled is the number of intervals stored by the repair
+	Filled     int64     `json:"filled" bun:"filled"`
+	Attempts   int       `json:"attempts" bun:"attempts"`
+	Error      string    `json:"error,omitempty" bun:"error"`
+	DetectedAt time.Time `json:"detected_at" bun:"detected_at"`
+	RepairedAt time.Time `json:"repaired_at,omitzero" bun:"repaired_at"`
+}
+
+// Duration returns the time between the prices around the gap
+func (d *DataGap) Duration() time.Duration {
+	return d.EndTime.Sub(d.StartTime)
+}
+
+// GapScanRequest asks for the gaps longer than MinDuration in a price series between From and To
+type GapScanRequest struct {
+	Symbol      string
+	Currency    string
+	Source      string
+	From        time.Time
+	To          time.Time
+	MinDuration time.Duration
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Freshness is how long ago the latest price served was received
+type Freshness struct {
+	Age time.Duration
+	// Stale is true once the age is over the stale threshold
+	Stale bool
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	HealthStatusOk       = "ok"
+	HealthStatusDegraded = "degraded"
+
+	WorkerConnectionWaiting    = "waiting"
+	WorkerConnectionConnecting = "connecting"
+	WorkerConnectionConnected  = "connected"
+	WorkerConnectionStopped    = "stopped"
+)
+
+// Health is the status of the service, degraded while the circuit of an exchange is not closed
+type Health struct {
+	Status  string         `json:"status"`
+	Workers []WorkerStatus `json:"workers"`
+}
+
+// WorkerStatus is the connection of the worker of an exchange and the state of its circuit breaker
+type WorkerStatus struct {
+	Source     string    `json:"source"`
+	Connection string    `json:"connection"`
+	Since      time.Time `json:"since"`
+	Circuit    string    `json:"circuit"`
+	// Failures counts the consecutive failed connections
+	Failures  int       `json:"failures"`
+	LastError string    `json:"last_error,omitempty"`
+	RetryAt   time.Time `json:"retry_at,omitzero"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"backend/internal/constant"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+const (
+	KrakenMethodSubscribe = "subscribe"
+	KrakenChannelTicker   = "ticker"
+)
+
+// KrakenSubscribeRequest subscribes to a channel of Kraken websocket v2
+type KrakenSubscribeRequest struct {
+	Method string                `json:"method"`
+	Params KrakenSubscribeParams `json:"params"`
+}
+
+type KrakenSubscribeParams struct {
+	Channel string   `json:"channel"`
+	Symbol  []string `json:"symbol"`
+}
+
+// KrakenMessage is any message received from Kraken websocket v2.
+// Channel messages carry Channel and Data, method responses carry Method, Success and Error
+type KrakenMessage struct {
+	Channel string         `json:"channel"`
+	Type    string         `json:"type"`
+	Data    []KrakenTicker `json:"data"`
+	Method  string         `json:"method"`
+	Success *bool          `json:"success"`
+	Error   string         `json:"error"`
+}
+
+type KrakenTicker struct {
+	Symbol string `json:"symbol"`
+	// Last is sent as a JSON number, it is decoded from its text without going through float64
+	Last decimal.Decimal `json:"last"`
+}
+
+// NewPriceDatumFromKrakenTicker converts a ticker of pair like BTC/USD into a datum
+func NewPriceDatumFromKrakenTicker(ticker KrakenTicker, timestamp time.Time) (PriceDatum, error) {
+	base, quote, ok := strings.Cut(ticker.Symbol, "/")
+	if !ok {
+		return PriceDatum{}, fmt.Errorf("invalid pair '%s'", ticker.Symbol)
+	}
+
+	return PriceDatum{
+		Timestamp: timestamp,
+		Symbol:    base,
+		Currency:  quote,
+		Source:    constant.SourceKraken,
+		Price:     ticker.Last,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewPriceDatumFromKrakenTicker(t *testing.T) {
+	timestamp := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		ticker  KrakenTicker
+		want    PriceDatum
+		wantErr bool
+	}{
+		{
+			name: "success",
+			ticker: KrakenTicker{
+				Symbol: "ETH/USDT",
+				Last:   decimal.RequireFromString("2500.5"),
+			},
+			want: PriceDatum{
+				Timestamp: timestamp,
+				Symbol:    "ETH",
+				Currency:  "USDT",
+				Source:    "kraken",
+				Price:     decimal.RequireFromString("2500.5"),
+			},
+		},
+		{
+			name: "invalid pair",
+			ticker: KrakenTicker{
+				Symbol: "ETHUSDT",
+				Last:   decimal.RequireFromString("2500.5"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPriceDatumFromKrakenTicker(tt.ticker, timestamp)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPriceDatumFromKrakenTicker() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPriceDatumFromKrakenTicker() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// MarketStats is the best bid and ask and the rolling 24h statistics of a market,
+// only the latest of each market is kept
+type MarketStats struct {
+	bun.BaseModel `json:"-" bun:"table:market_stats"`
+	Symbol        string    `json:"symbol,omitempty" bun:"symbol,pk"`
+	Currency      string    `json:"currency,omitempty" bun:"currency,pk"`
+	Source        string    `json:"source,omitempty" bun:"source,pk"`
+	Timestamp     time.Time `json:"timestamp,omitzero" bun:"timestamp"`
+	// BidPrice and AskPrice are the best prices of the order book, with the quantity available at them
+	BidPrice     decimal.Decimal `json:"bid_price" bun:"bid_price"`
+	BidQuantity  decimal.Decimal `json:"bid_quantity" bun:"bid_quantity"`
+	AskPrice     decimal.Decimal `json:"ask_price" bun:"ask_price"`
+	AskQuantity  decimal.Decimal `json:"ask_quantity" bun:"ask_quantity"`
+	LastQuantity decimal.Decimal `json:"last_quantity" bun:"last_quantity"`
+	// Volume is traded in the base asset and QuoteVolume in the quote asset over the last 24h
+	Volume             decimal.Decimal `json:"volume" bun:"volume"`
+	QuoteVolume        decimal.Decimal `json:"quote_volume" bun:"quote_volume"`
+	High               decimal.Decimal `json:"high" bun:"high"`
+	Low                decimal.Decimal `json:"low" bun:"low"`
+	PriceChangePercent decimal.Decimal `json:"price_change_percent" bun:"price_change_percent"`
+}
+
+// StatsCacheKey returns the key that the latest stats of a symbol quoted in currency are cached under.
+// An empty source refers to the latest stats received from any source
+func StatsCacheKey(source, symbol, currency string) string {
+	return "stats:" + CacheKey(source, symbol, currency)
+}
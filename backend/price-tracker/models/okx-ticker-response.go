@@ -0,0 +1,72 @@
+package models
+
+import (
+	"backend/internal/constant"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OkxOpSubscribe    = "subscribe"
+	OkxEventSubscribe = "subscribe"
+	OkxEventError     = "error"
+	OkxChannelTickers = "tickers"
+	OkxPing           = "ping"
+	OkxPong           = "pong"
+)
+
+// OkxSubscribeRequest subscribes to channels of OKX public websocket v5
+type OkxSubscribeRequest struct {
+	Op   string   `json:"op"`
+	Args []OkxArg `json:"args"`
+}
+
+type OkxArg struct {
+	Channel string `json:"channel"`
+	InstID  string `json:"instId"`
+}
+
+// OkxMessage is any message received from OKX public websocket v5.
+// Pushed data carries Arg and Data, events carry Event, Code and Msg
+type OkxMessage struct {
+	Event string      `json:"event"`
+	Code  string      `json:"code"`
+	Msg   string      `json:"msg"`
+	Arg   OkxArg      `json:"arg"`
+	Data  []OkxTicker `json:"data"`
+}
+
+type OkxTicker struct {
+	InstID string `json:"instId"`
+	Last   string `json:"last"`
+	Ts     string `json:"ts"`
+}
+
+// NewPriceDatumFromOkxTicker converts a ticker of instrument like BTC-USDT into a datum
+func NewPriceDatumFromOkxTicker(ticker OkxTicker) (PriceDatum, error) {
+	base, quote, ok := strings.Cut(ticker.InstID, "-")
+	if !ok {
+		return PriceDatum{}, fmt.Errorf("invalid instrument id '%s'", ticker.InstID)
+	}
+
+	price, err := decimal.NewFromString(ticker.Last)
+	if err != nil {
+		return PriceDatum{}, fmt.Errorf("parse decimal: %w", err)
+	}
+
+	ts, err := strconv.ParseInt(ticker.Ts, 10, 64)
+	if err != nil {
+		return PriceDatum{}, fmt.Errorf("parse timestamp: %w", err)
+	}
+
+	return PriceDatum{
+		Timestamp: time.UnixMilli(ts).UTC(),
+		Symbol:    base,
+		Currency:  quote,
+		Source:    constant.SourceOkx,
+		Price:     price,
+	}, nil
+}
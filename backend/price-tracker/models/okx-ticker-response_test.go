@@ -0,0 +1,63 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewPriceDatumFromOkxTicker(t *testing.T) {
+	tests := []struct {
+		name    string
+		ticker  OkxTicker
+		want    PriceDatum
+		wantErr bool
+	}{
+		{
+			name: "success",
+			ticker: OkxTicker{
+				InstID: "BTC-USDT",
+				Last:   "64000.1",
+				Ts:     "1700000000000",
+			},
+			want: PriceDatum{
+				Timestamp: time.UnixMilli(1700000000000).UTC(),
+				Symbol:    "BTC",
+				Currency:  "USDT",
+				Source:    "okx",
+				Price:     decimal.RequireFromString("64000.1"),
+			},
+		},
+		{
+			name: "invalid instrument id",
+			ticker: OkxTicker{
+				InstID: "BTCUSDT",
+				Last:   "64000.1",
+				Ts:     "1700000000000",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid timestamp",
+			ticker: OkxTicker{
+				InstID: "BTC-USDT",
+				Last:   "64000.1",
+				Ts:     "abc",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewPriceDatumFromOkxTicker(tt.ticker)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPriceDatumFromOkxTicker() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPriceDatumFromOkxTicker() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,10 @@
 package models
 
 import (
+	"backend/internal/constant"
 	"fmt"
+	"github.com/shopspring/decimal"
 	"github.com/uptrace/bun"
-	"strconv"
 	"time"
 )
 
@@ -12,18 +13,58 @@ type PriceDatum struct {
 	Timestamp     time.Time `json:"timestamp,omitzero" bun:"timestamp"`
 	Symbol        string    `json:"symbol,omitempty" bun:"symbol"`
 	Currency      string    `json:"currency,omitempty" bun:"currency"`
-	Price         float64   `json:"price,omitempty" bun:"price"`
+	Source        string    `json:"source,omitempty" bun:"source"`
+	// Price is exact, it is serialized as a string
+	Price decimal.Decimal `json:"price,omitzero" bun:"price"`
+	// TickSize is the price increment of the market, when the exchange publishes it
+	TickSize decimal.Decimal `json:"tick_size,omitzero" bun:"-"`
+	// Stats are the bid/ask and 24h stats of the market, only set on the latest price
+	Stats *MarketStats `json:"stats,omitempty" bun:"-"`
+	// ReceivedAt is when the price was received from its exchange, only set on the cached prices
+	ReceivedAt time.Time `json:"-" bun:"-"`
 }
 
-func NewPriceDatumFromBinanceResult(res BinanceResult, timestamp time.Time) (PriceDatum, error) {
-	price, err := strconv.ParseFloat(res.Price, 64)
+// Received returns when the price was received, its timestamp if that is not known as for the stored prices
+func (p PriceDatum) Received() time.Time {
+	if p.ReceivedAt.IsZero() {
+		return p.Timestamp
+	}
+
+	return p.ReceivedAt
+}
+
+func NewPriceDatumFromBinanceResult(res BinanceResult, pair SymbolPair, timestamp time.Time) (PriceDatum, error) {
+	price, err := decimal.NewFromString(res.Price)
 	if err != nil {
-		return PriceDatum{}, fmt.Errorf("parse float: %w", err)
+		return PriceDatum{}, fmt.Errorf("parse decimal: %w", err)
 	}
 	return PriceDatum{
 		Timestamp: timestamp,
-		Symbol:    res.Symbol[:len(res.Symbol)-4],
-		Currency:  res.Symbol[len(res.Symbol)-4:],
-		Price:     price,
+		Symbol:    pair.Base,
+		Currency:  pair.Quote,
+		Source:    constant.SourceBinance,
+		Price:     RoundToTick(price, pair.TickSize),
+		TickSize:  pair.TickSize,
 	}, nil
 }
+
+// RoundToTick returns the price rounded to the nearest multiple of the tick size,
+// with as many decimal places as the tick size. A zero tick size leaves the price untouched
+func RoundToTick(price, tickSize decimal.Decimal) decimal.Decimal {
+	if !tickSize.IsPositive() {
+		return price
+	}
+
+	places := max(-tickSize.Exponent(), 0)
+	return price.DivRound(tickSize, 0).Mul(tickSize).Round(places)
+}
+
+// CacheKey returns the key that the latest datum of a symbol quoted in currency is cached under.
+// An empty source refers to the latest datum received from any source
+func CacheKey(source, symbol, currency string) string {
+	if source == "" {
+		return symbol + "/" + currency
+	}
+
+	return source + ":" + symbol + "/" + currency
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/shopspring/decimal"
 	"reflect"
 	"testing"
 	"time"
@@ -9,6 +10,7 @@ import (
 func TestNewPriceDatumFromBinanceResult(t *testing.T) {
 	type args struct {
 		res       BinanceResult
+		pair      SymbolPair
 		timestamp time.Time
 	}
 	tests := []struct {
@@ -24,11 +26,48 @@ func TestNewPriceDatumFromBinanceResult(t *testing.T) {
 					Symbol: "BTCUSDT",
 					Price:  "123",
 				},
+				pair: SymbolPair{Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT"},
 			},
 			want: PriceDatum{
 				Symbol:   "BTC",
 				Currency: "USDT",
-				Price:    123,
+				Source:   "binance",
+				Price:    decimal.RequireFromString("123"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "quote other than usdt",
+			args: args{
+				res: BinanceResult{
+					Symbol: "ETHBTC",
+					Price:  "0.05",
+				},
+				pair: SymbolPair{Symbol: "ETHBTC", Base: "ETH", Quote: "BTC"},
+			},
+			want: PriceDatum{
+				Symbol:   "ETH",
+				Currency: "BTC",
+				Source:   "binance",
+				Price:    decimal.RequireFromString("0.05"),
+			},
+			wantErr: false,
+		},
+		{
+			name: "low priced token keeps every digit",
+			args: args{
+				res: BinanceResult{
+					Symbol: "SHIBUSDT",
+					Price:  "0.00001234",
+				},
+				pair: SymbolPair{Symbol: "SHIBUSDT", Base: "SHIB", Quote: "USDT", TickSize: decimal.RequireFromString("0.00000001")},
+			},
+			want: PriceDatum{
+				Symbol:   "SHIB",
+				Currency: "USDT",
+				Source:   "binance",
+				Price:    decimal.RequireFromString("0.00001234"),
+				TickSize: decimal.RequireFromString("0.00000001"),
 			},
 			wantErr: false,
 		},
@@ -45,7 +84,7 @@ func TestNewPriceDatumFromBinanceResult(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := NewPriceDatumFromBinanceResult(tt.args.res, tt.args.timestamp)
+			got, err := NewPriceDatumFromBinanceResult(tt.args.res, tt.args.pair, tt.args.timestamp)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewPriceDatumFromBinanceResult() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -56,3 +95,77 @@ func TestNewPriceDatumFromBinanceResult(t *testing.T) {
 		})
 	}
 }
+
+func TestRoundToTick(t *testing.T) {
+	tests := []struct {
+		name     string
+		price    string
+		tickSize string
+		want     string
+	}{
+		{
+			name:     "multiple of the tick size",
+			price:    "64000.01000000",
+			tickSize: "0.01",
+			want:     "64000.01",
+		},
+		{
+			name:     "rounded to the nearest tick",
+			price:    "0.000012346",
+			tickSize: "0.00000001",
+			want:     "0.00001235",
+		},
+		{
+			name:     "tick size above one",
+			price:    "1234",
+			tickSize: "5",
+			want:     "1235",
+		},
+		{
+			name:     "unknown tick size",
+			price:    "0.1234567890123456789",
+			tickSize: "0",
+			want:     "0.1234567890123456789",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RoundToTick(decimal.RequireFromString(tt.price), decimal.RequireFromString(tt.tickSize))
+			if got.String() != tt.want {
+				t.Errorf("RoundToTick() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		symbol   string
+		currency string
+		want     string
+	}{
+		{
+			name:     "any source",
+			source:   "",
+			symbol:   "BTC",
+			currency: "USDT",
+			want:     "BTC/USDT",
+		},
+		{
+			name:     "single source",
+			source:   "kraken",
+			symbol:   "BTC",
+			currency: "EUR",
+			want:     "kraken:BTC/EUR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CacheKey(tt.source, tt.symbol, tt.currency); got != tt.want {
+				t.Errorf("CacheKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PriceHistoryRequest asks for the prices of a symbol between From and To,
+// downsampled to the last price of every Step, or of Points equal buckets if Step is zero.
+// A zero From starts at the earliest stored price
+type PriceHistoryRequest struct {
+	Symbol   string
+	Currency string
+	Source   string
+	From     time.Time
+	To       time.Time
+	Points   int
+	Step     time.Duration
+	// MaxPoints bounds the number of steps the range is split into, once a zero From is resolved. 0 is unbounded
+	MaxPoints int
+}
+
+// PriceHistoryPresets are the ranges accepted as interval, ending now.
+// A zero duration covers all stored prices
+var PriceHistoryPresets = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24":  24 * time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"1y":  365 * 24 * time.Hour,
+	"all": 0,
+}
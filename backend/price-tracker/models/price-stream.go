@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PriceStreamRequest asks for the prices of Symbols quoted in Currency, from Source if set.
+// When resuming a stream, Since and Limit bound the stored prices replayed, oldest first
+type PriceStreamRequest struct {
+	Symbols  []string
+	Currency string
+	Source   string
+	Since    time.Time
+	Limit    int
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PriceTick is published on the event bus for each batch of prices a fetcher receives from its exchange
+type PriceTick struct {
+	Prices []PriceDatum
+	// ReceivedAt is when the fetcher received the prices
+	ReceivedAt time.Time
+}
+
+// MarketStatsTick is published on the event bus for each batch of market stats a fetcher receives
+type MarketStatsTick struct {
+	Stats      []MarketStats
+	ReceivedAt time.Time
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"github.com/uptrace/bun"
+	"time"
+)
+
+const (
+	// WebhookFormatJSON posts the event in a WebhookPayload, WebhookFormatSlack and WebhookFormatDiscord
+	// post its text as a message of a Slack or Discord incoming webhook
+	WebhookFormatJSON    = "json"
+	WebhookFormatSlack   = "slack"
+	WebhookFormatDiscord = "discord"
+
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	// WebhookDeliveryDead is a delivery given up after its last attempt, until it is retried by hand
+	WebhookDeliveryDead = "dead"
+
+	WebhookEventAlert = "alert"
+)
+
+// Webhook is an HTTP endpoint the events are posted to
+type Webhook struct {
+	bun.BaseModel `json:"-" bun:"table:webhooks"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	Name          string `json:"name" bun:"name"`
+	URL           string `json:"url" bun:"url"`
+	Format        string `json:"format" bun:"format"`
+	// Secret is the key of the HMAC-SHA256 signature of the payloads, only returned when the webhook is created
+	Secret    string    `json:"secret,omitempty" bun:"secret"`
+	Enabled   bool      `json:"enabled" bun:"enabled"`
+	CreatedAt time.Time `json:"created_at" bun:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bun:"updated_at"`
+}
+
+// WebhookEvent is an event to post to the webhooks, Title and Text being its summary for chat formats
+type WebhookEvent struct {
+	Type  string
+	Title string
+	Text  string
+	Data  any
+	Time  time.Time
+}
+
+// WebhookPayload is the body posted to the webhooks of the json format
+type WebhookPayload struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// WebhookDelivery is a payload to post to a webhook, kept in the outbox until it is delivered or given up
+type WebhookDelivery struct {
+	bun.BaseModel `json:"-" bun:"table:webhook_deliveries"`
+	ID            int64  `json:"id" bun:"id,pk,autoincrement"`
+	WebhookID     int64  `json:"webhook_id" bun:"webhook_id"`
+	EventType     string `json:"event_type" bun:"event_type"`
+	Payload       string `json:"payload" bun:"payload"`
+	Status        string `json:"status" bun:"status"`
+	Attempts      int    `json:"attempts" bun:"attempts"`
+	// NextAttemptAt is when a pending delivery is due
+	NextAttemptAt  time.Time `json:"next_attempt_at,omitzero" bun:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code,omitempty" bun:"last_status_code"`
+	LastError      string    `json:"last_error,omitempty" bun:"last_error"`
+	CreatedAt      time.Time `json:"created_at" bun:"created_at"`
+	DeliveredAt    time.Time `json:"delivered_at,omitzero" bun:"delivered_at"`
+}
+
+// WebhookDeliveryRequest asks for the latest deliveries, of a status and of a webhook if set
+type WebhookDeliveryRequest struct {
+	Status    string
+	WebhookID int64
+	Limit     int
+}
+
+// WebhookEvent returns the event posted to the webhooks when the alert fires
+func (a AlertEvent) WebhookEvent() WebhookEvent {
+	return WebhookEvent{
+		Type:  WebhookEventAlert,
+		Title: a.Title(),
+		Text:  a.Message,
+		Data:  a,
+		Time:  a.FiredAt,
+	}
+}
@@ -0,0 +1,1082 @@
+package repositories
+
+import (
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// sqlDialect holds the SQL that differs between the databases
+type sqlDialect struct {
+	// least and greatest are the scalar minimum and maximum functions
+	least    string
+	greatest string
+	// epoch returns the expression of a timestamp column in fractional unix seconds
+	epoch func(column string) string
+	// floor returns the expression of a non-negative number rounded down to an integer
+	floor func(expr string) string
+	// decimal returns the expression of a price column that compares by value, nil if the database cannot compare
+	// prices exactly. The highs and lows of the candles are then merged in Go before they are upserted
+	decimal func(column string) string
+	// skipLocked is the locking clause of the rows a transaction claims, skipping those another one has claimed,
+	// empty if the database runs one writing transaction at a time
+	skipLocked string
+}
+
+// bunRepository implements the queries shared by the databases supported by bun
+type bunRepository struct {
+	db      *bun.DB
+	dialect sqlDialect
+}
+
+func (s *bunRepository) Insert(ctx context.Context, datum models.PriceDatum) error {
+	return s.BulkInsert(ctx, []models.PriceDatum{datum})
+}
+
+// BulkInsert writes the data and merges it into the candles of every resolution in a single transaction
+func (s *bunRepository) BulkInsert(ctx context.Context, data []models.PriceDatum) error {
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewInsert().Model(&data).Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = s.upsertCandles(ctx, tx, models.NewCandlesFromPriceData(data))
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// upsertCandles merges the candles into the stored candles of the same period
+func (s *bunRepository) upsertCandles(ctx context.Context, db bun.IDB, candles []models.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+
+	high, low := "high = excluded.high", "low = excluded.low"
+	if s.dialect.decimal != nil {
+		high = "high = CASE WHEN " + s.dialect.decimal("excluded.high") + " > " + s.dialect.decimal("?TableAlias.high") +
+			" THEN excluded.high ELSE ?TableAlias.high END"
+		low = "low = CASE WHEN " + s.dialect.decimal("excluded.low") + " < " + s.dialect.decimal("?TableAlias.low") +
+			" THEN excluded.low ELSE ?TableAlias.low END"
+	} else {
+		err := mergeStoredExtremes(ctx, db, candles)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := db.NewInsert().
+		Model(&candles).
+		On("CONFLICT (symbol, currency, source, resolution, open_time) DO UPDATE").
+		Set("open = CASE WHEN excluded.first_tick < ?TableAlias.first_tick THEN excluded.open ELSE ?TableAlias.open END").
+		Set("first_tick = " + s.dialect.least + "(?TableAlias.first_tick, excluded.first_tick)").
+		Set(high).
+		Set(low).
+		Set("close = CASE WHEN excluded.last_tick >= ?TableAlias.last_tick THEN excluded.close ELSE ?TableAlias.close END").
+		Set("last_tick = " + s.dialect.greatest + "(?TableAlias.last_tick, excluded.last_tick)").
+		Set("count = ?TableAlias.count + excluded.count").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("upsert candles: %w", err)
+	}
+
+	return nil
+}
+
+// candleSeries identifies the candles of a market at a resolution
+type candleSeries struct {
+	symbol     string
+	currency   string
+	source     string
+	resolution string
+}
+
+// mergeStoredExtremes widens the high and low of each candle to those of the stored candle of the same period,
+// compared as decimals. It reads the stored candles of each series over the periods of the candles
+func mergeStoredExtremes(ctx context.Context, db bun.IDB, candles []models.Candle) error {
+	type period struct {
+		series   candleSeries
+		openTime int64
+	}
+	index := make(map[period]int, len(candles))
+	spans := make(map[candleSeries][2]time.Time)
+	for i, candle := range candles {
+		series := candleSeries{candle.Symbol, candle.Currency, candle.Source, candle.Resolution}
+		index[period{series, candle.OpenTime.UnixNano()}] = i
+
+		span, ok := spans[series]
+		if !ok || candle.OpenTime.Before(span[0]) {
+			span[0] = candle.OpenTime
+		}
+		if !ok || candle.OpenTime.After(span[1]) {
+			span[1] = candle.OpenTime
+		}
+		spans[series] = span
+	}
+
+	for series, span := range spans {
+		var stored []models.Candle
+		err := db.NewSelect().
+			Model(&stored).
+			Column("open_time", "high", "low").
+			Where("symbol = ?", series.symbol).
+			Where("currency = ?", series.currency).
+			Where("source = ?", series.source).
+			Where("resolution = ?", series.resolution).
+			Where("open_time >= ?", span[0]).
+			Where("open_time <= ?", span[1]).
+			Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("read stored candles: %w", err)
+		}
+
+		for _, old := range stored {
+			i, ok := index[period{series, old.OpenTime.UnixNano()}]
+			if !ok {
+				continue
+			}
+			candles[i].High = decimal.Max(candles[i].High, old.High)
+			candles[i].Low = decimal.Min(candles[i].Low, old.Low)
+		}
+	}
+
+	return nil
+}
+
+func (s *bunRepository) GetLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, error) {
+	var res models.PriceDatum
+
+	query := s.db.NewSelect().
+		Model(&res).
+		Where("symbol = ?", req.Symbol)
+	if req.Currency != "" {
+		query = query.Where("currency = ?", req.Currency)
+	}
+	query = whereSource(query, req.Source)
+	if !req.Timestamp.IsZero() {
+		query = query.Where("timestamp <= ?", req.Timestamp.UTC())
+	}
+
+	err := query.
+		Order("timestamp DESC").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return &res, nil
+}
+
+// GetPricesSince returns the raw prices of the requested symbols stored at or after req.Since, oldest first
+func (s *bunRepository) GetPricesSince(ctx context.Context, req models.PriceStreamRequest) ([]models.PriceDatum, error) {
+	res := make([]models.PriceDatum, 0)
+
+	query := s.db.NewSelect().
+		Model(&res).
+		Where("symbol IN (?)", bun.In(req.Symbols)).
+		Where("timestamp >= ?", req.Since.UTC())
+	if req.Currency != "" {
+		query = query.Where("currency = ?", req.Currency)
+	}
+	query = whereSource(query, req.Source)
+	if req.Limit > 0 {
+		query = query.Limit(req.Limit)
+	}
+
+	err := query.
+		Order("timestamp ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetPriceHistory returns the last price of every step in the requested range.
+// Without a step, the range is split into req.Points buckets, without a source the busiest one is read
+func (s *bunRepository) GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error) {
+	return s.priceHistory(ctx, req, "crypto_price", "timestamp")
+}
+
+// priceHistory returns the last price of every step in the requested range,
+// read from the prices of a table or view and its time column
+func (s *bunRepository) priceHistory(ctx context.Context, req models.PriceHistoryRequest, table, timeColumn string) ([]models.PriceDatum, error) {
+	if req.From.IsZero() {
+		first, err := s.getFirstTimestamp(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if first.IsZero() {
+			return nil, nil
+		}
+
+		req.From = first
+	}
+
+	step := req.Step
+	if step <= 0 {
+		step = max(req.To.Sub(req.From)/time.Duration(max(req.Points, 1)), time.Millisecond)
+	}
+	if req.MaxPoints > 0 && req.To.Sub(req.From)/step > time.Duration(req.MaxPoints) {
+		return nil, fmt.Errorf("%w, %s from %s exceeds %d", ErrTooManyPoints, step, req.From.UTC(), req.MaxPoints)
+	}
+
+	var res []models.PriceDatum
+
+	// Candle closes stand in for the raw prices deleted by retention,
+	// each of them is the raw price at the last tick of the candle. Only the candles of the resolution
+	// of the step are read, the closes of the other resolutions would compete for the same steps
+	resolution := models.CandleResolutionFor(step)
+
+	// A single series is read, the prices of the sources differ and would zigzag between them
+	if req.Source == "" {
+		source, err := s.busiestSource(ctx, req.Symbol, req.Currency, resolution, req.From, req.To)
+		if err != nil {
+			return nil, err
+		}
+		if source == "" {
+			return nil, nil
+		}
+
+		req.Source = source
+	}
+	query := `
+			WITH Ticks AS (
+				SELECT ? AS timestamp, symbol, currency, source, price
+				FROM ?
+				WHERE symbol = ?
+				AND (? = '' OR currency = ?)
+				AND source = ?
+				AND ? >= ?
+				AND ? <= ?
+				UNION ALL
+				SELECT last_tick, symbol, currency, source, close
+				FROM crypto_candle
+				WHERE symbol = ?
+				AND (? = '' OR currency = ?)
+				AND source = ?
+				AND resolution = ?
+				AND last_tick >= ?
+				AND last_tick <= ?
+			),
+			Bucketed AS (
+				SELECT timestamp, symbol, currency, source, price,
+				ROW_NUMBER() OVER (
+					PARTITION BY ` + s.dialect.floor("("+s.dialect.epoch("timestamp")+" - ?) / ?") + `
+					ORDER BY timestamp DESC
+				) AS rn
+				FROM Ticks
+			)
+			SELECT timestamp, symbol, currency, source, price
+			FROM Bucketed
+			WHERE rn = 1
+			ORDER BY timestamp ASC;`
+
+	err := s.db.NewRaw(query,
+		bun.Ident(timeColumn), bun.Ident(table),
+		req.Symbol, req.Currency, req.Currency, req.Source,
+		bun.Ident(timeColumn), req.From.UTC(), bun.Ident(timeColumn), req.To.UTC(),
+		req.Symbol, req.Currency, req.Currency, req.Source, resolution.Name, req.From.UTC(), req.To.UTC(),
+		float64(req.From.UnixMilli())/1000, step.Seconds(),
+	).Scan(ctx, &res)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// getFirstTimestamp returns the timestamp of the earliest price or candle matching the request,
+// zero if there is none
+func (s *bunRepository) getFirstTimestamp(ctx context.Context, req models.PriceHistoryRequest) (time.Time, error) {
+	var price models.PriceDatum
+	var candle models.Candle
+
+	for _, query := range []*bun.SelectQuery{
+		s.db.NewSelect().Model(&price).Column("timestamp").Order("timestamp ASC"),
+		s.db.NewSelect().Model(&candle).Column("first_tick").Order("first_tick ASC"),
+	} {
+		query = query.Where("symbol = ?", req.Symbol)
+		if req.Currency != "" {
+			query = query.Where("currency = ?", req.Currency)
+		}
+		query = whereSource(query, req.Source)
+
+		err := query.Limit(1).Scan(ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("read first timestamp: %w", err)
+		}
+	}
+
+	switch {
+	case candle.FirstTick.IsZero():
+		return price.Timestamp, nil
+	case price.Timestamp.IsZero() || candle.FirstTick.Before(price.Timestamp):
+		return candle.FirstTick, nil
+	default:
+		return price.Timestamp, nil
+	}
+}
+
+func (s *bunRepository) GetAllCryptoInfo(ctx context.Context) ([]models.PriceDatum, error) {
+	res := make([]models.PriceDatum, 0)
+
+	err := s.db.
+		NewRaw("SELECT DISTINCT symbol FROM ?", bun.Ident("crypto_price")).
+		Scan(ctx, &res)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetCandles returns the candles of a symbol opened in the requested range, oldest first.
+// Without a source, the candles are those of the source with the most prices in the range
+func (s *bunRepository) GetCandles(ctx context.Context, req models.CandleRequest) ([]models.Candle, error) {
+	res := make([]models.Candle, 0)
+
+	if req.Source == "" {
+		source, err := s.busiestSource(ctx, req.Symbol, req.Currency, req.Resolution, req.From, req.To)
+		if err != nil {
+			return nil, err
+		}
+		if source == "" {
+			return res, nil
+		}
+
+		req.Source = source
+	}
+
+	query := s.db.NewSelect().
+		Model(&res).
+		Where("symbol = ?", req.Symbol).
+		Where("currency = ?", req.Currency).
+		Where("resolution = ?", req.Resolution.Name).
+		Where("open_time >= ?", req.From).
+		Where("open_time < ?", req.To)
+	query = whereSource(query, req.Source)
+
+	err := query.
+		Order("open_time ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// busiestSource returns the source other than the index with the most prices rolled up into the candles
+// of a market and resolution between from and to, empty if there are none
+func (s *bunRepository) busiestSource(
+	ctx context.Context,
+	symbol, currency string,
+	resolution models.CandleResolution,
+	from, to time.Time,
+) (string, error) {
+	var sources []string
+
+	err := s.db.NewSelect().
+		Model((*models.Candle)(nil)).
+		Column("source").
+		Where("symbol = ?", symbol).
+		Where("? = '' OR currency = ?", currency, currency).
+		Where("source <> ?", constant.SourceIndex).
+		Where("resolution = ?", resolution.Name).
+		Where("open_time >= ?", from.UTC().Truncate(resolution.Duration)).
+		Where("open_time <= ?", to.UTC()).
+		Group("source").
+		OrderExpr("SUM(count) DESC, source ASC").
+		Limit(1).
+		Scan(ctx, &sources)
+	if err != nil {
+		return "", fmt.Errorf("read busiest source: %w", err)
+	}
+	if len(sources) == 0 {
+		return "", nil
+	}
+
+	return sources[0], nil
+}
+
+// ArchivePrices rolls the raw prices older than before into candles, unless a candle already counts them,
+// then deletes them. It works one minute of prices per transaction and returns the number of deleted rows
+func (s *bunRepository) ArchivePrices(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+
+	for {
+		var deleted int64
+
+		err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			var first models.PriceDatum
+			err := tx.NewSelect().
+				Model(&first).
+				Column("timestamp").
+				Where("timestamp < ?", before.UTC()).
+				Order("timestamp ASC").
+				Limit(1).
+				Scan(ctx)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("read first price: %w", err)
+			}
+
+			start := first.Timestamp.UTC().Truncate(time.Minute)
+			end := start.Add(time.Minute)
+			if end.After(before) {
+				end = before.UTC()
+			}
+
+			var rows []models.PriceDatum
+			err = tx.NewSelect().
+				Model(&rows).
+				Where("timestamp >= ?", start).
+				Where("timestamp < ?", end).
+				Scan(ctx)
+			if err != nil {
+				return fmt.Errorf("read prices: %w", err)
+			}
+
+			uncounted, err := uncountedPrices(ctx, tx, start, rows)
+			if err != nil {
+				return err
+			}
+
+			err = s.upsertCandles(ctx, tx, models.NewCandlesFromPriceData(uncounted))
+			if err != nil {
+				return err
+			}
+
+			res, err := tx.NewDelete().
+				Model((*models.PriceDatum)(nil)).
+				Where("timestamp >= ?", start).
+				Where("timestamp < ?", end).
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("delete prices: %w", err)
+			}
+
+			deleted, err = res.RowsAffected()
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		if deleted == 0 {
+			return total, nil
+		}
+
+		total += deleted
+	}
+}
+
+// uncountedPrices returns the rows of a minute not counted by their 1m candle,
+// such as rows stored before candles existed
+func uncountedPrices(ctx context.Context, db bun.IDB, minute time.Time, rows []models.PriceDatum) ([]models.PriceDatum, error) {
+	var candles []models.Candle
+	err := db.NewSelect().
+		Model(&candles).
+		Where("resolution = ?", models.CandleResolutions[0].Name).
+		Where("open_time = ?", minute).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read candles: %w", err)
+	}
+
+	type key struct {
+		symbol, currency, source string
+	}
+	counted := make(map[key]models.Candle, len(candles))
+	for _, candle := range candles {
+		counted[key{candle.Symbol, candle.Currency, candle.Source}] = candle
+	}
+
+	var res []models.PriceDatum
+	for _, row := range rows {
+		timestamp := row.Timestamp.UTC()
+		candle, ok := counted[key{row.Symbol, row.Currency, row.Source}]
+		if ok && !timestamp.Before(candle.FirstTick) && !timestamp.After(candle.LastTick) {
+			continue
+		}
+
+		res = append(res, row)
+	}
+
+	return res, nil
+}
+
+// DeleteCandles deletes the candles of the resolutions opened before the given time
+func (s *bunRepository) DeleteCandles(ctx context.Context, resolutions []string, before time.Time) (int64, error) {
+	if len(resolutions) == 0 {
+		return 0, nil
+	}
+
+	res, err := s.db.NewDelete().
+		Model((*models.Candle)(nil)).
+		Where("resolution IN (?)", bun.In(resolutions)).
+		Where("open_time < ?", before.UTC()).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("delete candles: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// GetPriceSeries returns the symbol, currency and source of every series with prices stored at or after since
+func (s *bunRepository) GetPriceSeries(ctx context.Context, since time.Time) ([]models.PriceDatum, error) {
+	res := make([]models.PriceDatum, 0)
+
+	err := s.db.NewSelect().
+		Model(&res).
+		ColumnExpr("source, symbol, currency").
+		Where("timestamp >= ?", since.UTC()).
+		GroupExpr("source, symbol, currency").
+		OrderExpr("source ASC, symbol ASC, currency ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// FindGaps returns the gaps between consecutive prices of a series longer than req.MinDuration
+func (s *bunRepository) FindGaps(ctx context.Context, req models.GapScanRequest) ([]models.DataGap, error) {
+	var res []models.DataGap
+
+	query := `
+			SELECT symbol, currency, source, start_time, end_time
+			FROM (
+				SELECT symbol, currency, source,
+				LAG(timestamp) OVER (ORDER BY timestamp) AS start_time,
+				timestamp AS end_time
+				FROM crypto_price
+				WHERE symbol = ?
+				AND currency = ?
+				AND source = ?
+				AND timestamp >= ?
+				AND timestamp <= ?
+			) AS series
+			WHERE start_time IS NOT NULL
+			AND ` + s.dialect.epoch("end_time") + " - " + s.dialect.epoch("start_time") + ` > ?
+			ORDER BY start_time ASC;`
+
+	err := s.db.NewRaw(query,
+		req.Symbol, req.Currency, req.Source, req.From.UTC(), req.To.UTC(), req.MinDuration.Seconds(),
+	).Scan(ctx, &res)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// SaveGaps records the gaps, skipping those already recorded
+func (s *bunRepository) SaveGaps(ctx context.Context, gaps []models.DataGap) error {
+	if len(gaps) == 0 {
+		return nil
+	}
+
+	_, err := s.db.NewInsert().
+		Model(&gaps).
+		On("CONFLICT (symbol, currency, source, start_time) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// GetGaps returns the recorded gaps of a status, or of any status if empty
+func (s *bunRepository) GetGaps(ctx context.Context, status string) ([]models.DataGap, error) {
+	res := make([]models.DataGap, 0)
+
+	query := s.db.NewSelect().Model(&res)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	err := query.
+		Order("start_time ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// UpdateGap writes the repair status of a recorded gap
+func (s *bunRepository) UpdateGap(ctx context.Context, gap models.DataGap) error {
+	_, err := s.db.NewUpdate().
+		Model(&gap).
+		Column("status", "filled", "attempts", "error", "repaired_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return nil
+}
+
+// SaveMarketStats replaces the stats of each market by the given ones, unless the stored ones are newer
+func (s *bunRepository) SaveMarketStats(ctx context.Context, stats []models.MarketStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	query := s.db.NewInsert().
+		Model(&stats).
+		On("CONFLICT (symbol, currency, source) DO UPDATE")
+	for _, column := range []string{
+		"timestamp", "bid_price", "bid_quantity", "ask_price", "ask_quantity", "last_quantity",
+		"volume", "quote_volume", "high", "low", "price_change_percent",
+	} {
+		query = query.Set("? = excluded.?", bun.Ident(column), bun.Ident(column))
+	}
+
+	_, err := query.
+		Where("excluded.timestamp >= ?TableAlias.timestamp").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("upsert: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bunRepository) GetMarketStats(ctx context.Context, req models.PriceDatum) (*models.MarketStats, error) {
+	var res models.MarketStats
+
+	query := s.db.NewSelect().
+		Model(&res).
+		Where("symbol = ?", req.Symbol)
+	if req.Currency != "" {
+		query = query.Where("currency = ?", req.Currency)
+	}
+	if req.Source != "" {
+		query = query.Where("source = ?", req.Source)
+	}
+
+	err := query.
+		Order("timestamp DESC").
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return &res, nil
+}
+
+// CreateAlertRule writes a new rule and sets its ID
+func (s *bunRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	_, err := s.db.NewInsert().
+		Model(rule).
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bunRepository) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	res := make([]models.AlertRule, 0)
+
+	err := s.db.NewSelect().
+		Model(&res).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *bunRepository) GetAlertRule(ctx context.Context, id int64) (*models.AlertRule, error) {
+	var res models.AlertRule
+
+	err := s.db.NewSelect().
+		Model(&res).
+		Where("id = ?", id).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return &res, nil
+}
+
+// UpdateAlertRule replaces a rule but its creation time, and returns ErrNotFound if there is none of its ID
+func (s *bunRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	res, err := s.db.NewUpdate().
+		Model(&rule).
+		ExcludeColumn("id", "created_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// DeleteAlertRule deletes a rule, its fired alerts are kept in the history
+func (s *bunRepository) DeleteAlertRule(ctx context.Context, id int64) error {
+	res, err := s.db.NewDelete().
+		Model((*models.AlertRule)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// RecordAlerts writes the evaluation state of the rules and the alerts they fired in a single transaction,
+// the rules deleted meanwhile are skipped
+func (s *bunRepository) RecordAlerts(ctx context.Context, rules []models.AlertRule, events []models.AlertEvent) error {
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, rule := range rules {
+			_, err := tx.NewUpdate().
+				Model(&rule).
+				Column("armed", "side", "last_fired_at").
+				WherePK().
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("update rule %d: %w", rule.ID, err)
+			}
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		_, err := tx.NewInsert().
+			Model(&events).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("insert events: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetAlertEvents returns the latest fired alerts, newest first
+func (s *bunRepository) GetAlertEvents(ctx context.Context, req models.AlertEventRequest) ([]models.AlertEvent, error) {
+	res := make([]models.AlertEvent, 0)
+
+	query := s.db.NewSelect().Model(&res)
+	if req.RuleID != 0 {
+		query = query.Where("rule_id = ?", req.RuleID)
+	}
+
+	err := query.
+		Order("fired_at DESC", "id DESC").
+		Limit(req.Limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// requireAffected returns ErrNotFound if the statement changed no row
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CreateWebhook writes a new webhook and sets its ID
+func (s *bunRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	_, err := s.db.NewInsert().
+		Model(webhook).
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+func (s *bunRepository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	res := make([]models.Webhook, 0)
+
+	err := s.db.NewSelect().
+		Model(&res).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+func (s *bunRepository) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	var res models.Webhook
+
+	err := s.db.NewSelect().
+		Model(&res).
+		Where("id = ?", id).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return &res, nil
+}
+
+// UpdateWebhook replaces a webhook but its creation time, and returns ErrNotFound if there is none of its ID
+func (s *bunRepository) UpdateWebhook(ctx context.Context, webhook models.Webhook) error {
+	res, err := s.db.NewUpdate().
+		Model(&webhook).
+		ExcludeColumn("id", "created_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+// DeleteWebhook deletes a webhook along with its pending deliveries, those done are kept
+func (s *bunRepository) DeleteWebhook(ctx context.Context, id int64) error {
+	return s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		res, err := tx.NewDelete().
+			Model((*models.Webhook)(nil)).
+			Where("id = ?", id).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+
+		err = requireAffected(res)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.WebhookDelivery)(nil)).
+			Where("webhook_id = ?", id).
+			Where("status = ?", models.WebhookDeliveryPending).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("delete pending deliveries: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// SaveDeliveries adds the deliveries to the outbox
+func (s *bunRepository) SaveDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	_, err := s.db.NewInsert().
+		Model(&deliveries).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueDeliveries returns at most limit pending deliveries due at the given time, the longest due first,
+// made due at until in the same transaction. The rows claimed by a concurrent poll are skipped
+func (s *bunRepository) ClaimDueDeliveries(ctx context.Context, at, until time.Time, limit int) ([]models.WebhookDelivery, error) {
+	res := make([]models.WebhookDelivery, 0)
+
+	err := s.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		query := tx.NewSelect().
+			Model(&res).
+			Where("status = ?", models.WebhookDeliveryPending).
+			Where("next_attempt_at <= ?", at).
+			Order("next_attempt_at ASC", "id ASC").
+			Limit(limit)
+		if s.dialect.skipLocked != "" {
+			query = query.For(s.dialect.skipLocked)
+		}
+		err := query.Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("read: %w", err)
+		}
+		if len(res) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, len(res))
+		for i := range res {
+			ids[i] = res[i].ID
+			res[i].NextAttemptAt = until
+		}
+		_, err = tx.NewUpdate().
+			Model((*models.WebhookDelivery)(nil)).
+			Set("next_attempt_at = ?", until).
+			Where("id IN (?)", bun.In(ids)).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("claim: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *bunRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	var res models.WebhookDelivery
+
+	err := s.db.NewSelect().
+		Model(&res).
+		Where("id = ?", id).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return &res, nil
+}
+
+// GetDeliveries returns the latest deliveries, newest first
+func (s *bunRepository) GetDeliveries(ctx context.Context, req models.WebhookDeliveryRequest) ([]models.WebhookDelivery, error) {
+	res := make([]models.WebhookDelivery, 0)
+
+	query := s.db.NewSelect().Model(&res)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.WebhookID != 0 {
+		query = query.Where("webhook_id = ?", req.WebhookID)
+	}
+
+	err := query.
+		Order("id DESC").
+		Limit(req.Limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// UpdateDelivery writes the outcome of an attempt of a delivery, or returns ErrNotFound
+func (s *bunRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	res, err := s.db.NewUpdate().
+		Model(&delivery).
+		Column("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+
+	return requireAffected(res)
+}
+
+func (s *bunRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	_, err := s.db.NewInsert().
+		Model(&anomalies).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("insert: %w", err)
+	}
+
+	return nil
+}
+
+// GetAnomalies returns the latest anomalies, newest first, of the market of the request if set
+func (s *bunRepository) GetAnomalies(ctx context.Context, req models.AnomalyRequest) ([]models.Anomaly, error) {
+	res := make([]models.Anomaly, 0)
+
+	query := s.db.NewSelect().Model(&res)
+	if req.Symbol != "" {
+		query = query.Where("symbol = ?", req.Symbol)
+	}
+	if req.Currency != "" {
+		query = query.Where("currency = ?", req.Currency)
+	}
+	if req.Source != "" {
+		query = query.Where("source = ?", req.Source)
+	}
+
+	err := query.
+		Order("timestamp DESC", "id DESC").
+		Limit(req.Limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	return res, nil
+}
+
+// whereSource filters a query on the source, or on every source but the index if it is empty.
+// The index is computed from the prices of the other sources, it is only read when asked for
+func whereSource(query *bun.SelectQuery, source string) *bun.SelectQuery {
+	if source != "" {
+		return query.Where("source = ?", source)
+	}
+
+	return query.Where("source <> ?", constant.SourceIndex)
+}
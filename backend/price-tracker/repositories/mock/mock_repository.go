@@ -2,24 +2,27 @@ package mock
 
 import (
 	"backend/price-tracker/models"
+	"context"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
+// MockRepository implements repositories.Repository, the context is not part of the expected calls
 type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) GetAllCryptoInfo() ([]models.PriceDatum, error) {
+func (m *MockRepository) GetAllCryptoInfo(ctx context.Context) ([]models.PriceDatum, error) {
 	args := m.Called()
 	return args.Get(0).([]models.PriceDatum), args.Error(1)
 }
 
-func (m *MockRepository) Insert(datum models.PriceDatum) error {
+func (m *MockRepository) Insert(ctx context.Context, datum models.PriceDatum) error {
 	args := m.Called(datum)
 	return args.Error(0)
 }
 
-func (m *MockRepository) GetLatestPrice(req models.PriceDatum) (*models.PriceDatum, error) {
+func (m *MockRepository) GetLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, error) {
 	args := m.Called(req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -27,7 +30,7 @@ func (m *MockRepository) GetLatestPrice(req models.PriceDatum) (*models.PriceDat
 	return args.Get(0).(*models.PriceDatum), args.Error(1)
 }
 
-func (m *MockRepository) GetPriceOfTheLast24h(req models.PriceDatum) ([]models.PriceDatum, error) {
+func (m *MockRepository) GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error) {
 	args := m.Called(req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -35,7 +38,204 @@ func (m *MockRepository) GetPriceOfTheLast24h(req models.PriceDatum) ([]models.P
 	return args.Get(0).([]models.PriceDatum), args.Error(1)
 }
 
-func (m *MockRepository) BulkInsert(data []models.PriceDatum) error {
+func (m *MockRepository) BulkInsert(ctx context.Context, data []models.PriceDatum) error {
 	args := m.Called(data)
 	return args.Error(0)
 }
+
+func (m *MockRepository) GetCandles(ctx context.Context, req models.CandleRequest) ([]models.Candle, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Candle), args.Error(1)
+}
+
+func (m *MockRepository) ArchivePrices(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) DeleteCandles(ctx context.Context, resolutions []string, before time.Time) (int64, error) {
+	args := m.Called(resolutions, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepository) Compact(ctx context.Context, vacuum bool) error {
+	args := m.Called(vacuum)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetPriceSeries(ctx context.Context, since time.Time) ([]models.PriceDatum, error) {
+	args := m.Called(since)
+	return args.Get(0).([]models.PriceDatum), args.Error(1)
+}
+
+func (m *MockRepository) FindGaps(ctx context.Context, req models.GapScanRequest) ([]models.DataGap, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DataGap), args.Error(1)
+}
+
+func (m *MockRepository) SaveGaps(ctx context.Context, gaps []models.DataGap) error {
+	args := m.Called(gaps)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetGaps(ctx context.Context, status string) ([]models.DataGap, error) {
+	args := m.Called(status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DataGap), args.Error(1)
+}
+
+func (m *MockRepository) UpdateGap(ctx context.Context, gap models.DataGap) error {
+	args := m.Called(gap)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveMarketStats(ctx context.Context, stats []models.MarketStats) error {
+	args := m.Called(stats)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetMarketStats(ctx context.Context, req models.PriceDatum) (*models.MarketStats, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MarketStats), args.Error(1)
+}
+
+func (m *MockRepository) GetPricesSince(ctx context.Context, req models.PriceStreamRequest) ([]models.PriceDatum, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.PriceDatum), args.Error(1)
+}
+
+func (m *MockRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AlertRule), args.Error(1)
+}
+
+func (m *MockRepository) GetAlertRule(ctx context.Context, id int64) (*models.AlertRule, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AlertRule), args.Error(1)
+}
+
+func (m *MockRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteAlertRule(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RecordAlerts(ctx context.Context, rules []models.AlertRule, events []models.AlertEvent) error {
+	args := m.Called(rules, events)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetAlertEvents(ctx context.Context, req models.AlertEventRequest) ([]models.AlertEvent, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AlertEvent), args.Error(1)
+}
+
+func (m *MockRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) UpdateWebhook(ctx context.Context, webhook models.Webhook) error {
+	args := m.Called(webhook)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteWebhook(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	args := m.Called(deliveries)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClaimDueDeliveries(ctx context.Context, at, until time.Time, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(at, until, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) GetDeliveries(ctx context.Context, req models.WebhookDeliveryRequest) ([]models.WebhookDelivery, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	args := m.Called(anomalies)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetAnomalies(ctx context.Context, req models.AnomalyRequest) ([]models.Anomaly, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Anomaly), args.Error(1)
+}
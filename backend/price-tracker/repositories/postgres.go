@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"backend/price-tracker/models"
+	"context"
+	"fmt"
+	"github.com/uptrace/bun"
+	"slices"
+	"time"
+)
+
+type PostgresRepositoryImpl struct {
+	bunRepository
+	// timescale is set when the database has the hypertables and aggregates of TimescaleDB
+	timescale bool
+}
+
+func NewPostgresRepository(db *bun.DB, timescale bool) Repository {
+	return &PostgresRepositoryImpl{
+		bunRepository: bunRepository{
+			db: db,
+			dialect: sqlDialect{
+				least:    "LEAST",
+				greatest: "GREATEST",
+				epoch: func(column string) string {
+					return "EXTRACT(EPOCH FROM " + column + ")"
+				},
+				floor: func(expr string) string {
+					return "FLOOR(" + expr + ")"
+				},
+				decimal: func(column string) string {
+					return column
+				},
+				skipLocked: "UPDATE SKIP LOCKED",
+			},
+		},
+		timescale: timescale,
+	}
+}
+
+// GetPriceHistory returns the last price of every step in the requested range.
+// With TimescaleDB, steps of a minute or more are read from the aggregate of the last price of every minute
+func (p *PostgresRepositoryImpl) GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error) {
+	if !p.timescale {
+		return p.bunRepository.GetPriceHistory(ctx, req)
+	}
+
+	step := req.Step
+	if step <= 0 && !req.From.IsZero() {
+		step = req.To.Sub(req.From) / time.Duration(max(req.Points, 1))
+	}
+	if !req.From.IsZero() && step < time.Minute {
+		return p.bunRepository.GetPriceHistory(ctx, req)
+	}
+
+	return p.priceHistory(ctx, req, "crypto_price_1m", "last_tick")
+}
+
+// DeleteCandles deletes the candles of the resolutions opened before the given time. With TimescaleDB, the chunks of
+// the aggregate of the last price of every minute older than that are dropped along with the minute candles
+func (p *PostgresRepositoryImpl) DeleteCandles(ctx context.Context, resolutions []string, before time.Time) (int64, error) {
+	count, err := p.bunRepository.DeleteCandles(ctx, resolutions, before)
+	if err != nil || !p.timescale || !slices.Contains(resolutions, models.CandleResolutions[0].Name) {
+		return count, err
+	}
+
+	_, err = p.db.ExecContext(ctx, "SELECT drop_chunks('crypto_price_1m', older_than => ?::timestamptz)", before)
+	if err != nil {
+		return count, fmt.Errorf("drop aggregate chunks: %w", err)
+	}
+
+	return count, nil
+}
+
+// Compact lets autovacuum reclaim the rows freed by deletions, and vacuums the tables now if vacuum is set
+func (p *PostgresRepositoryImpl) Compact(ctx context.Context, vacuum bool) error {
+	if !vacuum {
+		return nil
+	}
+
+	_, err := p.db.ExecContext(ctx, "VACUUM (ANALYZE) crypto_price, crypto_candle;")
+	if err != nil {
+		return fmt.Errorf("vacuum: %w", err)
+	}
+
+	return nil
+}
@@ -1,16 +1,117 @@
 package repositories
 
-import "backend/price-tracker/models"
+import (
+	"backend/price-tracker/models"
+	"context"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no price matches the request, whatever the database
+var ErrNotFound = sqlite3.ErrNotFound
+
+// ErrTooManyPoints is returned when the step of a history request splits its range into more than its max points
+var ErrTooManyPoints = errors.New("too many points")
+
+// IsBusy returns true if a write failed because another one held the lock it needed,
+// the write can be retried once that one is done
+func IsBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		switch pgErr.Field('C') {
+		// serialization_failure, deadlock_detected and lock_not_available
+		case "40001", "40P01", "55P03":
+			return true
+		}
+		return false
+	}
+
+	// The pure Go SQLite driver, used when cgo is disabled, only tells by the message
+	return err != nil && strings.Contains(err.Error(), "database is locked")
+}
 
 type Repository interface {
 	// Insert writes a single datum
-	Insert(datum models.PriceDatum) error
-	// BulkInsert writes a bulket of data
-	BulkInsert(data []models.PriceDatum) error
-	// GetLatestPrice returns the latest price of a symbol
-	GetLatestPrice(req models.PriceDatum) (*models.PriceDatum, error)
-	// GetPriceOfTheLast24h returns all prices of a symbol in the last 24h
-	GetPriceOfTheLast24h(req models.PriceDatum) ([]models.PriceDatum, error)
+	Insert(ctx context.Context, datum models.PriceDatum) error
+	// BulkInsert writes a bulket of data and rolls it up into candles
+	BulkInsert(ctx context.Context, data []models.PriceDatum) error
+	// GetLatestPrice returns the latest price of a symbol, at or before req.Timestamp if set
+	GetLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, error)
+	// GetPricesSince returns the raw prices of the requested symbols stored at or after req.Since, oldest first
+	GetPricesSince(ctx context.Context, req models.PriceStreamRequest) ([]models.PriceDatum, error)
+	// GetPriceHistory returns the prices of a symbol in the requested range, downsampled by step or points,
+	// of the source with the most prices in the range if req.Source is empty
+	GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error)
 	// GetAllCryptoInfo returns a list of all crypto basic information
-	GetAllCryptoInfo() ([]models.PriceDatum, error)
+	GetAllCryptoInfo(ctx context.Context) ([]models.PriceDatum, error)
+	// GetCandles returns the candles of a symbol opened in the requested range,
+	// of the source with the most prices in the range if req.Source is empty
+	GetCandles(ctx context.Context, req models.CandleRequest) ([]models.Candle, error)
+	// ArchivePrices rolls the raw prices older than before into candles and deletes them
+	ArchivePrices(ctx context.Context, before time.Time) (int64, error)
+	// DeleteCandles deletes the candles of the resolutions opened before the given time
+	DeleteCandles(ctx context.Context, resolutions []string, before time.Time) (int64, error)
+	// Compact reclaims the storage freed by deletions
+	Compact(ctx context.Context, vacuum bool) error
+	// GetPriceSeries returns the symbol, currency and source of every series with prices stored at or after since
+	GetPriceSeries(ctx context.Context, since time.Time) ([]models.PriceDatum, error)
+	// FindGaps returns the gaps between consecutive prices of a series longer than req.MinDuration
+	FindGaps(ctx context.Context, req models.GapScanRequest) ([]models.DataGap, error)
+	// SaveGaps records the gaps, skipping those already recorded
+	SaveGaps(ctx context.Context, gaps []models.DataGap) error
+	// GetGaps returns the recorded gaps of a status, or of any status if empty
+	GetGaps(ctx context.Context, status string) ([]models.DataGap, error)
+	// UpdateGap writes the repair status of a recorded gap
+	UpdateGap(ctx context.Context, gap models.DataGap) error
+	// SaveMarketStats replaces the stats of each market by the given ones
+	SaveMarketStats(ctx context.Context, stats []models.MarketStats) error
+	// GetMarketStats returns the latest stats of a symbol, of req.Currency and req.Source if set
+	GetMarketStats(ctx context.Context, req models.PriceDatum) (*models.MarketStats, error)
+	// CreateAlertRule writes a new rule and sets its ID
+	CreateAlertRule(ctx context.Context, rule *models.AlertRule) error
+	// GetAlertRules returns every rule, in order of creation
+	GetAlertRules(ctx context.Context) ([]models.AlertRule, error)
+	// GetAlertRule returns a rule by ID, or ErrNotFound
+	GetAlertRule(ctx context.Context, id int64) (*models.AlertRule, error)
+	// UpdateAlertRule replaces a rule, or returns ErrNotFound
+	UpdateAlertRule(ctx context.Context, rule models.AlertRule) error
+	// DeleteAlertRule deletes a rule, or returns ErrNotFound
+	DeleteAlertRule(ctx context.Context, id int64) error
+	// RecordAlerts writes the evaluation state of the rules along with the alerts they fired
+	RecordAlerts(ctx context.Context, rules []models.AlertRule, events []models.AlertEvent) error
+	// GetAlertEvents returns the latest fired alerts, newest first
+	GetAlertEvents(ctx context.Context, req models.AlertEventRequest) ([]models.AlertEvent, error)
+	// CreateWebhook writes a new webhook and sets its ID
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	// GetWebhooks returns every webhook, in order of creation
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	// GetWebhook returns a webhook by ID, or ErrNotFound
+	GetWebhook(ctx context.Context, id int64) (*models.Webhook, error)
+	// UpdateWebhook replaces a webhook, or returns ErrNotFound
+	UpdateWebhook(ctx context.Context, webhook models.Webhook) error
+	// DeleteWebhook deletes a webhook and its pending deliveries, or returns ErrNotFound
+	DeleteWebhook(ctx context.Context, id int64) error
+	// SaveDeliveries adds the deliveries to the outbox
+	SaveDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	// ClaimDueDeliveries returns at most limit pending deliveries due at the given time, claimed by making them
+	// due at until so that no other poll returns them meanwhile
+	ClaimDueDeliveries(ctx context.Context, at, until time.Time, limit int) ([]models.WebhookDelivery, error)
+	// GetDelivery returns a delivery by ID, or ErrNotFound
+	GetDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+	// GetDeliveries returns the latest deliveries, newest first
+	GetDeliveries(ctx context.Context, req models.WebhookDeliveryRequest) ([]models.WebhookDelivery, error)
+	// UpdateDelivery writes the outcome of an attempt of a delivery, or returns ErrNotFound
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	// SaveAnomalies writes the anomalies detected in the prices received
+	SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error
+	// GetAnomalies returns the latest anomalies, newest first
+	GetAnomalies(ctx context.Context, req models.AnomalyRequest) ([]models.Anomaly, error)
 }
@@ -0,0 +1,1143 @@
+package repositories
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/price-tracker/models"
+	"context"
+	"errors"
+	"fmt"
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+	"github.com/uptrace/bun"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// RepositoryConformanceSuite runs the same tests against every Repository implementation
+type RepositoryConformanceSuite struct {
+	suite.Suite
+	newDB         func() (*bun.DB, error)
+	newRepository func(db *bun.DB) Repository
+	// timescale is set when the database has the hypertables and aggregates of TimescaleDB
+	timescale  bool
+	db         *bun.DB
+	repository Repository
+}
+
+func (s *RepositoryConformanceSuite) SetupSuite() {
+	db, err := s.newDB()
+	s.Require().NoError(err)
+	s.db = db
+	s.repository = s.newRepository(db)
+
+	// Start from empty tables on databases that outlive the tests
+	for _, model := range []any{(*models.PriceDatum)(nil), (*models.Candle)(nil), (*models.DataGap)(nil), (*models.MarketStats)(nil),
+		(*models.AlertRule)(nil), (*models.AlertEvent)(nil), (*models.Webhook)(nil), (*models.WebhookDelivery)(nil),
+		(*models.Anomaly)(nil)} {
+		_, err = db.NewDelete().Model(model).Where("1 = 1").Exec(context.Background())
+		s.Require().NoError(err)
+	}
+}
+
+func (s *RepositoryConformanceSuite) TearDownSuite() {
+	if s.db != nil {
+		err := s.db.Close()
+		assert.NoError(s.T(), err)
+	}
+}
+
+func TestSqliteRepositorySuite(t *testing.T) {
+	suite.Run(t, &RepositoryConformanceSuite{
+		newDB: func() (*bun.DB, error) {
+			// Use in-memory SQLite for testing
+			return db.NewSqliteDB(config.SqliteConfig{
+				SqliteURL: ":memory:",
+			})
+		},
+		newRepository: NewSqliteRepository,
+	})
+}
+
+// TestPostgresRepositorySuite runs against an embedded Postgres, or against the database of POSTGRES_TEST_URL if set.
+// The suite runs again with TimescaleDB if the database has the extension, such as a local instance started with
+// `docker run -e POSTGRES_PASSWORD=postgres -p 5432:5432 timescale/timescaledb:latest-pg16`
+func TestPostgresRepositorySuite(t *testing.T) {
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		url = startEmbeddedPostgres(t)
+	}
+
+	// Without TimescaleDB first, the hypertables are kept once created
+	for _, timescale := range []bool{false, true} {
+		t.Run(fmt.Sprintf("timescale=%t", timescale), func(t *testing.T) {
+			if timescale && !hasTimescale(t, url) {
+				t.Skip("the timescaledb extension is not available")
+			}
+
+			suite.Run(t, &RepositoryConformanceSuite{
+				newDB: func() (*bun.DB, error) {
+					return db.NewPostgresDB(config.PostgresConfig{
+						PostgresURL:       url,
+						PostgresTimescale: timescale,
+					})
+				},
+				newRepository: func(db *bun.DB) Repository {
+					return NewPostgresRepository(db, timescale)
+				},
+				timescale: timescale,
+			})
+		})
+	}
+}
+
+// startEmbeddedPostgres starts a Postgres server for the test on a free port and returns its URL,
+// its binaries are downloaded on the first run
+func startEmbeddedPostgres(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := uint32(listener.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, listener.Close())
+
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(port).
+		RuntimePath(t.TempDir()).
+		Logger(io.Discard)
+	postgres := embeddedpostgres.NewDatabase(cfg)
+	err = postgres.Start()
+	if err != nil {
+		// Postgres cannot run as root, nor be downloaded without network
+		t.Skipf("start embedded postgres: %v", err)
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, postgres.Stop())
+	})
+
+	return cfg.GetConnectionURL() + "?sslmode=disable"
+}
+
+// hasTimescale returns true if the timescaledb extension can be created in the database
+func hasTimescale(t *testing.T, url string) bool {
+	database, err := db.OpenPostgresDB(config.PostgresConfig{PostgresURL: url})
+	require.NoError(t, err)
+	defer database.Close()
+
+	exists, err := database.NewSelect().
+		Table("pg_available_extensions").
+		Where("name = ?", "timescaledb").
+		Exists(context.Background())
+	require.NoError(t, err)
+
+	return exists
+}
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "sqlite busy", err: fmt.Errorf("bulk insert: %w", sqlite3.Error{Code: sqlite3.ErrBusy}), want: true},
+		{name: "sqlite locked", err: sqlite3.Error{Code: sqlite3.ErrLocked}, want: true},
+		{name: "sqlite constraint", err: sqlite3.Error{Code: sqlite3.ErrConstraint}, want: false},
+		{name: "pure go sqlite busy", err: errors.New("database is locked (5) (SQLITE_BUSY)"), want: true},
+		{name: "not found", err: ErrNotFound, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsBusy(tt.err))
+		})
+	}
+}
+
+func (s *RepositoryConformanceSuite) TestSaveAndGetLatestPrice() {
+	// Arrange
+	symbol := "BTC"
+	expect := models.PriceDatum{
+		Timestamp: time.Now().UTC().Truncate(time.Microsecond),
+		Symbol:    symbol,
+		Currency:  "",
+		Price:     decimal.RequireFromString("50000.00000001"),
+	}
+	// Act - Save
+	err := s.repository.Insert(context.Background(), expect)
+	assert.NoError(s.T(), err)
+
+	// Act - Get Latest
+	result, err := s.repository.GetLatestPrice(context.Background(), expect)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), result)
+	assert.Equal(s.T(), symbol, result.Symbol)
+	assert.Equal(s.T(), expect.Price.String(), result.Price.String())
+	assert.Equal(s.T(), expect.Timestamp, result.Timestamp.UTC())
+}
+
+func (s *RepositoryConformanceSuite) TestGetPriceHistory() {
+	// Arrange
+	symbol := "BTC"
+	expect := []models.PriceDatum{
+		{
+			Timestamp: time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond),
+			Symbol:    symbol,
+			Currency:  "",
+			Price:     decimal.NewFromInt(50000),
+		},
+		{
+			Timestamp: time.Now().UTC().Truncate(time.Microsecond),
+			Symbol:    symbol,
+			Currency:  "",
+			Price:     decimal.NewFromInt(50000),
+		},
+	}
+
+	// Act
+	errWrite := s.repository.BulkInsert(context.Background(), expect)
+	results, err := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: symbol,
+		From:   time.Now().Add(-24 * time.Hour),
+		To:     time.Now(),
+		Points: 30,
+	})
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), errWrite)
+
+	// Verify results are in chronological order
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Timestamp.Before(results[j].Timestamp)
+	})
+	for i, result := range results {
+		assert.Equal(s.T(), symbol, result.Symbol)
+		assert.Equal(s.T(), expect[i].Price.String(), result.Price.String())
+		assert.Equal(s.T(), expect[i].Timestamp, result.Timestamp.UTC())
+	}
+}
+
+func (s *RepositoryConformanceSuite) TestGetLatestPrice_NonExistentSymbol() {
+	// Act
+	result, err := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "NOTEXISTSYMBOL"})
+
+	// Assert
+	assert.Equal(s.T(), ErrNotFound, err)
+	assert.Nil(s.T(), result)
+}
+
+func (s *RepositoryConformanceSuite) TestGetPriceHistory_NoDataInRange() {
+	// Arrange
+	results, err := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "ABC",
+		From:   time.Now().Add(-24 * time.Hour),
+		To:     time.Now(),
+		Points: 30,
+	})
+	all, errAll := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{Symbol: "ABC", To: time.Now(), Points: 30})
+
+	// Assert
+	assert.Nil(s.T(), err)
+	assert.Nil(s.T(), results)
+	assert.Nil(s.T(), errAll)
+	assert.Nil(s.T(), all)
+}
+
+func (s *RepositoryConformanceSuite) TestGetPriceHistory_GetSymbolList() {
+	// Arrange
+	_, err := s.repository.GetAllCryptoInfo(context.Background())
+
+	// Assert
+	assert.Nil(s.T(), err)
+	//assert.Equal(s.T(), 0, len(results))
+}
+
+func (s *RepositoryConformanceSuite) TestGetLatestPrice_BySource() {
+	// Arrange
+	timestamp := time.Now().UTC().Truncate(time.Microsecond)
+	data := []models.PriceDatum{
+		{
+			Timestamp: timestamp.Add(-time.Minute),
+			Symbol:    "SRC",
+			Currency:  "USDT",
+			Source:    "binance",
+			Price:     decimal.NewFromInt(1),
+		},
+		{
+			Timestamp: timestamp,
+			Symbol:    "SRC",
+			Currency:  "USDT",
+			Source:    "kraken",
+			Price:     decimal.NewFromInt(2),
+		},
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	assert.NoError(s.T(), err)
+
+	// Act
+	latest, errLatest := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "SRC"})
+	binance, errBinance := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "SRC", Source: "binance"})
+	before, errBefore := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "SRC", Timestamp: timestamp.Add(-time.Second)})
+
+	// Assert
+	assert.NoError(s.T(), errLatest)
+	assert.Equal(s.T(), "kraken", latest.Source)
+	assert.Equal(s.T(), "2", latest.Price.String())
+	assert.NoError(s.T(), errBinance)
+	assert.Equal(s.T(), "binance", binance.Source)
+	assert.Equal(s.T(), "1", binance.Price.String())
+	assert.NoError(s.T(), errBefore)
+	assert.Equal(s.T(), "binance", before.Source)
+}
+
+func (s *RepositoryConformanceSuite) TestAnySource_ExcludesIndex() {
+	// Arrange
+	timestamp := time.Now().UTC().Truncate(time.Minute)
+	data := []models.PriceDatum{
+		{Timestamp: timestamp.Add(-time.Minute), Symbol: "IDX", Currency: "USDT", Source: "index", Price: decimal.NewFromInt(9)},
+		{Timestamp: timestamp.Add(time.Second), Symbol: "IDX", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(1)},
+		{Timestamp: timestamp.Add(2 * time.Second), Symbol: "IDX", Currency: "USDT", Source: "index", Price: decimal.NewFromInt(2)},
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	s.Require().NoError(err)
+
+	// Act
+	latest, errLatest := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "IDX"})
+	index, errIndex := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "IDX", Source: "index"})
+	since, errSince := s.repository.GetPricesSince(context.Background(), models.PriceStreamRequest{Symbols: []string{"IDX"}, Since: timestamp})
+	history, errHistory := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "IDX", Currency: "USDT", To: timestamp.Add(time.Minute), Step: time.Minute,
+	})
+	candles, errCandles := s.repository.GetCandles(context.Background(), models.CandleRequest{
+		Symbol: "IDX", Currency: "USDT", Resolution: models.CandleResolutions[0],
+		From: timestamp.Add(-time.Minute), To: timestamp.Add(time.Minute),
+	})
+
+	// Assert
+	s.Require().NoError(errLatest)
+	assert.Equal(s.T(), "binance", latest.Source)
+	s.Require().NoError(errIndex)
+	assert.Equal(s.T(), "2", index.Price.String())
+	s.Require().NoError(errSince)
+	s.Require().Len(since, 1)
+	assert.Equal(s.T(), "binance", since[0].Source)
+	s.Require().NoError(errHistory)
+	s.Require().Len(history, 1)
+	assert.Equal(s.T(), "binance", history[0].Source)
+	s.Require().NoError(errCandles)
+	s.Require().Len(candles, 1)
+	assert.Equal(s.T(), "binance", candles[0].Source)
+}
+
+func (s *RepositoryConformanceSuite) TestGetPricesSince() {
+	// Arrange
+	timestamp := time.Now().UTC().Truncate(time.Millisecond)
+	data := []models.PriceDatum{
+		{Timestamp: timestamp.Add(-time.Minute), Symbol: "SSE", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(1)},
+		{Timestamp: timestamp, Symbol: "SSE", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(2)},
+		{Timestamp: timestamp.Add(time.Second), Symbol: "SSE", Currency: "USDT", Source: "okx", Price: decimal.NewFromInt(3)},
+		{Timestamp: timestamp.Add(2 * time.Second), Symbol: "SSE", Currency: "EUR", Source: "binance", Price: decimal.NewFromInt(4)},
+		{Timestamp: timestamp.Add(3 * time.Second), Symbol: "OTHER", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(5)},
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	s.Require().NoError(err)
+
+	// Act
+	since, errSince := s.repository.GetPricesSince(context.Background(), models.PriceStreamRequest{Symbols: []string{"SSE"}, Currency: "USDT", Since: timestamp})
+	okx, errOkx := s.repository.GetPricesSince(context.Background(), models.PriceStreamRequest{Symbols: []string{"SSE"}, Currency: "USDT", Source: "okx", Since: timestamp})
+	limited, errLimited := s.repository.GetPricesSince(context.Background(), models.PriceStreamRequest{Symbols: []string{"SSE", "OTHER"}, Since: timestamp, Limit: 3})
+
+	// Assert
+	s.Require().NoError(errSince)
+	s.Require().Len(since, 2)
+	assert.Equal(s.T(), "2", since[0].Price.String())
+	assert.Equal(s.T(), "3", since[1].Price.String())
+	s.Require().NoError(errOkx)
+	s.Require().Len(okx, 1)
+	assert.Equal(s.T(), "okx", okx[0].Source)
+	s.Require().NoError(errLimited)
+	s.Require().Len(limited, 3)
+	assert.Equal(s.T(), "4", limited[2].Price.String())
+}
+
+func (s *RepositoryConformanceSuite) TestGetLatestPrice_ByCurrency() {
+	// Arrange
+	timestamp := time.Now().UTC().Truncate(time.Microsecond)
+	data := []models.PriceDatum{
+		{
+			Timestamp: timestamp,
+			Symbol:    "CUR",
+			Currency:  "EUR",
+			Source:    "binance",
+			Price:     decimal.NewFromInt(90),
+		},
+		{
+			Timestamp: timestamp.Add(-time.Minute),
+			Symbol:    "CUR",
+			Currency:  "USDT",
+			Source:    "binance",
+			Price:     decimal.NewFromInt(100),
+		},
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	assert.NoError(s.T(), err)
+
+	// Act
+	usdt, errUsdt := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "CUR", Currency: "USDT"})
+	history, errHistory := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol:   "CUR",
+		Currency: "EUR",
+		From:     timestamp.Add(-time.Hour),
+		To:       timestamp,
+		Points:   30,
+	})
+
+	// Assert
+	assert.NoError(s.T(), errUsdt)
+	assert.Equal(s.T(), "USDT", usdt.Currency)
+	assert.Equal(s.T(), "100", usdt.Price.String())
+	assert.NoError(s.T(), errHistory)
+	assert.Len(s.T(), history, 1)
+	assert.Equal(s.T(), "EUR", history[0].Currency)
+	assert.Equal(s.T(), "90", history[0].Price.String())
+}
+
+func (s *RepositoryConformanceSuite) TestGetCandles() {
+	// Arrange
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	datum := func(offset time.Duration, price int64) models.PriceDatum {
+		return models.PriceDatum{
+			Timestamp: start.Add(offset),
+			Symbol:    "CDL",
+			Currency:  "USDT",
+			Source:    "binance",
+			Price:     decimal.NewFromInt(price),
+		}
+	}
+	errFirst := s.repository.BulkInsert(context.Background(), []models.PriceDatum{
+		datum(10*time.Second, 100),
+		datum(20*time.Second, 110),
+		datum(90*time.Second, 95),
+	})
+	// A late batch of the first minute is merged into the existing candle
+	errLate := s.repository.BulkInsert(context.Background(), []models.PriceDatum{
+		datum(5*time.Second, 90),
+		datum(50*time.Second, 120),
+	})
+	minute, _ := models.ParseCandleResolution("1m")
+	hour, _ := models.ParseCandleResolution("1h")
+
+	// Act
+	minutes, errMinutes := s.repository.GetCandles(context.Background(), models.CandleRequest{
+		Symbol:     "CDL",
+		Currency:   "USDT",
+		Source:     "binance",
+		Resolution: minute,
+		From:       start,
+		To:         start.Add(time.Hour),
+	})
+	hours, errHours := s.repository.GetCandles(context.Background(), models.CandleRequest{
+		Symbol:     "CDL",
+		Currency:   "USDT",
+		Source:     "binance",
+		Resolution: hour,
+		From:       start,
+		To:         start.Add(time.Hour),
+	})
+
+	// Assert
+	assert.NoError(s.T(), errFirst)
+	assert.NoError(s.T(), errLate)
+	assert.NoError(s.T(), errMinutes)
+	assert.NoError(s.T(), errHours)
+
+	assert.Len(s.T(), minutes, 2)
+	assert.Equal(s.T(), start, minutes[0].OpenTime.UTC())
+	assert.Equal(s.T(), []string{"90", "120", "90", "120"}, []string{minutes[0].Open.String(), minutes[0].High.String(), minutes[0].Low.String(), minutes[0].Close.String()})
+	assert.Equal(s.T(), int64(4), minutes[0].Count)
+	assert.Equal(s.T(), start.Add(time.Minute), minutes[1].OpenTime.UTC())
+	assert.Equal(s.T(), int64(1), minutes[1].Count)
+
+	assert.Len(s.T(), hours, 1)
+	assert.Equal(s.T(), []string{"90", "120", "90", "95"}, []string{hours[0].Open.String(), hours[0].High.String(), hours[0].Low.String(), hours[0].Close.String()})
+	assert.Equal(s.T(), int64(5), hours[0].Count)
+}
+
+func (s *RepositoryConformanceSuite) TestGetCandles_ExactExtremes() {
+	// Arrange, prices that are the same float
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	datum := func(offset time.Duration, price string) models.PriceDatum {
+		return models.PriceDatum{
+			Timestamp: start.Add(offset),
+			Symbol:    "XCT",
+			Currency:  "USDT",
+			Source:    "binance",
+			Price:     decimal.RequireFromString(price),
+		}
+	}
+	for _, batch := range [][]models.PriceDatum{
+		{datum(10*time.Second, "0.10000000000000000002")},
+		{datum(20*time.Second, "0.10000000000000000003"), datum(30*time.Second, "0.10000000000000000002")},
+		{datum(40*time.Second, "0.10000000000000000001")},
+	} {
+		err := s.repository.BulkInsert(context.Background(), batch)
+		s.Require().NoError(err)
+	}
+
+	// Act
+	candles, err := s.repository.GetCandles(context.Background(), models.CandleRequest{
+		Symbol:     "XCT",
+		Currency:   "USDT",
+		Source:     "binance",
+		Resolution: models.CandleResolutions[0],
+		From:       start,
+		To:         start.Add(time.Minute),
+	})
+
+	// Assert
+	s.Require().NoError(err)
+	s.Require().Len(candles, 1)
+	assert.Equal(s.T(), "0.10000000000000000003", candles[0].High.String())
+	assert.Equal(s.T(), "0.10000000000000000001", candles[0].Low.String())
+	assert.Equal(s.T(), "0.10000000000000000001", candles[0].Close.String())
+	assert.Equal(s.T(), int64(4), candles[0].Count)
+}
+
+func (s *RepositoryConformanceSuite) TestGetCandles_AnySource() {
+	// Arrange, binance has more prices in the second minute but kraken in the range
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	datum := func(source string, offset time.Duration, price int64) models.PriceDatum {
+		return models.PriceDatum{
+			Timestamp: start.Add(offset),
+			Symbol:    "ANY",
+			Currency:  "USDT",
+			Source:    source,
+			Price:     decimal.NewFromInt(price),
+		}
+	}
+	err := s.repository.BulkInsert(context.Background(), []models.PriceDatum{
+		datum("binance", 10*time.Second, 100),
+		datum("kraken", 20*time.Second, 101),
+		datum("kraken", 30*time.Second, 102),
+		datum("binance", 70*time.Second, 103),
+		datum("binance", 80*time.Second, 104),
+		datum("kraken", 90*time.Second, 105),
+		datum("kraken", 100*time.Second, 106),
+	})
+	s.Require().NoError(err)
+	minute, _ := models.ParseCandleResolution("1m")
+
+	// Act
+	candles, err := s.repository.GetCandles(context.Background(), models.CandleRequest{
+		Symbol:     "ANY",
+		Currency:   "USDT",
+		Resolution: minute,
+		From:       start,
+		To:         start.Add(time.Hour),
+	})
+
+	// Assert
+	s.Require().NoError(err)
+	s.Require().Len(candles, 2)
+	assert.Equal(s.T(), "kraken", candles[0].Source)
+	assert.Equal(s.T(), "102", candles[0].Close.String())
+	assert.Equal(s.T(), "kraken", candles[1].Source)
+	assert.Equal(s.T(), "106", candles[1].Close.String())
+
+	// Act, the history follows the same source
+	history, err := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol:   "ANY",
+		Currency: "USDT",
+		From:     start,
+		To:       start.Add(2 * time.Minute),
+		Step:     30 * time.Second,
+	})
+
+	// Assert
+	s.Require().NoError(err)
+	var sources []string
+	for _, datum := range history {
+		sources = append(sources, datum.Source)
+	}
+	assert.Equal(s.T(), []string{"kraken", "kraken", "kraken"}, sources)
+}
+
+func (s *RepositoryConformanceSuite) TestGetPriceHistory_Downsampling() {
+	// Arrange
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []models.PriceDatum
+	for i := 0; i < 60; i++ {
+		data = append(data, models.PriceDatum{
+			Timestamp: from.Add(time.Duration(i) * time.Minute),
+			Symbol:    "DWN",
+			Currency:  "USDT",
+			Source:    "binance",
+			Price:     decimal.NewFromInt(int64(i)),
+		})
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	assert.NoError(s.T(), err)
+
+	// Act
+	byStep, errStep := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "DWN",
+		From:   from,
+		To:     from.Add(time.Hour),
+		Step:   15 * time.Minute,
+	})
+	byPoints, errPoints := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "DWN",
+		From:   from.Add(30 * time.Minute),
+		To:     from.Add(time.Hour),
+		Points: 3,
+	})
+	all, errAll := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "DWN",
+		To:     from.Add(time.Hour),
+		Points: 2,
+	})
+	_, errTooMany := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol:    "DWN",
+		To:        from.Add(time.Hour),
+		Step:      time.Second,
+		MaxPoints: 1000,
+	})
+
+	// Assert
+	assert.NoError(s.T(), errStep)
+	assert.Equal(s.T(), []string{"14", "29", "44", "59"}, prices(byStep))
+	assert.NoError(s.T(), errPoints)
+	assert.Equal(s.T(), []string{"39", "49", "59"}, prices(byPoints))
+	assert.NoError(s.T(), errAll)
+	assert.Equal(s.T(), []string{"29", "59"}, prices(all))
+	// The first price an hour before is 3600 steps away
+	assert.ErrorIs(s.T(), errTooMany, ErrTooManyPoints)
+}
+
+func prices(data []models.PriceDatum) []string {
+	var res []string
+	for _, datum := range data {
+		res = append(res, datum.Price.String())
+	}
+	return res
+}
+
+func (s *RepositoryConformanceSuite) TestArchivePrices() {
+	// Arrange
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	counted := []models.PriceDatum{
+		{Timestamp: from, Symbol: "ARC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(1)},
+		{Timestamp: from.Add(10 * time.Second), Symbol: "ARC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(3)},
+	}
+	// Stored before candles existed
+	legacy := []models.PriceDatum{
+		{Timestamp: from.Add(20 * time.Second), Symbol: "ARC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(2)},
+		{Timestamp: from.Add(2 * time.Minute), Symbol: "ARC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(4)},
+	}
+	recent := models.PriceDatum{Timestamp: from.Add(time.Hour), Symbol: "ARC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(5)}
+
+	err := s.repository.BulkInsert(context.Background(), append(counted, recent))
+	assert.NoError(s.T(), err)
+	_, err = s.db.NewInsert().Model(&legacy).Exec(context.Background())
+	assert.NoError(s.T(), err)
+
+	// Act
+	deleted, errArchive := s.repository.ArchivePrices(context.Background(), from.Add(30*time.Minute))
+
+	// Assert
+	assert.NoError(s.T(), errArchive)
+	assert.Equal(s.T(), int64(4), deleted)
+
+	latest, err := s.repository.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "ARC"})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), recent.Price.String(), latest.Price.String())
+
+	minutes, err := s.repository.GetCandles(context.Background(), models.CandleRequest{
+		Symbol:     "ARC",
+		Currency:   "USDT",
+		Source:     "binance",
+		Resolution: models.CandleResolutions[0],
+		From:       from,
+		To:         from.Add(30 * time.Minute),
+	})
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), minutes, 2)
+	assert.Equal(s.T(), int64(3), minutes[0].Count)
+	assert.Equal(s.T(), "1", minutes[0].Open.String())
+	assert.Equal(s.T(), "3", minutes[0].High.String())
+	assert.Equal(s.T(), "2", minutes[0].Close.String())
+	assert.Equal(s.T(), int64(1), minutes[1].Count)
+
+	// History falls back to the candle closes
+	history, err := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "ARC",
+		To:     from.Add(2 * time.Hour),
+		Step:   time.Minute,
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"2", "4", "5"}, prices(history))
+
+	// Only the candles of the resolution of the step are read, the minute left by its minute candle
+	// is not filled by the close of a coarser one
+	_, err = s.db.NewDelete().Model((*models.Candle)(nil)).
+		Where("symbol = ?", "ARC").
+		Where("resolution = ?", "1m").
+		Where("open_time = ?", from.Add(2*time.Minute)).
+		Exec(context.Background())
+	s.Require().NoError(err)
+	history, err = s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "ARC",
+		To:     from.Add(2 * time.Hour),
+		Step:   time.Minute,
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"2", "5"}, prices(history))
+	hourly, err := s.repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol: "ARC",
+		From:   from,
+		To:     from.Add(2 * time.Hour),
+		Step:   time.Hour,
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"4", "5"}, prices(hourly))
+}
+
+func (s *RepositoryConformanceSuite) TestDeleteCandles() {
+	// Arrange
+	from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := s.repository.BulkInsert(context.Background(), []models.PriceDatum{
+		{Timestamp: from, Symbol: "DEL", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(1)},
+		{Timestamp: from.Add(2 * time.Hour), Symbol: "DEL", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(2)},
+	})
+	assert.NoError(s.T(), err)
+
+	// Act
+	deleted, errDelete := s.repository.DeleteCandles(context.Background(), []string{"1m", "1h"}, from.Add(time.Hour))
+	errCompact := s.repository.Compact(context.Background(), true)
+
+	// Assert
+	assert.NoError(s.T(), errDelete)
+	assert.Equal(s.T(), int64(2), deleted)
+	assert.NoError(s.T(), errCompact)
+
+	for resolution, count := range map[string]int{"1m": 1, "1h": 1, "1d": 1} {
+		parsed, _ := models.ParseCandleResolution(resolution)
+		candles, err := s.repository.GetCandles(context.Background(), models.CandleRequest{
+			Symbol:     "DEL",
+			Currency:   "USDT",
+			Source:     "binance",
+			Resolution: parsed,
+			From:       from,
+			To:         from.Add(24 * time.Hour),
+		})
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), candles, count, resolution)
+	}
+}
+
+func (s *RepositoryConformanceSuite) TestDeleteCandles_DropsMinuteAggregate() {
+	if !s.timescale {
+		s.T().Skip("the minute aggregate needs TimescaleDB")
+	}
+
+	// Arrange
+	from := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := s.repository.BulkInsert(context.Background(), []models.PriceDatum{
+		{Timestamp: from, Symbol: "AGG", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(1)},
+	})
+	s.Require().NoError(err)
+	_, err = s.db.ExecContext(context.Background(), "CALL refresh_continuous_aggregate('crypto_price_1m', ?::timestamptz, ?::timestamptz)",
+		from, from.Add(24*time.Hour))
+	s.Require().NoError(err)
+
+	// Act
+	_, err = s.repository.DeleteCandles(context.Background(), []string{"1m"}, from.AddDate(0, 2, 0))
+
+	// Assert
+	assert.NoError(s.T(), err)
+	count, err := s.db.NewSelect().Table("crypto_price_1m").Where("symbol = ?", "AGG").Count(context.Background())
+	assert.NoError(s.T(), err)
+	assert.Zero(s.T(), count)
+}
+
+func (s *RepositoryConformanceSuite) TestGetPriceSeries() {
+	// Arrange
+	since := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []models.PriceDatum
+	for _, datum := range []models.PriceDatum{
+		{Timestamp: since.Add(-time.Minute), Symbol: "SER", Currency: "EUR", Source: "kraken"},
+		{Timestamp: since, Symbol: "SER", Currency: "USDT", Source: "binance"},
+		{Timestamp: since.Add(time.Minute), Symbol: "SER", Currency: "USDT", Source: "binance"},
+		{Timestamp: since.Add(time.Minute), Symbol: "SER", Currency: "USDT", Source: "index"},
+	} {
+		datum.Price = decimal.NewFromInt(1)
+		data = append(data, datum)
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	s.Require().NoError(err)
+
+	// Act
+	series, err := s.repository.GetPriceSeries(context.Background(), since)
+
+	// Assert, the kraken series has no price since
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []models.PriceDatum{
+		{Symbol: "SER", Currency: "USDT", Source: "binance"},
+		{Symbol: "SER", Currency: "USDT", Source: "index"},
+	}, series)
+}
+
+func (s *RepositoryConformanceSuite) TestFindAndSaveGaps() {
+	// Arrange
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []models.PriceDatum
+	for _, offset := range []time.Duration{0, time.Minute, 2 * time.Minute, 10 * time.Minute, 11 * time.Minute, 20 * time.Minute} {
+		data = append(data, models.PriceDatum{
+			Timestamp: from.Add(offset),
+			Symbol:    "GAP",
+			Currency:  "USDT",
+			Source:    "binance",
+			Price:     decimal.NewFromInt(1),
+		})
+	}
+	err := s.repository.BulkInsert(context.Background(), data)
+	assert.NoError(s.T(), err)
+
+	// Act
+	gaps, errFind := s.repository.FindGaps(context.Background(), models.GapScanRequest{
+		Symbol:      "GAP",
+		Currency:    "USDT",
+		Source:      "binance",
+		From:        from,
+		To:          from.Add(time.Hour),
+		MinDuration: 2 * time.Minute,
+	})
+	for i := range gaps {
+		gaps[i].Status = models.DataGapStatusOpen
+		gaps[i].DetectedAt = from.Add(time.Hour)
+	}
+	errSave := s.repository.SaveGaps(context.Background(), gaps)
+	// Overlapping scans find the same gaps again
+	errSaveAgain := s.repository.SaveGaps(context.Background(), gaps)
+	open, errOpen := s.repository.GetGaps(context.Background(), models.DataGapStatusOpen)
+
+	// Assert
+	assert.NoError(s.T(), errFind)
+	assert.Len(s.T(), gaps, 2)
+	assert.Equal(s.T(), from.Add(2*time.Minute), gaps[0].StartTime.UTC())
+	assert.Equal(s.T(), from.Add(10*time.Minute), gaps[0].EndTime.UTC())
+	assert.Equal(s.T(), from.Add(11*time.Minute), gaps[1].StartTime.UTC())
+	assert.Equal(s.T(), from.Add(20*time.Minute), gaps[1].EndTime.UTC())
+	assert.NoError(s.T(), errSave)
+	assert.NoError(s.T(), errSaveAgain)
+	assert.NoError(s.T(), errOpen)
+	assert.Len(s.T(), open, 2)
+
+	// Act
+	repaired := open[0]
+	repaired.Status = models.DataGapStatusRepaired
+	repaired.Filled = 7
+	repaired.Attempts = 1
+	repaired.RepairedAt = from.Add(2 * time.Hour)
+	errUpdate := s.repository.UpdateGap(context.Background(), repaired)
+	stillOpen, _ := s.repository.GetGaps(context.Background(), models.DataGapStatusOpen)
+	all, _ := s.repository.GetGaps(context.Background(), "")
+
+	// Assert
+	assert.NoError(s.T(), errUpdate)
+	assert.Len(s.T(), stillOpen, 1)
+	assert.Len(s.T(), all, 2)
+	assert.Equal(s.T(), int64(7), all[0].Filled)
+	assert.Equal(s.T(), models.DataGapStatusRepaired, all[0].Status)
+}
+
+func (s *RepositoryConformanceSuite) TestSaveAndGetMarketStats() {
+	// Arrange
+	timestamp := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	stats := func(source string, offset time.Duration, bid string) models.MarketStats {
+		return models.MarketStats{
+			Symbol:             "STA",
+			Currency:           "USDT",
+			Source:             source,
+			Timestamp:          timestamp.Add(offset),
+			BidPrice:           decimal.RequireFromString(bid),
+			BidQuantity:        decimal.RequireFromString("1.5"),
+			AskPrice:           decimal.RequireFromString("100.01"),
+			AskQuantity:        decimal.RequireFromString("2"),
+			LastQuantity:       decimal.RequireFromString("0.001"),
+			Volume:             decimal.RequireFromString("1234.5678"),
+			QuoteVolume:        decimal.RequireFromString("123456.78"),
+			High:               decimal.RequireFromString("105"),
+			Low:                decimal.RequireFromString("95"),
+			PriceChangePercent: decimal.RequireFromString("-1.25"),
+		}
+	}
+
+	// Act
+	errFirst := s.repository.SaveMarketStats(context.Background(), []models.MarketStats{stats("binance", 0, "99.99"), stats("kraken", -time.Minute, "99.5")})
+	errNewer := s.repository.SaveMarketStats(context.Background(), []models.MarketStats{stats("binance", time.Second, "99.98")})
+	// Stats received out of order do not overwrite newer ones
+	errOlder := s.repository.SaveMarketStats(context.Background(), []models.MarketStats{stats("binance", -time.Second, "99.97")})
+	latest, errLatest := s.repository.GetMarketStats(context.Background(), models.PriceDatum{Symbol: "STA"})
+	kraken, errKraken := s.repository.GetMarketStats(context.Background(), models.PriceDatum{Symbol: "STA", Currency: "USDT", Source: "kraken"})
+	_, errMissing := s.repository.GetMarketStats(context.Background(), models.PriceDatum{Symbol: "STA", Currency: "EUR"})
+
+	// Assert
+	assert.NoError(s.T(), errFirst)
+	assert.NoError(s.T(), errNewer)
+	assert.NoError(s.T(), errOlder)
+	assert.NoError(s.T(), errLatest)
+	assert.Equal(s.T(), "binance", latest.Source)
+	assert.Equal(s.T(), timestamp.Add(time.Second), latest.Timestamp.UTC())
+	assert.Equal(s.T(), "99.98", latest.BidPrice.String())
+	assert.Equal(s.T(), "1234.5678", latest.Volume.String())
+	assert.Equal(s.T(), "-1.25", latest.PriceChangePercent.String())
+	assert.NoError(s.T(), errKraken)
+	assert.Equal(s.T(), "99.5", kraken.BidPrice.String())
+	assert.ErrorIs(s.T(), errMissing, ErrNotFound)
+}
+
+func (s *RepositoryConformanceSuite) TestAlertRules() {
+	// Arrange
+	createdAt := time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)
+	rule := models.AlertRule{
+		Name:       "btc breakout",
+		Symbol:     "ALR",
+		Currency:   "USDT",
+		Kind:       models.AlertKindAbove,
+		Threshold:  decimal.RequireFromString("50000.5"),
+		Hysteresis: decimal.RequireFromString("0.5"),
+		Cooldown:   models.Duration(15 * time.Minute),
+		Email:      "ops@example.com",
+		Enabled:    true,
+		Armed:      true,
+		CreatedAt:  createdAt,
+		UpdatedAt:  createdAt,
+	}
+
+	// Act
+	errCreate := s.repository.CreateAlertRule(context.Background(), &rule)
+	fetched, errGet := s.repository.GetAlertRule(context.Background(), rule.ID)
+
+	// Assert
+	s.Require().NoError(errCreate)
+	assert.NotZero(s.T(), rule.ID)
+	assert.NoError(s.T(), errGet)
+	assert.Equal(s.T(), "btc breakout", fetched.Name)
+	assert.Equal(s.T(), "50000.5", fetched.Threshold.String())
+	assert.Equal(s.T(), models.Duration(15*time.Minute), fetched.Cooldown)
+	assert.Equal(s.T(), "ops@example.com", fetched.Email)
+	assert.True(s.T(), fetched.Enabled)
+	assert.True(s.T(), fetched.Armed)
+	assert.True(s.T(), fetched.LastFiredAt.IsZero())
+
+	// Act
+	updated := *fetched
+	updated.Kind = models.AlertKindMACross
+	updated.Window = models.Duration(time.Hour)
+	updated.CreatedAt = createdAt.Add(time.Hour)
+	errUpdate := s.repository.UpdateAlertRule(context.Background(), updated)
+	firedAt := createdAt.Add(2 * time.Hour)
+	updated.Armed = false
+	updated.Side = 1
+	updated.LastFiredAt = firedAt
+	errRecord := s.repository.RecordAlerts(context.Background(), []models.AlertRule{updated}, []models.AlertEvent{
+		{RuleID: rule.ID, Symbol: "ALR", Currency: "USDT", Source: "binance", Kind: models.AlertKindMACross,
+			Price: decimal.RequireFromString("50100"), Reference: decimal.RequireFromString("50000"), FiredAt: firedAt},
+		{RuleID: rule.ID + 1, Symbol: "ALR", Currency: "USDT", Source: "binance", Kind: models.AlertKindBelow,
+			Price: decimal.RequireFromString("1"), Reference: decimal.RequireFromString("2"), FiredAt: firedAt.Add(time.Minute)},
+	})
+	rules, errRules := s.repository.GetAlertRules(context.Background())
+	events, errEvents := s.repository.GetAlertEvents(context.Background(), models.AlertEventRequest{RuleID: rule.ID, Limit: 10})
+	all, _ := s.repository.GetAlertEvents(context.Background(), models.AlertEventRequest{Limit: 10})
+
+	// Assert
+	assert.NoError(s.T(), errUpdate)
+	assert.NoError(s.T(), errRecord)
+	assert.NoError(s.T(), errRules)
+	s.Require().Len(rules, 1)
+	assert.Equal(s.T(), models.AlertKindMACross, rules[0].Kind)
+	assert.Equal(s.T(), models.Duration(time.Hour), rules[0].Window)
+	// The creation time is kept
+	assert.Equal(s.T(), createdAt, rules[0].CreatedAt.UTC())
+	assert.False(s.T(), rules[0].Armed)
+	assert.Equal(s.T(), 1, rules[0].Side)
+	assert.Equal(s.T(), firedAt, rules[0].LastFiredAt.UTC())
+	assert.NoError(s.T(), errEvents)
+	s.Require().Len(events, 1)
+	assert.Equal(s.T(), "50100", events[0].Price.String())
+	assert.Equal(s.T(), "50000", events[0].Reference.String())
+	assert.Len(s.T(), all, 2)
+	assert.Equal(s.T(), models.AlertKindBelow, all[0].Kind)
+
+	// Act
+	errDelete := s.repository.DeleteAlertRule(context.Background(), rule.ID)
+	errDeleteAgain := s.repository.DeleteAlertRule(context.Background(), rule.ID)
+	_, errGetDeleted := s.repository.GetAlertRule(context.Background(), rule.ID)
+	errUpdateDeleted := s.repository.UpdateAlertRule(context.Background(), updated)
+	history, _ := s.repository.GetAlertEvents(context.Background(), models.AlertEventRequest{RuleID: rule.ID})
+
+	// Assert
+	assert.NoError(s.T(), errDelete)
+	assert.ErrorIs(s.T(), errDeleteAgain, ErrNotFound)
+	assert.ErrorIs(s.T(), errGetDeleted, ErrNotFound)
+	assert.ErrorIs(s.T(), errUpdateDeleted, ErrNotFound)
+	assert.Len(s.T(), history, 1)
+}
+
+func (s *RepositoryConformanceSuite) TestWebhooksAndDeliveries() {
+	// Arrange
+	createdAt := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	webhook := models.Webhook{
+		Name:      "ops",
+		URL:       "https://hooks.example.com/ops",
+		Format:    models.WebhookFormatSlack,
+		Secret:    "s3cret",
+		Enabled:   true,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+	errCreate := s.repository.CreateWebhook(context.Background(), &webhook)
+	s.Require().NoError(errCreate)
+
+	delivery := func(offset time.Duration, status string) models.WebhookDelivery {
+		return models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     models.WebhookEventAlert,
+			Payload:       `{"text":"BTC/USDT rose above 100"}`,
+			Status:        status,
+			NextAttemptAt: createdAt.Add(offset),
+			CreatedAt:     createdAt,
+		}
+	}
+
+	// Act
+	errSave := s.repository.SaveDeliveries(context.Background(), []models.WebhookDelivery{
+		delivery(time.Minute, models.WebhookDeliveryPending),
+		delivery(0, models.WebhookDeliveryPending),
+		delivery(time.Hour, models.WebhookDeliveryPending),
+		delivery(0, models.WebhookDeliveryDead),
+	})
+	lease := createdAt.Add(5 * time.Minute)
+	due, errDue := s.repository.ClaimDueDeliveries(context.Background(), createdAt.Add(time.Minute), lease, 10)
+	claimed, errClaimed := s.repository.ClaimDueDeliveries(context.Background(), createdAt.Add(2*time.Minute), lease, 10)
+	expired, errExpired := s.repository.ClaimDueDeliveries(context.Background(), lease, lease.Add(time.Minute), 1)
+
+	// Assert, the longest due first and hidden from the next polls until the lease has passed
+	assert.NoError(s.T(), errSave)
+	assert.NoError(s.T(), errDue)
+	s.Require().Len(due, 2)
+	assert.Greater(s.T(), due[0].ID, due[1].ID)
+	assert.Equal(s.T(), lease, due[0].NextAttemptAt.UTC())
+	assert.NoError(s.T(), errClaimed)
+	assert.Empty(s.T(), claimed)
+	assert.NoError(s.T(), errExpired)
+	s.Require().Len(expired, 1)
+	assert.Equal(s.T(), due[1].ID, expired[0].ID)
+
+	// Act
+	delivered := due[0]
+	delivered.Status = models.WebhookDeliveryDelivered
+	delivered.Attempts = 1
+	delivered.LastStatusCode = 200
+	delivered.DeliveredAt = createdAt.Add(time.Second)
+	errUpdate := s.repository.UpdateDelivery(context.Background(), delivered)
+	fetched, errGet := s.repository.GetDelivery(context.Background(), delivered.ID)
+	dead, errDead := s.repository.GetDeliveries(context.Background(), models.WebhookDeliveryRequest{
+		Status: models.WebhookDeliveryDead, WebhookID: webhook.ID, Limit: 10,
+	})
+
+	// Assert
+	assert.NoError(s.T(), errUpdate)
+	assert.NoError(s.T(), errGet)
+	assert.Equal(s.T(), models.WebhookDeliveryDelivered, fetched.Status)
+	assert.Equal(s.T(), 200, fetched.LastStatusCode)
+	assert.Equal(s.T(), `{"text":"BTC/USDT rose above 100"}`, fetched.Payload)
+	assert.NoError(s.T(), errDead)
+	assert.Len(s.T(), dead, 1)
+
+	// Act
+	updated := webhook
+	updated.URL = "https://hooks.example.com/other"
+	updated.CreatedAt = createdAt.Add(time.Hour)
+	errUpdateWebhook := s.repository.UpdateWebhook(context.Background(), updated)
+	webhooks, errWebhooks := s.repository.GetWebhooks(context.Background())
+	errDelete := s.repository.DeleteWebhook(context.Background(), webhook.ID)
+	_, errGetDeleted := s.repository.GetWebhook(context.Background(), webhook.ID)
+	errDeleteAgain := s.repository.DeleteWebhook(context.Background(), webhook.ID)
+	remaining, _ := s.repository.GetDeliveries(context.Background(), models.WebhookDeliveryRequest{WebhookID: webhook.ID})
+
+	// Assert
+	assert.NoError(s.T(), errUpdateWebhook)
+	assert.NoError(s.T(), errWebhooks)
+	s.Require().Len(webhooks, 1)
+	assert.Equal(s.T(), "https://hooks.example.com/other", webhooks[0].URL)
+	assert.Equal(s.T(), "s3cret", webhooks[0].Secret)
+	assert.Equal(s.T(), createdAt, webhooks[0].CreatedAt.UTC())
+	assert.NoError(s.T(), errDelete)
+	assert.ErrorIs(s.T(), errGetDeleted, ErrNotFound)
+	assert.ErrorIs(s.T(), errDeleteAgain, ErrNotFound)
+	// The pending deliveries are dropped with the webhook, the delivered and dead ones are kept
+	assert.Len(s.T(), remaining, 2)
+	_, errMissing := s.repository.GetDelivery(context.Background(), 1<<40)
+	assert.ErrorIs(s.T(), errMissing, ErrNotFound)
+}
+
+func (s *RepositoryConformanceSuite) TestAnomalies() {
+	// Arrange
+	timestamp := time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)
+	anomaly := func(offset time.Duration, symbol, source, kind string) models.Anomaly {
+		return models.Anomaly{
+			Timestamp:   timestamp.Add(offset),
+			Symbol:      symbol,
+			Currency:    "USDT",
+			Source:      source,
+			Kind:        kind,
+			Price:       decimal.RequireFromString("150000.5"),
+			Reference:   decimal.RequireFromString("100000"),
+			Score:       9.25,
+			Message:     symbol + "/USDT jumped",
+			Quarantined: true,
+		}
+	}
+
+	// Act
+	errSave := s.repository.SaveAnomalies(context.Background(), []models.Anomaly{
+		anomaly(0, "ANM", "binance", models.AnomalyKindJump),
+		anomaly(time.Minute, "ANM", "kraken", models.AnomalyKindZScore),
+		anomaly(2*time.Minute, "ANX", "binance", models.AnomalyKindFlatline),
+	})
+	errSaveNone := s.repository.SaveAnomalies(context.Background(), nil)
+	all, errAll := s.repository.GetAnomalies(context.Background(), models.AnomalyRequest{Limit: 10})
+	symbol, errSymbol := s.repository.GetAnomalies(context.Background(), models.AnomalyRequest{Symbol: "ANM", Limit: 10})
+	source, _ := s.repository.GetAnomalies(context.Background(),
+		models.AnomalyRequest{Symbol: "ANM", Currency: "USDT", Source: "binance", Limit: 10})
+	limited, _ := s.repository.GetAnomalies(context.Background(), models.AnomalyRequest{Limit: 1})
+
+	// Assert
+	s.Require().NoError(errSave)
+	assert.NoError(s.T(), errSaveNone)
+	assert.NoError(s.T(), errAll)
+	assert.Len(s.T(), all, 3)
+	assert.NoError(s.T(), errSymbol)
+	s.Require().Len(symbol, 2)
+	assert.Equal(s.T(), models.AnomalyKindZScore, symbol[0].Kind)
+	assert.Equal(s.T(), timestamp.Add(time.Minute), symbol[0].Timestamp.UTC())
+	assert.Equal(s.T(), "150000.5", symbol[0].Price.String())
+	assert.Equal(s.T(), "100000", symbol[0].Reference.String())
+	assert.Equal(s.T(), 9.25, symbol[0].Score)
+	assert.True(s.T(), symbol[0].Quarantined)
+	s.Require().Len(source, 1)
+	assert.Equal(s.T(), models.AnomalyKindJump, source[0].Kind)
+	s.Require().Len(limited, 1)
+	assert.Equal(s.T(), "ANX", limited[0].Symbol)
+}
@@ -0,0 +1,143 @@
+package repositories
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// busyRetryRepository retries the writes of the repository while the database is busy. The bus subscribers,
+// the backfill, the gap repair, the retention and the webhook outbox write from their own goroutines,
+// so those writes can contend for the SQLite lock
+type busyRetryRepository struct {
+	Repository
+	maxRetries int
+	backoff    time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+// NewBusyRetryRepository returns the repository retrying its writes up to WriteMaxRetries times while the
+// database is busy, waiting WriteRetryBackoff and twice as long after each retry
+func NewBusyRetryRepository(repository Repository, cfg config.PriceTrackerConfig) Repository {
+	return &busyRetryRepository{
+		Repository: repository,
+		maxRetries: cfg.WriteMaxRetries,
+		backoff:    cfg.WriteRetryBackoff,
+		sleep:      sleep,
+	}
+}
+
+func (b *busyRetryRepository) BulkInsert(ctx context.Context, data []models.PriceDatum) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.BulkInsert(ctx, data)
+	})
+}
+
+func (b *busyRetryRepository) SaveMarketStats(ctx context.Context, stats []models.MarketStats) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.SaveMarketStats(ctx, stats)
+	})
+}
+
+func (b *busyRetryRepository) RecordAlerts(ctx context.Context, rules []models.AlertRule, events []models.AlertEvent) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.RecordAlerts(ctx, rules, events)
+	})
+}
+
+func (b *busyRetryRepository) SaveAnomalies(ctx context.Context, anomalies []models.Anomaly) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.SaveAnomalies(ctx, anomalies)
+	})
+}
+
+func (b *busyRetryRepository) ArchivePrices(ctx context.Context, before time.Time) (int64, error) {
+	var res int64
+	err := b.retry(ctx, func() error {
+		var err error
+		res, err = b.Repository.ArchivePrices(ctx, before)
+		return err
+	})
+	return res, err
+}
+
+func (b *busyRetryRepository) DeleteCandles(ctx context.Context, resolutions []string, before time.Time) (int64, error) {
+	var res int64
+	err := b.retry(ctx, func() error {
+		var err error
+		res, err = b.Repository.DeleteCandles(ctx, resolutions, before)
+		return err
+	})
+	return res, err
+}
+
+func (b *busyRetryRepository) SaveGaps(ctx context.Context, gaps []models.DataGap) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.SaveGaps(ctx, gaps)
+	})
+}
+
+func (b *busyRetryRepository) UpdateGap(ctx context.Context, gap models.DataGap) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.UpdateGap(ctx, gap)
+	})
+}
+
+func (b *busyRetryRepository) SaveDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.SaveDeliveries(ctx, deliveries)
+	})
+}
+
+func (b *busyRetryRepository) ClaimDueDeliveries(ctx context.Context, at, until time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var res []models.WebhookDelivery
+	err := b.retry(ctx, func() error {
+		var err error
+		res, err = b.Repository.ClaimDueDeliveries(ctx, at, until, limit)
+		return err
+	})
+	return res, err
+}
+
+func (b *busyRetryRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	return b.retry(ctx, func() error {
+		return b.Repository.UpdateDelivery(ctx, delivery)
+	})
+}
+
+// retry runs write again while the database is busy, up to maxRetries times with a doubling backoff
+func (b *busyRetryRepository) retry(ctx context.Context, write func() error) error {
+	backoff := b.backoff
+	for attempt := 0; ; attempt++ {
+		err := write()
+		if err == nil || !IsBusy(err) {
+			return err
+		}
+		if attempt >= b.maxRetries {
+			return fmt.Errorf("database still busy after %d retries: %w", attempt, err)
+		}
+
+		slog.Warn("database busy, retrying write", "attempt", attempt+1, "backoff", backoff)
+		err = b.sleep(ctx, backoff)
+		if err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// sleep waits for d, or returns the error of ctx if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package repositories
+
+import (
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories/mock"
+	"context"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusyRetryRepository_BulkInsert(t *testing.T) {
+	btc := []models.PriceDatum{{Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")}}
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   bool
+		wantCalls int
+		wantSlept []time.Duration
+	}{
+		{
+			name:      "busy once",
+			errs:      []error{busy, nil},
+			wantCalls: 2,
+			wantSlept: []time.Duration{100 * time.Millisecond},
+		},
+		{
+			name:      "still busy",
+			errs:      []error{busy, busy, busy},
+			wantErr:   true,
+			wantCalls: 3,
+			wantSlept: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:      "not retried",
+			errs:      []error{errors.New("no such table: prices")},
+			wantErr:   true,
+			wantCalls: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mock.MockRepository)
+			for _, err := range tt.errs {
+				mockRepo.On("BulkInsert", btc).Return(err).Once()
+			}
+
+			var slept []time.Duration
+			repository := &busyRetryRepository{
+				Repository: mockRepo,
+				maxRetries: 2,
+				backoff:    100 * time.Millisecond,
+				sleep: func(ctx context.Context, d time.Duration) error {
+					slept = append(slept, d)
+					return nil
+				},
+			}
+
+			// Act
+			err := repository.BulkInsert(context.Background(), btc)
+
+			// Assert
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantSlept, slept)
+			mockRepo.AssertNumberOfCalls(t, "BulkInsert", tt.wantCalls)
+		})
+	}
+}
+
+func TestBusyRetryRepository_Writes(t *testing.T) {
+	busy := sqlite3.Error{Code: sqlite3.ErrBusy}
+	before := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		method string
+		args   []any
+		// returns are the values returned by the repository after it was busy
+		returns []any
+		write   func(repository Repository) error
+	}{
+		{
+			method:  "ArchivePrices",
+			args:    []any{before},
+			returns: []any{int64(3), nil},
+			write: func(repository Repository) error {
+				archived, err := repository.ArchivePrices(context.Background(), before)
+				assert.Equal(t, int64(3), archived)
+				return err
+			},
+		},
+		{
+			method:  "DeleteCandles",
+			args:    []any{[]string{"1m"}, before},
+			returns: []any{int64(2), nil},
+			write: func(repository Repository) error {
+				deleted, err := repository.DeleteCandles(context.Background(), []string{"1m"}, before)
+				assert.Equal(t, int64(2), deleted)
+				return err
+			},
+		},
+		{
+			method:  "SaveGaps",
+			args:    []any{[]models.DataGap(nil)},
+			returns: []any{nil},
+			write: func(repository Repository) error {
+				return repository.SaveGaps(context.Background(), nil)
+			},
+		},
+		{
+			method:  "UpdateGap",
+			args:    []any{models.DataGap{ID: 1}},
+			returns: []any{nil},
+			write: func(repository Repository) error {
+				return repository.UpdateGap(context.Background(), models.DataGap{ID: 1})
+			},
+		},
+		{
+			method:  "SaveDeliveries",
+			args:    []any{[]models.WebhookDelivery(nil)},
+			returns: []any{nil},
+			write: func(repository Repository) error {
+				return repository.SaveDeliveries(context.Background(), nil)
+			},
+		},
+		{
+			method:  "ClaimDueDeliveries",
+			args:    []any{before, before.Add(time.Minute), 10},
+			returns: []any{[]models.WebhookDelivery{{ID: 1}}, nil},
+			write: func(repository Repository) error {
+				due, err := repository.ClaimDueDeliveries(context.Background(), before, before.Add(time.Minute), 10)
+				assert.Len(t, due, 1)
+				return err
+			},
+		},
+		{
+			method:  "UpdateDelivery",
+			args:    []any{models.WebhookDelivery{ID: 1}},
+			returns: []any{nil},
+			write: func(repository Repository) error {
+				return repository.UpdateDelivery(context.Background(), models.WebhookDelivery{ID: 1})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			// Arrange
+			mockRepo := new(mock.MockRepository)
+			busyReturns := make([]any, len(tt.returns))
+			copy(busyReturns, tt.returns)
+			busyReturns[len(busyReturns)-1] = busy
+			mockRepo.On(tt.method, tt.args...).Return(busyReturns...).Once()
+			mockRepo.On(tt.method, tt.args...).Return(tt.returns...).Once()
+
+			repository := &busyRetryRepository{
+				Repository: mockRepo,
+				maxRetries: 2,
+				backoff:    100 * time.Millisecond,
+				sleep: func(ctx context.Context, d time.Duration) error {
+					return nil
+				},
+			}
+
+			// Act
+			err := tt.write(repository)
+
+			// Assert
+			assert.NoError(t, err)
+			mockRepo.AssertNumberOfCalls(t, tt.method, 2)
+		})
+	}
+}
+
+func TestBusyRetryRepository_Cancelled(t *testing.T) {
+	// Arrange
+	mockRepo := new(mock.MockRepository)
+	mockRepo.On("SaveAnomalies", []models.Anomaly(nil)).Return(sqlite3.Error{Code: sqlite3.ErrLocked})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repository := &busyRetryRepository{
+		Repository: mockRepo,
+		maxRetries: 2,
+		backoff:    time.Hour,
+		sleep:      sleep,
+	}
+
+	// Act
+	err := repository.SaveAnomalies(ctx, nil)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+	mockRepo.AssertNumberOfCalls(t, "SaveAnomalies", 1)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// priceSample is the last price received in an interval of a series
+type priceSample struct {
+	time  time.Time
+	price decimal.Decimal
+}
+
+// cachedAverage is a moving average of the closed samples up to closedAt
+type cachedAverage struct {
+	closedAt time.Time
+	value    decimal.Decimal
+	ok       bool
+}
+
+// priceSeries keeps the last price of each interval of a market over the longest window of the rules watching it
+type priceSeries struct {
+	interval time.Duration
+	window   time.Duration
+	samples  []priceSample
+	averages map[time.Duration]cachedAverage
+}
+
+func newPriceSeries(interval time.Duration) *priceSeries {
+	return &priceSeries{
+		interval: interval,
+		averages: make(map[time.Duration]cachedAverage),
+	}
+}
+
+// add records a price as the last one of its interval and drops the samples no longer in the window,
+// keeping the one at the start of it and the closed interval the averages end on. Prices older than
+// the last sample are ignored
+func (p *priceSeries) add(timestamp time.Time, price decimal.Decimal) {
+	n := len(p.samples)
+	switch {
+	case n > 0 && timestamp.Before(p.samples[n-1].time):
+		return
+	case n > 0 && timestamp.Truncate(p.interval).Equal(p.samples[n-1].time.Truncate(p.interval)):
+		p.samples[n-1] = priceSample{time: timestamp, price: price}
+	default:
+		p.samples = append(p.samples, priceSample{time: timestamp, price: price})
+	}
+
+	start := timestamp.Add(-p.window - p.interval)
+	drop := 0
+	for drop+1 < len(p.samples) && !p.samples[drop+1].time.After(start) {
+		drop++
+	}
+	p.samples = p.samples[drop:]
+}
+
+// prepend adds older samples, such as the closes of stored candles, before the first one
+func (p *priceSeries) prepend(samples []priceSample) {
+	var older []priceSample
+	for _, sample := range samples {
+		if len(p.samples) > 0 && !sample.time.Before(p.samples[0].time) {
+			break
+		}
+		older = append(older, sample)
+	}
+
+	p.samples = append(older, p.samples...)
+}
+
+// priceAt returns the last price at or before the time, false if the series does not reach back to it
+func (p *priceSeries) priceAt(timestamp time.Time) (decimal.Decimal, bool) {
+	for i := len(p.samples) - 1; i >= 0; i-- {
+		if !p.samples[i].time.After(timestamp) {
+			return p.samples[i].price, true
+		}
+	}
+
+	return decimal.Decimal{}, false
+}
+
+// average returns the mean of the closed samples over the window, the last sample being the interval in progress,
+// false until the series covers the window
+func (p *priceSeries) average(window time.Duration) (decimal.Decimal, bool) {
+	if len(p.samples) < 2 {
+		return decimal.Decimal{}, false
+	}
+
+	closed := p.samples[:len(p.samples)-1]
+	closedAt := closed[len(closed)-1].time
+	if cached, ok := p.averages[window]; ok && cached.closedAt.Equal(closedAt) {
+		return cached.value, cached.ok
+	}
+
+	start := closedAt.Add(-window)
+	average := cachedAverage{closedAt: closedAt}
+	if !closed[0].time.After(start) {
+		sum := decimal.Zero
+		count := 0
+		for _, sample := range closed {
+			if sample.time.After(start) {
+				sum = sum.Add(sample.price)
+				count++
+			}
+		}
+		average.value = sum.Div(decimal.NewFromInt(int64(count)))
+		average.ok = true
+	}
+
+	p.averages[window] = average
+	return average.value, average.ok
+}
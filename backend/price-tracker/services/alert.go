@@ -0,0 +1,544 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/bus"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidAlertRule is returned when a rule to create or update is not valid, wrapped with the reason
+var ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+// averageDecimals is the precision of the moving averages reported in alerts
+const averageDecimals = 8
+
+var hundred = decimal.NewFromInt(100)
+
+type AlertService interface {
+	// Load reads the rules and the recent prices their windows need, before the first tick is evaluated
+	Load(ctx context.Context) error
+	// GetRules returns every rule, in order of creation
+	GetRules(ctx context.Context) ([]models.AlertRule, error)
+	// GetRule returns a rule by ID, or repositories.ErrNotFound
+	GetRule(ctx context.Context, id int64) (*models.AlertRule, error)
+	// CreateRule validates and stores a new rule, evaluated from the next tick
+	CreateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error)
+	// UpdateRule validates and replaces the rule of the same ID, which is armed again
+	UpdateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error)
+	// DeleteRule deletes a rule, or returns repositories.ErrNotFound
+	DeleteRule(ctx context.Context, id int64) error
+	// GetEvents returns the latest fired alerts, newest first
+	GetEvents(ctx context.Context, req models.AlertEventRequest) ([]models.AlertEvent, error)
+	// Evaluate checks the prices of a tick against the rules of their market,
+	// then records and publishes the alerts fired
+	Evaluate(tick models.PriceTick)
+}
+
+type AlertServiceImpl struct {
+	repository repositories.Repository
+	events     bus.Publisher[models.AlertEvent]
+	cfg        config.AlertConfig
+	now        func() time.Time
+
+	mu    sync.Mutex
+	rules map[int64]*models.AlertRule
+	// markets are the enabled rules of each market, by the cache key of the market with no source
+	markets map[string][]*models.AlertRule
+	// windows are the longest windows of the rules of each market, by the cache key of the market with no source
+	// then by the source the rules watch
+	windows map[string]map[string]time.Duration
+	// series are the recent prices of each source of the markets watched by rules with a window,
+	// by the cache key of the market with no source then by source
+	series map[string]map[string]*priceSeries
+}
+
+func NewAlertService(
+	repository repositories.Repository,
+	events bus.Publisher[models.AlertEvent],
+	cfg config.AlertConfig,
+) AlertService {
+	return &AlertServiceImpl{
+		repository: repository,
+		events:     events,
+		cfg:        cfg,
+		now:        time.Now,
+		rules:      make(map[int64]*models.AlertRule),
+		markets:    make(map[string][]*models.AlertRule),
+		windows:    make(map[string]map[string]time.Duration),
+		series:     make(map[string]map[string]*priceSeries),
+	}
+}
+
+// Load reads the rules, then fills the series of their windows with the closes of the stored minute candles
+func (a *AlertServiceImpl) Load(ctx context.Context) error {
+	rules, err := a.repository.GetAlertRules(ctx)
+	if err != nil {
+		return fmt.Errorf("get rules: %w", err)
+	}
+
+	a.mu.Lock()
+	for i := range rules {
+		a.rules[rules[i].ID] = &rules[i]
+	}
+	a.reindex()
+	a.mu.Unlock()
+
+	for _, rule := range rules {
+		a.warm(ctx, rule)
+	}
+
+	return nil
+}
+
+func (a *AlertServiceImpl) GetRules(ctx context.Context) ([]models.AlertRule, error) {
+	return a.repository.GetAlertRules(ctx)
+}
+
+func (a *AlertServiceImpl) GetRule(ctx context.Context, id int64) (*models.AlertRule, error) {
+	return a.repository.GetAlertRule(ctx, id)
+}
+
+func (a *AlertServiceImpl) CreateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	err := a.validate(&rule)
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.now().UTC()
+	rule.ID = 0
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+	rule.Armed = true
+	rule.Side = 0
+	rule.LastFiredAt = time.Time{}
+
+	err = a.repository.CreateAlertRule(ctx, &rule)
+	if err != nil {
+		return nil, fmt.Errorf("create rule: %w", err)
+	}
+
+	a.watch(ctx, rule)
+	return &rule, nil
+}
+
+// UpdateRule replaces the rule of the same ID and arms it again, the cooldown still runs from its last alert
+func (a *AlertServiceImpl) UpdateRule(ctx context.Context, rule models.AlertRule) (*models.AlertRule, error) {
+	err := a.validate(&rule)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := a.repository.GetAlertRule(ctx, rule.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.CreatedAt = existing.CreatedAt
+	rule.UpdatedAt = a.now().UTC()
+	rule.Armed = true
+	rule.Side = 0
+	rule.LastFiredAt = existing.LastFiredAt
+
+	err = a.repository.UpdateAlertRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+
+	a.watch(ctx, rule)
+	return &rule, nil
+}
+
+func (a *AlertServiceImpl) DeleteRule(ctx context.Context, id int64) error {
+	err := a.repository.DeleteAlertRule(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.rules, id)
+	a.reindex()
+	return nil
+}
+
+func (a *AlertServiceImpl) GetEvents(ctx context.Context, req models.AlertEventRequest) ([]models.AlertEvent, error) {
+	return a.repository.GetAlertEvents(ctx, req)
+}
+
+// Evaluate checks each price of the tick against the enabled rules of its market, in order.
+// The rules whose state changed and the alerts fired are stored together, then the alerts are published
+func (a *AlertServiceImpl) Evaluate(tick models.PriceTick) {
+	a.mu.Lock()
+
+	changed := make(map[int64]struct{})
+	var events []models.AlertEvent
+	for _, datum := range tick.Prices {
+		market := models.CacheKey("", datum.Symbol, datum.Currency)
+		rules := a.markets[market]
+		if len(rules) == 0 {
+			continue
+		}
+
+		if series := a.sourceSeries(market, datum.Source); series != nil {
+			series.add(datum.Timestamp, datum.Price)
+		}
+
+		for _, rule := range rules {
+			if watchedSource(*rule) != datum.Source {
+				continue
+			}
+
+			event, ok := a.evaluate(rule, datum)
+			if !ok {
+				continue
+			}
+			changed[rule.ID] = struct{}{}
+			if event != nil {
+				events = append(events, *event)
+			}
+		}
+	}
+
+	states := make([]models.AlertRule, 0, len(changed))
+	for id := range changed {
+		states = append(states, *a.rules[id])
+	}
+
+	a.mu.Unlock()
+
+	if len(states) == 0 {
+		return
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+
+	// Stored past the shutdown like the prices, the alerts are published whether they are stored or not
+	err := a.repository.RecordAlerts(context.Background(), states, events)
+	if err != nil {
+		slog.Error("record alerts", "rules", len(states), "alerts", len(events), "err", err)
+	}
+
+	for _, event := range events {
+		slog.Info("alert fired", "rule", event.RuleID, "message", event.Message)
+		a.events.Publish(event)
+	}
+}
+
+// evaluate updates the state of a rule with a price of its market, it returns true if the state changed
+// along with the alert if the rule fired
+func (a *AlertServiceImpl) evaluate(rule *models.AlertRule, datum models.PriceDatum) (*models.AlertEvent, bool) {
+	market := datum.Symbol + "/" + datum.Currency
+	hysteresis := rule.Hysteresis.Div(hundred)
+
+	switch rule.Kind {
+	case models.AlertKindAbove:
+		return a.evaluateLevel(rule, datum, rule.Threshold,
+			datum.Price.GreaterThanOrEqual(rule.Threshold),
+			datum.Price.LessThan(rule.Threshold.Mul(decimal.NewFromInt(1).Sub(hysteresis))),
+			fmt.Sprintf("%s rose above %s at %s on %s", market, rule.Threshold, datum.Price, datum.Source))
+	case models.AlertKindBelow:
+		return a.evaluateLevel(rule, datum, rule.Threshold,
+			datum.Price.LessThanOrEqual(rule.Threshold),
+			datum.Price.GreaterThan(rule.Threshold.Mul(decimal.NewFromInt(1).Add(hysteresis))),
+			fmt.Sprintf("%s fell below %s at %s on %s", market, rule.Threshold, datum.Price, datum.Source))
+	case models.AlertKindPercentChange:
+		series := a.series[models.CacheKey("", datum.Symbol, datum.Currency)][datum.Source]
+		window := time.Duration(rule.Window)
+		reference, ok := series.priceAt(datum.Timestamp.Add(-window))
+		if !ok || reference.IsZero() {
+			return nil, false
+		}
+
+		change := datum.Price.Sub(reference).Div(reference).Mul(hundred)
+		rearm := rule.Threshold.Mul(decimal.NewFromInt(1).Sub(hysteresis))
+		reached, rearmed := change.GreaterThanOrEqual(rule.Threshold), change.LessThan(rearm)
+		if rule.Threshold.IsNegative() {
+			reached, rearmed = change.LessThanOrEqual(rule.Threshold), change.GreaterThan(rearm)
+		}
+		return a.evaluateLevel(rule, datum, reference, reached, rearmed,
+			fmt.Sprintf("%s changed by %s%% in %s to %s on %s", market, change.StringFixed(2), window, datum.Price, datum.Source))
+	case models.AlertKindMACross:
+		series := a.series[models.CacheKey("", datum.Symbol, datum.Currency)][datum.Source]
+		window := time.Duration(rule.Window)
+		average, ok := series.average(window)
+		if !ok {
+			return nil, false
+		}
+		average = average.Round(averageDecimals)
+
+		side := rule.Side
+		switch {
+		case datum.Price.GreaterThan(average.Mul(decimal.NewFromInt(1).Add(hysteresis))):
+			side = 1
+		case datum.Price.LessThan(average.Mul(decimal.NewFromInt(1).Sub(hysteresis))):
+			side = -1
+		}
+		if side == rule.Side {
+			return nil, false
+		}
+
+		// The first side seen is where the price starts from, not a cross
+		previous := rule.Side
+		rule.Side = side
+		if previous == 0 || !a.cooledDown(rule, datum.Timestamp) {
+			return nil, true
+		}
+
+		direction := "above"
+		if side < 0 {
+			direction = "below"
+		}
+		return a.fire(rule, datum, average,
+			fmt.Sprintf("%s crossed %s its %s moving average of %s at %s on %s",
+				market, direction, window, average, datum.Price, datum.Source)), true
+	default:
+		return nil, false
+	}
+}
+
+// evaluateLevel fires a rule armed once the price reached its level, then arms it again once the price
+// moved back past the hysteresis
+func (a *AlertServiceImpl) evaluateLevel(
+	rule *models.AlertRule,
+	datum models.PriceDatum,
+	reference decimal.Decimal,
+	reached, rearmed bool,
+	message string,
+) (*models.AlertEvent, bool) {
+	if !rule.Armed {
+		if !rearmed {
+			return nil, false
+		}
+		rule.Armed = true
+		return nil, true
+	}
+
+	if !reached || !a.cooledDown(rule, datum.Timestamp) {
+		return nil, false
+	}
+
+	rule.Armed = false
+	return a.fire(rule, datum, reference, message), true
+}
+
+// cooledDown returns true if the cooldown since the last alert of the rule is over at the time
+func (a *AlertServiceImpl) cooledDown(rule *models.AlertRule, timestamp time.Time) bool {
+	return rule.LastFiredAt.IsZero() || !timestamp.Before(rule.LastFiredAt.Add(time.Duration(rule.Cooldown)))
+}
+
+func (a *AlertServiceImpl) fire(
+	rule *models.AlertRule,
+	datum models.PriceDatum,
+	reference decimal.Decimal,
+	message string,
+) *models.AlertEvent {
+	rule.LastFiredAt = datum.Timestamp
+
+	return &models.AlertEvent{
+		RuleID:    rule.ID,
+		Name:      rule.Name,
+		Symbol:    datum.Symbol,
+		Currency:  datum.Currency,
+		Source:    datum.Source,
+		Kind:      rule.Kind,
+		Price:     datum.Price,
+		Reference: reference,
+		Message:   message,
+		FiredAt:   datum.Timestamp,
+		Email:     rule.Email,
+	}
+}
+
+// validate normalizes the market, the source and the email of a rule and checks its parameters
+func (a *AlertServiceImpl) validate(rule *models.AlertRule) error {
+	rule.Symbol = strings.ToUpper(strings.TrimSpace(rule.Symbol))
+	rule.Currency = strings.ToUpper(strings.TrimSpace(rule.Currency))
+	rule.Source = strings.ToLower(strings.TrimSpace(rule.Source))
+	if rule.Currency == "" {
+		rule.Currency = constant.USDT
+	}
+	if rule.Email = strings.TrimSpace(rule.Email); rule.Email != "" {
+		address, err := mail.ParseAddress(rule.Email)
+		if err != nil {
+			return fmt.Errorf("%w: email is not an address: %w", ErrInvalidAlertRule, err)
+		}
+		rule.Email = address.Address
+	}
+
+	window := time.Duration(rule.Window)
+	switch {
+	case rule.Symbol == "":
+		return fmt.Errorf("%w: symbol is required", ErrInvalidAlertRule)
+	case window < 0 || window > a.cfg.AlertMaxWindow:
+		return fmt.Errorf("%w: window must be between 0 and %s", ErrInvalidAlertRule, a.cfg.AlertMaxWindow)
+	case rule.Cooldown < 0:
+		return fmt.Errorf("%w: cooldown must not be negative", ErrInvalidAlertRule)
+	case rule.Hysteresis.IsNegative() || rule.Hysteresis.GreaterThanOrEqual(hundred):
+		return fmt.Errorf("%w: hysteresis must be a percent between 0 and 100", ErrInvalidAlertRule)
+	}
+
+	switch rule.Source {
+	case "", constant.SourceBinance, constant.SourceCoinbase, constant.SourceKraken, constant.SourceOkx, constant.SourceIndex:
+	default:
+		return fmt.Errorf("%w: source must be one of %s, %s, %s, %s or %s, or empty for the index", ErrInvalidAlertRule,
+			constant.SourceBinance, constant.SourceCoinbase, constant.SourceKraken, constant.SourceOkx, constant.SourceIndex)
+	}
+
+	switch rule.Kind {
+	case models.AlertKindAbove, models.AlertKindBelow:
+		if !rule.Threshold.IsPositive() {
+			return fmt.Errorf("%w: threshold must be a positive price", ErrInvalidAlertRule)
+		}
+		rule.Window = 0
+	case models.AlertKindPercentChange, models.AlertKindMACross:
+		if rule.Kind == models.AlertKindPercentChange && rule.Threshold.IsZero() {
+			return fmt.Errorf("%w: threshold must be a non-zero percent", ErrInvalidAlertRule)
+		}
+		if window < a.cfg.AlertSampleInterval {
+			return fmt.Errorf("%w: window must be at least %s", ErrInvalidAlertRule, a.cfg.AlertSampleInterval)
+		}
+	default:
+		return fmt.Errorf("%w: kind must be one of %s, %s, %s or %s", ErrInvalidAlertRule,
+			models.AlertKindAbove, models.AlertKindBelow, models.AlertKindPercentChange, models.AlertKindMACross)
+	}
+
+	return nil
+}
+
+// watch replaces the rule evaluated under its ID and fills the series of its window
+func (a *AlertServiceImpl) watch(ctx context.Context, rule models.AlertRule) {
+	a.mu.Lock()
+	a.rules[rule.ID] = &rule
+	a.reindex()
+	a.mu.Unlock()
+
+	a.warm(ctx, rule)
+}
+
+// reindex groups the enabled rules by market along with the longest window of each market and source,
+// then keeps the series still watched over their longest window. It must be called with the lock held
+func (a *AlertServiceImpl) reindex() {
+	ids := make([]int64, 0, len(a.rules))
+	for id := range a.rules {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	markets := make(map[string][]*models.AlertRule)
+	windows := make(map[string]map[string]time.Duration)
+	for _, id := range ids {
+		rule := a.rules[id]
+		if !rule.Enabled {
+			continue
+		}
+
+		market := models.CacheKey("", rule.Symbol, rule.Currency)
+		markets[market] = append(markets[market], rule)
+		if rule.Window > 0 {
+			if windows[market] == nil {
+				windows[market] = make(map[string]time.Duration)
+			}
+			source := watchedSource(*rule)
+			windows[market][source] = max(windows[market][source], time.Duration(rule.Window))
+		}
+	}
+	a.markets = markets
+	a.windows = windows
+
+	for market, sources := range a.series {
+		for source := range sources {
+			a.sourceSeries(market, source)
+		}
+	}
+	for market, sources := range windows {
+		for source := range sources {
+			a.sourceSeries(market, source)
+		}
+	}
+}
+
+// sourceSeries returns the series of a market and source over the longest window of the rules watching them,
+// created if needed, or nil after dropping it if no rule with a window does. It must be called with the lock held
+func (a *AlertServiceImpl) sourceSeries(market, source string) *priceSeries {
+	window := a.windows[market][source]
+	if window == 0 {
+		delete(a.series[market], source)
+		if len(a.series[market]) == 0 {
+			delete(a.series, market)
+		}
+		return nil
+	}
+
+	if a.series[market] == nil {
+		a.series[market] = make(map[string]*priceSeries)
+	}
+	series, ok := a.series[market][source]
+	if !ok {
+		series = newPriceSeries(a.cfg.AlertSampleInterval)
+		a.series[market][source] = series
+	}
+	series.window = window
+
+	return series
+}
+
+// warm fills the series of a rule, back to the start of its window,
+// with the closes of the stored minute candles older than its first price
+func (a *AlertServiceImpl) warm(ctx context.Context, rule models.AlertRule) {
+	if rule.Window == 0 || !rule.Enabled {
+		return
+	}
+
+	source := watchedSource(rule)
+	now := a.now().UTC()
+	candles, err := a.repository.GetCandles(ctx, models.CandleRequest{
+		Symbol:     rule.Symbol,
+		Currency:   rule.Currency,
+		Source:     source,
+		Resolution: models.CandleResolutions[0],
+		From:       now.Add(-time.Duration(rule.Window) - models.CandleResolutions[0].Duration),
+		To:         now,
+	})
+	if err != nil {
+		slog.Error("warm alert series", "rule", rule.ID, "err", err)
+		return
+	}
+
+	samples := make([]priceSample, 0, len(candles))
+	for _, candle := range candles {
+		samples = append(samples, priceSample{time: candle.LastTick, price: candle.Close})
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if series, ok := a.series[models.CacheKey("", rule.Symbol, rule.Currency)][source]; ok {
+		series.prepend(samples)
+	}
+}
+
+// watchedSource returns the source whose prices a rule is checked against. A rule without a source watches the index,
+// the arming of a single rule would flap between exchanges quoting either side of its level
+func watchedSource(rule models.AlertRule) string {
+	if rule.Source == "" {
+		return constant.SourceIndex
+	}
+
+	return rule.Source
+}
@@ -0,0 +1,399 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories/mock"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	m "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// recordingPublisher implements bus.Publisher by keeping the published events
+type recordingPublisher[T any] struct {
+	events []T
+}
+
+func (r *recordingPublisher[T]) Publish(event T) {
+	r.events = append(r.events, event)
+}
+
+type AlertServiceTestSuite struct {
+	suite.Suite
+	mockRepo *mock.MockRepository
+	alerts   *recordingPublisher[models.AlertEvent]
+	now      time.Time
+	service  *AlertServiceImpl
+}
+
+func (s *AlertServiceTestSuite) SetupTest() {
+	s.mockRepo = new(mock.MockRepository)
+	s.alerts = &recordingPublisher[models.AlertEvent]{}
+	s.now = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.service = NewAlertService(s.mockRepo, s.alerts, config.AlertConfig{
+		AlertSampleInterval: 10 * time.Second,
+		AlertMaxWindow:      24 * time.Hour,
+	}).(*AlertServiceImpl)
+	s.service.now = func() time.Time { return s.now }
+}
+
+func TestAlertServiceSuite(t *testing.T) {
+	suite.Run(t, new(AlertServiceTestSuite))
+}
+
+// load makes the rules the ones evaluated, as read on startup
+func (s *AlertServiceTestSuite) load(rules ...models.AlertRule) {
+	s.mockRepo.On("GetAlertRules").Return(rules, nil).Once()
+	s.mockRepo.On("GetCandles", m.Anything).Return([]models.Candle{}, nil)
+	s.mockRepo.On("RecordAlerts", m.Anything, m.Anything).Return(nil)
+
+	err := s.service.Load(context.Background())
+	s.Require().NoError(err)
+}
+
+// evaluate evaluates a tick of a single BTC/USDT index price received at the offset from now
+func (s *AlertServiceTestSuite) evaluate(offset time.Duration, price string) {
+	s.service.Evaluate(models.PriceTick{Prices: []models.PriceDatum{{
+		Timestamp: s.now.Add(offset),
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "index",
+		Price:     decimal.RequireFromString(price),
+	}}})
+}
+
+func (s *AlertServiceTestSuite) TestCreateRule() {
+	// Arrange
+	s.mockRepo.On("CreateAlertRule", m.Anything).Run(func(args m.Arguments) {
+		args.Get(0).(*models.AlertRule).ID = 7
+	}).Return(nil)
+
+	// Act
+	rule, err := s.service.CreateRule(context.Background(), models.AlertRule{
+		Symbol:    " btc ",
+		Source:    "Binance",
+		Kind:      models.AlertKindAbove,
+		Threshold: decimal.NewFromInt(100),
+		Window:    models.Duration(time.Hour),
+		Email:     " Ops <OPS@example.com> ",
+		Enabled:   true,
+		Armed:     false,
+	})
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(7), rule.ID)
+	assert.Equal(s.T(), "BTC", rule.Symbol)
+	assert.Equal(s.T(), "USDT", rule.Currency)
+	assert.Equal(s.T(), "binance", rule.Source)
+	assert.Equal(s.T(), "OPS@example.com", rule.Email)
+	assert.Zero(s.T(), rule.Window)
+	assert.True(s.T(), rule.Armed)
+	assert.Equal(s.T(), s.now, rule.CreatedAt)
+	assert.Len(s.T(), s.service.markets[models.CacheKey("", "BTC", "USDT")], 1)
+}
+
+func (s *AlertServiceTestSuite) TestCreateRule_Invalid() {
+	valid := models.AlertRule{
+		Symbol:    "BTC",
+		Kind:      models.AlertKindPercentChange,
+		Threshold: decimal.NewFromInt(5),
+		Window:    models.Duration(time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		modify func(rule *models.AlertRule)
+	}{
+		{"missing symbol", func(rule *models.AlertRule) { rule.Symbol = "" }},
+		{"unknown kind", func(rule *models.AlertRule) { rule.Kind = "sideways" }},
+		{"zero percent", func(rule *models.AlertRule) { rule.Threshold = decimal.Zero }},
+		{"window shorter than a sample", func(rule *models.AlertRule) { rule.Window = models.Duration(time.Second) }},
+		{"window too long", func(rule *models.AlertRule) { rule.Window = models.Duration(48 * time.Hour) }},
+		{"negative cooldown", func(rule *models.AlertRule) { rule.Cooldown = models.Duration(-time.Minute) }},
+		{"hysteresis of 100%", func(rule *models.AlertRule) { rule.Hysteresis = decimal.NewFromInt(100) }},
+		{"email not an address", func(rule *models.AlertRule) { rule.Email = "ops at example.com" }},
+		{"unknown source", func(rule *models.AlertRule) { rule.Source = "binanse" }},
+		{"negative price", func(rule *models.AlertRule) {
+			rule.Kind = models.AlertKindBelow
+			rule.Threshold = decimal.NewFromInt(-1)
+		}},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			rule := valid
+			tt.modify(&rule)
+
+			// Act
+			_, err := s.service.CreateRule(context.Background(), rule)
+
+			// Assert
+			assert.ErrorIs(s.T(), err, ErrInvalidAlertRule)
+		})
+	}
+	s.mockRepo.AssertNotCalled(s.T(), "CreateAlertRule", m.Anything)
+}
+
+func (s *AlertServiceTestSuite) TestUpdateRule_ArmsAgain() {
+	// Arrange
+	firedAt := s.now.Add(-time.Minute)
+	existing := &models.AlertRule{ID: 3, CreatedAt: s.now.Add(-time.Hour), LastFiredAt: firedAt}
+	s.mockRepo.On("GetAlertRule", int64(3)).Return(existing, nil)
+	s.mockRepo.On("UpdateAlertRule", m.Anything).Return(nil)
+
+	// Act
+	rule, err := s.service.UpdateRule(context.Background(), models.AlertRule{
+		ID:        3,
+		Symbol:    "BTC",
+		Kind:      models.AlertKindBelow,
+		Threshold: decimal.NewFromInt(90),
+		Enabled:   true,
+	})
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), rule.Armed)
+	assert.Equal(s.T(), s.now.Add(-time.Hour), rule.CreatedAt)
+	assert.Equal(s.T(), s.now, rule.UpdatedAt)
+	// The cooldown runs from the last alert before the update
+	assert.Equal(s.T(), firedAt, rule.LastFiredAt)
+}
+
+func (s *AlertServiceTestSuite) TestEvaluate_AboveWithHysteresisAndCooldown() {
+	// Arrange
+	s.load(models.AlertRule{
+		ID:         1,
+		Name:       "breakout",
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Kind:       models.AlertKindAbove,
+		Threshold:  decimal.NewFromInt(100),
+		Hysteresis: decimal.NewFromInt(1),
+		Cooldown:   models.Duration(5 * time.Minute),
+		Email:      "ops@example.com",
+		Enabled:    true,
+		Armed:      true,
+	})
+
+	// Act
+	s.evaluate(0, "99")
+	s.evaluate(time.Second, "100.5")
+	// Not armed again until the price falls under 99
+	s.evaluate(2*time.Second, "99.5")
+	s.evaluate(3*time.Second, "101")
+	s.evaluate(4*time.Second, "98.9")
+	// Armed, but in the cooldown of the first alert
+	s.evaluate(time.Minute, "101")
+	s.evaluate(6*time.Minute, "101")
+
+	// Assert
+	s.Require().Len(s.alerts.events, 2)
+	assert.Equal(s.T(), int64(1), s.alerts.events[0].RuleID)
+	assert.Equal(s.T(), "breakout", s.alerts.events[0].Name)
+	assert.Equal(s.T(), "100.5", s.alerts.events[0].Price.String())
+	assert.Equal(s.T(), "100", s.alerts.events[0].Reference.String())
+	assert.Equal(s.T(), "BTC/USDT rose above 100 at 100.5 on index", s.alerts.events[0].Message)
+	assert.Equal(s.T(), s.now.Add(time.Second), s.alerts.events[0].FiredAt)
+	assert.Equal(s.T(), "ops@example.com", s.alerts.events[0].Email)
+	assert.Equal(s.T(), s.now.Add(6*time.Minute), s.alerts.events[1].FiredAt)
+
+	// The state is stored along with each alert
+	s.mockRepo.AssertNumberOfCalls(s.T(), "RecordAlerts", 3)
+	last := s.mockRepo.Calls[len(s.mockRepo.Calls)-1]
+	assert.Equal(s.T(), "RecordAlerts", last.Method)
+	states := last.Arguments.Get(0).([]models.AlertRule)
+	s.Require().Len(states, 1)
+	assert.False(s.T(), states[0].Armed)
+	assert.Equal(s.T(), s.now.Add(6*time.Minute), states[0].LastFiredAt)
+	assert.Len(s.T(), last.Arguments.Get(1).([]models.AlertEvent), 1)
+}
+
+func (s *AlertServiceTestSuite) TestEvaluate_PercentChange() {
+	tests := []struct {
+		name      string
+		threshold string
+		prices    []string
+		fired     []string
+	}{
+		{
+			name:      "rise",
+			threshold: "5",
+			prices:    []string{"100", "103", "105", "104", "110"},
+			fired:     []string{"BTC/USDT changed by 5.00% in 1m0s to 105 on index"},
+		},
+		{
+			name:      "fall",
+			threshold: "-5",
+			prices:    []string{"100", "100", "100", "96", "94"},
+			fired:     []string{"BTC/USDT changed by -6.00% in 1m0s to 94 on index"},
+		},
+		{
+			name:      "not enough history",
+			threshold: "1",
+			prices:    []string{"100", "200"},
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.SetupTest()
+			s.load(models.AlertRule{
+				ID:        1,
+				Symbol:    "BTC",
+				Currency:  "USDT",
+				Kind:      models.AlertKindPercentChange,
+				Threshold: decimal.RequireFromString(tt.threshold),
+				Window:    models.Duration(time.Minute),
+				Enabled:   true,
+				Armed:     true,
+			})
+
+			// Act, a price every 30s
+			for i, price := range tt.prices {
+				s.evaluate(time.Duration(i)*30*time.Second, price)
+			}
+
+			// Assert
+			var fired []string
+			for _, event := range s.alerts.events {
+				fired = append(fired, event.Message)
+			}
+			assert.Equal(s.T(), tt.fired, fired)
+		})
+	}
+}
+
+func (s *AlertServiceTestSuite) TestEvaluate_AnySourceWatchesIndex() {
+	// Arrange
+	s.load(models.AlertRule{
+		ID:         1,
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Kind:       models.AlertKindAbove,
+		Threshold:  decimal.NewFromInt(100),
+		Hysteresis: decimal.NewFromInt(1),
+		Enabled:    true,
+		Armed:      true,
+	})
+	price := func(offset time.Duration, source, price string) models.PriceDatum {
+		return models.PriceDatum{
+			Timestamp: s.now.Add(offset),
+			Symbol:    "BTC",
+			Currency:  "USDT",
+			Source:    source,
+			Price:     decimal.RequireFromString(price),
+		}
+	}
+
+	// Act, kraken trades above the level and binance below its hysteresis while the index stays above it
+	for i := range 3 {
+		offset := time.Duration(i) * time.Second
+		s.service.Evaluate(models.PriceTick{Prices: []models.PriceDatum{
+			price(offset, "kraken", "101"),
+			price(offset, "binance", "98"),
+			price(offset, "index", "100.5"),
+		}})
+	}
+
+	// Assert
+	s.Require().Len(s.alerts.events, 1)
+	assert.Equal(s.T(), "BTC/USDT rose above 100 at 100.5 on index", s.alerts.events[0].Message)
+	assert.False(s.T(), s.service.rules[1].Armed)
+}
+
+func (s *AlertServiceTestSuite) TestEvaluate_MACross() {
+	// Arrange
+	s.load(models.AlertRule{
+		ID:       1,
+		Symbol:   "BTC",
+		Currency: "USDT",
+		Kind:     models.AlertKindMACross,
+		Window:   models.Duration(30 * time.Second),
+		Enabled:  true,
+		Armed:    true,
+	})
+
+	// Act, a price every 10s
+	for i, price := range []string{"100", "100", "100", "100", "100", "110", "90", "91"} {
+		s.evaluate(time.Duration(i)*10*time.Second, price)
+	}
+
+	// Assert, starting above the average of 100 is not a cross
+	s.Require().Len(s.alerts.events, 1)
+	assert.Equal(s.T(), "BTC/USDT crossed below its 30s moving average of 103.33333333 at 90 on index",
+		s.alerts.events[0].Message)
+	assert.Equal(s.T(), "103.33333333", s.alerts.events[0].Reference.String())
+	assert.Equal(s.T(), -1, s.service.rules[1].Side)
+}
+
+func (s *AlertServiceTestSuite) TestEvaluate_OtherSourceOrDisabled() {
+	// Arrange
+	s.load(
+		models.AlertRule{
+			ID:        1,
+			Symbol:    "BTC",
+			Currency:  "USDT",
+			Source:    "kraken",
+			Kind:      models.AlertKindAbove,
+			Threshold: decimal.NewFromInt(100),
+			Enabled:   true,
+			Armed:     true,
+		},
+		models.AlertRule{
+			ID:        2,
+			Symbol:    "BTC",
+			Currency:  "USDT",
+			Kind:      models.AlertKindAbove,
+			Threshold: decimal.NewFromInt(100),
+			Armed:     true,
+		},
+	)
+
+	// Act
+	s.evaluate(0, "150")
+
+	// Assert
+	assert.Empty(s.T(), s.alerts.events)
+	s.mockRepo.AssertNotCalled(s.T(), "RecordAlerts", m.Anything, m.Anything)
+}
+
+func (s *AlertServiceTestSuite) TestLoad_WarmsWindowFromCandles() {
+	// Arrange
+	s.mockRepo.On("GetAlertRules").Return([]models.AlertRule{{
+		ID:        1,
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Kind:      models.AlertKindPercentChange,
+		Threshold: decimal.NewFromInt(10),
+		Window:    models.Duration(time.Hour),
+		Enabled:   true,
+		Armed:     true,
+	}}, nil)
+	s.mockRepo.On("GetCandles", models.CandleRequest{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "index",
+		Resolution: models.CandleResolutions[0],
+		From:       s.now.Add(-61 * time.Minute),
+		To:         s.now,
+	}).Return([]models.Candle{
+		{OpenTime: s.now.Add(-61 * time.Minute), Close: decimal.NewFromInt(100), LastTick: s.now.Add(-60*time.Minute - time.Second)},
+		{OpenTime: s.now.Add(-30 * time.Minute), Close: decimal.NewFromInt(105), LastTick: s.now.Add(-30 * time.Minute)},
+	}, nil)
+	s.mockRepo.On("RecordAlerts", m.Anything, m.Anything).Return(nil)
+
+	// Act
+	err := s.service.Load(context.Background())
+	s.evaluate(0, "110")
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.Require().Len(s.alerts.events, 1)
+	assert.Equal(s.T(), "100", s.alerts.events[0].Reference.String())
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/bus"
+	"context"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// minRelativeDeviation is the smallest deviation of the anomaly checks relative to the price, for the markets
+// without a known tick size
+const minRelativeDeviation = 1e-4
+
+// AnomalyService screens the prices the fetchers receive before they are published on the prices bus
+type AnomalyService interface {
+	// Publish checks the prices of a tick, publishes the anomalies found and hands the tick on to the prices bus,
+	// without the prices quarantined
+	Publish(tick models.PriceTick)
+	// SaveAnomalies writes the anomalies of the anomalies bus
+	SaveAnomalies(anomalies []models.Anomaly)
+	// GetAnomalies returns the latest anomalies, newest first
+	GetAnomalies(ctx context.Context, req models.AnomalyRequest) ([]models.Anomaly, error)
+}
+
+type AnomalyServiceImpl struct {
+	repository repositories.Repository
+	prices     bus.Publisher[models.PriceTick]
+	anomalies  bus.Publisher[models.Anomaly]
+	cfg        config.AnomalyConfig
+
+	mu sync.Mutex
+	// markets are the recent prices of each market, by its cache key with its source
+	markets map[string]*anomalyWindow
+}
+
+// anomalyWindow is the recent prices of a market
+type anomalyWindow struct {
+	// prices are the last prices not flagged, oldest first
+	prices []float64
+	// flagged are the prices flagged in a row since the last one that was not
+	flagged []float64
+	last    decimal.Decimal
+
+	// flatPrice is the price received since flatSince, flatReported once it is flagged as a flatline
+	flatPrice    decimal.Decimal
+	flatSince    time.Time
+	flatReported bool
+}
+
+func NewAnomalyService(
+	repository repositories.Repository,
+	prices bus.Publisher[models.PriceTick],
+	anomalies bus.Publisher[models.Anomaly],
+	cfg config.AnomalyConfig,
+) AnomalyService {
+	return &AnomalyServiceImpl{
+		repository: repository,
+		prices:     prices,
+		anomalies:  anomalies,
+		cfg:        cfg,
+		markets:    make(map[string]*anomalyWindow),
+	}
+}
+
+// Publish checks each price of the tick against the window of its market. The prices flagged as zscore or jump
+// are left out of the tick if quarantine is enabled, a tick with no price left is not published
+func (a *AnomalyServiceImpl) Publish(tick models.PriceTick) {
+	a.mu.Lock()
+
+	var found []models.Anomaly
+	kept := make([]models.PriceDatum, 0, len(tick.Prices))
+	for _, datum := range tick.Prices {
+		anomalies, quarantined := a.check(datum)
+		found = append(found, anomalies...)
+		if !quarantined {
+			kept = append(kept, datum)
+		}
+	}
+
+	a.mu.Unlock()
+
+	for _, anomaly := range found {
+		slog.Warn("price anomaly", "kind", anomaly.Kind, "quarantined", anomaly.Quarantined, "message", anomaly.Message)
+		a.anomalies.Publish(anomaly)
+	}
+
+	if len(kept) == 0 && len(tick.Prices) > 0 {
+		return
+	}
+	tick.Prices = kept
+	a.prices.Publish(tick)
+}
+
+// SaveAnomalies writes the anomalies, past the shutdown like the prices
+func (a *AnomalyServiceImpl) SaveAnomalies(anomalies []models.Anomaly) {
+	err := a.repository.SaveAnomalies(context.Background(), anomalies)
+	if err != nil {
+		slog.Error("save anomalies", "count", len(anomalies), "err", err)
+	}
+}
+
+func (a *AnomalyServiceImpl) GetAnomalies(ctx context.Context, req models.AnomalyRequest) ([]models.Anomaly, error) {
+	return a.repository.GetAnomalies(ctx, req)
+}
+
+// check updates the window of the market of a price and returns the anomalies of the price,
+// along with true if it is quarantined. It must be called with the lock held
+func (a *AnomalyServiceImpl) check(datum models.PriceDatum) ([]models.Anomaly, bool) {
+	key := models.CacheKey(datum.Source, datum.Symbol, datum.Currency)
+	window, ok := a.markets[key]
+	if !ok {
+		window = &anomalyWindow{}
+		a.markets[key] = window
+	}
+
+	var anomalies []models.Anomaly
+	if anomaly := a.flatline(window, datum); anomaly != nil {
+		anomalies = append(anomalies, *anomaly)
+	}
+
+	price := datum.Price.InexactFloat64()
+	outlier := a.outlier(window, datum)
+	if outlier == nil {
+		window.accept(price, a.cfg.AnomalyWindow)
+		window.flagged = nil
+		window.last = datum.Price
+		return anomalies, false
+	}
+
+	// A level held for long enough is a move of the market rather than bad prints
+	window.flagged = append(window.flagged, price)
+	if len(window.flagged) >= a.cfg.AnomalyResetAfter {
+		window.prices = window.flagged
+		window.flagged = nil
+		window.last = datum.Price
+		return anomalies, false
+	}
+
+	outlier.Quarantined = a.cfg.AnomalyQuarantine
+	return append(anomalies, *outlier), outlier.Quarantined
+}
+
+// outlier returns the anomaly of a price too far from the previous price or from the mean of the window,
+// nil if it is not or the window has too few prices
+func (a *AnomalyServiceImpl) outlier(window *anomalyWindow, datum models.PriceDatum) *models.Anomaly {
+	n := len(window.prices)
+	if n < max(a.cfg.AnomalyMinSamples, 2) {
+		return nil
+	}
+
+	var sum, sumMoves float64
+	for i, p := range window.prices {
+		sum += p
+		if i > 0 {
+			move := p - window.prices[i-1]
+			sumMoves += move * move
+		}
+	}
+	mean := sum / float64(n)
+	var sumDeviations float64
+	for _, p := range window.prices {
+		sumDeviations += (p - mean) * (p - mean)
+	}
+	std := math.Sqrt(sumDeviations / float64(n))
+	// The usual move is the root mean square of the moves, whatever the trend
+	move := math.Sqrt(sumMoves / float64(n-1))
+
+	market := datum.Symbol + "/" + datum.Currency
+	price := datum.Price.InexactFloat64()
+
+	// A flat market barely deviates, a move of a tick is not an anomaly however quiet it has been
+	floor := math.Max(datum.TickSize.InexactFloat64(), math.Abs(price)*minRelativeDeviation)
+	std = math.Max(std, floor)
+	move = math.Max(move, floor)
+
+	if jump := math.Abs(price-window.last.InexactFloat64()) / move; move > 0 && jump > a.cfg.AnomalyJumpThreshold {
+		return newAnomaly(datum, models.AnomalyKindJump, window.last, jump,
+			fmt.Sprintf("%s jumped from %s to %s on %s, %.1f times its usual move",
+				market, window.last, datum.Price, datum.Source, jump))
+	}
+
+	if z := math.Abs(price-mean) / std; std > 0 && z > a.cfg.AnomalyZScoreThreshold {
+		reference := decimal.NewFromFloat(mean).Round(averageDecimals)
+		return newAnomaly(datum, models.AnomalyKindZScore, reference, z,
+			fmt.Sprintf("%s at %s on %s is %.1f standard deviations from its mean of %s over %d prices",
+				market, datum.Price, datum.Source, z, reference, n))
+	}
+
+	return nil
+}
+
+// flatline returns the anomaly of a market whose price has not changed for AnomalyFlatlineAfter,
+// once per flatline
+func (a *AnomalyServiceImpl) flatline(window *anomalyWindow, datum models.PriceDatum) *models.Anomaly {
+	if window.flatSince.IsZero() || !datum.Price.Equal(window.flatPrice) {
+		window.flatPrice = datum.Price
+		window.flatSince = datum.Timestamp
+		window.flatReported = false
+		return nil
+	}
+
+	flat := datum.Timestamp.Sub(window.flatSince)
+	if a.cfg.AnomalyFlatlineAfter <= 0 || flat < a.cfg.AnomalyFlatlineAfter || window.flatReported {
+		return nil
+	}
+
+	window.flatReported = true
+	return newAnomaly(datum, models.AnomalyKindFlatline, window.flatPrice, flat.Seconds(),
+		fmt.Sprintf("%s/%s stayed at %s on %s for %s",
+			datum.Symbol, datum.Currency, datum.Price, datum.Source, flat.Round(time.Second)))
+}
+
+// accept adds a price to the window, dropping the oldest beyond size
+func (w *anomalyWindow) accept(price float64, size int) {
+	w.prices = append(w.prices, price)
+	if len(w.prices) > size {
+		w.prices = w.prices[len(w.prices)-size:]
+	}
+}
+
+func newAnomaly(datum models.PriceDatum, kind string, reference decimal.Decimal, score float64, message string) *models.Anomaly {
+	return &models.Anomaly{
+		Timestamp: datum.Timestamp,
+		Symbol:    datum.Symbol,
+		Currency:  datum.Currency,
+		Source:    datum.Source,
+		Kind:      kind,
+		Price:     datum.Price,
+		Reference: reference,
+		Score:     math.Round(score*100) / 100,
+		Message:   message,
+	}
+}
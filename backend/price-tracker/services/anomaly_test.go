@@ -0,0 +1,219 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories/mock"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AnomalyServiceTestSuite struct {
+	suite.Suite
+	prices    *recordingPublisher[models.PriceTick]
+	anomalies *recordingPublisher[models.Anomaly]
+	cfg       config.AnomalyConfig
+	now       time.Time
+	service   AnomalyService
+}
+
+func (s *AnomalyServiceTestSuite) SetupTest() {
+	s.prices = &recordingPublisher[models.PriceTick]{}
+	s.anomalies = &recordingPublisher[models.Anomaly]{}
+	s.cfg = config.AnomalyConfig{
+		AnomalyWindow:          20,
+		AnomalyMinSamples:      10,
+		AnomalyZScoreThreshold: 6,
+		AnomalyJumpThreshold:   10,
+		AnomalyFlatlineAfter:   time.Minute,
+		AnomalyResetAfter:      3,
+		AnomalyQuarantine:      true,
+	}
+	s.now = time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	s.newService()
+}
+
+func (s *AnomalyServiceTestSuite) newService() {
+	s.service = NewAnomalyService(new(mock.MockRepository), s.prices, s.anomalies, s.cfg)
+}
+
+func TestAnomalyServiceSuite(t *testing.T) {
+	suite.Run(t, new(AnomalyServiceTestSuite))
+}
+
+// publish publishes a tick of a single binance BTC/USDT price received at the offset from now
+func (s *AnomalyServiceTestSuite) publish(offset time.Duration, price string) {
+	s.service.Publish(models.PriceTick{Prices: []models.PriceDatum{{
+		Timestamp: s.now.Add(offset),
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "binance",
+		Price:     decimal.RequireFromString(price),
+	}}})
+}
+
+// warm publishes 20 prices alternating between 100 and 100.2, a mean of 100.1 and a standard deviation of 0.1
+// with moves of 0.2, one per second
+func (s *AnomalyServiceTestSuite) warm() {
+	for i := 0; i < 20; i++ {
+		price := "100"
+		if i%2 == 1 {
+			price = "100.2"
+		}
+		s.publish(time.Duration(i)*time.Second, price)
+	}
+	s.Require().Empty(s.anomalies.events)
+	s.prices.events = nil
+}
+
+// published returns the prices handed on to the prices bus
+func (s *AnomalyServiceTestSuite) published() []string {
+	var res []string
+	for _, tick := range s.prices.events {
+		for _, datum := range tick.Prices {
+			res = append(res, datum.Price.String())
+		}
+	}
+	return res
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_JumpQuarantined() {
+	// Arrange
+	s.warm()
+
+	// Act
+	s.publish(20*time.Second, "150")
+	s.publish(21*time.Second, "100")
+
+	// Assert
+	assert.Equal(s.T(), []string{"100"}, s.published())
+	s.Require().Len(s.anomalies.events, 1)
+	anomaly := s.anomalies.events[0]
+	assert.Equal(s.T(), models.AnomalyKindJump, anomaly.Kind)
+	assert.Equal(s.T(), "150", anomaly.Price.String())
+	assert.Equal(s.T(), "100.2", anomaly.Reference.String())
+	assert.Equal(s.T(), 249.0, anomaly.Score)
+	assert.True(s.T(), anomaly.Quarantined)
+	assert.Equal(s.T(), s.now.Add(20*time.Second), anomaly.Timestamp)
+	assert.Equal(s.T(), "BTC/USDT jumped from 100.2 to 150 on binance, 249.0 times its usual move", anomaly.Message)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_ZScore() {
+	// Arrange
+	s.warm()
+
+	// Act, 3.5 usual moves from the previous price but 8 standard deviations from the mean
+	s.publish(20*time.Second, "100.9")
+
+	// Assert
+	assert.Empty(s.T(), s.published())
+	s.Require().Len(s.anomalies.events, 1)
+	anomaly := s.anomalies.events[0]
+	assert.Equal(s.T(), models.AnomalyKindZScore, anomaly.Kind)
+	assert.Equal(s.T(), "100.1", anomaly.Reference.String())
+	assert.Equal(s.T(), 8.0, anomaly.Score)
+	assert.Equal(s.T(),
+		"BTC/USDT at 100.9 on binance is 8.0 standard deviations from its mean of 100.1 over 20 prices", anomaly.Message)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_NotQuarantined() {
+	// Arrange
+	s.cfg.AnomalyQuarantine = false
+	s.newService()
+	s.warm()
+
+	// Act
+	s.publish(20*time.Second, "150")
+
+	// Assert
+	assert.Equal(s.T(), []string{"150"}, s.published())
+	s.Require().Len(s.anomalies.events, 1)
+	assert.False(s.T(), s.anomalies.events[0].Quarantined)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_NewLevel() {
+	// Arrange
+	s.warm()
+
+	// Act
+	s.publish(20*time.Second, "150")
+	s.publish(21*time.Second, "150.2")
+	s.publish(22*time.Second, "150.1")
+	s.publish(23*time.Second, "150.3")
+
+	// Assert, the third price in a row is taken as the new level
+	assert.Equal(s.T(), []string{"150.1", "150.3"}, s.published())
+	assert.Len(s.T(), s.anomalies.events, 2)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_OneTickInFlatWindow() {
+	// Arrange, a quiet market with a standard deviation of 0.0009
+	s.cfg.AnomalyWindow = 120
+	s.cfg.AnomalyFlatlineAfter = 0
+	s.newService()
+	for i := 0; i < 119; i++ {
+		s.publish(time.Duration(i)*time.Second, "100.00")
+	}
+	s.publish(119*time.Second, "100.01")
+	s.prices.events = nil
+
+	// Act
+	s.publish(120*time.Second, "100.01")
+	s.publish(121*time.Second, "100.02")
+
+	// Assert
+	assert.Equal(s.T(), []string{"100.01", "100.02"}, s.published())
+	assert.Empty(s.T(), s.anomalies.events)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_TooFewPrices() {
+	// Act
+	for i, price := range []string{"100", "100.2", "150", "100"} {
+		s.publish(time.Duration(i)*time.Second, price)
+	}
+
+	// Assert
+	assert.Equal(s.T(), []string{"100", "100.2", "150", "100"}, s.published())
+	assert.Empty(s.T(), s.anomalies.events)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_Flatline() {
+	// Act
+	s.publish(0, "100")
+	s.publish(30*time.Second, "100")
+	s.publish(61*time.Second, "100")
+	s.publish(2*time.Minute, "100")
+	s.publish(3*time.Minute, "100.5")
+	s.publish(4*time.Minute, "100.5")
+
+	// Assert, reported once and not quarantined
+	assert.Len(s.T(), s.published(), 6)
+	s.Require().Len(s.anomalies.events, 2)
+	anomaly := s.anomalies.events[0]
+	assert.Equal(s.T(), models.AnomalyKindFlatline, anomaly.Kind)
+	assert.Equal(s.T(), 61.0, anomaly.Score)
+	assert.False(s.T(), anomaly.Quarantined)
+	assert.Equal(s.T(), "BTC/USDT stayed at 100 on binance for 1m1s", anomaly.Message)
+	assert.Equal(s.T(), "100.5", s.anomalies.events[1].Price.String())
+	assert.Equal(s.T(), 60.0, s.anomalies.events[1].Score)
+}
+
+func (s *AnomalyServiceTestSuite) TestPublish_PerSource() {
+	// Arrange
+	s.warm()
+
+	// Act, a source whose window is empty
+	s.service.Publish(models.PriceTick{Prices: []models.PriceDatum{
+		{Timestamp: s.now, Symbol: "BTC", Currency: "USDT", Source: "kraken", Price: decimal.NewFromInt(150)},
+		{Timestamp: s.now, Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(150)},
+	}})
+
+	// Assert
+	assert.Equal(s.T(), []string{"150"}, s.published())
+	s.Require().Len(s.anomalies.events, 1)
+	assert.Equal(s.T(), "binance", s.anomalies.events[0].Source)
+}
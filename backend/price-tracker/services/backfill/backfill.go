@@ -0,0 +1,14 @@
+package backfill
+
+import (
+	"context"
+	"time"
+)
+
+type Backfiller interface {
+	// Backfill fetches the history missing from the database up to the start of the service and stores it
+	Backfill(ctx context.Context) error
+	// BackfillRange fetches the 1m intervals of an exchange symbol opened in [start, end) and stores them,
+	// returns the number of intervals stored
+	BackfillRange(ctx context.Context, symbol string, start, end time.Time) (int, error)
+}
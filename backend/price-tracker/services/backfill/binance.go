@@ -0,0 +1,248 @@
+package backfill
+
+import (
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/symbols"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// binanceKlinesLimit is the maximum number of klines returned per request
+	binanceKlinesLimit = 1000
+	// binanceKlinesWeight is the request weight of /api/v3/klines
+	binanceKlinesWeight = 2
+	// binanceMaxRetries bounds the retries of a request rejected by the rate limit
+	binanceMaxRetries = 3
+
+	binanceUsedWeightHeader = "X-MBX-USED-WEIGHT-1M"
+)
+
+type BinanceBackfillerImpl struct {
+	baseURL    string
+	httpClient *http.Client
+	symbols    []string
+	lookback   time.Duration
+	maxWeight  int
+	registry   symbols.SymbolRegistry
+	repository repositories.Repository
+	// until is the start of the service, later prices come from the streams
+	until time.Time
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func NewBinanceBackfiller(
+	baseURL string,
+	backfillSymbols []string,
+	lookback time.Duration,
+	maxWeight int,
+	registry symbols.SymbolRegistry,
+	repository repositories.Repository,
+) Backfiller {
+	return &BinanceBackfillerImpl{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+		symbols:    backfillSymbols,
+		lookback:   lookback,
+		maxWeight:  maxWeight,
+		registry:   registry,
+		repository: repository,
+		until:      time.Now().UTC(),
+		now:        time.Now,
+		sleep:      sleep,
+	}
+}
+
+// Backfill fetches the 1m klines of every symbol since its latest stored price, within the lookback,
+// up to the start of the service and stores them as binance prices
+func (b *BinanceBackfillerImpl) Backfill(ctx context.Context) error {
+	if b.lookback <= 0 {
+		return nil
+	}
+
+	for _, symbol := range b.symbols {
+		pair, ok := b.registry.Lookup(symbol)
+		if !ok {
+			slog.Warn("backfill skips untracked symbol", "symbol", symbol)
+			continue
+		}
+
+		count, err := b.backfillSymbol(ctx, pair)
+		if err != nil {
+			return fmt.Errorf("backfill %s: %w", symbol, err)
+		}
+
+		slog.Info("backfill binance klines", "symbol", symbol, "count", count)
+	}
+
+	return nil
+}
+
+// backfillSymbol stores the klines of the whole minutes missing before b.until, returns the number of klines
+func (b *BinanceBackfillerImpl) backfillSymbol(ctx context.Context, pair models.SymbolPair) (int, error) {
+	start := b.until.Add(-b.lookback).Truncate(time.Minute)
+	end := b.until.Truncate(time.Minute)
+
+	latest, err := b.repository.GetLatestPrice(ctx, models.PriceDatum{
+		Timestamp: b.until,
+		Symbol:    pair.Base,
+		Currency:  pair.Quote,
+		Source:    constant.SourceBinance,
+	})
+	if err == nil && !latest.Timestamp.Before(start) {
+		// The minute of the latest price is already rolled up into candles
+		start = latest.Timestamp.UTC().Truncate(time.Minute).Add(time.Minute)
+	}
+
+	return b.backfillRange(ctx, pair, start, end)
+}
+
+// BackfillRange fetches the 1m klines of an exchange symbol opened in [start, end) and stores them,
+// returns the number of klines stored
+func (b *BinanceBackfillerImpl) BackfillRange(ctx context.Context, symbol string, start, end time.Time) (int, error) {
+	pair, ok := b.registry.Lookup(symbol)
+	if !ok {
+		return 0, fmt.Errorf("untracked symbol '%s'", symbol)
+	}
+
+	return b.backfillRange(ctx, pair, start.UTC(), end.UTC())
+}
+
+func (b *BinanceBackfillerImpl) backfillRange(ctx context.Context, pair models.SymbolPair, start, end time.Time) (int, error) {
+	count := 0
+	for start.Before(end) {
+		klines, err := b.fetchKlines(ctx, pair.Symbol, start, end)
+		if err != nil {
+			return count, err
+		}
+		if len(klines) == 0 {
+			break
+		}
+
+		var bulk []models.PriceDatum
+		for _, kline := range klines {
+			data, err := kline.ToPriceData(pair)
+			if err != nil {
+				return count, fmt.Errorf("convert kline: %w", err)
+			}
+			bulk = append(bulk, data...)
+		}
+
+		err = b.repository.BulkInsert(ctx, bulk)
+		if err != nil {
+			return count, fmt.Errorf("bulk insert: %w", err)
+		}
+
+		count += len(klines)
+		start = time.UnixMilli(klines[len(klines)-1].OpenTime).UTC().Add(time.Minute)
+	}
+
+	return count, nil
+}
+
+// fetchKlines returns the 1m klines opened in [start, end), waiting for the rate limit when needed
+func (b *BinanceBackfillerImpl) fetchKlines(ctx context.Context, symbol string, start, end time.Time) ([]models.BinanceKline, error) {
+	query := url.Values{}
+	query.Set("symbol", symbol)
+	query.Set("interval", "1m")
+	query.Set("startTime", strconv.FormatInt(start.UnixMilli(), 10))
+	query.Set("endTime", strconv.FormatInt(end.UnixMilli()-1, 10))
+	query.Set("limit", strconv.Itoa(binanceKlinesLimit))
+
+	for attempt := 0; ; attempt++ {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/api/v3/klines?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("new request: %w", err)
+		}
+
+		resp, err := b.httpClient.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("http request: %w", err)
+		}
+
+		klines, retryAfter, err := b.readKlines(ctx, resp)
+		if err == nil {
+			return klines, nil
+		}
+		if retryAfter == 0 || attempt >= binanceMaxRetries {
+			return nil, err
+		}
+
+		slog.Warn("backfill rate limited", "symbol", symbol, "retry_after", retryAfter)
+		err = b.sleep(ctx, retryAfter)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readKlines decodes the klines of a response, or returns how long to wait if the rate limit rejected it.
+// It waits for the next minute once the used weight would exceed b.maxWeight
+func (b *BinanceBackfillerImpl) readKlines(ctx context.Context, resp *http.Response) ([]models.BinanceKline, time.Duration, error) {
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return nil, retryAfter(resp), errors.New("http status 429, rate limited")
+	case http.StatusTeapot:
+		return nil, 0, errors.New("http status 418, ip banned")
+	default:
+		return nil, 0, fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	var klines []models.BinanceKline
+	err := json.NewDecoder(resp.Body).Decode(&klines)
+	if err != nil {
+		return nil, 0, fmt.Errorf("json decode: %w", err)
+	}
+
+	used, err := strconv.Atoi(resp.Header.Get(binanceUsedWeightHeader))
+	if err == nil && used+binanceKlinesWeight > b.maxWeight {
+		now := b.now()
+		wait := now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+
+		slog.Info("backfill waits for binance weight limit", "used_weight", used, "wait", wait)
+		err = b.sleep(ctx, wait)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return klines, 0, nil
+}
+
+// retryAfter returns the wait in seconds of the Retry-After header, a minute if it is missing
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return time.Minute
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// sleep waits for d, or returns the error of ctx if it is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,242 @@
+package backfill
+
+import (
+	"backend/internal/config"
+	"backend/internal/db"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/repositories/mock"
+	"backend/price-tracker/services/symbols"
+	"context"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	m "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// klinesServer serves at most pageSize 1m klines of the requested range, priced by their minute
+func klinesServer(t *testing.T, pageSize int, handler func(w http.ResponseWriter) bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/klines", r.URL.Path)
+		assert.Equal(t, "1m", r.URL.Query().Get("interval"))
+
+		if handler != nil && !handler(w) {
+			return
+		}
+
+		start, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("endTime"), 10, 64)
+
+		var klines []string
+		for open := start; open <= end && len(klines) < pageSize; open += time.Minute.Milliseconds() {
+			price := open / time.Minute.Milliseconds() % 1000
+			klines = append(klines, fmt.Sprintf(`[%d,"%d","%d","%d","%d","1.0",%d,"1.0",1,"1.0","1.0","0"]`,
+				open, price, price+1, price-1, price, open+time.Minute.Milliseconds()-1))
+		}
+
+		w.Write([]byte("[" + strings.Join(klines, ",") + "]"))
+	}))
+}
+
+func newTestBackfiller(baseURL string, repo *mock.MockRepository, until time.Time, slept *[]time.Duration) *BinanceBackfillerImpl {
+	return &BinanceBackfillerImpl{
+		baseURL:    baseURL,
+		httpClient: http.DefaultClient,
+		symbols:    []string{"BTCUSDT", "BTCTRY"},
+		lookback:   30 * time.Minute,
+		maxWeight:  1000,
+		registry:   symbols.NewBinanceSymbolRegistry("", []string{"USDT"}),
+		repository: repo,
+		until:      until,
+		now:        func() time.Time { return until.Add(15 * time.Second) },
+		sleep: func(ctx context.Context, d time.Duration) error {
+			*slept = append(*slept, d)
+			return nil
+		},
+	}
+}
+
+// insertedMinutes returns the distinct minutes of the prices bulk inserted by the backfill
+func insertedMinutes(repo *mock.MockRepository) []time.Time {
+	var minutes []time.Time
+	for _, call := range repo.Calls {
+		if call.Method != "BulkInsert" {
+			continue
+		}
+		for _, datum := range call.Arguments.Get(0).([]models.PriceDatum) {
+			minute := datum.Timestamp.Truncate(time.Minute)
+			if len(minutes) == 0 || !minutes[len(minutes)-1].Equal(minute) {
+				minutes = append(minutes, minute)
+			}
+		}
+	}
+	return minutes
+}
+
+func TestBinanceBackfillerImpl_Backfill(t *testing.T) {
+	until := time.Date(2025, 3, 1, 10, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		latest      *models.PriceDatum
+		wantMinutes int
+		wantFirst   time.Time
+	}{
+		{
+			name:        "empty database",
+			wantMinutes: 30,
+			wantFirst:   time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:        "resume after latest price",
+			latest:      &models.PriceDatum{Timestamp: time.Date(2025, 3, 1, 9, 50, 10, 0, time.UTC)},
+			wantMinutes: 9,
+			wantFirst:   time.Date(2025, 3, 1, 9, 51, 0, 0, time.UTC),
+		},
+		{
+			name:        "latest price before lookback",
+			latest:      &models.PriceDatum{Timestamp: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+			wantMinutes: 30,
+			wantFirst:   time.Date(2025, 3, 1, 9, 30, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := klinesServer(t, 7, nil)
+			defer server.Close()
+
+			repo := new(mock.MockRepository)
+			if tt.latest != nil {
+				repo.On("GetLatestPrice", m.Anything).Return(tt.latest, nil)
+			} else {
+				repo.On("GetLatestPrice", m.Anything).Return(nil, sqlite3.ErrNotFound)
+			}
+			repo.On("BulkInsert", m.Anything).Return(nil)
+
+			var slept []time.Duration
+			err := newTestBackfiller(server.URL, repo, until, &slept).Backfill(context.Background())
+
+			assert.NoError(t, err)
+			minutes := insertedMinutes(repo)
+			assert.Len(t, minutes, tt.wantMinutes)
+			assert.Equal(t, tt.wantFirst, minutes[0])
+			// The minute of the service start is left to the streams
+			assert.Equal(t, time.Date(2025, 3, 1, 9, 59, 0, 0, time.UTC), minutes[len(minutes)-1])
+			repo.AssertCalled(t, "GetLatestPrice", models.PriceDatum{
+				Timestamp: until,
+				Symbol:    "BTC",
+				Currency:  "USDT",
+				Source:    "binance",
+			})
+			assert.Empty(t, slept)
+		})
+	}
+}
+
+func TestBinanceBackfillerImpl_Backfill_RateLimit(t *testing.T) {
+	until := time.Date(2025, 3, 1, 10, 0, 30, 0, time.UTC)
+
+	var requests atomic.Int32
+	server := klinesServer(t, 1000, func(w http.ResponseWriter) bool {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return false
+		}
+
+		w.Header().Set(binanceUsedWeightHeader, "999")
+		return true
+	})
+	defer server.Close()
+
+	repo := new(mock.MockRepository)
+	repo.On("GetLatestPrice", m.Anything).Return(nil, sqlite3.ErrNotFound)
+	repo.On("BulkInsert", m.Anything).Return(nil)
+
+	var slept []time.Duration
+	err := newTestBackfiller(server.URL, repo, until, &slept).Backfill(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, insertedMinutes(repo), 30)
+	// Retry-After, then the rest of the minute once the weight limit is reached
+	assert.Equal(t, []time.Duration{2 * time.Second, 15 * time.Second}, slept)
+}
+
+func TestBinanceBackfillerImpl_Backfill_Banned(t *testing.T) {
+	server := klinesServer(t, 1000, func(w http.ResponseWriter) bool {
+		w.WriteHeader(http.StatusTeapot)
+		return false
+	})
+	defer server.Close()
+
+	repo := new(mock.MockRepository)
+	repo.On("GetLatestPrice", m.Anything).Return(nil, sqlite3.ErrNotFound)
+
+	var slept []time.Duration
+	err := newTestBackfiller(server.URL, repo, time.Now(), &slept).Backfill(context.Background())
+
+	assert.ErrorContains(t, err, "418")
+	repo.AssertNotCalled(t, "BulkInsert", m.Anything)
+}
+
+func TestBinanceBackfillerImpl_BackfillRange(t *testing.T) {
+	server := klinesServer(t, 1000, nil)
+	defer server.Close()
+
+	repo := new(mock.MockRepository)
+	repo.On("BulkInsert", m.Anything).Return(nil)
+
+	var slept []time.Duration
+	backfiller := newTestBackfiller(server.URL, repo, time.Now(), &slept)
+	start := time.Date(2025, 3, 1, 9, 11, 0, 0, time.UTC)
+
+	count, err := backfiller.BackfillRange(context.Background(), "BTCUSDT", start, start.Add(9*time.Minute))
+	_, errUntracked := backfiller.BackfillRange(context.Background(), "BTCTRY", start, start.Add(9*time.Minute))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9, count)
+	assert.Equal(t, start, insertedMinutes(repo)[0])
+	assert.ErrorContains(t, errUntracked, "untracked symbol 'BTCTRY'")
+}
+
+// The chart asks for the history without a source, it reads the backfilled binance prices
+func TestBinanceBackfillerImpl_Backfill_History(t *testing.T) {
+	// Arrange
+	until := time.Date(2025, 3, 1, 10, 0, 30, 0, time.UTC)
+	server := klinesServer(t, 1000, nil)
+	defer server.Close()
+
+	database, err := db.NewSqliteDB(config.SqliteConfig{SqliteURL: ":memory:"})
+	require.NoError(t, err)
+	defer database.Close()
+	repository := repositories.NewSqliteRepository(database)
+
+	var slept []time.Duration
+	backfiller := newTestBackfiller(server.URL, nil, until, &slept)
+	backfiller.repository = repository
+
+	// Act
+	err = backfiller.Backfill(context.Background())
+	history, errHistory := repository.GetPriceHistory(context.Background(), models.PriceHistoryRequest{
+		Symbol:   "BTC",
+		Currency: "USDT",
+		From:     until.Truncate(time.Hour).Add(-time.Hour),
+		To:       until,
+		Step:     time.Minute,
+	})
+
+	// Assert, a price for each of the 30 minutes backfilled
+	assert.NoError(t, err)
+	assert.NoError(t, errHistory)
+	assert.Len(t, history, 30)
+	assert.Equal(t, "binance", history[0].Source)
+}
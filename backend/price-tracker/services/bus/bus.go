@@ -0,0 +1,190 @@
+package bus
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dropLogInterval is how many dropped events of a subscriber are logged once
+const dropLogInterval = 1000
+
+// Publisher is the side of a bus the producers of events see
+type Publisher[T any] interface {
+	// Publish queues the event for every subscriber without blocking,
+	// a subscriber whose queue is full misses it
+	Publish(event T)
+}
+
+// StatsReporter returns the counters of the subscribers of a bus
+type StatsReporter interface {
+	Stats() []SubscriberStats
+}
+
+// Bus hands the events of type T published by producers to each of its subscribers
+type Bus[T any] interface {
+	Publisher[T]
+	StatsReporter
+	// Subscribe hands the events published from now on to handle, in order, from a goroutine of the subscriber.
+	// Up to queueSize events wait for handle, further ones are dropped
+	Subscribe(name string, queueSize int, handle func(T))
+	// SubscribeBatch is Subscribe handing the events in batches, each of at most maxSize events
+	// collected for at most maxWait after its first one
+	SubscribeBatch(name string, queueSize, maxSize int, maxWait time.Duration, handle func([]T))
+	// Close stops accepting events and returns once the subscribers handled those queued
+	Close()
+}
+
+// SubscriberStats are the counters of a subscriber of a bus
+type SubscriberStats struct {
+	Bus        string `json:"bus"`
+	Subscriber string `json:"subscriber"`
+	Queued     int    `json:"queued"`
+	Capacity   int    `json:"capacity"`
+	Delivered  int64  `json:"delivered"`
+	Dropped    int64  `json:"dropped"`
+}
+
+type BusImpl[T any] struct {
+	name        string
+	mu          sync.RWMutex
+	closed      bool
+	subscribers []*subscriber[T]
+	wg          sync.WaitGroup
+}
+
+type subscriber[T any] struct {
+	name      string
+	queue     chan T
+	delivered atomic.Int64
+	dropped   atomic.Int64
+}
+
+func New[T any](name string) Bus[T] {
+	return &BusImpl[T]{
+		name: name,
+	}
+}
+
+func (b *BusImpl[T]) Publish(event T) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return
+	}
+
+	for _, sub := range b.subscribers {
+		select {
+		case sub.queue <- event:
+		default:
+			dropped := sub.dropped.Add(1)
+			if dropped == 1 || dropped%dropLogInterval == 0 {
+				slog.Warn("event bus subscriber is lagging, events dropped", "bus", b.name, "subscriber", sub.name, "dropped", dropped)
+			}
+		}
+	}
+}
+
+func (b *BusImpl[T]) Subscribe(name string, queueSize int, handle func(T)) {
+	b.subscribe(name, queueSize, func(sub *subscriber[T]) {
+		for event := range sub.queue {
+			handle(event)
+			sub.delivered.Add(1)
+		}
+	})
+}
+
+func (b *BusImpl[T]) SubscribeBatch(name string, queueSize, maxSize int, maxWait time.Duration, handle func([]T)) {
+	b.subscribe(name, queueSize, func(sub *subscriber[T]) {
+		for {
+			batch, ok := collectBatch(sub.queue, maxSize, maxWait)
+			if len(batch) > 0 {
+				handle(batch)
+				sub.delivered.Add(int64(len(batch)))
+			}
+			if !ok {
+				return
+			}
+		}
+	})
+}
+
+// subscribe adds a subscriber whose events are handled by run from its own goroutine, until its queue is closed
+func (b *BusImpl[T]) subscribe(name string, queueSize int, run func(sub *subscriber[T])) {
+	sub := &subscriber[T]{
+		name:  name,
+		queue: make(chan T, queueSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers = append(b.subscribers, sub)
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		run(sub)
+	}()
+}
+
+// collectBatch waits for an event, then collects the following ones until the batch is full or maxWait elapsed.
+// It returns false once the queue is closed, with the events collected before
+func collectBatch[T any](queue <-chan T, maxSize int, maxWait time.Duration) ([]T, bool) {
+	event, ok := <-queue
+	if !ok {
+		return nil, false
+	}
+
+	batch := []T{event}
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	for len(batch) < maxSize {
+		select {
+		case event, ok := <-queue:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, event)
+		case <-timer.C:
+			return batch, true
+		}
+	}
+
+	return batch, true
+}
+
+func (b *BusImpl[T]) Stats() []SubscriberStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make([]SubscriberStats, 0, len(b.subscribers))
+	for _, sub := range b.subscribers {
+		stats = append(stats, SubscriberStats{
+			Bus:        b.name,
+			Subscriber: sub.name,
+			Queued:     len(sub.queue),
+			Capacity:   cap(sub.queue),
+			Delivered:  sub.delivered.Load(),
+			Dropped:    sub.dropped.Load(),
+		})
+	}
+
+	return stats
+}
+
+func (b *BusImpl[T]) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, sub := range b.subscribers {
+			close(sub.queue)
+		}
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+}
@@ -0,0 +1,104 @@
+package bus
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusImpl_Publish(t *testing.T) {
+	// Arrange
+	b := New[int]("test")
+
+	var fast []int
+	b.Subscribe("fast", 10, func(event int) {
+		fast = append(fast, event)
+	})
+
+	// The slow subscriber blocks on its first event until released
+	release := make(chan struct{})
+	var slow []int
+	var once sync.Once
+	received := make(chan struct{})
+	b.Subscribe("slow", 1, func(event int) {
+		once.Do(func() {
+			close(received)
+			<-release
+		})
+		slow = append(slow, event)
+	})
+
+	// Act
+	b.Publish(1)
+	<-received
+	for event := 2; event <= 5; event++ {
+		b.Publish(event)
+	}
+	close(release)
+	b.Close()
+	b.Publish(6)
+
+	// Assert
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, fast)
+	// The slow subscriber was handling 1 with room for a single queued event
+	assert.Equal(t, []int{1, 2}, slow)
+	assert.Equal(t, []SubscriberStats{
+		{Bus: "test", Subscriber: "fast", Capacity: 10, Delivered: 5},
+		{Bus: "test", Subscriber: "slow", Capacity: 1, Delivered: 2, Dropped: 3},
+	}, b.Stats())
+}
+
+func TestBusImpl_SubscribeBatch(t *testing.T) {
+	// Arrange
+	b := New[int]("test")
+
+	batches := make(chan []int, 10)
+	b.SubscribeBatch("batch", 10, 3, time.Hour, func(events []int) {
+		batches <- events
+	})
+
+	// Act
+	for event := 1; event <= 7; event++ {
+		b.Publish(event)
+	}
+	full := [][]int{<-batches, <-batches}
+	// Closing hands the last batch without waiting for it to fill up
+	b.Close()
+	close(batches)
+
+	// Assert
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}}, full)
+	var rest [][]int
+	for batch := range batches {
+		rest = append(rest, batch)
+	}
+	assert.Equal(t, [][]int{{7}}, rest)
+	assert.Equal(t, []SubscriberStats{
+		{Bus: "test", Subscriber: "batch", Capacity: 10, Delivered: 7},
+	}, b.Stats())
+}
+
+func TestBusImpl_SubscribeBatch_MaxWait(t *testing.T) {
+	// Arrange
+	b := New[int]("test")
+	defer b.Close()
+
+	batches := make(chan []int, 10)
+	b.SubscribeBatch("batch", 10, 100, 10*time.Millisecond, func(events []int) {
+		batches <- events
+	})
+
+	// Act
+	b.Publish(1)
+	b.Publish(2)
+
+	// Assert
+	select {
+	case batch := <-batches:
+		assert.Equal(t, []int{1, 2}, batch)
+	case <-time.After(time.Second):
+		t.Fatal("batch not handed after max wait")
+	}
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"backend/price-tracker/models"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+)
+
+const (
+	// maxConversionLegs bounds the number of markets chained in a conversion
+	maxConversionLegs = 3
+	// inverseRatePrecision is the number of decimal places of the rates of inverted markets
+	inverseRatePrecision = 18
+)
+
+var ErrNoConversionPath = errors.New("no conversion path")
+
+type ConversionService interface {
+	// Convert returns the amount converted between two assets using the latest prices,
+	// triangulating through other assets when there is no direct market
+	Convert(req models.ConversionRequest) (*models.Conversion, error)
+}
+
+type ConversionServiceImpl struct {
+	cache *sync.Map
+}
+
+func NewConversionService(cache *sync.Map) ConversionService {
+	return &ConversionServiceImpl{
+		cache: cache,
+	}
+}
+
+// Convert returns the amount converted between two assets using the latest prices,
+// triangulating through other assets when there is no direct market
+func (c *ConversionServiceImpl) Convert(req models.ConversionRequest) (*models.Conversion, error) {
+	res := &models.Conversion{
+		From:   req.From,
+		To:     req.To,
+		Amount: req.Amount,
+		Rate:   decimal.NewFromInt(1),
+		Path:   []string{req.From},
+		Legs:   []models.ConversionLeg{},
+	}
+
+	if req.From != req.To {
+		legs, err := c.findPath(req.From, req.To)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, leg := range legs {
+			res.Rate = res.Rate.Mul(leg.Rate)
+			res.Path = append(res.Path, leg.To)
+			if res.Timestamp.IsZero() || leg.Timestamp.Before(res.Timestamp) {
+				res.Timestamp = leg.Timestamp
+			}
+		}
+		res.Legs = legs
+	}
+
+	res.Result = req.Amount.Mul(res.Rate)
+
+	return res, nil
+}
+
+// findPath searches the shortest chain of markets between two assets in the latest prices
+func (c *ConversionServiceImpl) findPath(from, to string) ([]models.ConversionLeg, error) {
+	graph := c.buildGraph()
+
+	previous := map[string]models.ConversionLeg{}
+	visited := map[string]bool{from: true}
+	frontier := []string{from}
+
+	for depth := 0; depth < maxConversionLegs && len(frontier) > 0; depth++ {
+		var next []string
+		for _, asset := range frontier {
+			for _, leg := range graph[asset] {
+				if visited[leg.To] {
+					continue
+				}
+
+				visited[leg.To] = true
+				previous[leg.To] = leg
+				next = append(next, leg.To)
+			}
+		}
+
+		if visited[to] {
+			var legs []models.ConversionLeg
+			for asset := to; asset != from; asset = previous[asset].From {
+				legs = append([]models.ConversionLeg{previous[asset]}, legs...)
+			}
+
+			return legs, nil
+		}
+
+		frontier = next
+	}
+
+	return nil, fmt.Errorf("%w from '%s' to '%s'", ErrNoConversionPath, from, to)
+}
+
+// buildGraph returns the legs available from each asset, every market can be traversed both ways
+func (c *ConversionServiceImpl) buildGraph() map[string][]models.ConversionLeg {
+	graph := make(map[string][]models.ConversionLeg)
+
+	c.cache.Range(func(key, value any) bool {
+		datum, ok := value.(models.PriceDatum)
+		if !ok || !datum.Price.IsPositive() {
+			return true
+		}
+
+		// Use only the latest datum of any source for each market
+		if key != models.CacheKey("", datum.Symbol, datum.Currency) {
+			return true
+		}
+
+		market := datum.Symbol + "/" + datum.Currency
+		graph[datum.Symbol] = append(graph[datum.Symbol], models.ConversionLeg{
+			From:      datum.Symbol,
+			To:        datum.Currency,
+			Market:    market,
+			Source:    datum.Source,
+			Rate:      datum.Price,
+			Timestamp: datum.Timestamp,
+		})
+		graph[datum.Currency] = append(graph[datum.Currency], models.ConversionLeg{
+			From:      datum.Currency,
+			To:        datum.Symbol,
+			Market:    market,
+			Source:    datum.Source,
+			Inverted:  true,
+			Rate:      decimal.NewFromInt(1).DivRound(datum.Price, inverseRatePrecision),
+			Timestamp: datum.Timestamp,
+		})
+
+		return true
+	})
+
+	// Make the search deterministic, sorted by target asset and preferring non inverted markets
+	for asset := range graph {
+		sort.Slice(graph[asset], func(i, j int) bool {
+			a, b := graph[asset][i], graph[asset][j]
+			if a.To != b.To {
+				return a.To < b.To
+			}
+			return !a.Inverted && b.Inverted
+		})
+	}
+
+	return graph
+}
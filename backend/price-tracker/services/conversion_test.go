@@ -0,0 +1,84 @@
+package services
+
+import (
+	"backend/price-tracker/models"
+	"github.com/shopspring/decimal"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversionServiceImpl_Convert(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	cache := &sync.Map{}
+	for _, datum := range []models.PriceDatum{
+		{Timestamp: now, Symbol: "ETH", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("2000")},
+		{Timestamp: now.Add(-time.Minute), Symbol: "EUR", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("1.25")},
+		{Timestamp: now, Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("50000")},
+		{Timestamp: now, Symbol: "ETH", Currency: "BTC", Source: "binance", Price: decimal.RequireFromString("0.04")},
+	} {
+		cache.Store(models.CacheKey("", datum.Symbol, datum.Currency), datum)
+		cache.Store(models.CacheKey(datum.Source, datum.Symbol, datum.Currency), datum)
+	}
+
+	tests := []struct {
+		name       string
+		req        models.ConversionRequest
+		wantResult string
+		wantPath   []string
+		wantTime   time.Time
+		wantErr    error
+	}{
+		{
+			name:       "direct market",
+			req:        models.ConversionRequest{From: "ETH", To: "USDT", Amount: decimal.RequireFromString("2.5")},
+			wantResult: "5000",
+			wantPath:   []string{"ETH", "USDT"},
+			wantTime:   now,
+		},
+		{
+			name:       "inverted market",
+			req:        models.ConversionRequest{From: "USDT", To: "BTC", Amount: decimal.RequireFromString("100000")},
+			wantResult: "2",
+			wantPath:   []string{"USDT", "BTC"},
+			wantTime:   now,
+		},
+		{
+			name:       "triangulated",
+			req:        models.ConversionRequest{From: "ETH", To: "EUR", Amount: decimal.RequireFromString("2.5")},
+			wantResult: "4000",
+			wantPath:   []string{"ETH", "USDT", "EUR"},
+			wantTime:   now.Add(-time.Minute),
+		},
+		{
+			name:       "same asset",
+			req:        models.ConversionRequest{From: "ETH", To: "ETH", Amount: decimal.RequireFromString("3")},
+			wantResult: "3",
+			wantPath:   []string{"ETH"},
+		},
+		{
+			name:    "no path",
+			req:     models.ConversionRequest{From: "ETH", To: "JPY", Amount: decimal.RequireFromString("1")},
+			wantErr: ErrNoConversionPath,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewConversionService(cache)
+
+			got, err := c.Convert(tt.req)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+			assert.Equal(t, tt.wantResult, got.Result.String())
+			assert.Equal(t, tt.wantPath, got.Path)
+			assert.Equal(t, tt.wantTime, got.Timestamp)
+			assert.Len(t, got.Legs, len(tt.wantPath)-1)
+		})
+	}
+}
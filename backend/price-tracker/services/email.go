@@ -0,0 +1,292 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/mail"
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates/alert-email.txt templates/alert-email.html
+var emailTemplates embed.FS
+
+var (
+	alertEmailText = texttemplate.Must(texttemplate.ParseFS(emailTemplates, "templates/alert-email.txt"))
+	alertEmailHTML = htmltemplate.Must(htmltemplate.ParseFS(emailTemplates, "templates/alert-email.html"))
+)
+
+type EmailService interface {
+	// Add collects an alert into the next email to the address of its rule, if it has one
+	Add(event models.AlertEvent)
+	// SendDue sends the emails whose digest window elapsed, within the rate limit of their recipient
+	SendDue(ctx context.Context) error
+	// Flush sends every alert collected without waiting for the end of its digest window
+	Flush(ctx context.Context) error
+}
+
+type EmailServiceImpl struct {
+	sender mail.Sender
+	cfg    config.EmailConfig
+	now    func() time.Time
+
+	mu sync.Mutex
+	// digests are the alerts collected for each recipient
+	digests map[string]*emailDigest
+	// sent are the times of the emails sent to each recipient within the rate period
+	sent map[string][]time.Time
+}
+
+// emailDigest is the next email to a recipient
+type emailDigest struct {
+	alerts []models.AlertEvent
+	// omitted counts the alerts over the maximum of an email, not listed
+	omitted int
+	dueAt   time.Time
+	// attempts counts the failed attempts to send it
+	attempts int
+}
+
+func NewEmailService(sender mail.Sender, cfg config.EmailConfig) EmailService {
+	return &EmailServiceImpl{
+		sender:  sender,
+		cfg:     cfg,
+		now:     time.Now,
+		digests: make(map[string]*emailDigest),
+		sent:    make(map[string][]time.Time),
+	}
+}
+
+// Add collects an alert into the digest of its recipient, due a digest window after its first alert
+func (e *EmailServiceImpl) Add(event models.AlertEvent) {
+	if event.Email == "" {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	digest, ok := e.digests[event.Email]
+	if !ok {
+		digest = &emailDigest{dueAt: e.now().Add(e.cfg.EmailDigestWindow)}
+		e.digests[event.Email] = digest
+	}
+
+	if len(digest.alerts) < e.cfg.EmailDigestMaxAlerts {
+		digest.alerts = append(digest.alerts, event)
+	} else {
+		digest.omitted++
+	}
+}
+
+// SendDue sends the digests that are due or full, those of a recipient over its rate limit being held
+// until a slot is free. A digest that failed is sent again after the retry delay, up to the maximum attempts
+// unless the server rejected it permanently
+func (e *EmailServiceImpl) SendDue(ctx context.Context) error {
+	return e.send(ctx, false)
+}
+
+// Flush sends every digest within the rate limit, the others are dropped and logged
+func (e *EmailServiceImpl) Flush(ctx context.Context) error {
+	err := e.send(ctx, true)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for recipient, digest := range e.digests {
+		slog.Warn("email alerts dropped", "to", recipient, "alerts", len(digest.alerts)+digest.omitted)
+	}
+	e.digests = make(map[string]*emailDigest)
+
+	return err
+}
+
+func (e *EmailServiceImpl) send(ctx context.Context, all bool) error {
+	now := e.now()
+
+	e.mu.Lock()
+	var recipients []string
+	batch := make(map[string]*emailDigest)
+	for recipient, digest := range e.digests {
+		full := len(digest.alerts) >= e.cfg.EmailDigestMaxAlerts
+		if (!all && !full && now.Before(digest.dueAt)) || !e.allowed(recipient, now) {
+			continue
+		}
+
+		recipients = append(recipients, recipient)
+		batch[recipient] = digest
+		delete(e.digests, recipient)
+	}
+	e.mu.Unlock()
+
+	sort.Strings(recipients)
+
+	var errs []error
+	for i, recipient := range recipients {
+		digest := batch[recipient]
+		err := e.sendDigest(ctx, recipient, digest)
+
+		e.mu.Lock()
+		if err == nil {
+			e.sent[recipient] = append(e.sent[recipient], now)
+		} else if ctx.Err() != nil {
+			// Cut by ctx, the digests not attempted are kept as they are
+			e.requeue(recipient, digest)
+		} else {
+			errs = append(errs, fmt.Errorf("send to %s: %w", recipient, err))
+			digest.attempts++
+			if mail.IsPermanent(err) || digest.attempts >= e.cfg.EmailMaxAttempts {
+				slog.Error("email alerts dropped", "to", recipient, "alerts", len(digest.alerts)+digest.omitted,
+					"attempts", digest.attempts, "err", err)
+			} else {
+				digest.dueAt = now.Add(e.cfg.EmailRetryDelay)
+				e.requeue(recipient, digest)
+			}
+		}
+		e.mu.Unlock()
+
+		if ctx.Err() != nil {
+			e.mu.Lock()
+			for _, rest := range recipients[i+1:] {
+				e.requeue(rest, batch[rest])
+			}
+			e.mu.Unlock()
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// allowed returns true if the recipient can be sent an email at the time, it forgets the emails sent
+// before the rate period. It must be called with the lock held
+func (e *EmailServiceImpl) allowed(recipient string, now time.Time) bool {
+	sent := e.sent[recipient]
+	for len(sent) > 0 && !sent[0].After(now.Add(-e.cfg.EmailRatePeriod)) {
+		sent = sent[1:]
+	}
+	if len(sent) == 0 {
+		delete(e.sent, recipient)
+	} else {
+		e.sent[recipient] = sent
+	}
+
+	return e.cfg.EmailRateLimit <= 0 || len(sent) < e.cfg.EmailRateLimit
+}
+
+// requeue puts a digest that was not sent back before the alerts collected since.
+// It must be called with the lock held
+func (e *EmailServiceImpl) requeue(recipient string, digest *emailDigest) {
+	if newer, ok := e.digests[recipient]; ok {
+		for _, event := range newer.alerts {
+			if len(digest.alerts) < e.cfg.EmailDigestMaxAlerts {
+				digest.alerts = append(digest.alerts, event)
+			} else {
+				digest.omitted++
+			}
+		}
+		digest.omitted += newer.omitted
+	}
+
+	e.digests[recipient] = digest
+}
+
+func (e *EmailServiceImpl) sendDigest(ctx context.Context, recipient string, digest *emailDigest) error {
+	msg, err := e.render(recipient, digest)
+	if err != nil {
+		return err
+	}
+
+	return e.sender.Send(ctx, msg)
+}
+
+// alertEmail is the data of the email templates
+type alertEmail struct {
+	Count   int
+	Alerts  []alertEmailItem
+	Omitted int
+}
+
+type alertEmailItem struct {
+	Title     string
+	Message   string
+	Symbol    string
+	Currency  string
+	Source    string
+	Price     string
+	Reference string
+	Change    string
+	FiredAt   string
+	ChartURL  string
+}
+
+// render returns the email of a digest, with the subject of its alert if it has a single one
+func (e *EmailServiceImpl) render(recipient string, digest *emailDigest) (mail.Message, error) {
+	data := alertEmail{
+		Count:   len(digest.alerts) + digest.omitted,
+		Omitted: digest.omitted,
+	}
+	for _, event := range digest.alerts {
+		change := event.Change().StringFixed(2) + "%"
+		if event.Change().IsPositive() {
+			change = "+" + change
+		}
+
+		data.Alerts = append(data.Alerts, alertEmailItem{
+			Title:     event.Title(),
+			Message:   event.Message,
+			Symbol:    event.Symbol,
+			Currency:  event.Currency,
+			Source:    event.Source,
+			Price:     event.Price.String(),
+			Reference: event.Reference.String(),
+			Change:    change,
+			FiredAt:   event.FiredAt.UTC().Format("2006-01-02 15:04:05 UTC"),
+			ChartURL:  e.chartURL(event),
+		})
+	}
+
+	var text, html bytes.Buffer
+	err := alertEmailText.Execute(&text, data)
+	if err != nil {
+		return mail.Message{}, fmt.Errorf("render text: %w", err)
+	}
+	err = alertEmailHTML.Execute(&html, data)
+	if err != nil {
+		return mail.Message{}, fmt.Errorf("render html: %w", err)
+	}
+
+	subject := fmt.Sprintf("%d price alerts", data.Count)
+	if data.Count == 1 {
+		subject = "Price alert: " + data.Alerts[0].Title
+	}
+
+	return mail.Message{
+		From:    e.cfg.EmailFrom,
+		To:      []string{recipient},
+		Subject: subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+		Date:    e.now(),
+	}, nil
+}
+
+// chartURL returns the link to the chart of the market of an alert
+func (e *EmailServiceImpl) chartURL(event models.AlertEvent) string {
+	return strings.NewReplacer(
+		"{symbol}", url.QueryEscape(event.Symbol),
+		"{currency}", url.QueryEscape(event.Currency),
+		"{source}", url.QueryEscape(event.Source),
+	).Replace(e.cfg.EmailChartURL)
+}
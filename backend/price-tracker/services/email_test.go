@@ -0,0 +1,266 @@
+package services
+
+import (
+	"backend/internal/config"
+	smtp_test_setup "backend/internal/smtp-test-setup"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/mail"
+	"context"
+	"mime"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type EmailServiceTestSuite struct {
+	suite.Suite
+	server  *smtp_test_setup.SMTPTestServer
+	now     time.Time
+	service *EmailServiceImpl
+}
+
+func (s *EmailServiceTestSuite) SetupTest() {
+	server, err := smtp_test_setup.NewSMTPTestServer()
+	s.Require().NoError(err)
+	s.server = server
+
+	cfg := config.EmailConfig{
+		EmailSMTPHost:        server.Host(),
+		EmailSMTPPort:        server.Port(),
+		EmailTimeout:         5 * time.Second,
+		EmailFrom:            "Price Tracker <alerts@example.com>",
+		EmailDigestWindow:    time.Minute,
+		EmailDigestMaxAlerts: 3,
+		EmailRateLimit:       2,
+		EmailRatePeriod:      time.Hour,
+		EmailRetryDelay:      30 * time.Second,
+		EmailMaxAttempts:     3,
+		EmailChartURL:        "https://charts.example.com/{source}/{symbol}?currency={currency}",
+	}
+	s.now = time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.service = NewEmailService(mail.NewSender(cfg), cfg).(*EmailServiceImpl)
+	s.service.now = func() time.Time { return s.now }
+}
+
+func (s *EmailServiceTestSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func TestEmailServiceSuite(t *testing.T) {
+	suite.Run(t, new(EmailServiceTestSuite))
+}
+
+// alert returns an alert of BTC/USDT on binance, at the price, fired now for the address
+func (s *EmailServiceTestSuite) alert(email, price string) models.AlertEvent {
+	return models.AlertEvent{
+		RuleID:    1,
+		Name:      "breakout",
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "binance",
+		Kind:      models.AlertKindAbove,
+		Price:     decimal.RequireFromString(price),
+		Reference: decimal.NewFromInt(100),
+		Message:   "BTC/USDT rose above 100 at " + price + " on binance",
+		FiredAt:   s.now,
+		Email:     email,
+	}
+}
+
+// sent returns the emails the server accepted so far, by recipient
+func (s *EmailServiceTestSuite) sent() map[string]smtp_test_setup.ReceivedMessage {
+	res := make(map[string]smtp_test_setup.ReceivedMessage)
+	for {
+		select {
+		case msg := <-s.server.Messages():
+			res[msg.To[0]] = msg
+		default:
+			return res
+		}
+	}
+}
+
+// subject returns the decoded subject of an email
+func (s *EmailServiceTestSuite) subject(msg smtp_test_setup.ReceivedMessage) string {
+	header, err := msg.Header()
+	s.Require().NoError(err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	s.Require().NoError(err)
+	return subject
+}
+
+func (s *EmailServiceTestSuite) TestSendDue_Digest() {
+	// Arrange
+	s.service.Add(s.alert("ops@example.com", "100.5"))
+	s.service.Add(s.alert("ops@example.com", "101"))
+	s.service.Add(s.alert("trader@example.com", "99.5"))
+	s.service.Add(s.alert("", "102"))
+
+	// Act, before the end of the digest window
+	err := s.service.SendDue(context.Background())
+
+	// Assert
+	s.Require().NoError(err)
+	assert.Empty(s.T(), s.sent())
+
+	// Act
+	s.now = s.now.Add(time.Minute)
+	err = s.service.SendDue(context.Background())
+
+	// Assert
+	s.Require().NoError(err)
+	sent := s.sent()
+	s.Require().Len(sent, 2)
+	assert.Equal(s.T(), "2 price alerts", s.subject(sent["ops@example.com"]))
+	assert.Equal(s.T(), "Price alert: breakout", s.subject(sent["trader@example.com"]))
+	assert.Equal(s.T(), "alerts@example.com", sent["ops@example.com"].From)
+
+	bodies, err := sent["ops@example.com"].Bodies()
+	s.Require().NoError(err)
+	text, html := bodies["text/plain"], bodies["text/html"]
+	for _, body := range []string{text, html} {
+		assert.Contains(s.T(), body, "2 price alerts fired")
+		assert.Contains(s.T(), body, "BTC/USDT rose above 100 at 100.5 on binance")
+		assert.Contains(s.T(), body, "BTC/USDT on binance")
+		assert.Contains(s.T(), body, "101")
+		assert.Contains(s.T(), body, "2025-05-01 12:00:00 UTC")
+	}
+	assert.Contains(s.T(), text, "Change:  +0.50% from 100")
+	assert.Contains(s.T(), text, "Change:  +1.00% from 100")
+	assert.Contains(s.T(), html, "<td>&#43;1.00% from 100</td>")
+	assert.Contains(s.T(), text, "Chart:   https://charts.example.com/binance/BTC?currency=USDT")
+	assert.Contains(s.T(), html, `<a href="https://charts.example.com/binance/BTC?currency=USDT">View chart</a>`)
+
+	bodies, err = sent["trader@example.com"].Bodies()
+	s.Require().NoError(err)
+	assert.Contains(s.T(), bodies["text/plain"], "-0.50% from 100")
+	assert.Empty(s.T(), s.service.digests)
+}
+
+func (s *EmailServiceTestSuite) TestSendDue_FullDigestSentEarly() {
+	// Arrange
+	for _, price := range []string{"101", "102", "103", "104", "105"} {
+		s.service.Add(s.alert("ops@example.com", price))
+	}
+
+	// Act
+	err := s.service.SendDue(context.Background())
+
+	// Assert
+	s.Require().NoError(err)
+	sent := s.sent()
+	s.Require().Len(sent, 1)
+	assert.Equal(s.T(), "5 price alerts", s.subject(sent["ops@example.com"]))
+	bodies, err := sent["ops@example.com"].Bodies()
+	s.Require().NoError(err)
+	assert.Contains(s.T(), bodies["text/plain"], "at 103 on binance")
+	assert.NotContains(s.T(), bodies["text/plain"], "at 104 on binance")
+	assert.Contains(s.T(), bodies["text/plain"], "And 2 more alerts not listed here.")
+}
+
+func (s *EmailServiceTestSuite) TestSendDue_RateLimited() {
+	// Arrange, two emails sent within the hour
+	for i := 0; i < 2; i++ {
+		s.service.Add(s.alert("ops@example.com", "101"))
+		s.now = s.now.Add(time.Minute)
+		err := s.service.SendDue(context.Background())
+		s.Require().NoError(err)
+	}
+	s.Require().Len(s.server.Messages(), 2)
+	s.sent()
+
+	// Act, the next alerts are held until an hour after the first email
+	s.service.Add(s.alert("ops@example.com", "102"))
+	s.now = s.now.Add(time.Minute)
+	errHeld := s.service.SendDue(context.Background())
+	held := s.sent()
+	s.service.Add(s.alert("ops@example.com", "103"))
+	s.now = s.now.Add(58 * time.Minute)
+	errSent := s.service.SendDue(context.Background())
+	sent := s.sent()
+
+	// Assert
+	assert.NoError(s.T(), errHeld)
+	assert.Empty(s.T(), held)
+	assert.NoError(s.T(), errSent)
+	s.Require().Len(sent, 1)
+	assert.Equal(s.T(), "2 price alerts", s.subject(sent["ops@example.com"]))
+}
+
+func (s *EmailServiceTestSuite) TestSendDue_RetriedAfterFailure() {
+	// Arrange
+	s.server.Reject("451 try again later")
+	s.service.Add(s.alert("ops@example.com", "101"))
+	s.now = s.now.Add(time.Minute)
+
+	// Act
+	errFailed := s.service.SendDue(context.Background())
+	s.service.Add(s.alert("ops@example.com", "102"))
+	s.server.Reject("")
+	s.now = s.now.Add(10 * time.Second)
+	errEarly := s.service.SendDue(context.Background())
+	early := s.sent()
+	s.now = s.now.Add(20 * time.Second)
+	errRetried := s.service.SendDue(context.Background())
+	retried := s.sent()
+
+	// Assert
+	assert.ErrorContains(s.T(), errFailed, "send to ops@example.com")
+	assert.ErrorContains(s.T(), errFailed, "try again later")
+	assert.NoError(s.T(), errEarly)
+	assert.Empty(s.T(), early)
+	assert.NoError(s.T(), errRetried)
+	s.Require().Len(retried, 1)
+	// The failed alert is sent along with the one collected since
+	assert.Equal(s.T(), "2 price alerts", s.subject(retried["ops@example.com"]))
+}
+
+func (s *EmailServiceTestSuite) TestSendDue_DroppedAfterMaxAttempts() {
+	// Arrange
+	s.server.Reject("451 try again later")
+	s.service.Add(s.alert("ops@example.com", "101"))
+
+	// Act
+	var errs []error
+	for range 3 {
+		s.now = s.now.Add(time.Minute)
+		errs = append(errs, s.service.SendDue(context.Background()))
+	}
+
+	// Assert
+	for _, err := range errs {
+		assert.ErrorContains(s.T(), err, "try again later")
+	}
+	assert.Empty(s.T(), s.service.digests)
+}
+
+func (s *EmailServiceTestSuite) TestSendDue_DroppedOnPermanentFailure() {
+	// Arrange
+	s.server.Reject("550 mailbox unavailable")
+	s.service.Add(s.alert("ops@example.com", "101"))
+	s.service.Add(s.alert("dev@example.com", "101"))
+	s.now = s.now.Add(time.Minute)
+
+	// Act
+	err := s.service.SendDue(context.Background())
+
+	// Assert
+	assert.ErrorContains(s.T(), err, "mailbox unavailable")
+	assert.Empty(s.T(), s.service.digests)
+}
+
+func (s *EmailServiceTestSuite) TestFlush() {
+	// Arrange
+	s.service.Add(s.alert("ops@example.com", "101"))
+
+	// Act
+	err := s.service.Flush(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), s.sent(), 1)
+	assert.Empty(s.T(), s.service.digests)
+}
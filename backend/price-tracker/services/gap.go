@@ -0,0 +1,174 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/backfill"
+	"backend/price-tracker/services/symbols"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type GapService interface {
+	// DetectGaps scans every stored series for gaps and records them
+	DetectGaps(ctx context.Context) error
+	// RepairGaps fills the open gaps from the exchange history, those it has no history for are marked as failed
+	RepairGaps(ctx context.Context) error
+	// GetGaps returns the recorded gaps of a status, or of any status if empty
+	GetGaps(ctx context.Context, status string) ([]models.DataGap, error)
+}
+
+type GapServiceImpl struct {
+	repository repositories.Repository
+	backfiller backfill.Backfiller
+	registry   symbols.SymbolRegistry
+	cfg        config.PriceTrackerConfig
+	now        func() time.Time
+}
+
+func NewGapService(
+	repository repositories.Repository,
+	backfiller backfill.Backfiller,
+	registry symbols.SymbolRegistry,
+	cfg config.PriceTrackerConfig,
+) GapService {
+	return &GapServiceImpl{
+		repository: repository,
+		backfiller: backfiller,
+		registry:   registry,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// DetectGaps scans every series with prices stored over the lookback
+// for gaps longer than the threshold and records them
+func (g *GapServiceImpl) DetectGaps(ctx context.Context) error {
+	now := g.now().UTC()
+	from := now.Add(-g.cfg.GapLookback)
+
+	series, err := g.repository.GetPriceSeries(ctx, from)
+	if err != nil {
+		return fmt.Errorf("get price series: %w", err)
+	}
+
+	for _, s := range series {
+		key := models.CacheKey(s.Source, s.Symbol, s.Currency)
+
+		gaps, err := g.repository.FindGaps(ctx, models.GapScanRequest{
+			Symbol:      s.Symbol,
+			Currency:    s.Currency,
+			Source:      s.Source,
+			From:        from,
+			To:          now,
+			MinDuration: g.cfg.GapThreshold,
+		})
+		if err != nil {
+			return fmt.Errorf("find gaps of %s: %w", key, err)
+		}
+		if len(gaps) == 0 {
+			continue
+		}
+
+		for i := range gaps {
+			gaps[i].Status = models.DataGapStatusOpen
+			gaps[i].DetectedAt = now
+		}
+
+		err = g.repository.SaveGaps(ctx, gaps)
+		if err != nil {
+			return fmt.Errorf("save gaps of %s: %w", key, err)
+		}
+
+		slog.Info("detected price gaps", "series", key, "count", len(gaps))
+	}
+
+	return nil
+}
+
+// RepairGaps fills the whole minutes of the open gaps from the binance klines.
+// A gap failing or filling nothing GapMaxAttempts times is marked as failed, as is at once a gap of a series
+// with no klines or with no whole minute in it
+func (g *GapServiceImpl) RepairGaps(ctx context.Context) error {
+	gaps, err := g.repository.GetGaps(ctx, models.DataGapStatusOpen)
+	if err != nil {
+		return fmt.Errorf("get open gaps: %w", err)
+	}
+
+	var errs []error
+	for _, gap := range gaps {
+		start := gap.StartTime.UTC().Truncate(time.Minute).Add(time.Minute)
+		end := gap.EndTime.UTC().Truncate(time.Minute)
+
+		var unrepairable string
+		switch {
+		case !g.repairable(gap):
+			unrepairable = fmt.Sprintf("no %s history to repair %s/%s from", gap.Source, gap.Symbol, gap.Currency)
+		case !start.Before(end):
+			unrepairable = "no whole minute to repair from the klines"
+		}
+		if unrepairable != "" {
+			gap.Status = models.DataGapStatusFailed
+			gap.Error = unrepairable
+			slog.Warn("unrepairable price gap", "id", gap.ID, "err", gap.Error)
+
+			err = g.repository.UpdateGap(ctx, gap)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("update gap %d: %w", gap.ID, err))
+			}
+			continue
+		}
+
+		filled, err := g.backfiller.BackfillRange(ctx, gap.Symbol+gap.Currency, start, end)
+		if ctx.Err() != nil {
+			// Interrupted by the shutdown, the repair is not counted as an attempt
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		// The gap would not be found again once marked as repaired
+		if err == nil && filled == 0 {
+			err = errors.New("no klines in the gap")
+		}
+
+		gap.Attempts++
+		if err != nil {
+			gap.Error = err.Error()
+			if gap.Attempts >= g.cfg.GapMaxAttempts {
+				gap.Status = models.DataGapStatusFailed
+			}
+			slog.Warn("repair price gap", "id", gap.ID, "attempts", gap.Attempts, "err", err)
+		} else {
+			gap.Status = models.DataGapStatusRepaired
+			gap.Filled = int64(filled)
+			gap.Error = ""
+			gap.RepairedAt = g.now().UTC()
+			slog.Info("repaired price gap", "id", gap.ID, "filled", filled)
+		}
+
+		err = g.repository.UpdateGap(ctx, gap)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("update gap %d: %w", gap.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetGaps returns the recorded gaps of a status, or of any status if empty
+func (g *GapServiceImpl) GetGaps(ctx context.Context, status string) ([]models.DataGap, error) {
+	return g.repository.GetGaps(ctx, status)
+}
+
+// repairable returns true if the gap is in a binance series of a market the klines can be fetched for
+func (g *GapServiceImpl) repairable(gap models.DataGap) bool {
+	if gap.Source != constant.SourceBinance {
+		return false
+	}
+
+	_, ok := g.registry.Lookup(gap.Symbol + gap.Currency)
+	return ok
+}
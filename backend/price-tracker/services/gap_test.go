@@ -0,0 +1,219 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories/mock"
+	"backend/price-tracker/services/symbols"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	m "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// MockBackfiller implements backfill.Backfiller
+type MockBackfiller struct {
+	m.Mock
+}
+
+func (b *MockBackfiller) Backfill(ctx context.Context) error {
+	args := b.Called()
+	return args.Error(0)
+}
+
+func (b *MockBackfiller) BackfillRange(ctx context.Context, symbol string, start, end time.Time) (int, error) {
+	args := b.Called(symbol, start, end)
+	return args.Int(0), args.Error(1)
+}
+
+type GapServiceTestSuite struct {
+	suite.Suite
+	mockRepo       *mock.MockRepository
+	mockBackfiller *MockBackfiller
+	now            time.Time
+	service        *GapServiceImpl
+}
+
+func (s *GapServiceTestSuite) SetupTest() {
+	s.mockRepo = new(mock.MockRepository)
+	s.mockBackfiller = new(MockBackfiller)
+	s.now = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.service = &GapServiceImpl{
+		repository: s.mockRepo,
+		backfiller: s.mockBackfiller,
+		registry:   symbols.NewBinanceSymbolRegistry("", []string{"USDT"}),
+		cfg: config.PriceTrackerConfig{
+			GapThreshold:   2 * time.Minute,
+			GapLookback:    time.Hour,
+			GapMaxAttempts: 2,
+		},
+		now: func() time.Time { return s.now },
+	}
+}
+
+func TestGapServiceSuite(t *testing.T) {
+	suite.Run(t, new(GapServiceTestSuite))
+}
+
+func (s *GapServiceTestSuite) TestDetectGaps() {
+	// Arrange
+	s.mockRepo.On("GetPriceSeries", s.now.Add(-time.Hour)).Return([]models.PriceDatum{
+		{Symbol: "BTC", Currency: "USDT", Source: "binance"},
+		{Symbol: "ETH", Currency: "EUR", Source: "kraken"},
+	}, nil)
+	s.mockRepo.On("FindGaps", models.GapScanRequest{
+		Symbol:      "ETH",
+		Currency:    "EUR",
+		Source:      "kraken",
+		From:        s.now.Add(-time.Hour),
+		To:          s.now,
+		MinDuration: 2 * time.Minute,
+	}).Return([]models.DataGap{}, nil)
+
+	found := models.DataGap{
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "binance",
+		StartTime: s.now.Add(-30 * time.Minute),
+		EndTime:   s.now.Add(-20 * time.Minute),
+	}
+	s.mockRepo.On("FindGaps", models.GapScanRequest{
+		Symbol:      "BTC",
+		Currency:    "USDT",
+		Source:      "binance",
+		From:        s.now.Add(-time.Hour),
+		To:          s.now,
+		MinDuration: 2 * time.Minute,
+	}).Return([]models.DataGap{found}, nil)
+
+	saved := found
+	saved.Status = models.DataGapStatusOpen
+	saved.DetectedAt = s.now
+	s.mockRepo.On("SaveGaps", []models.DataGap{saved}).Return(nil)
+
+	// Act
+	err := s.service.DetectGaps(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockRepo.AssertNumberOfCalls(s.T(), "SaveGaps", 1)
+}
+
+func (s *GapServiceTestSuite) TestRepairGaps() {
+	// Arrange
+	repaired := models.DataGap{
+		ID:        1,
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "binance",
+		StartTime: time.Date(2025, 3, 1, 9, 10, 30, 0, time.UTC),
+		EndTime:   time.Date(2025, 3, 1, 9, 20, 15, 0, time.UTC),
+		Status:    models.DataGapStatusOpen,
+	}
+	failing := repaired
+	failing.ID = 2
+	failing.Symbol = "ETH"
+	failing.Attempts = 1
+	s.mockRepo.On("GetGaps", models.DataGapStatusOpen).Return([]models.DataGap{repaired, failing}, nil)
+
+	s.mockBackfiller.On("BackfillRange", "BTCUSDT",
+		time.Date(2025, 3, 1, 9, 11, 0, 0, time.UTC), time.Date(2025, 3, 1, 9, 20, 0, 0, time.UTC),
+	).Return(9, nil)
+	s.mockBackfiller.On("BackfillRange", "ETHUSDT", m.Anything, m.Anything).Return(0, errors.New("http status 500"))
+
+	s.mockRepo.On("UpdateGap", m.MatchedBy(func(gap models.DataGap) bool {
+		return gap.ID == 1 && gap.Status == models.DataGapStatusRepaired && gap.Filled == 9 &&
+			gap.Attempts == 1 && gap.RepairedAt.Equal(s.now)
+	})).Return(nil)
+	s.mockRepo.On("UpdateGap", m.MatchedBy(func(gap models.DataGap) bool {
+		return gap.ID == 2 && gap.Status == models.DataGapStatusFailed && gap.Attempts == 2 &&
+			gap.Error == "http status 500"
+	})).Return(nil)
+
+	// Act
+	err := s.service.RepairGaps(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockBackfiller.AssertExpectations(s.T())
+}
+
+func (s *GapServiceTestSuite) TestRepairGaps_Unrepairable() {
+	// Arrange
+	gap := models.DataGap{
+		ID:        1,
+		Symbol:    "ETH",
+		Currency:  "EUR",
+		Source:    "kraken",
+		StartTime: time.Date(2025, 3, 1, 9, 10, 30, 0, time.UTC),
+		EndTime:   time.Date(2025, 3, 1, 9, 20, 15, 0, time.UTC),
+		Status:    models.DataGapStatusOpen,
+	}
+	// BTCTRY is not quoted in a tracked quote asset
+	untracked := gap
+	untracked.ID = 2
+	untracked.Symbol = "BTC"
+	untracked.Currency = "TRY"
+	untracked.Source = "binance"
+	short := gap
+	short.ID = 3
+	short.Source = "binance"
+	short.Currency = "USDT"
+	short.StartTime = time.Date(2025, 3, 1, 9, 10, 30, 0, time.UTC)
+	short.EndTime = time.Date(2025, 3, 1, 9, 11, 45, 0, time.UTC)
+	s.mockRepo.On("GetGaps", models.DataGapStatusOpen).Return([]models.DataGap{gap, untracked, short}, nil)
+
+	s.mockRepo.On("UpdateGap", m.MatchedBy(func(gap models.DataGap) bool {
+		return gap.ID == 1 && gap.Status == models.DataGapStatusFailed && gap.Attempts == 0 &&
+			gap.Error == "no kraken history to repair ETH/EUR from"
+	})).Return(nil)
+	s.mockRepo.On("UpdateGap", m.MatchedBy(func(gap models.DataGap) bool {
+		return gap.ID == 2 && gap.Status == models.DataGapStatusFailed &&
+			gap.Error == "no binance history to repair BTC/TRY from"
+	})).Return(nil)
+	s.mockRepo.On("UpdateGap", m.MatchedBy(func(gap models.DataGap) bool {
+		return gap.ID == 3 && gap.Status == models.DataGapStatusFailed && gap.Attempts == 0 &&
+			gap.Error == "no whole minute to repair from the klines"
+	})).Return(nil)
+
+	// Act
+	err := s.service.RepairGaps(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockBackfiller.AssertNotCalled(s.T(), "BackfillRange", m.Anything, m.Anything, m.Anything)
+}
+
+func (s *GapServiceTestSuite) TestRepairGaps_NothingFilled() {
+	// Arrange
+	gap := models.DataGap{
+		ID:        1,
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "binance",
+		StartTime: time.Date(2025, 3, 1, 9, 10, 30, 0, time.UTC),
+		EndTime:   time.Date(2025, 3, 1, 9, 20, 15, 0, time.UTC),
+		Status:    models.DataGapStatusOpen,
+		Attempts:  1,
+	}
+	s.mockRepo.On("GetGaps", models.DataGapStatusOpen).Return([]models.DataGap{gap}, nil)
+	s.mockBackfiller.On("BackfillRange", "BTCUSDT", m.Anything, m.Anything).Return(0, nil)
+	s.mockRepo.On("UpdateGap", m.MatchedBy(func(gap models.DataGap) bool {
+		return gap.ID == 1 && gap.Status == models.DataGapStatusFailed && gap.Attempts == 2 &&
+			gap.Filled == 0 && gap.Error == "no klines in the gap"
+	})).Return(nil)
+
+	// Act
+	err := s.service.RepairGaps(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.mockRepo.AssertExpectations(s.T())
+}
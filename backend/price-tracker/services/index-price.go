@@ -0,0 +1,182 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/bus"
+	"context"
+	"github.com/shopspring/decimal"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type IndexPriceService interface {
+	// UpdateIndexPrices computes the index price of every symbol quoted by the sources,
+	// then publishes them as a tick of their own series
+	UpdateIndexPrices(ctx context.Context) error
+}
+
+type IndexPriceServiceImpl struct {
+	cache  *sync.Map
+	prices bus.Publisher[models.PriceTick]
+	cfg    config.IndexPriceConfig
+	now    func() time.Time
+}
+
+// NewIndexPriceService returns the service computing the index from the cached quotes,
+// its ticks are published to prices like those of the exchanges
+func NewIndexPriceService(
+	cache *sync.Map,
+	prices bus.Publisher[models.PriceTick],
+	cfg config.IndexPriceConfig,
+) IndexPriceService {
+	return &IndexPriceServiceImpl{
+		cache:  cache,
+		prices: prices,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// UpdateIndexPrices computes the index price of every symbol quoted by the sources,
+// then publishes them as a tick of their own series
+func (i *IndexPriceServiceImpl) UpdateIndexPrices(ctx context.Context) error {
+	now := i.now().UTC()
+
+	var prices []models.PriceDatum
+	for _, quotes := range i.latestQuotesBySymbol() {
+		datum, ok := i.computeIndexPrice(quotes, now)
+		if !ok {
+			continue
+		}
+
+		prices = append(prices, datum)
+	}
+
+	if len(prices) == 0 {
+		return nil
+	}
+
+	i.prices.Publish(models.PriceTick{Prices: prices, ReceivedAt: now})
+
+	return nil
+}
+
+// latestQuotesBySymbol groups the cached latest datum of every exchange by symbol and currency
+func (i *IndexPriceServiceImpl) latestQuotesBySymbol() map[string][]models.PriceDatum {
+	quotes := make(map[string][]models.PriceDatum)
+
+	i.cache.Range(func(key, value any) bool {
+		datum, ok := value.(models.PriceDatum)
+		if !ok || datum.Source == "" || datum.Source == constant.SourceIndex {
+			return true
+		}
+
+		// Skip entries cached as the latest datum of any source
+		if key != models.CacheKey(datum.Source, datum.Symbol, datum.Currency) {
+			return true
+		}
+
+		group := datum.Symbol + "/" + datum.Currency
+		quotes[group] = append(quotes[group], datum)
+
+		return true
+	})
+
+	return quotes
+}
+
+// computeIndexPrice returns the weighted median of the quotes that are neither stale
+// nor too far from the median of all fresh quotes
+func (i *IndexPriceServiceImpl) computeIndexPrice(quotes []models.PriceDatum, now time.Time) (models.PriceDatum, bool) {
+	var fresh []models.PriceDatum
+	for _, quote := range quotes {
+		if now.Sub(quote.Timestamp) <= i.cfg.IndexMaxAge {
+			fresh = append(fresh, quote)
+		}
+	}
+	if len(fresh) == 0 {
+		return models.PriceDatum{}, false
+	}
+
+	prices := make([]decimal.Decimal, 0, len(fresh))
+	for _, quote := range fresh {
+		prices = append(prices, quote.Price)
+	}
+	median := weightedMedian(prices, nil)
+	maxDeviation := decimal.NewFromFloat(i.cfg.IndexMaxDeviation)
+
+	var accepted []decimal.Decimal
+	var weights []float64
+	for _, quote := range fresh {
+		if median.IsPositive() && quote.Price.Sub(median).Abs().Div(median).GreaterThan(maxDeviation) {
+			slog.Warn("index price rejects outlier",
+				"symbol", quote.Symbol, "source", quote.Source, "price", quote.Price, "median", median)
+			continue
+		}
+
+		weight, ok := i.cfg.IndexSourceWeights[quote.Source]
+		if !ok {
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+
+		accepted = append(accepted, quote.Price)
+		weights = append(weights, weight)
+	}
+	if len(accepted) == 0 || len(accepted) < i.cfg.IndexMinSources {
+		return models.PriceDatum{}, false
+	}
+
+	return models.PriceDatum{
+		Timestamp: now,
+		Symbol:    fresh[0].Symbol,
+		Currency:  fresh[0].Currency,
+		Source:    constant.SourceIndex,
+		Price:     weightedMedian(accepted, weights),
+	}, true
+}
+
+// weightedMedian returns the price at which half of the total weight is reached,
+// averaging the two middle prices on an exact split. Nil weights weigh every price equally
+func weightedMedian(prices []decimal.Decimal, weights []float64) decimal.Decimal {
+	type item struct {
+		price  decimal.Decimal
+		weight float64
+	}
+
+	items := make([]item, 0, len(prices))
+	total := 0.0
+	for idx, price := range prices {
+		weight := 1.0
+		if weights != nil {
+			weight = weights[idx]
+		}
+
+		items = append(items, item{price: price, weight: weight})
+		total += weight
+	}
+
+	sort.Slice(items, func(a, b int) bool {
+		return items[a].price.LessThan(items[b].price)
+	})
+
+	cumulative := 0.0
+	for idx, it := range items {
+		cumulative += it.weight
+		if math.Abs(cumulative-total/2) < 1e-9 && idx+1 < len(items) {
+			return it.price.Add(items[idx+1].price).Mul(decimal.New(5, -1))
+		}
+		if cumulative > total/2 {
+			return it.price
+		}
+	}
+
+	return items[len(items)-1].price
+}
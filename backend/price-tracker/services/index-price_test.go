@@ -0,0 +1,133 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"context"
+	"github.com/shopspring/decimal"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IndexPriceServiceTestSuite struct {
+	suite.Suite
+	cache   *sync.Map
+	prices  *recordingPublisher[models.PriceTick]
+	now     time.Time
+	service *IndexPriceServiceImpl
+}
+
+func (s *IndexPriceServiceTestSuite) SetupTest() {
+	s.cache = &sync.Map{}
+	s.prices = &recordingPublisher[models.PriceTick]{}
+	s.now = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.service = &IndexPriceServiceImpl{
+		cache:  s.cache,
+		prices: s.prices,
+		cfg: config.IndexPriceConfig{
+			IndexMaxAge:        30 * time.Second,
+			IndexMaxDeviation:  0.02,
+			IndexMinSources:    1,
+			IndexSourceWeights: map[string]float64{"binance": 2},
+		},
+		now: func() time.Time { return s.now },
+	}
+}
+
+func TestIndexPriceServiceSuite(t *testing.T) {
+	suite.Run(t, new(IndexPriceServiceTestSuite))
+}
+
+func (s *IndexPriceServiceTestSuite) storeQuote(source string, price string, age time.Duration) {
+	datum := models.PriceDatum{
+		Timestamp: s.now.Add(-age),
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    source,
+		Price:     decimal.RequireFromString(price),
+	}
+	s.cache.Store(models.CacheKey("", datum.Symbol, datum.Currency), datum)
+	s.cache.Store(models.CacheKey(datum.Source, datum.Symbol, datum.Currency), datum)
+}
+
+func (s *IndexPriceServiceTestSuite) TestUpdateIndexPrices() {
+	// Arrange
+	s.storeQuote("binance", "100", time.Second)
+	s.storeQuote("coinbase", "101", time.Second)
+	s.storeQuote("kraken", "102", time.Second)
+	s.storeQuote("okx", "150", time.Second)        // outlier
+	s.storeQuote("bitstamp", "90", 10*time.Minute) // stale
+
+	// Act
+	err := s.service.UpdateIndexPrices(context.Background())
+
+	// Assert: binance weighs 2 out of 4, so the median splits between binance and coinbase
+	expect := models.PriceDatum{
+		Timestamp: s.now,
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Source:    "index",
+		Price:     decimal.RequireFromString("100.5"),
+	}
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []models.PriceTick{{Prices: []models.PriceDatum{expect}, ReceivedAt: s.now}}, s.prices.events)
+}
+
+func (s *IndexPriceServiceTestSuite) TestUpdateIndexPrices_NotEnoughSources() {
+	// Arrange
+	s.service.cfg.IndexMinSources = 2
+	s.storeQuote("binance", "100", time.Second)
+	s.storeQuote("coinbase", "101", time.Hour)
+
+	// Act
+	err := s.service.UpdateIndexPrices(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), s.prices.events)
+}
+
+func TestWeightedMedian(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  []string
+		weights []float64
+		want    string
+	}{
+		{
+			name:   "odd count",
+			prices: []string{"3", "1", "2"},
+			want:   "2",
+		},
+		{
+			name:   "even count",
+			prices: []string{"4", "1", "3", "2"},
+			want:   "2.5",
+		},
+		{
+			name:    "weighted",
+			prices:  []string{"1", "2", "3"},
+			weights: []float64{5, 1, 1},
+			want:    "1",
+		},
+		{
+			name:   "single",
+			prices: []string{"42"},
+			want:   "42",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := make([]decimal.Decimal, 0, len(tt.prices))
+			for _, price := range tt.prices {
+				prices = append(prices, decimal.RequireFromString(price))
+			}
+
+			assert.Equal(t, tt.want, weightedMedian(prices, tt.weights).String())
+		})
+	}
+}
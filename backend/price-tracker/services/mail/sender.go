@@ -0,0 +1,140 @@
+package mail
+
+import (
+	"backend/internal/config"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// Sender sends emails
+type Sender interface {
+	// Send sends a message to its recipients, until ctx is done
+	Send(ctx context.Context, msg Message) error
+}
+
+type SenderImpl struct {
+	host        string
+	addr        string
+	auth        smtp.Auth
+	implicitTLS bool
+	timeout     time.Duration
+}
+
+// NewSender returns a sender through the SMTP server of config
+func NewSender(cfg config.EmailConfig) Sender {
+	var auth smtp.Auth
+	if cfg.EmailSMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.EmailSMTPUsername, cfg.EmailSMTPPassword, cfg.EmailSMTPHost)
+	}
+
+	return &SenderImpl{
+		host:        cfg.EmailSMTPHost,
+		addr:        net.JoinHostPort(cfg.EmailSMTPHost, strconv.Itoa(cfg.EmailSMTPPort)),
+		auth:        auth,
+		implicitTLS: cfg.EmailSMTPImplicitTLS,
+		timeout:     cfg.EmailTimeout,
+	}
+}
+
+// Send sends a message in a session of its own, upgraded with STARTTLS when the server offers it
+func (s *SenderImpl) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("parse from: %w", err)
+	}
+	data, err := msg.Bytes()
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	if s.implicitTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: s.host})
+	}
+
+	// The session is bounded by ctx, which interrupts any read or write in progress once done
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("greeting: %w", err)
+	}
+	defer client.Close()
+
+	err = s.send(client, from.Address, msg.To, data)
+	if err != nil && ctx.Err() != nil {
+		return errors.Join(err, ctx.Err())
+	}
+
+	return err
+}
+
+func (s *SenderImpl) send(client *smtp.Client, from string, to []string, data []byte) error {
+	if ok, _ := client.Extension("STARTTLS"); ok && !s.implicitTLS {
+		err := client.StartTLS(&tls.Config{ServerName: s.host})
+		if err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	if s.auth != nil {
+		err := client.Auth(s.auth)
+		if err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	err := client.Mail(from)
+	if err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	for _, recipient := range to {
+		err = client.Rcpt(recipient)
+		if err != nil {
+			return fmt.Errorf("rcpt to %s: %w", recipient, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return fmt.Errorf("write data: %w", err)
+	}
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("end data: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// IsPermanent returns true if the server rejected a message with a permanent failure, a 5xx reply,
+// which sending it again would not change
+func IsPermanent(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500 && reply.Code <= 599
+}
@@ -0,0 +1,89 @@
+package mail
+
+import (
+	"backend/internal/config"
+	smtp_test_setup "backend/internal/smtp-test-setup"
+	"context"
+	"mime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSender(t *testing.T) (Sender, *smtp_test_setup.SMTPTestServer) {
+	server, err := smtp_test_setup.NewSMTPTestServer()
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	return NewSender(config.EmailConfig{
+		EmailSMTPHost: server.Host(),
+		EmailSMTPPort: server.Port(),
+		EmailTimeout:  5 * time.Second,
+	}), server
+}
+
+func TestSenderImpl_Send(t *testing.T) {
+	// Arrange
+	sender, server := newTestSender(t)
+	msg := Message{
+		From:    "Price Tracker <alerts@example.com>",
+		To:      []string{"ops@example.com"},
+		Subject: "Price alert: BTC ≥ 100",
+		Text:    "BTC/USDT rose above 100",
+		HTML:    `<p>BTC/USDT rose above 100, <a href="https://example.com/?symbol=BTC&amp;currency=USDT">chart</a></p>`,
+		Date:    time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	// Act
+	err := sender.Send(context.Background(), msg)
+
+	// Assert
+	require.NoError(t, err)
+	received := <-server.Messages()
+	assert.Equal(t, "alerts@example.com", received.From)
+	assert.Equal(t, []string{"ops@example.com"}, received.To)
+
+	header, err := received.Header()
+	require.NoError(t, err)
+	to, err := header.AddressList("To")
+	require.NoError(t, err)
+	assert.Equal(t, "ops@example.com", to[0].Address)
+	subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	date, err := header.Date()
+	require.NoError(t, err)
+	assert.True(t, msg.Date.Equal(date))
+
+	bodies, err := received.Bodies()
+	require.NoError(t, err)
+	assert.Equal(t, msg.Text, bodies["text/plain"])
+	assert.Equal(t, msg.HTML, bodies["text/html"])
+}
+
+func TestSenderImpl_Send_Rejected(t *testing.T) {
+	// Arrange
+	sender, server := newTestSender(t)
+	server.Reject("451 try again later")
+
+	// Act
+	err := sender.Send(context.Background(), Message{From: "alerts@example.com", To: []string{"ops@example.com"}})
+
+	// Assert
+	assert.ErrorContains(t, err, "try again later")
+	assert.False(t, IsPermanent(err))
+	assert.Empty(t, server.Messages())
+}
+
+func TestSenderImpl_Send_InvalidFrom(t *testing.T) {
+	// Arrange
+	sender, _ := newTestSender(t)
+
+	// Act
+	err := sender.Send(context.Background(), Message{From: "alerts at example.com", To: []string{"ops@example.com"}})
+
+	// Assert
+	assert.ErrorContains(t, err, "parse from")
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"backend/internal/constant"
+	"backend/price-tracker/models"
+	"sync"
+	"time"
+)
+
+// PriceCache keeps the cache up to date with the latest prices and stats, each of its methods subscribes to the event bus
+type PriceCache interface {
+	// CachePrices caches each price as the latest of its own source and, unless it is an index price,
+	// of any source, along with when it was received
+	CachePrices(tick models.PriceTick)
+	// CacheMarketStats caches the stats as the latest of any source and of their own source
+	CacheMarketStats(tick models.MarketStatsTick)
+}
+
+type PriceCacheImpl struct {
+	cache *sync.Map
+}
+
+func NewPriceCache(cache *sync.Map) PriceCache {
+	return &PriceCacheImpl{
+		cache: cache,
+	}
+}
+
+func (p *PriceCacheImpl) CachePrices(tick models.PriceTick) {
+	receivedAt := tick.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now().UTC()
+	}
+
+	for _, datum := range tick.Prices {
+		datum.ReceivedAt = receivedAt
+		// The index is computed from the latest prices of the exchanges, it is not one of them
+		if datum.Source != constant.SourceIndex {
+			p.cache.Store(models.CacheKey("", datum.Symbol, datum.Currency), datum)
+		}
+		p.cache.Store(models.CacheKey(datum.Source, datum.Symbol, datum.Currency), datum)
+	}
+}
+
+func (p *PriceCacheImpl) CacheMarketStats(tick models.MarketStatsTick) {
+	for _, stats := range tick.Stats {
+		p.cache.Store(models.StatsCacheKey("", stats.Symbol, stats.Currency), stats)
+		p.cache.Store(models.StatsCacheKey(stats.Source, stats.Symbol, stats.Currency), stats)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"backend/price-tracker/models"
+	"github.com/shopspring/decimal"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceCacheImpl_CachePrices(t *testing.T) {
+	// Arrange
+	cache := &sync.Map{}
+	priceCache := NewPriceCache(cache)
+
+	tick := models.PriceTick{
+		Prices: []models.PriceDatum{
+			{Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")},
+			{Symbol: "ETH", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("10")},
+		},
+		ReceivedAt: time.Now().UTC(),
+	}
+
+	// Act
+	priceCache.CachePrices(tick)
+
+	// Assert
+	for _, key := range []string{"BTC/USDT", "binance:BTC/USDT", "ETH/USDT", "binance:ETH/USDT"} {
+		value, ok := cache.Load(key)
+		assert.True(t, ok, key)
+		assert.Equal(t, tick.ReceivedAt, value.(models.PriceDatum).ReceivedAt, key)
+	}
+}
+
+func TestPriceCacheImpl_CachePrices_Index(t *testing.T) {
+	// Arrange
+	cache := &sync.Map{}
+	priceCache := NewPriceCache(cache)
+	binance := models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")}
+	index := models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "index", Price: decimal.RequireFromString("101")}
+
+	// Act
+	priceCache.CachePrices(models.PriceTick{Prices: []models.PriceDatum{binance}})
+	priceCache.CachePrices(models.PriceTick{Prices: []models.PriceDatum{index}})
+
+	// Assert, the latest price of any source stays that of an exchange
+	latest, ok := cache.Load("BTC/USDT")
+	assert.True(t, ok)
+	assert.Equal(t, "binance", latest.(models.PriceDatum).Source)
+	cached, ok := cache.Load("index:BTC/USDT")
+	assert.True(t, ok)
+	assert.Equal(t, "101", cached.(models.PriceDatum).Price.String())
+}
+
+func TestPriceCacheImpl_CacheMarketStats(t *testing.T) {
+	// Arrange
+	cache := &sync.Map{}
+	priceCache := NewPriceCache(cache)
+
+	tick := models.MarketStatsTick{
+		Stats: []models.MarketStats{
+			{Symbol: "BTC", Currency: "USDT", Source: "binance", High: decimal.RequireFromString("105")},
+		},
+	}
+
+	// Act
+	priceCache.CacheMarketStats(tick)
+
+	// Assert
+	value, ok := cache.Load(models.StatsCacheKey("", "BTC", "USDT"))
+	assert.True(t, ok)
+	assert.Equal(t, "105", value.(models.MarketStats).High.String())
+	_, ok = cache.Load(models.StatsCacheKey("binance", "BTC", "USDT"))
+	assert.True(t, ok)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/stream"
+	"context"
+)
+
+type PriceStreamService interface {
+	// Subscribe returns a subscription to the prices stored from now on matching req
+	Subscribe(req models.PriceStreamRequest) *stream.Subscription
+	// Unsubscribe ends a subscription
+	Unsubscribe(sub *stream.Subscription)
+	// GetMissedPrices returns the stored prices matching req since req.Since,
+	// for a client resuming its stream after a disconnect
+	GetMissedPrices(ctx context.Context, req models.PriceStreamRequest) ([]models.PriceDatum, error)
+}
+
+type PriceStreamServiceImpl struct {
+	broadcaster stream.PriceBroadcaster
+	repository  repositories.Repository
+	cfg         config.StreamConfig
+}
+
+func NewPriceStreamService(
+	broadcaster stream.PriceBroadcaster,
+	repository repositories.Repository,
+	cfg config.StreamConfig,
+) PriceStreamService {
+	return &PriceStreamServiceImpl{
+		broadcaster: broadcaster,
+		repository:  repository,
+		cfg:         cfg,
+	}
+}
+
+func (p *PriceStreamServiceImpl) Subscribe(req models.PriceStreamRequest) *stream.Subscription {
+	return p.broadcaster.Subscribe(req)
+}
+
+func (p *PriceStreamServiceImpl) Unsubscribe(sub *stream.Subscription) {
+	p.broadcaster.Unsubscribe(sub)
+}
+
+// GetMissedPrices returns the stored prices matching req since req.Since, at most the resume limit of them
+func (p *PriceStreamServiceImpl) GetMissedPrices(ctx context.Context, req models.PriceStreamRequest) ([]models.PriceDatum, error) {
+	req.Limit = p.cfg.StreamResumeLimit
+
+	return p.repository.GetPricesSince(ctx, req)
+}
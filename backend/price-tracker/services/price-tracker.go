@@ -1,85 +1,229 @@
 package services
 
 import (
+	"backend/internal/config"
+	"backend/internal/constant"
 	"backend/price-tracker/models"
 	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/reconnect"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 )
 
+// ErrStalePrice is returned when the latest price was received longer ago than the max age
+var ErrStalePrice = errors.New("stale price")
+
 type PriceTrackingService interface {
-	// GetLatestPrice returns the latest price of a symbol.
-	// First, it tries to fetch from cache.
-	// Second, it tries to fetch from API.
-	// Finally, it tries to fetch from database
-	GetLatestPrice(req models.PriceDatum) (*models.PriceDatum, error)
-	// GetPriceOfTheLast24h returns all prices of a symbol in the last 24h
-	GetPriceOfTheLast24h(req models.PriceDatum) ([]models.PriceDatum, error)
-	// IsSymbolValid returns true if them symbol is valid and exist
-	IsSymbolValid(symbol string) bool
+	// GetLatestPrice returns the latest price of a symbol from any source, or from req.Source if set,
+	// along with how long ago it was received.
+	// Source "index" returns the composite price computed across sources.
+	// First, it tries to fetch from cache unless stale.
+	// Second, it tries to fetch from API unless it keeps failing, and caches the price.
+	// Finally, it tries the stale cache then the database.
+	// It returns ErrStalePrice along with its freshness if the price is older than the max age
+	GetLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, models.Freshness, error)
+	// GetPriceHistory returns the prices of a symbol in the requested range, downsampled by step or points
+	GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error)
+	// IsSymbolValid returns true if them symbol is valid and exist in the currency
+	IsSymbolValid(symbol, currency string) bool
 	// GetCryptoList return all crypto basic information
-	GetCryptoList() ([]models.PriceDatum, error)
+	GetCryptoList(ctx context.Context) ([]models.PriceDatum, error)
+	// GetCandles returns the candles of a symbol covering the requested range
+	GetCandles(ctx context.Context, req models.CandleRequest) ([]models.Candle, error)
 }
 
 type PriceTrackingServiceImpl struct {
 	cache      *sync.Map
+	prices     PriceCache
 	repository repositories.Repository
 	httpClient *http.Client
+	baseURL    string
+	// breaker paces the requests to the REST API, which are skipped while it holds them back
+	breaker reconnect.Breaker
+	cfg     config.FreshnessConfig
+	now     func() time.Time
+}
+
+// binanceStatusError is returned when Binance's REST API answers with an HTTP error,
+// such as 429 when rate limited or 418 once banned for ignoring it
+type binanceStatusError struct {
+	StatusCode int
+	// RetryAfter is the wait asked by the exchange, zero if it did not tell
+	RetryAfter time.Duration
+}
+
+func (e *binanceStatusError) Error() string {
+	return fmt.Sprintf("http status %d", e.StatusCode)
 }
 
-func NewPriceTrackingService(cache *sync.Map, repository repositories.Repository) PriceTrackingService {
+func NewPriceTrackingService(cache *sync.Map, repository repositories.Repository, baseURL string,
+	breaker reconnect.Breaker, cfg config.FreshnessConfig) PriceTrackingService {
 	return &PriceTrackingServiceImpl{
 		cache:      cache,
+		prices:     NewPriceCache(cache),
 		repository: repository,
 		httpClient: &http.Client{
 			Timeout: time.Second * 2,
 		},
+		baseURL: baseURL,
+		breaker: breaker,
+		cfg:     cfg,
+		now:     time.Now,
 	}
 }
 
-// GetLatestPrice returns the latest price of a symbol.
-// First, it tries to fetch from cache. Next if not found
-// Second, it tries to fetch from binance API. Next if not found
-// Finally, it tries to fetch from database
-func (p *PriceTrackingServiceImpl) GetLatestPrice(req models.PriceDatum) (*models.PriceDatum, error) {
-	if val, ok := p.cache.Load(req.Symbol); ok {
-		res := val.(models.PriceDatum)
-		return &res, nil
-	} else if res, err := p.fetchPriceFromBinanceAPI(req); err == nil {
-		return res, nil
-	} else {
+// GetLatestPrice returns the latest price of a symbol along with its market stats when they are known,
+// and how long ago it was received
+func (p *PriceTrackingServiceImpl) GetLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, models.Freshness, error) {
+	res, err := p.getLatestPrice(ctx, req)
+	if err != nil {
+		return nil, models.Freshness{}, err
+	}
+
+	freshness := p.freshness(*res)
+	if p.cfg.PriceMaxAge > 0 && freshness.Age > p.cfg.PriceMaxAge {
+		return nil, freshness, fmt.Errorf("%w, the latest %s/%s was received %s ago",
+			ErrStalePrice, req.Symbol, req.Currency, freshness.Age.Round(time.Second))
+	}
+
+	res.Stats = p.getMarketStats(ctx, req)
+
+	return res, freshness, nil
+}
+
+// getLatestPrice returns the latest price of a symbol.
+// First, it tries to fetch from cache. Next if not found or stale
+// Second, it tries to fetch from binance API unless its breaker holds the requests back. Next if not found
+// Finally, it returns the stale cached price or fetches from database
+func (p *PriceTrackingServiceImpl) getLatestPrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, error) {
+	val, cached := p.cache.Load(models.CacheKey(req.Source, req.Symbol, req.Currency))
+	var res models.PriceDatum
+	if cached {
+		res = val.(models.PriceDatum)
+		if !p.freshness(res).Stale {
+			return &res, nil
+		}
+	}
+
+	if (req.Source == "" || req.Source == constant.SourceBinance) && p.breaker.Next() == 0 {
+		fetched, err := p.refreshBinancePrice(ctx, req)
+		if err == nil {
+			return fetched, nil
+		}
 		slog.Error("fetchPriceFromBinanceAPI", "err", err)
+	}
+
+	if cached {
+		return &res, nil
+	}
 
-		res, err := p.repository.GetLatestPrice(req)
-		if err != nil {
-			return nil, err
+	return p.repository.GetLatestPrice(ctx, req)
+}
+
+// refreshBinancePrice fetches the latest price of a symbol from Binance's REST API and caches it.
+// The outcome is recorded by the breaker, a rate limit holds the requests back for as long as asked
+func (p *PriceTrackingServiceImpl) refreshBinancePrice(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, error) {
+	p.breaker.Attempt()
+
+	res, err := p.fetchPriceFromBinanceAPI(ctx, req)
+	if err != nil {
+		var statusErr *binanceStatusError
+		switch {
+		case ctx.Err() != nil:
+		case errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode == http.StatusTeapot):
+			p.breaker.Trip(err, statusErr.RetryAfter)
+		default:
+			p.breaker.Failure(err)
 		}
+		return nil, err
+	}
+	p.breaker.Success()
+
+	res.ReceivedAt = p.now().UTC()
+	p.prices.CachePrices(models.PriceTick{Prices: []models.PriceDatum{*res}, ReceivedAt: res.ReceivedAt})
 
-		return res, nil
+	return res, nil
+}
+
+// freshness returns how long ago a price was received and whether it is stale
+func (p *PriceTrackingServiceImpl) freshness(datum models.PriceDatum) models.Freshness {
+	age := max(p.now().Sub(datum.Received()), 0)
+
+	return models.Freshness{
+		Age:   age,
+		Stale: p.cfg.PriceStaleAfter > 0 && age > p.cfg.PriceStaleAfter,
+	}
+}
+
+// getMarketStats returns the latest stats of the market from cache, or from database if not cached.
+// The composite index has no stats of its own, the stats of any source are returned for it
+func (p *PriceTrackingServiceImpl) getMarketStats(ctx context.Context, req models.PriceDatum) *models.MarketStats {
+	source := req.Source
+	if source == constant.SourceIndex {
+		source = ""
+	}
+
+	if val, ok := p.cache.Load(models.StatsCacheKey(source, req.Symbol, req.Currency)); ok {
+		if stats, ok := val.(models.MarketStats); ok {
+			return &stats
+		}
+	}
+
+	stats, err := p.repository.GetMarketStats(ctx, models.PriceDatum{Symbol: req.Symbol, Currency: req.Currency, Source: source})
+	if err != nil {
+		if !errors.Is(err, repositories.ErrNotFound) {
+			slog.Error("get market stats", "err", err)
+		}
+		return nil
 	}
+
+	return stats
 }
 
 // fetchPriceFromBinanceAPI returns the latest price of a symbol from Binance's REST API
-func (p *PriceTrackingServiceImpl) fetchPriceFromBinanceAPI(req models.PriceDatum) (*models.PriceDatum, error) {
-	url := fmt.Sprintf("https://api.binance.com/api/v3/ticker/price?symbol=%s", req.Symbol+req.Currency)
+func (p *PriceTrackingServiceImpl) fetchPriceFromBinanceAPI(ctx context.Context, req models.PriceDatum) (*models.PriceDatum, error) {
+	url := fmt.Sprintf("%s/api/v3/ticker/price?symbol=%s", p.baseURL, req.Symbol+req.Currency)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
 
-	resp, err := p.httpClient.Get(url)
+	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("http request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return nil, &binanceStatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: time.Duration(max(seconds, 0)) * time.Second,
+		}
+	}
+
 	var binanceResult models.BinanceResult
 	err = json.NewDecoder(resp.Body).Decode(&binanceResult)
 	if err != nil {
 		return nil, fmt.Errorf("json decode: %w", err)
 	}
 
-	res, err := models.NewPriceDatumFromBinanceResult(binanceResult, time.Now())
+	pair := models.SymbolPair{
+		Symbol: req.Symbol + req.Currency,
+		Base:   req.Symbol,
+		Quote:  req.Currency,
+	}
+
+	res, err := models.NewPriceDatumFromBinanceResult(binanceResult, pair, p.now())
 	if err != nil {
 		return nil, fmt.Errorf("convert: %w", err)
 	}
@@ -87,9 +231,15 @@ func (p *PriceTrackingServiceImpl) fetchPriceFromBinanceAPI(req models.PriceDatu
 	return &res, nil
 }
 
-// GetPriceOfTheLast24h returns all prices of a symbol in the last 24h
-func (p *PriceTrackingServiceImpl) GetPriceOfTheLast24h(req models.PriceDatum) ([]models.PriceDatum, error) {
-	res, err := p.repository.GetPriceOfTheLast24h(req)
+// GetPriceHistory returns the prices of a symbol in the requested range, downsampled by step or points.
+// A zero req.From starts at the earliest stored price
+func (p *PriceTrackingServiceImpl) GetPriceHistory(ctx context.Context, req models.PriceHistoryRequest) ([]models.PriceDatum, error) {
+	if !req.From.IsZero() {
+		req.From = req.From.UTC()
+	}
+	req.To = req.To.UTC()
+
+	res, err := p.repository.GetPriceHistory(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -97,15 +247,24 @@ func (p *PriceTrackingServiceImpl) GetPriceOfTheLast24h(req models.PriceDatum) (
 	return res, nil
 }
 
-// IsSymbolValid returns true if symbol is valid and exist
-func (p *PriceTrackingServiceImpl) IsSymbolValid(symbol string) bool {
-	if _, ok := p.cache.Load(symbol); ok {
+// IsSymbolValid returns true if symbol is valid and exist in the currency
+func (p *PriceTrackingServiceImpl) IsSymbolValid(symbol, currency string) bool {
+	if _, ok := p.cache.Load(models.CacheKey("", symbol, currency)); ok {
 		return true
 	}
 
 	return false
 }
 
-func (p *PriceTrackingServiceImpl) GetCryptoList() ([]models.PriceDatum, error) {
-	return p.repository.GetAllCryptoInfo()
+func (p *PriceTrackingServiceImpl) GetCryptoList(ctx context.Context) ([]models.PriceDatum, error) {
+	return p.repository.GetAllCryptoInfo(ctx)
+}
+
+// GetCandles returns the candles of a symbol covering the requested range,
+// including the candle that is open at req.From
+func (p *PriceTrackingServiceImpl) GetCandles(ctx context.Context, req models.CandleRequest) ([]models.Candle, error) {
+	req.From = req.From.UTC().Truncate(req.Resolution.Duration)
+	req.To = req.To.UTC()
+
+	return p.repository.GetCandles(ctx, req)
 }
@@ -1,10 +1,15 @@
 package services
 
 import (
+	"backend/internal/config"
 	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
 	"backend/price-tracker/repositories/mock"
+	"backend/price-tracker/services/reconnect"
+	"context"
 	"errors"
 	"fmt"
+	"github.com/shopspring/decimal"
 	"io"
 	"net/http"
 	"strings"
@@ -13,6 +18,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	m "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -22,13 +28,23 @@ type PriceTrackingServiceTestSuite struct {
 	cache      *sync.Map
 	service    PriceTrackingService
 	httpClient *http.Client
+	now        time.Time
 }
 
 func (s *PriceTrackingServiceTestSuite) SetupTest() {
 	s.mockRepo = new(mock.MockRepository)
 	s.cache = &sync.Map{}
 	s.httpClient = &http.Client{}
-	s.service = NewPriceTrackingService(s.cache, s.mockRepo)
+	s.now = time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	breaker := reconnect.NewBreaker("binance_api", config.PriceTrackerConfig{
+		ReconnectInitialDelay: time.Minute,
+		ReconnectMaxDelay:     time.Hour,
+	})
+	s.service = NewPriceTrackingService(s.cache, s.mockRepo, "https://binance.test", breaker, config.FreshnessConfig{
+		PriceStaleAfter: time.Minute,
+		PriceMaxAge:     10 * time.Minute,
+	})
+	s.service.(*PriceTrackingServiceImpl).now = func() time.Time { return s.now }
 }
 
 func TestPriceTrackingServiceSuite(t *testing.T) {
@@ -37,20 +53,200 @@ func TestPriceTrackingServiceSuite(t *testing.T) {
 
 func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice() {
 	// Arrange
-	symbol := "BTC"
 	expectedPrice := models.PriceDatum{
-		Symbol:   "BTC",
-		Currency: "USDT",
-		Price:    50000.0,
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Price:      decimal.RequireFromString("50000"),
+		ReceivedAt: s.now.Add(-1500 * time.Millisecond),
+	}
+	s.cache.Store("BTC/USDT", expectedPrice)
+	s.mockRepo.On("GetMarketStats", m.Anything).Return(nil, repositories.ErrNotFound)
+
+	// Act
+	price, freshness, err := s.service.GetLatestPrice(context.Background(), expectedPrice)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), expectedPrice.Price.String(), price.Price.String())
+	assert.Equal(s.T(), models.Freshness{Age: 1500 * time.Millisecond}, freshness)
+}
+
+func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice_BySource() {
+	// Arrange
+	cached := models.PriceDatum{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "kraken",
+		Price:      decimal.RequireFromString("50001"),
+		ReceivedAt: s.now,
+	}
+	s.cache.Store("kraken:BTC/USDT", cached)
+
+	stored := models.PriceDatum{
+		Timestamp: s.now.Add(-time.Second),
+		Symbol:    "ETH",
+		Currency:  "USDT",
+		Source:    "okx",
+		Price:     decimal.RequireFromString("2500"),
+	}
+	s.mockRepo.On("GetLatestPrice", models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx"}).Return(&stored, nil)
+	s.mockRepo.On("GetMarketStats", m.Anything).Return(nil, repositories.ErrNotFound)
+
+	// Act
+	fromCache, _, errCache := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "kraken"})
+	fromRepo, freshness, errRepo := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx"})
+
+	// Assert
+	assert.NoError(s.T(), errCache)
+	assert.Equal(s.T(), cached, *fromCache)
+	assert.NoError(s.T(), errRepo)
+	assert.Equal(s.T(), stored, *fromRepo)
+	// The stored prices are as old as their timestamp
+	assert.Equal(s.T(), time.Second, freshness.Age)
+	s.mockRepo.AssertExpectations(s.T())
+}
+
+func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice_WithStats() {
+	// Arrange
+	s.cache.Store("BTC/USDT", models.PriceDatum{Symbol: "BTC", Currency: "USDT", Price: decimal.RequireFromString("50000"), ReceivedAt: s.now})
+	s.cache.Store("index:BTC/USDT", models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "index", Price: decimal.RequireFromString("50001"), Timestamp: s.now})
+	s.cache.Store("okx:ETH/USDT", models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx", Price: decimal.RequireFromString("2500"), ReceivedAt: s.now})
+	cached := models.MarketStats{Symbol: "BTC", Currency: "USDT", Source: "binance", BidPrice: decimal.RequireFromString("49999")}
+	s.cache.Store(models.StatsCacheKey("", "BTC", "USDT"), cached)
+	stored := models.MarketStats{Symbol: "ETH", Currency: "USDT", Source: "okx", AskPrice: decimal.RequireFromString("2501")}
+	s.mockRepo.On("GetMarketStats", models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx"}).Return(&stored, nil)
+
+	// Act
+	latest, _, errLatest := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT"})
+	index, _, errIndex := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "index"})
+	fromRepo, _, errRepo := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx"})
+
+	// Assert
+	assert.NoError(s.T(), errLatest)
+	assert.Equal(s.T(), &cached, latest.Stats)
+	assert.NoError(s.T(), errIndex)
+	assert.Equal(s.T(), &cached, index.Stats)
+	assert.NoError(s.T(), errRepo)
+	assert.Equal(s.T(), &stored, fromRepo.Stats)
+	s.mockRepo.AssertExpectations(s.T())
+}
+
+func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice_Stale() {
+	// Arrange
+	cached := models.PriceDatum{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "kraken",
+		Price:      decimal.RequireFromString("50001"),
+		ReceivedAt: s.now.Add(-2 * time.Minute),
+	}
+	s.cache.Store("kraken:BTC/USDT", cached)
+	s.mockRepo.On("GetMarketStats", m.Anything).Return(nil, repositories.ErrNotFound)
+
+	// Act
+	price, freshness, err := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "kraken"})
+
+	// Assert, served as it is but flagged
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), cached, *price)
+	assert.Equal(s.T(), models.Freshness{Age: 2 * time.Minute, Stale: true}, freshness)
+}
+
+func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice_StaleFetchedFromAPI() {
+	// Arrange
+	s.cache.Store("BTC/USDT", models.PriceDatum{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "binance",
+		Price:      decimal.RequireFromString("50000"),
+		ReceivedAt: s.now.Add(-2 * time.Minute),
+	})
+	transport := &mockRoundTripper{
+		response: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"symbol": "BTCUSDT", "price": "51000.00"}`)),
+		},
 	}
-	s.cache.Store(symbol, expectedPrice)
+	s.service.(*PriceTrackingServiceImpl).httpClient = &http.Client{Transport: transport}
+	s.mockRepo.On("GetMarketStats", m.Anything).Return(nil, repositories.ErrNotFound)
 
 	// Act
-	price, err := s.service.GetLatestPrice(expectedPrice)
+	price, freshness, err := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT"})
 
 	// Assert
 	assert.NoError(s.T(), err)
-	assert.Equal(s.T(), expectedPrice.Price, price.Price)
+	assert.Equal(s.T(), "51000", price.Price.String())
+	assert.Equal(s.T(), models.Freshness{}, freshness)
+	assert.Equal(s.T(), "https://binance.test/api/v3/ticker/price?symbol=BTCUSDT", transport.requestURL)
+	for _, key := range []string{"BTC/USDT", "binance:BTC/USDT"} {
+		cached, ok := s.cache.Load(key)
+		s.Require().True(ok, key)
+		assert.Equal(s.T(), "51000", cached.(models.PriceDatum).Price.String())
+		assert.Equal(s.T(), s.now, cached.(models.PriceDatum).ReceivedAt)
+	}
+}
+
+func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice_APIFailureBacksOff() {
+	// Arrange
+	stale := models.PriceDatum{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "binance",
+		Price:      decimal.RequireFromString("50000"),
+		ReceivedAt: s.now.Add(-2 * time.Minute),
+	}
+	s.cache.Store("BTC/USDT", stale)
+	transport := &mockRoundTripper{
+		response: &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader(`{"symbol": "BTCUSDT", "price": "51000.00"}`)),
+		},
+	}
+	s.service.(*PriceTrackingServiceImpl).httpClient = &http.Client{Transport: transport}
+	s.mockRepo.On("GetMarketStats", m.Anything).Return(nil, repositories.ErrNotFound)
+
+	// Act
+	first, _, errFirst := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT"})
+	second, _, errSecond := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT"})
+
+	// Assert, the error status is not taken as a price and the API is not asked again during the backoff
+	assert.NoError(s.T(), errFirst)
+	assert.Equal(s.T(), "50000", first.Price.String())
+	assert.NoError(s.T(), errSecond)
+	assert.Equal(s.T(), "50000", second.Price.String())
+	assert.Equal(s.T(), 1, transport.requests)
+}
+
+func (s *PriceTrackingServiceTestSuite) TestGetLatestPrice_TooOld() {
+	// Arrange
+	s.cache.Store("kraken:BTC/USDT", models.PriceDatum{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "kraken",
+		Price:      decimal.RequireFromString("50001"),
+		ReceivedAt: s.now.Add(-time.Hour),
+	})
+	s.mockRepo.On("GetLatestPrice", models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx"}).Return(&models.PriceDatum{
+		Timestamp: s.now.Add(-11 * time.Minute),
+		Symbol:    "ETH",
+		Currency:  "USDT",
+		Source:    "okx",
+		Price:     decimal.RequireFromString("2500"),
+	}, nil)
+
+	// Act
+	fromCache, freshnessCache, errCache := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "kraken"})
+	fromRepo, freshnessRepo, errRepo := s.service.GetLatestPrice(context.Background(), models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "okx"})
+
+	// Assert
+	assert.ErrorIs(s.T(), errCache, ErrStalePrice)
+	assert.EqualError(s.T(), errCache, "stale price, the latest BTC/USDT was received 1h0m0s ago")
+	assert.Nil(s.T(), fromCache)
+	assert.Equal(s.T(), models.Freshness{Age: time.Hour, Stale: true}, freshnessCache)
+	assert.ErrorIs(s.T(), errRepo, ErrStalePrice)
+	assert.Nil(s.T(), fromRepo)
+	assert.Equal(s.T(), 11*time.Minute, freshnessRepo.Age)
+	s.mockRepo.AssertExpectations(s.T())
 }
 
 func (s *PriceTrackingServiceTestSuite) TestGetPriceHistory() {
@@ -59,40 +255,74 @@ func (s *PriceTrackingServiceTestSuite) TestGetPriceHistory() {
 	now := time.Now().UTC()
 	from := now.Add(-1 * time.Hour)
 	to := now
-	req := models.PriceDatum{
+	req := models.PriceHistoryRequest{
 		Symbol:   symbol,
 		Currency: "USDT",
+		From:     from,
+		To:       to,
+		Points:   30,
 	}
 	expectedPrices := []models.PriceDatum{
 		{
 			Symbol:    symbol,
 			Currency:  "USDT",
-			Price:     50000.0,
+			Price:     decimal.RequireFromString("50000"),
 			Timestamp: from.Add(30 * time.Minute),
 		},
 		{
 			Symbol:    symbol,
 			Currency:  "USDT",
-			Price:     51000.0,
+			Price:     decimal.RequireFromString("51000"),
 			Timestamp: to,
 		},
 	}
-	s.mockRepo.On("GetPriceOfTheLast24h", req).Return(expectedPrices, nil)
+	s.mockRepo.On("GetPriceHistory", req).Return(expectedPrices, nil)
 
 	// Act
-	prices, err := s.service.GetPriceOfTheLast24h(req)
+	prices, err := s.service.GetPriceHistory(context.Background(), req)
 
 	// Assert
 	assert.NoError(s.T(), err)
 	for i, price := range prices {
 		assert.Equal(s.T(), expectedPrices[i].Symbol, price.Symbol)
 		assert.Equal(s.T(), expectedPrices[i].Currency, price.Currency)
-		assert.Equal(s.T(), expectedPrices[i].Price, price.Price)
+		assert.Equal(s.T(), expectedPrices[i].Price.String(), price.Price.String())
 		assert.Equal(s.T(), expectedPrices[i].Timestamp.UTC(), price.Timestamp.UTC())
 	}
 	s.mockRepo.AssertExpectations(s.T())
 }
 
+func (s *PriceTrackingServiceTestSuite) TestGetCandles() {
+	// Arrange
+	resolution, _ := models.ParseCandleResolution("1h")
+	from := time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC)
+	to := from.Add(5 * time.Hour)
+	expected := []models.Candle{{Symbol: "BTC", Resolution: "1h", OpenTime: from.Truncate(time.Hour)}}
+	s.mockRepo.On("GetCandles", models.CandleRequest{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "index",
+		Resolution: resolution,
+		From:       from.Truncate(time.Hour),
+		To:         to,
+	}).Return(expected, nil)
+
+	// Act
+	candles, err := s.service.GetCandles(context.Background(), models.CandleRequest{
+		Symbol:     "BTC",
+		Currency:   "USDT",
+		Source:     "index",
+		Resolution: resolution,
+		From:       from,
+		To:         to,
+	})
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), expected, candles)
+	s.mockRepo.AssertExpectations(s.T())
+}
+
 func TestPriceTrackingServiceImpl_fetchPriceFromBinanceAPI(t *testing.T) {
 	// Setup test cases
 	testCases := []struct {
@@ -116,7 +346,7 @@ func TestPriceTrackingServiceImpl_fetchPriceFromBinanceAPI(t *testing.T) {
 			expectedResult: &models.PriceDatum{
 				Symbol:    "BTC",
 				Currency:  "USDT",
-				Price:     64235.12,
+				Price:     decimal.RequireFromString("64235.12"),
 				Timestamp: time.Time{}, // Will be compared separately
 			},
 			expectedError: false,
@@ -133,6 +363,18 @@ func TestPriceTrackingServiceImpl_fetchPriceFromBinanceAPI(t *testing.T) {
 			expectedResult: nil,
 			expectedError:  true,
 		},
+		{
+			name: "rate limited",
+			request: models.PriceDatum{
+				Symbol:   "BTC",
+				Currency: "USDT",
+			},
+			mockResponse:   `{"code": -1003, "msg": "Too many requests"}`,
+			mockStatusCode: http.StatusTooManyRequests,
+			mockError:      nil,
+			expectedResult: nil,
+			expectedError:  true,
+		},
 		{
 			name: "invalid json response",
 			request: models.PriceDatum{
@@ -167,10 +409,12 @@ func TestPriceTrackingServiceImpl_fetchPriceFromBinanceAPI(t *testing.T) {
 			// Create the service with the mock client
 			service := &PriceTrackingServiceImpl{
 				httpClient: mockClient,
+				baseURL:    "https://api.binance.com",
+				now:        time.Now,
 			}
 
 			// Call the method being tested
-			result, err := service.fetchPriceFromBinanceAPI(tc.request)
+			result, err := service.fetchPriceFromBinanceAPI(context.Background(), tc.request)
 
 			// Check error
 			if tc.expectedError && err == nil {
@@ -195,8 +439,8 @@ func TestPriceTrackingServiceImpl_fetchPriceFromBinanceAPI(t *testing.T) {
 					if result.Currency != tc.expectedResult.Currency {
 						t.Errorf("Expected Currency %s, got %s", tc.expectedResult.Currency, result.Currency)
 					}
-					if result.Price != tc.expectedResult.Price {
-						t.Errorf("Expected Price %f, got %f", tc.expectedResult.Price, result.Price)
+					if !result.Price.Equal(tc.expectedResult.Price) {
+						t.Errorf("Expected Price %s, got %s", tc.expectedResult.Price, result.Price)
 					}
 					// Check that timestamp is recent
 					timeNow := time.Now()
@@ -221,16 +465,19 @@ type mockRoundTripper struct {
 	response   *http.Response
 	err        error
 	requestURL string
+	requests   int
 }
 
 func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
 	m.requestURL = req.URL.String()
+	m.requests++
 	return m.response, m.err
 }
 
 func TestPriceTrackingServiceImpl_IsSymbolValid(t *testing.T) {
 	type args struct {
-		symbol string
+		symbol   string
+		currency string
 	}
 	tests := []struct {
 		name string
@@ -239,18 +486,23 @@ func TestPriceTrackingServiceImpl_IsSymbolValid(t *testing.T) {
 	}{
 		{
 			name: "valid",
-			args: args{symbol: "ABC"},
+			args: args{symbol: "ABC", currency: "USDT"},
 			want: true,
 		},
 		{
 			name: "invalid",
-			args: args{symbol: "BCD"},
+			args: args{symbol: "BCD", currency: "USDT"},
+			want: false,
+		},
+		{
+			name: "invalid currency",
+			args: args{symbol: "ABC", currency: "EUR"},
 			want: false,
 		},
 	}
 
 	m := sync.Map{}
-	m.Store("ABC", true)
+	m.Store("ABC/USDT", true)
 
 	p := &PriceTrackingServiceImpl{
 		cache: &m,
@@ -258,7 +510,7 @@ func TestPriceTrackingServiceImpl_IsSymbolValid(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equalf(t, tt.want, p.IsSymbolValid(tt.args.symbol), "IsSymbolValid(%v)", tt.args.symbol)
+			assert.Equalf(t, tt.want, p.IsSymbolValid(tt.args.symbol, tt.args.currency), "IsSymbolValid(%v, %v)", tt.args.symbol, tt.args.currency)
 		})
 	}
 }
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"context"
+	"log/slog"
+)
+
+// PriceWriter writes the ticks of the event bus to the database, from the single goroutine of a batch subscription
+// so that the price writes never contend with each other. Its writes are not cancelled by the shutdown,
+// which closes the bus and waits for the writer to drain the queued ticks
+type PriceWriter interface {
+	// WritePrices writes the prices of the ticks in inserts of at most WriteBatchMaxRows prices
+	WritePrices(ticks []models.PriceTick)
+	// WriteMarketStats writes the latest stats of each market among the ticks
+	WriteMarketStats(ticks []models.MarketStatsTick)
+}
+
+type PriceWriterImpl struct {
+	repository repositories.Repository
+	cfg        config.PriceTrackerConfig
+}
+
+func NewPriceWriter(repository repositories.Repository, cfg config.PriceTrackerConfig) PriceWriter {
+	return &PriceWriterImpl{
+		repository: repository,
+		cfg:        cfg,
+	}
+}
+
+func (p *PriceWriterImpl) WritePrices(ticks []models.PriceTick) {
+	var data []models.PriceDatum
+	for _, tick := range ticks {
+		data = append(data, tick.Prices...)
+	}
+
+	size := p.cfg.WriteBatchMaxRows
+	if size <= 0 {
+		size = len(data)
+	}
+
+	for start := 0; start < len(data); start += size {
+		chunk := data[start:min(start+size, len(data))]
+
+		err := p.repository.BulkInsert(context.Background(), chunk)
+		if err != nil {
+			slog.Error("bulk insert", "count", len(chunk), "err", err)
+			continue
+		}
+
+		slog.Info("bulk insert", "count", len(chunk))
+	}
+}
+
+func (p *PriceWriterImpl) WriteMarketStats(ticks []models.MarketStatsTick) {
+	// Each market is written once, a single upsert must not update the same row twice
+	var stats []models.MarketStats
+	index := map[string]int{}
+	for _, tick := range ticks {
+		for _, s := range tick.Stats {
+			key := models.StatsCacheKey(s.Source, s.Symbol, s.Currency)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(stats)
+				stats = append(stats, s)
+			} else if !s.Timestamp.Before(stats[i].Timestamp) {
+				stats[i] = s
+			}
+		}
+	}
+	if len(stats) == 0 {
+		return
+	}
+
+	err := p.repository.SaveMarketStats(context.Background(), stats)
+	if err != nil {
+		slog.Error("save market stats", "count", len(stats), "err", err)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories/mock"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func newTestPriceWriter(repository *mock.MockRepository) *PriceWriterImpl {
+	return NewPriceWriter(repository, config.PriceTrackerConfig{
+		WriteBatchMaxRows: 2,
+	}).(*PriceWriterImpl)
+}
+
+func TestPriceWriterImpl_WritePrices(t *testing.T) {
+	// Arrange
+	mockRepo := new(mock.MockRepository)
+	writer := newTestPriceWriter(mockRepo)
+
+	btc := models.PriceDatum{Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")}
+	eth := models.PriceDatum{Symbol: "ETH", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("10")}
+	sol := models.PriceDatum{Symbol: "SOL", Currency: "USDT", Source: "kraken", Price: decimal.RequireFromString("1")}
+
+	mockRepo.On("BulkInsert", []models.PriceDatum{btc, eth}).Return(nil).Once()
+	mockRepo.On("BulkInsert", []models.PriceDatum{sol}).Return(nil).Once()
+
+	// Act
+	writer.WritePrices([]models.PriceTick{
+		{Prices: []models.PriceDatum{btc}},
+		{Prices: []models.PriceDatum{eth, sol}},
+	})
+
+	// Assert
+	mockRepo.AssertExpectations(t)
+}
+
+func TestPriceWriterImpl_WriteMarketStats(t *testing.T) {
+	// Arrange
+	mockRepo := new(mock.MockRepository)
+	writer := newTestPriceWriter(mockRepo)
+
+	older := models.MarketStats{Symbol: "BTC", Currency: "USDT", Source: "binance", Timestamp: time.UnixMilli(1000).UTC(), High: decimal.RequireFromString("100")}
+	newer := models.MarketStats{Symbol: "BTC", Currency: "USDT", Source: "binance", Timestamp: time.UnixMilli(2000).UTC(), High: decimal.RequireFromString("105")}
+	eth := models.MarketStats{Symbol: "ETH", Currency: "USDT", Source: "binance", Timestamp: time.UnixMilli(1000).UTC(), High: decimal.RequireFromString("10")}
+	mockRepo.On("SaveMarketStats", []models.MarketStats{newer, eth}).Return(nil).Once()
+
+	// Act
+	writer.WriteMarketStats([]models.MarketStatsTick{
+		{Stats: []models.MarketStats{older, eth}},
+		{Stats: []models.MarketStats{newer}},
+	})
+	writer.WriteMarketStats(nil)
+
+	// Assert
+	mockRepo.AssertExpectations(t)
+}
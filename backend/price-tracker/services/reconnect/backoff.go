@@ -0,0 +1,37 @@
+package reconnect
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes the delay before a retry, doubling from Initial up to Max after each failure.
+// Jitter is the fraction of the delay in [0, 1] drawn at random, so that clients failing together spread
+// their retries
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	Jitter  float64
+	// random returns a number in [0, 1)
+	random func() float64
+}
+
+// Delay returns the delay after the given number of consecutive failures, none before the first
+func (b Backoff) Delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+
+	// Compared to Max shifted back rather than Initial shifted, which overflows
+	delay := b.Max
+	if shift := failures - 1; shift < 63 && b.Initial <= b.Max>>shift {
+		delay = b.Initial << shift
+	}
+
+	random := b.random
+	if random == nil {
+		random = rand.Float64
+	}
+
+	return delay - time.Duration(b.Jitter*random()*float64(delay))
+}
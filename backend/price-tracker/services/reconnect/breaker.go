@@ -0,0 +1,163 @@
+package reconnect
+
+import (
+	"backend/internal/config"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// State is the state of the circuit of a breaker
+type State string
+
+const (
+	// StateClosed lets the attempts through, after a backoff growing with the failures
+	StateClosed State = "closed"
+	// StateOpen holds the attempts back until its cooldown elapsed
+	StateOpen State = "open"
+	// StateHalfOpen lets a single attempt through, whose outcome closes or opens the circuit again
+	StateHalfOpen State = "half_open"
+)
+
+// Breaker paces the connection attempts to an exchange, and stops them for a while
+// once the exchange keeps failing or refuses them
+type Breaker interface {
+	// Next returns how long to wait before the next attempt
+	Next() time.Duration
+	// Attempt records the start of an attempt, an open circuit whose cooldown elapsed turns half-open
+	Attempt()
+	// Success records a working connection and closes the circuit
+	Success()
+	// Failure records a failed attempt, the circuit opens after BreakerThreshold consecutive ones
+	Failure(err error)
+	// Trip records an attempt refused by the exchange and opens the circuit for at least wait
+	Trip(err error, wait time.Duration)
+	// Status returns the state of the circuit
+	Status() Status
+}
+
+// Status is the state of the circuit of a breaker along with its failures
+type Status struct {
+	State     State
+	Failures  int
+	LastError string
+	// RetryAt is the earliest time of the next attempt, zero if it can start now
+	RetryAt time.Time
+}
+
+type BreakerImpl struct {
+	name      string
+	backoff   Backoff
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	lastErr  error
+	retryAt  time.Time
+}
+
+func NewBreaker(name string, cfg config.PriceTrackerConfig) Breaker {
+	return &BreakerImpl{
+		name: name,
+		backoff: Backoff{
+			Initial: cfg.ReconnectInitialDelay,
+			Max:     cfg.ReconnectMaxDelay,
+			Jitter:  cfg.ReconnectJitter,
+		},
+		threshold: cfg.BreakerThreshold,
+		cooldown:  cfg.BreakerCooldown,
+		now:       time.Now,
+		state:     StateClosed,
+	}
+}
+
+func (b *BreakerImpl) Next() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return max(b.retryAt.Sub(b.now()), 0)
+}
+
+func (b *BreakerImpl) Attempt() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && !b.now().Before(b.retryAt) {
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *BreakerImpl) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.lastErr = nil
+	b.retryAt = time.Time{}
+	b.setState(StateClosed)
+}
+
+func (b *BreakerImpl) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastErr = err
+	delay := b.backoff.Delay(b.failures)
+
+	if b.state == StateHalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		delay = max(delay, b.cooldown)
+		b.retryAt = b.now().Add(delay)
+		b.setState(StateOpen)
+		return
+	}
+
+	b.retryAt = b.now().Add(delay)
+	slog.Info("connection failed, retrying", "name", b.name, "failures", b.failures, "retry_in", delay, "err", err)
+}
+
+func (b *BreakerImpl) Trip(err error, wait time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastErr = err
+	b.retryAt = b.now().Add(max(wait, b.backoff.Delay(b.failures)))
+	b.setState(StateOpen)
+}
+
+func (b *BreakerImpl) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		State:    b.state,
+		Failures: b.failures,
+	}
+	if b.lastErr != nil {
+		status.LastError = b.lastErr.Error()
+	}
+	if b.retryAt.After(b.now()) {
+		status.RetryAt = b.retryAt
+	}
+
+	return status
+}
+
+// setState moves the circuit to a state and logs the transition, b.mu must be held
+func (b *BreakerImpl) setState(state State) {
+	from := b.state
+	b.state = state
+
+	if state == StateOpen {
+		slog.Warn("circuit breaker opened", "name", b.name, "from", from,
+			"failures", b.failures, "retry_in", b.retryAt.Sub(b.now()), "err", b.lastErr)
+		return
+	}
+	if from != state {
+		slog.Info("circuit breaker state changed", "name", b.name, "from", from, "to", state)
+	}
+}
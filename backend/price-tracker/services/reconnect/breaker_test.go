@@ -0,0 +1,100 @@
+package reconnect
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Max: 10 * time.Second}
+	jittered := Backoff{Initial: time.Second, Max: 10 * time.Second, Jitter: 0.5, random: func() float64 { return 0.5 }}
+
+	tests := []struct {
+		name     string
+		backoff  Backoff
+		failures int
+		want     time.Duration
+	}{
+		{name: "no failure", backoff: backoff, failures: 0, want: 0},
+		{name: "first failure", backoff: backoff, failures: 1, want: time.Second},
+		{name: "doubled", backoff: backoff, failures: 3, want: 4 * time.Second},
+		{name: "capped", backoff: backoff, failures: 5, want: 10 * time.Second},
+		{name: "capped without overflow", backoff: backoff, failures: 100, want: 10 * time.Second},
+		{name: "capped before the doubling overflows", backoff: Backoff{Initial: time.Hour, Max: 1_000_000 * time.Hour}, failures: 32,
+			want: 1_000_000 * time.Hour},
+		{name: "jittered", backoff: jittered, failures: 3, want: 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.backoff.Delay(tt.failures))
+		})
+	}
+}
+
+type BreakerTestSuite struct {
+	suite.Suite
+	now     time.Time
+	breaker *BreakerImpl
+}
+
+func (s *BreakerTestSuite) SetupTest() {
+	s.now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.breaker = &BreakerImpl{
+		name:      "binance",
+		backoff:   Backoff{Initial: time.Second, Max: 10 * time.Second},
+		threshold: 3,
+		cooldown:  time.Minute,
+		now:       func() time.Time { return s.now },
+		state:     StateClosed,
+	}
+}
+
+func TestBreakerSuite(t *testing.T) {
+	suite.Run(t, new(BreakerTestSuite))
+}
+
+func (s *BreakerTestSuite) TestFailures() {
+	err := errors.New("connection refused")
+
+	// The first attempt starts at once, then the backoff doubles
+	s.Equal(time.Duration(0), s.breaker.Next())
+	s.breaker.Failure(err)
+	s.Equal(time.Second, s.breaker.Next())
+	s.breaker.Failure(err)
+	s.Equal(2*time.Second, s.breaker.Next())
+
+	// The threshold opens the circuit for the cooldown
+	s.breaker.Failure(err)
+	s.Equal(Status{State: StateOpen, Failures: 3, LastError: "connection refused", RetryAt: s.now.Add(time.Minute)}, s.breaker.Status())
+
+	// Past the cooldown a single attempt is let through, its failure opens the circuit again
+	s.now = s.now.Add(time.Minute)
+	s.breaker.Attempt()
+	s.Equal(StateHalfOpen, s.breaker.Status().State)
+	s.breaker.Failure(err)
+	s.Equal(StateOpen, s.breaker.Status().State)
+	s.Equal(time.Minute, s.breaker.Next())
+
+	// A success closes it
+	s.now = s.now.Add(time.Minute)
+	s.breaker.Attempt()
+	s.breaker.Success()
+	s.Equal(Status{State: StateClosed}, s.breaker.Status())
+	s.Equal(time.Duration(0), s.breaker.Next())
+}
+
+func (s *BreakerTestSuite) TestTrip() {
+	// A refusal opens the circuit at once, for the wait asked by the exchange
+	s.breaker.Trip(errors.New("http status 429"), 2*time.Minute)
+	s.Equal(Status{State: StateOpen, Failures: 1, LastError: "http status 429", RetryAt: s.now.Add(2 * time.Minute)}, s.breaker.Status())
+
+	// An attempt before the end of the wait leaves it open
+	s.now = s.now.Add(time.Minute)
+	s.breaker.Attempt()
+	s.Equal(StateOpen, s.breaker.Status().State)
+	s.Equal(time.Minute, s.breaker.Next())
+}
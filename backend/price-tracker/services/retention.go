@@ -0,0 +1,95 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type RetentionService interface {
+	// EnforceRetention rolls up and deletes the data past its retention window, then compacts the database
+	EnforceRetention(ctx context.Context) error
+}
+
+type RetentionServiceImpl struct {
+	repository repositories.Repository
+	cfg        config.PriceTrackerConfig
+	now        func() time.Time
+	lastVacuum time.Time
+}
+
+func NewRetentionService(repository repositories.Repository, cfg config.PriceTrackerConfig) RetentionService {
+	now := time.Now
+
+	return &RetentionServiceImpl{
+		repository: repository,
+		cfg:        cfg,
+		now:        now,
+		lastVacuum: now(),
+	}
+}
+
+// EnforceRetention rolls up and deletes the data past its retention window, then compacts the database.
+// A zero retention keeps the data forever
+func (r *RetentionServiceImpl) EnforceRetention(ctx context.Context) error {
+	now := r.now().UTC()
+
+	if r.cfg.RawRetention > 0 {
+		count, err := r.repository.ArchivePrices(ctx, now.Add(-r.cfg.RawRetention))
+		if err != nil {
+			return fmt.Errorf("archive prices: %w", err)
+		}
+
+		slog.Info("retention archived prices", "count", count)
+	}
+
+	for retention, resolutions := range r.candleRetentions() {
+		if retention <= 0 {
+			continue
+		}
+
+		count, err := r.repository.DeleteCandles(ctx, resolutions, now.Add(-retention))
+		if err != nil {
+			return fmt.Errorf("delete candles %v: %w", resolutions, err)
+		}
+
+		slog.Info("retention deleted candles", "resolutions", resolutions, "count", count)
+	}
+
+	vacuum := r.cfg.VacuumInterval > 0 && now.Sub(r.lastVacuum) >= r.cfg.VacuumInterval
+
+	err := r.repository.Compact(ctx, vacuum)
+	if err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+
+	if vacuum {
+		r.lastVacuum = now
+	}
+
+	return nil
+}
+
+// candleRetentions groups the candle resolutions by the retention of their tier:
+// minute candles are shorter than an hour, hourly candles shorter than a day
+func (r *RetentionServiceImpl) candleRetentions() map[time.Duration][]string {
+	tiers := make(map[time.Duration][]string)
+
+	for _, resolution := range models.CandleResolutions {
+		retention := r.cfg.DailyCandleRetention
+		switch {
+		case resolution.Duration < time.Hour:
+			retention = r.cfg.MinuteCandleRetention
+		case resolution.Duration < 24*time.Hour:
+			retention = r.cfg.HourlyCandleRetention
+		}
+
+		tiers[retention] = append(tiers[retention], resolution.Name)
+	}
+
+	return tiers
+}
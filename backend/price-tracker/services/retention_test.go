@@ -0,0 +1,88 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/repositories/mock"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	m "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetentionServiceTestSuite struct {
+	suite.Suite
+	mockRepo *mock.MockRepository
+	now      time.Time
+	service  *RetentionServiceImpl
+}
+
+func (s *RetentionServiceTestSuite) SetupTest() {
+	s.mockRepo = new(mock.MockRepository)
+	s.now = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	s.service = &RetentionServiceImpl{
+		repository: s.mockRepo,
+		cfg: config.PriceTrackerConfig{
+			RawRetention:          24 * time.Hour,
+			MinuteCandleRetention: 7 * 24 * time.Hour,
+			HourlyCandleRetention: 30 * 24 * time.Hour,
+			VacuumInterval:        24 * time.Hour,
+		},
+		now:        func() time.Time { return s.now },
+		lastVacuum: s.now.Add(-time.Hour),
+	}
+}
+
+func TestRetentionServiceSuite(t *testing.T) {
+	suite.Run(t, new(RetentionServiceTestSuite))
+}
+
+func (s *RetentionServiceTestSuite) TestEnforceRetention() {
+	// Arrange
+	s.mockRepo.On("ArchivePrices", s.now.Add(-24*time.Hour)).Return(int64(10), nil)
+	s.mockRepo.On("DeleteCandles", []string{"1m", "5m", "15m"}, s.now.Add(-7*24*time.Hour)).Return(int64(5), nil)
+	s.mockRepo.On("DeleteCandles", []string{"1h", "4h"}, s.now.Add(-30*24*time.Hour)).Return(int64(2), nil)
+	s.mockRepo.On("Compact", false).Return(nil)
+
+	// Act
+	err := s.service.EnforceRetention(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.mockRepo.AssertExpectations(s.T())
+	// Daily candles are kept forever
+	s.mockRepo.AssertNumberOfCalls(s.T(), "DeleteCandles", 2)
+}
+
+func (s *RetentionServiceTestSuite) TestEnforceRetention_Vacuum() {
+	// Arrange
+	s.service.cfg = config.PriceTrackerConfig{VacuumInterval: time.Hour}
+	s.mockRepo.On("Compact", true).Return(nil).Once()
+	s.mockRepo.On("Compact", false).Return(nil).Once()
+
+	// Act
+	errFirst := s.service.EnforceRetention(context.Background())
+	errSecond := s.service.EnforceRetention(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), errFirst)
+	assert.NoError(s.T(), errSecond)
+	assert.Equal(s.T(), s.now, s.service.lastVacuum)
+	s.mockRepo.AssertExpectations(s.T())
+	s.mockRepo.AssertNotCalled(s.T(), "ArchivePrices", m.Anything)
+}
+
+func (s *RetentionServiceTestSuite) TestEnforceRetention_ArchiveError() {
+	// Arrange
+	s.mockRepo.On("ArchivePrices", m.Anything).Return(int64(0), errors.New("database is locked"))
+
+	// Act
+	err := s.service.EnforceRetention(context.Background())
+
+	// Assert
+	assert.ErrorContains(s.T(), err, "archive prices: database is locked")
+	s.mockRepo.AssertNotCalled(s.T(), "Compact", m.Anything)
+}
@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// Client is a websocket connection registered to the hub. Its events wait in a bounded queue
+// until the connection writes them, events arriving while the queue is full are dropped
+type Client struct {
+	queue chan []byte
+	// markets are the subscriptions of the client, guarded by the hub
+	markets map[string]struct{}
+	dropped atomic.Int64
+}
+
+func NewClient(queueSize int) *Client {
+	return &Client{
+		queue:   make(chan []byte, queueSize),
+		markets: make(map[string]struct{}),
+	}
+}
+
+// Queue returns the events to write to the connection, it is closed once the client is unregistered
+func (c *Client) Queue() <-chan []byte {
+	return c.queue
+}
+
+// Dropped returns the number of events dropped while the queue was full
+func (c *Client) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// Send queues a message for the connection unless the queue is full,
+// it must not be called once the client is unregistered
+func (c *Client) Send(message []byte) bool {
+	select {
+	case c.queue <- message:
+		return true
+	default:
+		c.dropped.Add(1)
+		return false
+	}
+}
+
+func (c *Client) subscriptions() []string {
+	markets := make([]string, 0, len(c.markets))
+	for market := range c.markets {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+
+	return markets
+}
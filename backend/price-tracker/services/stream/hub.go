@@ -0,0 +1,183 @@
+package stream
+
+import (
+	"backend/price-tracker/models"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrTooManySubscriptions is returned when a client would exceed the subscriptions allowed per connection
+var ErrTooManySubscriptions = errors.New("too many subscriptions")
+
+// Hub fans the events of the ingestion out to the websocket clients subscribed to their market,
+// a market being a symbol and the currency it is quoted in such as BTC/USDT
+type Hub interface {
+	// Publish sends an event of each price and of the updated minute candle of its market
+	PricePublisher
+	// PublishEvent sends an event to the clients subscribed to the market of symbol and currency
+	PublishEvent(symbol, currency string, event models.ClientEvent)
+	// Register adds a client with no subscription
+	Register(client *Client)
+	// Unregister removes a client from all its subscriptions and closes its queue
+	Unregister(client *Client)
+	// Subscribe adds the markets to the subscriptions of a client and returns all of them
+	Subscribe(client *Client, markets []string) ([]string, error)
+	// Unsubscribe removes the markets from the subscriptions of a client and returns the remaining ones
+	Unsubscribe(client *Client, markets []string) []string
+}
+
+type HubImpl struct {
+	maxSubscriptions int
+	mu               sync.RWMutex
+	clients          map[*Client]struct{}
+	subscribers      map[string]map[*Client]struct{}
+	// candles are the open minute candles of each market and source, merged from the published prices
+	candlesMu sync.Mutex
+	candles   map[string]models.Candle
+}
+
+func NewHub(maxSubscriptions int) Hub {
+	return &HubImpl{
+		maxSubscriptions: maxSubscriptions,
+		clients:          make(map[*Client]struct{}),
+		subscribers:      make(map[string]map[*Client]struct{}),
+		candles:          make(map[string]models.Candle),
+	}
+}
+
+// Publish sends a price event of each datum, then a candle event of each minute candle the data updated
+func (h *HubImpl) Publish(data []models.PriceDatum) {
+	for _, datum := range data {
+		h.PublishEvent(datum.Symbol, datum.Currency, models.ClientEvent{
+			Type: models.ClientEventPrice,
+			Data: datum,
+		})
+	}
+
+	for _, candle := range h.updateCandles(data) {
+		h.PublishEvent(candle.Symbol, candle.Currency, models.ClientEvent{
+			Type: models.ClientEventCandle,
+			Data: candle,
+		})
+	}
+}
+
+// updateCandles merges the minute candles of the data into the open ones and returns those updated.
+// Prices of a minute already closed are left to the repository
+func (h *HubImpl) updateCandles(data []models.PriceDatum) []models.Candle {
+	h.candlesMu.Lock()
+	defer h.candlesMu.Unlock()
+
+	var updated []models.Candle
+	for _, candle := range models.NewCandlesFromPriceData(data) {
+		if candle.Resolution != models.CandleResolutions[0].Name {
+			continue
+		}
+
+		key := models.CacheKey(candle.Source, candle.Symbol, candle.Currency)
+		if open, ok := h.candles[key]; ok {
+			if candle.OpenTime.Before(open.OpenTime) {
+				continue
+			}
+			if candle.OpenTime.Equal(open.OpenTime) {
+				candle = open.Merge(candle)
+			}
+		}
+
+		h.candles[key] = candle
+		updated = append(updated, candle)
+	}
+
+	return updated
+}
+
+func (h *HubImpl) PublishEvent(symbol, currency string, event models.ClientEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	subscribers := h.subscribers[models.CacheKey("", symbol, currency)]
+	if len(subscribers) == 0 {
+		return
+	}
+
+	message, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("marshal client event", "type", event.Type, "err", err)
+		return
+	}
+
+	for client := range subscribers {
+		client.Send(message)
+	}
+}
+
+func (h *HubImpl) Register(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clients[client] = struct{}{}
+}
+
+func (h *HubImpl) Unregister(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+
+	for market := range client.markets {
+		h.removeSubscriber(market, client)
+	}
+	delete(h.clients, client)
+
+	// No event is enqueued once the client is out of the subscribers
+	close(client.queue)
+}
+
+// Subscribe adds the markets to the subscriptions of a client, none of them if it would exceed the limit
+func (h *HubImpl) Subscribe(client *Client, markets []string) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	added := 0
+	for _, market := range markets {
+		if _, ok := client.markets[market]; !ok {
+			added++
+		}
+	}
+	if len(client.markets)+added > h.maxSubscriptions {
+		return client.subscriptions(), ErrTooManySubscriptions
+	}
+
+	for _, market := range markets {
+		client.markets[market] = struct{}{}
+		if h.subscribers[market] == nil {
+			h.subscribers[market] = make(map[*Client]struct{})
+		}
+		h.subscribers[market][client] = struct{}{}
+	}
+
+	return client.subscriptions(), nil
+}
+
+func (h *HubImpl) Unsubscribe(client *Client, markets []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, market := range markets {
+		delete(client.markets, market)
+		h.removeSubscriber(market, client)
+	}
+
+	return client.subscriptions()
+}
+
+func (h *HubImpl) removeSubscriber(market string, client *Client) {
+	delete(h.subscribers[market], client)
+	if len(h.subscribers[market]) == 0 {
+		delete(h.subscribers, market)
+	}
+}
@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"backend/price-tracker/models"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHubImpl_Subscribe(t *testing.T) {
+	// Arrange
+	hub := NewHub(2)
+	client := NewClient(8)
+	hub.Register(client)
+
+	// Act
+	subscribed, errSubscribed := hub.Subscribe(client, []string{"BTC/USDT", "ETH/USDT"})
+	again, errAgain := hub.Subscribe(client, []string{"BTC/USDT"})
+	exceeded, errExceeded := hub.Subscribe(client, []string{"SOL/USDT"})
+	remaining := hub.Unsubscribe(client, []string{"BTC/USDT"})
+
+	// Assert
+	assert.NoError(t, errSubscribed)
+	assert.Equal(t, []string{"BTC/USDT", "ETH/USDT"}, subscribed)
+	assert.NoError(t, errAgain)
+	assert.Equal(t, []string{"BTC/USDT", "ETH/USDT"}, again)
+	assert.ErrorIs(t, errExceeded, ErrTooManySubscriptions)
+	assert.Equal(t, []string{"BTC/USDT", "ETH/USDT"}, exceeded)
+	assert.Equal(t, []string{"ETH/USDT"}, remaining)
+}
+
+func TestHubImpl_Publish(t *testing.T) {
+	// Arrange
+	hub := NewHub(10)
+	slow := NewClient(1)
+	hub.Register(slow)
+	hub.Subscribe(slow, []string{"BTC/USDT"})
+
+	start := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// Act
+	hub.Publish([]models.PriceDatum{
+		{Timestamp: start.Add(10 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(100)},
+	})
+	hub.Publish([]models.PriceDatum{
+		{Timestamp: start.Add(20 * time.Second), Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.NewFromInt(90)},
+	})
+	hub.Unregister(slow)
+
+	// Assert
+	var events []models.ClientEvent
+	for message := range slow.Queue() {
+		var event models.ClientEvent
+		assert.NoError(t, json.Unmarshal(message, &event))
+		events = append(events, event)
+	}
+	// The queue only had room for the first price, the candle events and the second price were dropped
+	assert.Len(t, events, 1)
+	assert.Equal(t, models.ClientEventPrice, events[0].Type)
+	assert.Equal(t, int64(3), slow.Dropped())
+
+	// The open candle kept merging the prices of the minute
+	candle := hub.(*HubImpl).candles["binance:BTC/USDT"]
+	assert.Equal(t, "100", candle.Open.String())
+	assert.Equal(t, "90", candle.Close.String())
+	assert.Equal(t, int64(2), candle.Count)
+}
@@ -0,0 +1,58 @@
+package stream
+
+import (
+	"backend/price-tracker/models"
+	"sync"
+)
+
+// PriceBroadcaster hands each price to the subscriptions of its market
+type PriceBroadcaster interface {
+	PricePublisher
+	// Subscribe returns a subscription to the prices published from now on matching req
+	Subscribe(req models.PriceStreamRequest) *Subscription
+	// Unsubscribe stops handing prices to the subscription
+	Unsubscribe(sub *Subscription)
+}
+
+type PriceBroadcasterImpl struct {
+	mu            sync.RWMutex
+	subscriptions map[*Subscription]struct{}
+}
+
+func NewPriceBroadcaster() PriceBroadcaster {
+	return &PriceBroadcasterImpl{
+		subscriptions: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish hands each price to the subscriptions of its symbol
+func (b *PriceBroadcasterImpl) Publish(data []models.PriceDatum) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscriptions {
+		for _, datum := range data {
+			if sub.matches(datum) {
+				sub.push(datum)
+			}
+		}
+	}
+}
+
+func (b *PriceBroadcasterImpl) Subscribe(req models.PriceStreamRequest) *Subscription {
+	sub := newSubscription(req)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions[sub] = struct{}{}
+
+	return sub
+}
+
+func (b *PriceBroadcasterImpl) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscriptions, sub)
+}
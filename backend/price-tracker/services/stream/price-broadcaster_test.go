@@ -0,0 +1,48 @@
+package stream
+
+import (
+	"backend/price-tracker/models"
+	"github.com/shopspring/decimal"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceBroadcasterImpl_Publish(t *testing.T) {
+	// Arrange
+	b := NewPriceBroadcaster()
+	all := b.Subscribe(models.PriceStreamRequest{Symbols: []string{"BTC", "ETH"}, Currency: "USDT"})
+	okx := b.Subscribe(models.PriceStreamRequest{Symbols: []string{"BTC"}, Currency: "USDT", Source: "okx"})
+	gone := b.Subscribe(models.PriceStreamRequest{Symbols: []string{"BTC"}, Currency: "USDT"})
+	b.Unsubscribe(gone)
+
+	// Act
+	b.Publish([]models.PriceDatum{
+		{Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("100")},
+		{Symbol: "ETH", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("10")},
+		{Symbol: "BTC", Currency: "EUR", Source: "binance", Price: decimal.RequireFromString("90")},
+		{Symbol: "BTC", Currency: "USDT", Source: "okx", Price: decimal.RequireFromString("101")},
+	})
+	b.Publish([]models.PriceDatum{
+		{Symbol: "BTC", Currency: "USDT", Source: "binance", Price: decimal.RequireFromString("102")},
+	})
+
+	// Assert
+	assert.Len(t, all.Ready(), 1)
+	data := all.Next()
+	assert.Len(t, data, 3)
+	// The stale binance price was replaced in place by the newer one
+	assert.Equal(t, "102", data[0].Price.String())
+	assert.Equal(t, "ETH", data[1].Symbol)
+	assert.Equal(t, "okx", data[2].Source)
+	assert.Equal(t, int64(1), all.Dropped())
+	assert.Empty(t, all.Next())
+
+	data = okx.Next()
+	assert.Len(t, data, 1)
+	assert.Equal(t, "101", data[0].Price.String())
+	assert.Zero(t, okx.Dropped())
+
+	assert.Empty(t, gone.Ready())
+	assert.Empty(t, gone.Next())
+}
@@ -0,0 +1,9 @@
+package stream
+
+import "backend/price-tracker/models"
+
+// PricePublisher is handed the prices received by the ingestion
+type PricePublisher interface {
+	// Publish hands the prices over without blocking on slow consumers
+	Publish(data []models.PriceDatum)
+}
@@ -0,0 +1,96 @@
+package stream
+
+import (
+	"backend/price-tracker/models"
+	"sync"
+	"sync/atomic"
+)
+
+// Subscription buffers the prices published for a client until it reads them.
+// The buffer holds a single price per market, so a slow client skips the stale prices
+// instead of slowing down the publisher or falling further behind
+type Subscription struct {
+	req     models.PriceStreamRequest
+	symbols map[string]struct{}
+	mu      sync.Mutex
+	pending map[string]models.PriceDatum
+	// keys are the markets of pending prices in the order they were first updated
+	keys    []string
+	ready   chan struct{}
+	dropped atomic.Int64
+}
+
+func newSubscription(req models.PriceStreamRequest) *Subscription {
+	symbols := make(map[string]struct{}, len(req.Symbols))
+	for _, symbol := range req.Symbols {
+		symbols[symbol] = struct{}{}
+	}
+
+	return &Subscription{
+		req:     req,
+		symbols: symbols,
+		pending: make(map[string]models.PriceDatum),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// Request returns the request the subscription was made for
+func (s *Subscription) Request() models.PriceStreamRequest {
+	return s.req
+}
+
+// Ready is signaled when prices are pending
+func (s *Subscription) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// Next returns the pending prices and clears them
+func (s *Subscription) Next() []models.PriceDatum {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]models.PriceDatum, 0, len(s.keys))
+	for _, key := range s.keys {
+		data = append(data, s.pending[key])
+	}
+
+	s.keys = s.keys[:0]
+	clear(s.pending)
+
+	return data
+}
+
+// Dropped returns the number of stale prices replaced before the client read them
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *Subscription) matches(datum models.PriceDatum) bool {
+	if _, ok := s.symbols[datum.Symbol]; !ok {
+		return false
+	}
+	if s.req.Currency != "" && s.req.Currency != datum.Currency {
+		return false
+	}
+
+	return s.req.Source == "" || s.req.Source == datum.Source
+}
+
+// push replaces the pending price of the datum's market, if any, and signals the client
+func (s *Subscription) push(datum models.PriceDatum) {
+	key := models.CacheKey(datum.Source, datum.Symbol, datum.Currency)
+
+	s.mu.Lock()
+	if _, ok := s.pending[key]; ok {
+		s.dropped.Add(1)
+	} else {
+		s.keys = append(s.keys, key)
+	}
+	s.pending[key] = datum
+	s.mu.Unlock()
+
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
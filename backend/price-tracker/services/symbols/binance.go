@@ -0,0 +1,120 @@
+package symbols
+
+import (
+	"backend/price-tracker/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type BinanceSymbolRegistryImpl struct {
+	baseURL     string
+	httpClient  *http.Client
+	quoteAssets []string
+	mu          sync.RWMutex
+	pairs       map[string]models.SymbolPair
+}
+
+func NewBinanceSymbolRegistry(baseURL string, quoteAssets []string) SymbolRegistry {
+	// Longest quote assets first, so BTCFDUSD is not split as BTCF/DUSD
+	sorted := append([]string(nil), quoteAssets...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	return &BinanceSymbolRegistryImpl{
+		baseURL:     baseURL,
+		quoteAssets: sorted,
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+	}
+}
+
+// Load fetches the trading markets from Binance's exchangeInfo and keeps those of tracked quote assets.
+// A failed load keeps the markets loaded before
+func (b *BinanceSymbolRegistryImpl) Load(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"/api/v3/exchangeInfo", nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http status %d", resp.StatusCode)
+	}
+
+	var info models.BinanceExchangeInfo
+	err = json.NewDecoder(resp.Body).Decode(&info)
+	if err != nil {
+		return fmt.Errorf("json decode: %w", err)
+	}
+
+	pairs := make(map[string]models.SymbolPair)
+	for _, symbol := range info.Symbols {
+		if symbol.Status != models.BinanceSymbolStatusTrading || !b.isTracked(symbol.QuoteAsset) {
+			continue
+		}
+
+		pairs[symbol.Symbol] = models.SymbolPair{
+			Symbol:   symbol.Symbol,
+			Base:     symbol.BaseAsset,
+			Quote:    symbol.QuoteAsset,
+			TickSize: symbol.TickSize(),
+		}
+	}
+
+	b.mu.Lock()
+	b.pairs = pairs
+	b.mu.Unlock()
+
+	slog.Info("loaded binance exchange info", "pairs", len(pairs))
+
+	return nil
+}
+
+// Lookup returns the base and quote asset of an exchange symbol such as BTCEUR.
+// Until the exchange info is loaded it falls back to matching the tracked quote assets as suffix
+func (b *BinanceSymbolRegistryImpl) Lookup(symbol string) (models.SymbolPair, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.pairs != nil {
+		pair, ok := b.pairs[symbol]
+		return pair, ok
+	}
+
+	for _, quote := range b.quoteAssets {
+		base, ok := strings.CutSuffix(symbol, quote)
+		if ok && base != "" {
+			return models.SymbolPair{
+				Symbol: symbol,
+				Base:   base,
+				Quote:  quote,
+			}, true
+		}
+	}
+
+	return models.SymbolPair{}, false
+}
+
+func (b *BinanceSymbolRegistryImpl) isTracked(quote string) bool {
+	for _, q := range b.quoteAssets {
+		if q == quote {
+			return true
+		}
+	}
+
+	return false
+}
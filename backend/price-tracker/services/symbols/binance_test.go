@@ -0,0 +1,122 @@
+package symbols
+
+import (
+	"backend/price-tracker/models"
+	"context"
+	"github.com/shopspring/decimal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBinanceSymbolRegistryImpl_Lookup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/exchangeInfo", r.URL.Path)
+		w.Write([]byte(`{"symbols":[
+			{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","filters":[
+				{"filterType":"PRICE_FILTER","minPrice":"0.01000000","maxPrice":"1000000.00000000","tickSize":"0.01000000"},
+				{"filterType":"LOT_SIZE","minQty":"0.00001000","maxQty":"9000.00000000","stepSize":"0.00001000"}
+			]},
+			{"symbol":"BTCEUR","status":"TRADING","baseAsset":"BTC","quoteAsset":"EUR"},
+			{"symbol":"ETHBTC","status":"TRADING","baseAsset":"ETH","quoteAsset":"BTC"},
+			{"symbol":"BTCFDUSD","status":"TRADING","baseAsset":"BTC","quoteAsset":"FDUSD"},
+			{"symbol":"BTCTRY","status":"TRADING","baseAsset":"BTC","quoteAsset":"TRY"},
+			{"symbol":"LUNAUSDT","status":"BREAK","baseAsset":"LUNA","quoteAsset":"USDT"}
+		]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		load   bool
+		symbol string
+		want   models.SymbolPair
+		found  bool
+	}{
+		{
+			name:   "usdt",
+			load:   true,
+			symbol: "BTCUSDT",
+			want:   models.SymbolPair{Symbol: "BTCUSDT", Base: "BTC", Quote: "USDT", TickSize: decimal.RequireFromString("0.01")},
+			found:  true,
+		},
+		{
+			name:   "eur",
+			load:   true,
+			symbol: "BTCEUR",
+			want:   models.SymbolPair{Symbol: "BTCEUR", Base: "BTC", Quote: "EUR"},
+			found:  true,
+		},
+		{
+			name:   "btc quoted",
+			load:   true,
+			symbol: "ETHBTC",
+			want:   models.SymbolPair{Symbol: "ETHBTC", Base: "ETH", Quote: "BTC"},
+			found:  true,
+		},
+		{
+			name:   "fdusd",
+			load:   true,
+			symbol: "BTCFDUSD",
+			want:   models.SymbolPair{Symbol: "BTCFDUSD", Base: "BTC", Quote: "FDUSD"},
+			found:  true,
+		},
+		{
+			name:   "untracked quote asset",
+			load:   true,
+			symbol: "BTCTRY",
+			found:  false,
+		},
+		{
+			name:   "not trading",
+			load:   true,
+			symbol: "LUNAUSDT",
+			found:  false,
+		},
+		{
+			name:   "fallback before load",
+			load:   false,
+			symbol: "BTCFDUSD",
+			want:   models.SymbolPair{Symbol: "BTCFDUSD", Base: "BTC", Quote: "FDUSD"},
+			found:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewBinanceSymbolRegistry(server.URL, []string{"USDT", "EUR", "BTC", "FDUSD"})
+			if tt.load {
+				assert.NoError(t, registry.Load(context.Background()))
+			}
+
+			got, found := registry.Lookup(tt.symbol)
+
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBinanceSymbolRegistryImpl_Load_RetriedAfterError(t *testing.T) {
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !failed {
+			failed = true
+			w.WriteHeader(http.StatusTeapot)
+			return
+		}
+		w.Write([]byte(`{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT"}]}`))
+	}))
+	defer server.Close()
+
+	registry := NewBinanceSymbolRegistry(server.URL, []string{"USDT"})
+
+	assert.Error(t, registry.Load(context.Background()))
+	_, found := registry.Lookup("LUNAUSDT")
+	assert.True(t, found, "split by quote asset until loaded")
+
+	assert.NoError(t, registry.Load(context.Background()))
+	_, found = registry.Lookup("LUNAUSDT")
+	assert.False(t, found)
+}
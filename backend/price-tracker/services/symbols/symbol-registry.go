@@ -0,0 +1,14 @@
+package symbols
+
+import (
+	"backend/price-tracker/models"
+	"context"
+)
+
+type SymbolRegistry interface {
+	// Load fetches the markets metadata from the exchange, replacing the one loaded before
+	Load(ctx context.Context) error
+	// Lookup returns the base and quote asset of an exchange symbol such as BTCEUR,
+	// only markets quoted in a tracked quote asset are found
+	Lookup(symbol string) (models.SymbolPair, bool)
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/reconnect"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidWebhook is returned when a webhook to create or update is not valid, wrapped with the reason
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// ErrDeliveryNotDead is returned when retrying a delivery that was not given up
+var ErrDeliveryNotDead = errors.New("only dead deliveries can be retried")
+
+// Headers of the posted payloads. The signature is the hex HMAC-SHA256, keyed with the secret of the webhook,
+// of the timestamp, a dot and the body, so that a receiver can reject a payload replayed later
+const (
+	WebhookHeaderID        = "X-Webhook-Id"
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+// maxResponseBody bounds the response of a receiver read before closing it
+const maxResponseBody = 64 << 10
+
+type WebhookService interface {
+	// GetWebhooks returns every webhook, without its secret
+	GetWebhooks(ctx context.Context) ([]models.Webhook, error)
+	// GetWebhook returns a webhook by ID without its secret, or repositories.ErrNotFound
+	GetWebhook(ctx context.Context, id int64) (*models.Webhook, error)
+	// CreateWebhook validates and stores a new webhook, with a generated secret unless it has one
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error)
+	// UpdateWebhook validates and replaces the webhook of the same ID, keeping its secret unless a new one is given
+	UpdateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error)
+	// DeleteWebhook deletes a webhook and its pending deliveries, or returns repositories.ErrNotFound
+	DeleteWebhook(ctx context.Context, id int64) error
+	// Enqueue adds a delivery of the event to every enabled webhook to the outbox
+	Enqueue(ctx context.Context, event models.WebhookEvent) error
+	// DeliverDue claims and posts the deliveries of the outbox that are due
+	DeliverDue(ctx context.Context) error
+	// GetDeliveries returns the latest deliveries, newest first
+	GetDeliveries(ctx context.Context, req models.WebhookDeliveryRequest) ([]models.WebhookDelivery, error)
+	// RetryDelivery sends a dead delivery back to the outbox, due at once with no attempt counted
+	RetryDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error)
+}
+
+type WebhookServiceImpl struct {
+	repository repositories.Repository
+	client     *http.Client
+	backoff    reconnect.Backoff
+	cfg        config.WebhookConfig
+	now        func() time.Time
+}
+
+func NewWebhookService(repository repositories.Repository, cfg config.WebhookConfig) WebhookService {
+	return &WebhookServiceImpl{
+		repository: repository,
+		client:     &http.Client{Timeout: cfg.WebhookTimeout},
+		backoff: reconnect.Backoff{
+			Initial: cfg.WebhookRetryInitialDelay,
+			Max:     cfg.WebhookRetryMaxDelay,
+			Jitter:  cfg.WebhookRetryJitter,
+		},
+		cfg: cfg,
+		now: time.Now,
+	}
+}
+
+func (w *WebhookServiceImpl) GetWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	webhooks, err := w.repository.GetWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (w *WebhookServiceImpl) GetWebhook(ctx context.Context, id int64) (*models.Webhook, error) {
+	webhook, err := w.repository.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// CreateWebhook stores a new webhook and returns it with its secret, the only time it is returned
+func (w *WebhookServiceImpl) CreateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	err := validateWebhook(&webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret, err = newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := w.now().UTC()
+	webhook.ID = 0
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+
+	err = w.repository.CreateWebhook(ctx, &webhook)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (w *WebhookServiceImpl) UpdateWebhook(ctx context.Context, webhook models.Webhook) (*models.Webhook, error) {
+	err := validateWebhook(&webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := w.repository.GetWebhook(ctx, webhook.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := webhook.Secret != ""
+	if !rotated {
+		webhook.Secret = existing.Secret
+	}
+	webhook.CreatedAt = existing.CreatedAt
+	webhook.UpdatedAt = w.now().UTC()
+
+	err = w.repository.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rotated {
+		webhook.Secret = ""
+	}
+	return &webhook, nil
+}
+
+func (w *WebhookServiceImpl) DeleteWebhook(ctx context.Context, id int64) error {
+	return w.repository.DeleteWebhook(ctx, id)
+}
+
+// Enqueue renders the event in the format of each enabled webhook and adds the deliveries to the outbox,
+// which keeps them until they are delivered across restarts
+func (w *WebhookServiceImpl) Enqueue(ctx context.Context, event models.WebhookEvent) error {
+	webhooks, err := w.repository.GetWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("get webhooks: %w", err)
+	}
+
+	now := w.now().UTC()
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if !webhook.Enabled {
+			continue
+		}
+
+		payload, err := renderWebhookPayload(webhook.Format, event)
+		if err != nil {
+			return fmt.Errorf("render %s payload: %w", webhook.Format, err)
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	err = w.repository.SaveDeliveries(ctx, deliveries)
+	if err != nil {
+		return fmt.Errorf("save deliveries: %w", err)
+	}
+
+	return nil
+}
+
+// DeliverDue claims a batch of the due deliveries and posts those of each webhook in order, the webhooks at the same
+// time so that an unreachable receiver does not hold up the others. A failed attempt is retried after a backoff,
+// up to the maximum attempts after which the delivery is dead. An attempt cut by ctx is not counted
+func (w *WebhookServiceImpl) DeliverDue(ctx context.Context) error {
+	now := w.now().UTC()
+	due, err := w.repository.ClaimDueDeliveries(ctx, now, now.Add(w.cfg.WebhookLease), w.cfg.WebhookBatchSize)
+	if err != nil {
+		return fmt.Errorf("claim due deliveries: %w", err)
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	webhooks, err := w.repository.GetWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("get webhooks: %w", err)
+	}
+	byID := make(map[int64]models.Webhook, len(webhooks))
+	for _, webhook := range webhooks {
+		byID[webhook.ID] = webhook
+	}
+
+	byWebhook := make(map[int64][]models.WebhookDelivery)
+	for _, delivery := range due {
+		byWebhook[delivery.WebhookID] = append(byWebhook[delivery.WebhookID], delivery)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(byWebhook))
+	for id, deliveries := range byWebhook {
+		webhook, ok := byID[id]
+		if !ok {
+			// Deleted since the batch was read, along with its deliveries
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- w.deliver(ctx, webhook, deliveries)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var res []error
+	for err := range errs {
+		res = append(res, err)
+	}
+	return errors.Join(res...)
+}
+
+// deliver posts the deliveries of a webhook in order. After a failed attempt the remaining ones are put back
+// due with the failed one rather than each waiting for the timeout of a receiver that is likely down
+func (w *WebhookServiceImpl) deliver(ctx context.Context, webhook models.Webhook, deliveries []models.WebhookDelivery) error {
+	var resumeAt time.Time
+	for _, delivery := range deliveries {
+		switch {
+		case !resumeAt.IsZero():
+			delivery.NextAttemptAt = resumeAt
+		case !webhook.Enabled:
+			delivery.Status = models.WebhookDeliveryDead
+			delivery.LastError = "webhook disabled"
+		default:
+			statusCode, err := w.post(ctx, webhook, delivery)
+			if ctx.Err() != nil {
+				return nil
+			}
+			w.recordAttempt(&delivery, webhook, statusCode, err)
+			if err != nil {
+				resumeAt = delivery.NextAttemptAt
+				if delivery.Status == models.WebhookDeliveryDead {
+					resumeAt = w.now().UTC()
+				}
+			}
+		}
+
+		err := w.repository.UpdateDelivery(ctx, delivery)
+		if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+			return fmt.Errorf("update delivery %d: %w", delivery.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *WebhookServiceImpl) GetDeliveries(ctx context.Context, req models.WebhookDeliveryRequest) ([]models.WebhookDelivery, error) {
+	return w.repository.GetDeliveries(ctx, req)
+}
+
+func (w *WebhookServiceImpl) RetryDelivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	delivery, err := w.repository.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != models.WebhookDeliveryDead {
+		return nil, ErrDeliveryNotDead
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = w.now().UTC()
+
+	err = w.repository.UpdateDelivery(ctx, *delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// post sends the payload of a delivery signed with the secret of its webhook,
+// and returns the status code of the response with an error unless it is a success
+func (w *WebhookServiceImpl) post(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookHeaderID, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// Drained so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxResponseBody))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	return res.StatusCode, nil
+}
+
+// recordAttempt updates a delivery with the outcome of an attempt
+func (w *WebhookServiceImpl) recordAttempt(delivery *models.WebhookDelivery, webhook models.Webhook, statusCode int, err error) {
+	now := w.now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = now
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= w.cfg.WebhookMaxAttempts {
+		delivery.Status = models.WebhookDeliveryDead
+		slog.Error("webhook delivery dead", "webhook", webhook.ID, "delivery", delivery.ID,
+			"attempts", delivery.Attempts, "err", err)
+		return
+	}
+
+	delivery.NextAttemptAt = now.Add(w.backoff.Delay(delivery.Attempts))
+	slog.Warn("webhook delivery failed", "webhook", webhook.ID, "delivery", delivery.ID,
+		"attempts", delivery.Attempts, "retry_at", delivery.NextAttemptAt, "err", err)
+}
+
+// SignWebhookPayload returns the signature header of a payload posted at the unix timestamp
+func SignWebhookPayload(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// slackMessage is the body of a Slack incoming webhook
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// discordMessage is the body of a Discord webhook
+type discordMessage struct {
+	Content string `json:"content"`
+}
+
+// renderWebhookPayload returns the body of the event in a webhook format
+func renderWebhookPayload(format string, event models.WebhookEvent) (string, error) {
+	var body any
+	switch format {
+	case models.WebhookFormatSlack:
+		body = slackMessage{Text: fmt.Sprintf("*%s*\n%s", event.Title, event.Text)}
+	case models.WebhookFormatDiscord:
+		body = discordMessage{Content: fmt.Sprintf("**%s**\n%s", event.Title, event.Text)}
+	default:
+		body = models.WebhookPayload{Type: event.Type, Time: event.Time, Data: event.Data}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(payload), nil
+}
+
+// validateWebhook checks the URL of a webhook and defaults its format to json
+func validateWebhook(webhook *models.Webhook) error {
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+
+	switch webhook.Format {
+	case "":
+		webhook.Format = models.WebhookFormatJSON
+	case models.WebhookFormatJSON, models.WebhookFormatSlack, models.WebhookFormatDiscord:
+	default:
+		return fmt.Errorf("%w: format must be one of %s, %s or %s", ErrInvalidWebhook,
+			models.WebhookFormatJSON, models.WebhookFormatSlack, models.WebhookFormatDiscord)
+	}
+
+	return nil
+}
+
+// newWebhookSecret returns a random secret of 32 bytes in hex
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"backend/internal/config"
+	"backend/price-tracker/models"
+	"backend/price-tracker/repositories/mock"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	m "github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookServiceTestSuite struct {
+	suite.Suite
+	mockRepo *mock.MockRepository
+	now      time.Time
+	service  *WebhookServiceImpl
+	// received are the requests of the receiver, answered with status
+	received chan *http.Request
+	bodies   chan string
+	status   int
+	receiver *httptest.Server
+}
+
+func (s *WebhookServiceTestSuite) SetupTest() {
+	s.mockRepo = new(mock.MockRepository)
+	s.now = time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	s.service = NewWebhookService(s.mockRepo, config.WebhookConfig{
+		WebhookBatchSize:         10,
+		WebhookLease:             time.Minute,
+		WebhookTimeout:           time.Second,
+		WebhookMaxAttempts:       3,
+		WebhookRetryInitialDelay: 10 * time.Second,
+		WebhookRetryMaxDelay:     time.Hour,
+	}).(*WebhookServiceImpl)
+	s.service.now = func() time.Time { return s.now }
+
+	s.received = make(chan *http.Request, 10)
+	s.bodies = make(chan string, 10)
+	s.status = http.StatusNoContent
+	s.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.received <- r
+		s.bodies <- string(body)
+		w.WriteHeader(s.status)
+	}))
+}
+
+func (s *WebhookServiceTestSuite) TearDownTest() {
+	s.receiver.Close()
+}
+
+func TestWebhookServiceSuite(t *testing.T) {
+	suite.Run(t, new(WebhookServiceTestSuite))
+}
+
+func (s *WebhookServiceTestSuite) webhook(id int64, format string) models.Webhook {
+	return models.Webhook{ID: id, URL: s.receiver.URL + "/hook", Format: format, Secret: "s3cret", Enabled: true}
+}
+
+func (s *WebhookServiceTestSuite) pending(id, webhookID int64, attempts int) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID:            id,
+		WebhookID:     webhookID,
+		EventType:     models.WebhookEventAlert,
+		Payload:       `{"text":"*breakout*\nBTC/USDT rose above 100 at 101 on binance"}`,
+		Status:        models.WebhookDeliveryPending,
+		Attempts:      attempts,
+		NextAttemptAt: s.now,
+	}
+}
+
+func (s *WebhookServiceTestSuite) TestCreateWebhook() {
+	// Arrange
+	s.mockRepo.On("CreateWebhook", m.Anything).Run(func(args m.Arguments) {
+		args.Get(0).(*models.Webhook).ID = 1
+	}).Return(nil)
+
+	// Act
+	webhook, err := s.service.CreateWebhook(context.Background(), models.Webhook{URL: " https://hooks.example.com/a "})
+	_, errURL := s.service.CreateWebhook(context.Background(), models.Webhook{URL: "hooks.example.com/a"})
+	_, errFormat := s.service.CreateWebhook(context.Background(), models.Webhook{URL: "https://hooks.example.com/a", Format: "teams"})
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(1), webhook.ID)
+	assert.Equal(s.T(), "https://hooks.example.com/a", webhook.URL)
+	assert.Equal(s.T(), models.WebhookFormatJSON, webhook.Format)
+	assert.Len(s.T(), webhook.Secret, 64)
+	assert.ErrorIs(s.T(), errURL, ErrInvalidWebhook)
+	assert.ErrorIs(s.T(), errFormat, ErrInvalidWebhook)
+	s.mockRepo.AssertNumberOfCalls(s.T(), "CreateWebhook", 1)
+}
+
+func (s *WebhookServiceTestSuite) TestUpdateWebhook_KeepsSecret() {
+	// Arrange
+	existing := s.webhook(2, models.WebhookFormatJSON)
+	s.mockRepo.On("GetWebhook", int64(2)).Return(&existing, nil)
+	updated := existing
+	updated.Format = models.WebhookFormatDiscord
+	updated.UpdatedAt = s.now
+	s.mockRepo.On("UpdateWebhook", updated).Return(nil)
+
+	// Act
+	webhook, err := s.service.UpdateWebhook(context.Background(), models.Webhook{
+		ID: 2, URL: existing.URL, Format: models.WebhookFormatDiscord, Enabled: true,
+	})
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), webhook.Secret)
+	s.mockRepo.AssertExpectations(s.T())
+}
+
+func (s *WebhookServiceTestSuite) TestEnqueue() {
+	// Arrange
+	disabled := s.webhook(4, models.WebhookFormatJSON)
+	disabled.Enabled = false
+	s.mockRepo.On("GetWebhooks").Return([]models.Webhook{
+		s.webhook(1, models.WebhookFormatJSON),
+		s.webhook(2, models.WebhookFormatSlack),
+		s.webhook(3, models.WebhookFormatDiscord),
+		disabled,
+	}, nil)
+	s.mockRepo.On("SaveDeliveries", m.Anything).Return(nil)
+	event := models.AlertEvent{
+		RuleID:    7,
+		Name:      "breakout",
+		Symbol:    "BTC",
+		Currency:  "USDT",
+		Price:     decimal.NewFromInt(101),
+		Reference: decimal.NewFromInt(100),
+		Message:   "BTC/USDT rose above 100 at 101 on binance",
+		FiredAt:   s.now,
+	}
+
+	// Act
+	err := s.service.Enqueue(context.Background(), event.WebhookEvent())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	deliveries := s.mockRepo.Calls[1].Arguments.Get(0).([]models.WebhookDelivery)
+	s.Require().Len(deliveries, 3)
+	assert.JSONEq(s.T(), `{"type":"alert","time":"2025-06-01T10:00:00Z","data":{"id":0,"rule_id":7,"name":"breakout",
+		"symbol":"BTC","currency":"USDT","source":"","kind":"","price":"101","reference":"100",
+		"message":"BTC/USDT rose above 100 at 101 on binance","fired_at":"2025-06-01T10:00:00Z"}}`, deliveries[0].Payload)
+	assert.JSONEq(s.T(), `{"text":"*breakout*\nBTC/USDT rose above 100 at 101 on binance"}`, deliveries[1].Payload)
+	assert.JSONEq(s.T(), `{"content":"**breakout**\nBTC/USDT rose above 100 at 101 on binance"}`, deliveries[2].Payload)
+	for _, delivery := range deliveries {
+		assert.Equal(s.T(), models.WebhookDeliveryPending, delivery.Status)
+		assert.Equal(s.T(), models.WebhookEventAlert, delivery.EventType)
+		assert.Equal(s.T(), s.now, delivery.NextAttemptAt)
+	}
+}
+
+func (s *WebhookServiceTestSuite) TestDeliverDue_Signed() {
+	// Arrange
+	delivery := s.pending(9, 1, 0)
+	s.mockRepo.On("ClaimDueDeliveries", s.now, s.now.Add(time.Minute), 10).Return([]models.WebhookDelivery{delivery}, nil)
+	s.mockRepo.On("GetWebhooks").Return([]models.Webhook{s.webhook(1, models.WebhookFormatSlack)}, nil)
+	delivered := delivery
+	delivered.Status = models.WebhookDeliveryDelivered
+	delivered.Attempts = 1
+	delivered.LastStatusCode = http.StatusNoContent
+	delivered.DeliveredAt = s.now
+	s.mockRepo.On("UpdateDelivery", delivered).Return(nil)
+
+	// Act
+	err := s.service.DeliverDue(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	req := <-s.received
+	body := <-s.bodies
+	assert.Equal(s.T(), "/hook", req.URL.Path)
+	assert.Equal(s.T(), "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(s.T(), "9", req.Header.Get(WebhookHeaderID))
+	assert.Equal(s.T(), "alert", req.Header.Get(WebhookHeaderEvent))
+	assert.Equal(s.T(), "1748772000", req.Header.Get(WebhookHeaderTimestamp))
+	assert.Equal(s.T(), delivery.Payload, body)
+	// printf '1748772000.%s' "$body" | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(s.T(), "sha256=efe69d84d096e895781f8472fd10db0dd2f18cf14ee8a2efe8fe04b16c9aba65",
+		req.Header.Get(WebhookHeaderSignature))
+	s.mockRepo.AssertExpectations(s.T())
+}
+
+func (s *WebhookServiceTestSuite) TestDeliverDue_RetriesThenDead() {
+	// Arrange
+	s.status = http.StatusInternalServerError
+	s.mockRepo.On("ClaimDueDeliveries", s.now, s.now.Add(time.Minute), 10).Return([]models.WebhookDelivery{
+		s.pending(1, 1, 1),
+		s.pending(2, 1, 0),
+		s.pending(3, 2, 2),
+		s.pending(4, 5, 0),
+	}, nil)
+	disabled := s.webhook(5, models.WebhookFormatJSON)
+	disabled.Enabled = false
+	s.mockRepo.On("GetWebhooks").Return([]models.Webhook{
+		s.webhook(1, models.WebhookFormatJSON),
+		s.webhook(2, models.WebhookFormatJSON),
+		disabled,
+	}, nil)
+	s.mockRepo.On("UpdateDelivery", m.Anything).Return(nil)
+
+	// Act
+	err := s.service.DeliverDue(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	updates := s.updates()
+	s.Require().Len(updates, 4)
+
+	// The second failure waits twice the initial delay
+	assert.Equal(s.T(), models.WebhookDeliveryPending, updates[0].Status)
+	assert.Equal(s.T(), 2, updates[0].Attempts)
+	assert.Equal(s.T(), s.now.Add(20*time.Second), updates[0].NextAttemptAt)
+	assert.Equal(s.T(), http.StatusInternalServerError, updates[0].LastStatusCode)
+	assert.Equal(s.T(), "unexpected status 500 Internal Server Error", updates[0].LastError)
+	// The next one of the webhook is not posted, it waits for the failed one
+	assert.Equal(s.T(), models.WebhookDeliveryPending, updates[1].Status)
+	assert.Equal(s.T(), 0, updates[1].Attempts)
+	assert.Equal(s.T(), s.now.Add(20*time.Second), updates[1].NextAttemptAt)
+	// The third is the last
+	assert.Equal(s.T(), models.WebhookDeliveryDead, updates[2].Status)
+	assert.Equal(s.T(), 3, updates[2].Attempts)
+	// A disabled webhook is not posted to
+	assert.Equal(s.T(), models.WebhookDeliveryDead, updates[3].Status)
+	assert.Equal(s.T(), 0, updates[3].Attempts)
+	assert.Equal(s.T(), "webhook disabled", updates[3].LastError)
+	assert.Len(s.T(), s.received, 2)
+}
+
+func (s *WebhookServiceTestSuite) TestDeliverDue_WebhooksAtTheSameTime() {
+	// Arrange, a receiver answering once both webhooks have posted to it, or timing out
+	var posted sync.WaitGroup
+	posted.Add(2)
+	both := make(chan struct{})
+	go func() {
+		posted.Wait()
+		close(both)
+	}()
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		posted.Done()
+		select {
+		case <-both:
+			w.WriteHeader(http.StatusNoContent)
+		case <-r.Context().Done():
+		}
+	}))
+	defer receiver.Close()
+	first := s.webhook(1, models.WebhookFormatJSON)
+	first.URL = receiver.URL
+	second := s.webhook(2, models.WebhookFormatJSON)
+	second.URL = receiver.URL
+	s.mockRepo.On("ClaimDueDeliveries", s.now, s.now.Add(time.Minute), 10).Return([]models.WebhookDelivery{
+		s.pending(1, 1, 0),
+		s.pending(2, 2, 0),
+	}, nil)
+	s.mockRepo.On("GetWebhooks").Return([]models.Webhook{first, second}, nil)
+	s.mockRepo.On("UpdateDelivery", m.Anything).Return(nil)
+
+	// Act
+	err := s.service.DeliverDue(context.Background())
+
+	// Assert
+	assert.NoError(s.T(), err)
+	updates := s.updates()
+	s.Require().Len(updates, 2)
+	assert.Equal(s.T(), models.WebhookDeliveryDelivered, updates[0].Status)
+	assert.Equal(s.T(), models.WebhookDeliveryDelivered, updates[1].Status)
+}
+
+// updates returns the deliveries updated in the repository, by ID
+func (s *WebhookServiceTestSuite) updates() []models.WebhookDelivery {
+	var res []models.WebhookDelivery
+	for _, call := range s.mockRepo.Calls {
+		if call.Method == "UpdateDelivery" {
+			res = append(res, call.Arguments.Get(0).(models.WebhookDelivery))
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
+
+func (s *WebhookServiceTestSuite) TestDeliverDue_ShutdownDoesNotCountAttempt() {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer blocked.Close()
+	webhook := s.webhook(1, models.WebhookFormatJSON)
+	webhook.URL = blocked.URL
+	s.mockRepo.On("ClaimDueDeliveries", s.now, s.now.Add(time.Minute), 10).Return([]models.WebhookDelivery{s.pending(1, 1, 0)}, nil)
+	s.mockRepo.On("GetWebhooks").Return([]models.Webhook{webhook}, nil)
+
+	// Act
+	err := s.service.DeliverDue(ctx)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	s.mockRepo.AssertNotCalled(s.T(), "UpdateDelivery", m.Anything)
+}
+
+func (s *WebhookServiceTestSuite) TestRetryDelivery() {
+	// Arrange
+	dead := s.pending(1, 1, 3)
+	dead.Status = models.WebhookDeliveryDead
+	dead.NextAttemptAt = s.now.Add(-time.Hour)
+	s.mockRepo.On("GetDelivery", int64(1)).Return(&dead, nil)
+	delivered := s.pending(2, 1, 1)
+	delivered.Status = models.WebhookDeliveryDelivered
+	s.mockRepo.On("GetDelivery", int64(2)).Return(&delivered, nil)
+	retried := s.pending(1, 1, 0)
+	s.mockRepo.On("UpdateDelivery", retried).Return(nil)
+
+	// Act
+	res, err := s.service.RetryDelivery(context.Background(), 1)
+	_, errDelivered := s.service.RetryDelivery(context.Background(), 2)
+
+	// Assert
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), retried, *res)
+	assert.ErrorIs(s.T(), errDelivered, ErrDeliveryNotDead)
+	s.mockRepo.AssertExpectations(s.T())
+}
@@ -2,42 +2,65 @@ package ws
 
 import (
 	"backend/price-tracker/models"
-	"backend/price-tracker/repositories"
+	"backend/price-tracker/services/bus"
+	"backend/price-tracker/services/symbols"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type BinanceWebsocketImpl struct {
-	url           string
-	cache         *sync.Map
-	repository    repositories.Repository
-	fetchInterval time.Duration
+	tickPublisher
+	url     string
+	streams []string
+	// tradeSymbols are priced by their trade stream, left out of the mini tickers
+	tradeSymbols map[string]bool
+	registry     symbols.SymbolRegistry
+	requestID    atomic.Int64
+	writeMu      sync.Mutex
 }
 
 func NewBinanceWebsocket(
-	cache *sync.Map,
 	url string,
-	fetchInterval int64,
-	repository repositories.Repository,
+	tradeSymbols []string,
+	tickerSymbols []string,
+	registry symbols.SymbolRegistry,
+	prices bus.Publisher[models.PriceTick],
+	stats bus.Publisher[models.MarketStatsTick],
 ) WebSocketFetcher {
+	streams := []string{models.BinanceMiniTickerArrStream}
+	traded := make(map[string]bool, len(tradeSymbols))
+	for _, symbol := range tradeSymbols {
+		streams = append(streams, models.BinanceTradeStream(symbol))
+		traded[strings.ToUpper(symbol)] = true
+	}
+	for _, symbol := range tickerSymbols {
+		streams = append(streams, models.BinanceTickerStream(symbol))
+	}
+
 	return &BinanceWebsocketImpl{
-		cache:         cache,
-		url:           url,
-		repository:    repository,
-		fetchInterval: time.Duration(fetchInterval),
+		tickPublisher: tickPublisher{
+			prices: prices,
+			stats:  stats,
+		},
+		url:          url,
+		streams:      streams,
+		tradeSymbols: traded,
+		registry:     registry,
 	}
 }
 
 // Connect returns the connection to websocket
-func (b *BinanceWebsocketImpl) Connect() (*websocket.Conn, error) {
-	conn, _, err := websocket.DefaultDialer.Dial(b.url, nil)
+func (b *BinanceWebsocketImpl) Connect(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := dial(ctx, b.url)
 	if err != nil {
-		return nil, fmt.Errorf("dial: %w", err)
+		return nil, err
 	}
 
 	slog.Info("connected to binance websocket")
@@ -45,70 +68,156 @@ func (b *BinanceWebsocketImpl) Connect() (*websocket.Conn, error) {
 	return conn, nil
 }
 
-// Fetch keeps running every few seconds to retrieve latest price from given connection
-func (b *BinanceWebsocketImpl) Fetch(conn *websocket.Conn) error {
+// Fetch subscribes to the configured streams and keeps reading market updates from given connection
+func (b *BinanceWebsocketImpl) Fetch(ctx context.Context, conn *websocket.Conn) error {
 	defer conn.Close()
+	defer closeOnDone(ctx, conn)()
 
-	slog.Info("fetching price from binance")
+	slog.Info("subscribing to binance streams", "streams", b.streams)
 
-	for {
-		// Request latest price
-		err := conn.WriteJSON(map[string]interface{}{
-			"id":     "043a7cf2-bde3-4888-9604-c8ac41fcba4d",
-			"method": "ticker.price",
-		})
-		if err != nil {
-			return fmt.Errorf("write: %w", err)
-		}
+	err := b.Subscribe(conn, b.streams...)
+	if err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
 
-		// Read the response
+	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
 			return fmt.Errorf("read: %w", err)
 		}
 
-		// Parse response
-		var priceTicker models.BinancePriceTicker
-		if err := json.Unmarshal(message, &priceTicker); err != nil {
-			return fmt.Errorf("unmarshal: %w", err)
-		}
-		if priceTicker.Status != 200 {
-			return errors.New("price not available")
+		err = b.handleMessage(message)
+		if err != nil {
+			return err
 		}
+	}
+}
+
+// Subscribe sends a SUBSCRIBE control frame for the given streams
+func (b *BinanceWebsocketImpl) Subscribe(conn *websocket.Conn, streams ...string) error {
+	return b.sendControl(conn, models.BinanceMethodSubscribe, streams)
+}
+
+func (b *BinanceWebsocketImpl) sendControl(conn *websocket.Conn, method string, streams []string) error {
+	req := models.BinanceStreamRequest{
+		Method: method,
+		Params: streams,
+		ID:     b.requestID.Add(1),
+	}
 
-		// Update latest price to cache and db
-		go b.updateLatestPrice(priceTicker)
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
 
-		time.Sleep(b.fetchInterval * time.Second)
+	err := conn.WriteJSON(req)
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
 	}
+
+	return nil
 }
 
-func (b *BinanceWebsocketImpl) updateLatestPrice(priceTicker models.BinancePriceTicker) {
-	var bulk []models.PriceDatum
+// handleMessage parses a frame from the combined stream and dispatches it by stream name
+func (b *BinanceWebsocketImpl) handleMessage(message []byte) error {
+	var msg models.BinanceStreamMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if msg.IsControlResponse() {
+		if msg.Error != nil {
+			return fmt.Errorf("control request %d: %w", *msg.ID, msg.Error)
+		}
 
-	timestamp := time.Now() // set fixed timestamp for this batch
+		slog.Info("binance control request acknowledged", "id", *msg.ID)
+		return nil
+	}
 
-	for _, res := range priceTicker.BinanceResult {
-		if res.IsCurrencyUSDT() {
+	switch {
+	case msg.Stream == models.BinanceMiniTickerArrStream:
+		var tickers []models.BinanceMiniTicker
+		if err := json.Unmarshal(msg.Data, &tickers); err != nil {
+			return fmt.Errorf("unmarshal mini ticker: %w", err)
+		}
 
-			datum, err := models.NewPriceDatumFromBinanceResult(res, timestamp)
-			if err != nil {
-				slog.Error("create price datum from binance", "err", err)
+		// Each ticker is stamped with its own event time, the symbols with a trade stream are priced by their trades
+		prices := make([]binancePrice, 0, len(tickers))
+		for _, ticker := range tickers {
+			if b.tradeSymbols[ticker.Symbol] {
 				continue
 			}
+			prices = append(prices, binancePrice{result: ticker.ToBinanceResult(), timestamp: ticker.Time()})
+		}
+
+		// Publish the latest price
+		b.updateLatestPrice(prices)
+
+	case models.IsBinanceTradeStream(msg.Stream):
+		var trade models.BinanceTrade
+		if err := json.Unmarshal(msg.Data, &trade); err != nil {
+			return fmt.Errorf("unmarshal trade: %w", err)
+		}
 
-			b.cache.Store(datum.Symbol, datum)
+		// Publish the latest price
+		b.updateLatestPrice([]binancePrice{{result: trade.ToBinanceResult(), timestamp: trade.Time()}})
 
-			bulk = append(bulk, datum)
+	case models.IsBinanceTickerStream(msg.Stream):
+		var ticker models.BinanceTicker
+		if err := json.Unmarshal(msg.Data, &ticker); err != nil {
+			return fmt.Errorf("unmarshal ticker: %w", err)
 		}
+
+		// Publish the latest stats
+		b.updateMarketStats(ticker)
+
+	default:
+		slog.Warn("unknown binance stream", "stream", msg.Stream)
 	}
 
-	if len(bulk) > 0 {
-		err := b.repository.BulkInsert(bulk)
+	return nil
+}
+
+// binancePrice is a price received from a stream with the time of its event
+type binancePrice struct {
+	result    models.BinanceResult
+	timestamp time.Time
+}
+
+func (b *BinanceWebsocketImpl) updateLatestPrice(prices []binancePrice) {
+	var bulk []models.PriceDatum
+
+	for _, price := range prices {
+		// Skip markets not quoted in a tracked quote asset
+		pair, ok := b.registry.Lookup(price.result.Symbol)
+		if !ok {
+			continue
+		}
+
+		datum, err := models.NewPriceDatumFromBinanceResult(price.result, pair, price.timestamp)
 		if err != nil {
-			slog.Error("bulk insert", "err", err)
+			slog.Error("create price datum from binance", "err", err)
+			continue
 		}
 
-		slog.Info("bulk insert", "count", len(bulk))
+		bulk = append(bulk, datum)
+	}
+
+	b.publishPrices(bulk)
+}
+
+func (b *BinanceWebsocketImpl) updateMarketStats(ticker models.BinanceTicker) {
+	pair, ok := b.registry.Lookup(ticker.Symbol)
+	if !ok {
+		return
+	}
+
+	stats, err := ticker.ToMarketStats(pair)
+	if err != nil {
+		slog.Error("create market stats from binance", "err", err)
+		return
 	}
+
+	b.publishMarketStats([]models.MarketStats{stats})
 }
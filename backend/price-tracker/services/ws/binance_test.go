@@ -2,29 +2,35 @@ package ws
 
 import (
 	"backend/price-tracker/models"
-	"backend/price-tracker/repositories/mock"
-	"sync"
+	"backend/price-tracker/services/symbols"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
-	m "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 )
 
 type BinanceWebsocketTestSuite struct {
 	suite.Suite
-	mockRepo *mock.MockRepository
-	cache    *sync.Map
-	ws       BinanceWebsocketImpl
+	prices publishedTicks[models.PriceTick]
+	stats  publishedTicks[models.MarketStatsTick]
+	ws     *BinanceWebsocketImpl
 }
 
 func (s *BinanceWebsocketTestSuite) SetupTest() {
-	s.mockRepo = new(mock.MockRepository)
-	s.cache = &sync.Map{}
-	s.ws = BinanceWebsocketImpl{
-		cache:      s.cache,
-		repository: s.mockRepo,
+	s.prices = make(publishedTicks[models.PriceTick], 1)
+	s.stats = make(publishedTicks[models.MarketStatsTick], 1)
+	s.ws = &BinanceWebsocketImpl{
+		tickPublisher: tickPublisher{
+			prices: s.prices,
+			stats:  s.stats,
+		},
+		registry: symbols.NewBinanceSymbolRegistry("", []string{"USDT"}),
 	}
 }
 
@@ -34,51 +40,262 @@ func TestBinanceWebsocketSuite(t *testing.T) {
 
 func (s *BinanceWebsocketTestSuite) TestUpdateLatestPrice() {
 	// Arrange
-	expect := []models.PriceDatum{
+	timestamp := time.Now().UTC()
+	prices := []binancePrice{
 		{
-			Timestamp: time.Now().UTC(),
-			Symbol:    "BTC",
-			Currency:  "USDT",
-			Price:     100.0,
+			result:    models.BinanceResult{Symbol: "BTCUSDT", Price: "100.0"},
+			timestamp: timestamp,
 		},
 		{
-			Timestamp: time.Now().UTC(),
-			Symbol:    "ABC",
-			Currency:  "USDT",
-			Price:     200.0,
-		},
-	}
-	priceTicker := models.BinancePriceTicker{
-		Status: 200,
-		BinanceResult: []models.BinanceResult{
-			{
-				Symbol: "BTCUSDT",
-				Price:  "100.0",
-			},
-			{
-				Symbol: "ABCUSDT",
-				Price:  "200.0",
-			},
+			result:    models.BinanceResult{Symbol: "ABCUSDT", Price: "200.0"},
+			timestamp: timestamp.Add(-time.Second),
 		},
 	}
 
 	// Act
-	s.mockRepo.On("BulkInsert", m.MatchedBy(func(data []models.PriceDatum) bool {
-		return len(data) == 2 &&
-			data[0].Symbol == "BTC" && data[0].Price == 100.0 &&
-			data[1].Symbol == "ABC" && data[1].Price == 200.0
-	})).Return(nil)
+	s.ws.updateLatestPrice(prices)
 
-	s.ws.updateLatestPrice(priceTicker)
+	// Assert
+	tick := <-s.prices
+	assert.Len(s.T(), tick.Prices, 2)
+	assert.Equal(s.T(), "BTC", tick.Prices[0].Symbol)
+	assert.Equal(s.T(), "USDT", tick.Prices[0].Currency)
+	assert.Equal(s.T(), "binance", tick.Prices[0].Source)
+	assert.Equal(s.T(), "100", tick.Prices[0].Price.String())
+	assert.Equal(s.T(), timestamp, tick.Prices[0].Timestamp)
+	assert.Equal(s.T(), "ABC", tick.Prices[1].Symbol)
+	assert.Equal(s.T(), "200", tick.Prices[1].Price.String())
+	assert.Equal(s.T(), timestamp.Add(-time.Second), tick.Prices[1].Timestamp)
+	assert.False(s.T(), tick.ReceivedAt.IsZero())
+}
+
+func (s *BinanceWebsocketTestSuite) TestUpdateLatestPrice_QuoteAssets() {
+	// Arrange
+	s.ws.registry = symbols.NewBinanceSymbolRegistry("", []string{"USDT", "EUR", "BTC", "FDUSD"})
+	timestamp := time.Now().UTC()
+	prices := []binancePrice{
+		{result: models.BinanceResult{Symbol: "BTCEUR", Price: "90000.0"}, timestamp: timestamp},
+		{result: models.BinanceResult{Symbol: "ETHBTC", Price: "0.05"}, timestamp: timestamp},
+		{result: models.BinanceResult{Symbol: "BTCFDUSD", Price: "95000.0"}, timestamp: timestamp},
+		{result: models.BinanceResult{Symbol: "BTCTRY", Price: "3000000.0"}, timestamp: timestamp},
+	}
+
+	// Act
+	s.ws.updateLatestPrice(prices)
 
 	// Assert
-	if value, ok := s.cache.Load("BTC"); ok {
-		assert.Equal(s.T(), value.(models.PriceDatum).Symbol, expect[0].Symbol)
-		assert.Equal(s.T(), value.(models.PriceDatum).Price, expect[0].Price)
+	var pairs []string
+	for _, datum := range (<-s.prices).Prices {
+		pairs = append(pairs, datum.Symbol+"/"+datum.Currency)
 	}
+	assert.Equal(s.T(), []string{"BTC/EUR", "ETH/BTC", "BTC/FDUSD"}, pairs)
+}
+
+func (s *BinanceWebsocketTestSuite) TestUpdateLatestPrice_NoTrackedMarket() {
+	// Act
+	s.ws.updateLatestPrice([]binancePrice{{result: models.BinanceResult{Symbol: "BTCTRY", Price: "3000000.0"}, timestamp: time.Now().UTC()}})
+
+	// Assert
+	assert.Empty(s.T(), s.prices)
+}
+
+func (s *BinanceWebsocketTestSuite) TestHandleMessage_MiniTickerArr() {
+	// Arrange
+	message := `{"stream":"!miniTicker@arr","data":[
+		{"e":"24hrMiniTicker","E":1700000000000,"s":"BTCUSDT","c":"100.5","o":"99","h":"101","l":"98","v":"10","q":"1000"},
+		{"e":"24hrMiniTicker","E":1700000000000,"s":"ETHBTC","c":"0.05","o":"0.05","h":"0.05","l":"0.05","v":"1","q":"1"}
+	]}`
+
+	// Act
+	err := s.ws.handleMessage([]byte(message))
+
+	// Assert
+	assert.NoError(s.T(), err)
+	data := (<-s.prices).Prices
+	assert.Len(s.T(), data, 1)
+	assert.Equal(s.T(), "BTC", data[0].Symbol)
+	assert.Equal(s.T(), "100.5", data[0].Price.String())
+	assert.Equal(s.T(), time.UnixMilli(1700000000000).UTC(), data[0].Timestamp)
+}
+
+func (s *BinanceWebsocketTestSuite) TestHandleMessage_MiniTickerArrEventTimes() {
+	// Arrange
+	message := `{"stream":"!miniTicker@arr","data":[
+		{"e":"24hrMiniTicker","E":1700000000000,"s":"BTCUSDT","c":"100.5","o":"99","h":"101","l":"98","v":"10","q":"1000"},
+		{"e":"24hrMiniTicker","E":1699999999000,"s":"ETHUSDT","c":"2000","o":"2000","h":"2000","l":"2000","v":"1","q":"1"}
+	]}`
+
+	// Act
+	err := s.ws.handleMessage([]byte(message))
+
+	// Assert
+	assert.NoError(s.T(), err)
+	data := (<-s.prices).Prices
+	s.Require().Len(data, 2)
+	assert.Equal(s.T(), time.UnixMilli(1700000000000).UTC(), data[0].Timestamp)
+	assert.Equal(s.T(), time.UnixMilli(1699999999000).UTC(), data[1].Timestamp)
+}
+
+func (s *BinanceWebsocketTestSuite) TestHandleMessage_MiniTickerArrSkipsTradedSymbols() {
+	// Arrange
+	s.ws.tradeSymbols = map[string]bool{"BTCUSDT": true}
+	message := `{"stream":"!miniTicker@arr","data":[
+		{"e":"24hrMiniTicker","E":1700000000000,"s":"BTCUSDT","c":"100.5","o":"99","h":"101","l":"98","v":"10","q":"1000"},
+		{"e":"24hrMiniTicker","E":1700000000000,"s":"ETHUSDT","c":"2000","o":"2000","h":"2000","l":"2000","v":"1","q":"1"}
+	]}`
 
-	if value, ok := s.cache.Load("ABC"); ok {
-		assert.Equal(s.T(), value.(models.PriceDatum).Symbol, expect[1].Symbol)
-		assert.Equal(s.T(), value.(models.PriceDatum).Price, expect[1].Price)
+	// Act
+	err := s.ws.handleMessage([]byte(message))
+
+	// Assert
+	assert.NoError(s.T(), err)
+	data := (<-s.prices).Prices
+	s.Require().Len(data, 1)
+	assert.Equal(s.T(), "ETH", data[0].Symbol)
+}
+
+func (s *BinanceWebsocketTestSuite) TestHandleMessage_Trade() {
+	// Arrange
+	message := `{"stream":"btcusdt@trade","data":{"e":"trade","E":1700000000001,"s":"BTCUSDT","t":1,"p":"101.25","q":"0.1","T":1700000000000}}`
+
+	// Act
+	err := s.ws.handleMessage([]byte(message))
+
+	// Assert
+	assert.NoError(s.T(), err)
+	data := (<-s.prices).Prices
+	assert.Len(s.T(), data, 1)
+	assert.Equal(s.T(), "101.25", data[0].Price.String())
+}
+
+func (s *BinanceWebsocketTestSuite) TestHandleMessage_Ticker() {
+	// Arrange
+	message := `{"stream":"btcusdt@ticker","data":{"e":"24hrTicker","E":1700000000000,"s":"BTCUSDT","p":"10","P":"1.5","c":"101","Q":"0.2","b":"100.9","B":"3","a":"101.1","A":"4","o":"91","h":"105","l":"90","v":"1000","q":"100000","O":1699913600000,"C":1700000000000,"L":42}}`
+
+	// Act
+	err := s.ws.handleMessage([]byte(message))
+
+	// Assert
+	assert.NoError(s.T(), err)
+	stats := (<-s.stats).Stats
+	assert.Len(s.T(), stats, 1)
+	assert.Equal(s.T(), "BTC", stats[0].Symbol)
+	assert.Equal(s.T(), "100.9", stats[0].BidPrice.String())
+	assert.Equal(s.T(), "101.1", stats[0].AskPrice.String())
+	assert.Equal(s.T(), "105", stats[0].High.String())
+}
+
+func (s *BinanceWebsocketTestSuite) TestHandleMessage_ControlResponse() {
+	// Act
+	errAck := s.ws.handleMessage([]byte(`{"result":null,"id":1}`))
+	errFail := s.ws.handleMessage([]byte(`{"error":{"code":2,"msg":"Invalid request"},"id":2}`))
+
+	// Assert
+	assert.NoError(s.T(), errAck)
+	assert.ErrorContains(s.T(), errFail, "Invalid request")
+	assert.Empty(s.T(), s.prices)
+}
+
+func TestBinanceWebsocketImpl_Fetch(t *testing.T) {
+	// Arrange
+	requests := make(chan models.BinanceStreamRequest, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var req models.BinanceStreamRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		requests <- req
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"result":null,"id":1}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@trade","data":{"e":"trade","s":"BTCUSDT","p":"1.5","T":1700000000000}}`))
+	}))
+	defer server.Close()
+
+	prices := make(publishedTicks[models.PriceTick], 1)
+	b := NewBinanceWebsocket("ws"+strings.TrimPrefix(server.URL, "http"), []string{"BTCUSDT"}, nil,
+		symbols.NewBinanceSymbolRegistry("", []string{"USDT"}), prices, make(publishedTicks[models.MarketStatsTick]))
+
+	// Act
+	conn, err := b.Connect(context.Background())
+	assert.NoError(t, err)
+	err = b.Fetch(context.Background(), conn)
+
+	// Assert
+	assert.Error(t, err)
+
+	req := <-requests
+	assert.Equal(t, models.BinanceMethodSubscribe, req.Method)
+	assert.Equal(t, []string{"!miniTicker@arr", "btcusdt@trade"}, req.Params)
+
+	select {
+	case tick := <-prices:
+		assert.Equal(t, "1.5", tick.Prices[0].Price.String())
+	default:
+		t.Fatal("no price was published")
 	}
 }
+
+func TestBinanceWebsocketImpl_Fetch_Cancel(t *testing.T) {
+	// Arrange
+	closed := make(chan error, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// The default close handler answers the close frame of the client
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	b := NewBinanceWebsocket("ws"+strings.TrimPrefix(server.URL, "http"), []string{"BTCUSDT"}, nil,
+		symbols.NewBinanceSymbolRegistry("", []string{"USDT"}),
+		make(publishedTicks[models.PriceTick], 1), make(publishedTicks[models.MarketStatsTick]))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act
+	conn, err := b.Connect(ctx)
+	assert.NoError(t, err)
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err = b.Fetch(ctx, conn)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, websocket.IsCloseError(<-closed, websocket.CloseNormalClosure))
+}
+
+func TestBinanceWebsocketImpl_Connect_Refused(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	b := NewBinanceWebsocket("ws"+strings.TrimPrefix(server.URL, "http"), []string{"BTCUSDT"}, nil,
+		symbols.NewBinanceSymbolRegistry("", []string{"USDT"}),
+		make(publishedTicks[models.PriceTick], 1), make(publishedTicks[models.MarketStatsTick]))
+
+	// Act
+	_, err := b.Connect(context.Background())
+
+	// Assert
+	var handshakeErr *HandshakeError
+	assert.ErrorAs(t, err, &handshakeErr)
+	assert.Equal(t, &HandshakeError{StatusCode: http.StatusTooManyRequests, RetryAfter: 30 * time.Second}, handshakeErr)
+}
@@ -0,0 +1,103 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/bus"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"log/slog"
+)
+
+type CoinbaseWebsocketImpl struct {
+	tickPublisher
+	url      string
+	products []string
+}
+
+func NewCoinbaseWebsocket(
+	url string,
+	products []string,
+	prices bus.Publisher[models.PriceTick],
+) WebSocketFetcher {
+	return &CoinbaseWebsocketImpl{
+		tickPublisher: tickPublisher{
+			prices: prices,
+		},
+		url:      url,
+		products: products,
+	}
+}
+
+// Connect returns the connection to websocket
+func (c *CoinbaseWebsocketImpl) Connect(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := dial(ctx, c.url)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("connected to coinbase websocket")
+
+	return conn, nil
+}
+
+// Fetch subscribes to the ticker channel of the configured products and keeps reading from given connection
+func (c *CoinbaseWebsocketImpl) Fetch(ctx context.Context, conn *websocket.Conn) error {
+	defer conn.Close()
+	defer closeOnDone(ctx, conn)()
+
+	slog.Info("subscribing to coinbase ticker", "products", c.products)
+
+	err := conn.WriteJSON(models.CoinbaseSubscribeRequest{
+		Type:       models.CoinbaseTypeSubscribe,
+		ProductIDs: c.products,
+		Channels:   []string{models.CoinbaseChannelTicker},
+	})
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read: %w", err)
+		}
+
+		err = c.handleMessage(message)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleMessage parses a message from the feed and dispatches it by type
+func (c *CoinbaseWebsocketImpl) handleMessage(message []byte) error {
+	var msg models.CoinbaseMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	switch msg.Type {
+	case models.CoinbaseTypeTicker:
+		datum, err := models.NewPriceDatumFromCoinbaseTicker(msg)
+		if err != nil {
+			slog.Error("create price datum from coinbase", "err", err)
+			return nil
+		}
+
+		// Publish the latest price
+		c.publishPrices([]models.PriceDatum{datum})
+
+	case models.CoinbaseTypeSubscriptions:
+		slog.Info("coinbase subscription acknowledged")
+
+	case models.CoinbaseTypeError:
+		return fmt.Errorf("coinbase: %s: %s", msg.Message, msg.Reason)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoinbaseWebsocketImpl_handleMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantErr     bool
+		wantPublish *models.PriceDatum
+	}{
+		{
+			name:    "ticker",
+			message: `{"type":"ticker","sequence":1,"product_id":"BTC-USDT","price":"64000.5","time":"2025-03-01T10:00:00.000000Z"}`,
+			wantPublish: &models.PriceDatum{
+				Timestamp: time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC),
+				Symbol:    "BTC",
+				Currency:  "USDT",
+				Source:    "coinbase",
+				Price:     decimal.RequireFromString("64000.5"),
+			},
+		},
+		{
+			name:    "subscriptions",
+			message: `{"type":"subscriptions","channels":[{"name":"ticker","product_ids":["BTC-USDT"]}]}`,
+		},
+		{
+			name:    "error",
+			message: `{"type":"error","message":"Failed to subscribe","reason":"BTC-XYZ is not a valid product"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			message: `{invalid`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks := make(publishedTicks[models.PriceTick], 1)
+			c := NewCoinbaseWebsocket("", nil, ticks).(*CoinbaseWebsocketImpl)
+
+			err := c.handleMessage([]byte(tt.message))
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantPublish == nil {
+				assert.Empty(t, ticks)
+				return
+			}
+
+			data := (<-ticks).Prices
+			assert.Equal(t, []models.PriceDatum{*tt.wantPublish}, data)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/bus"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"time"
+)
+
+type KrakenWebsocketImpl struct {
+	tickPublisher
+	url   string
+	pairs []string
+}
+
+func NewKrakenWebsocket(
+	url string,
+	pairs []string,
+	prices bus.Publisher[models.PriceTick],
+) WebSocketFetcher {
+	return &KrakenWebsocketImpl{
+		tickPublisher: tickPublisher{
+			prices: prices,
+		},
+		url:   url,
+		pairs: pairs,
+	}
+}
+
+// Connect returns the connection to websocket
+func (k *KrakenWebsocketImpl) Connect(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := dial(ctx, k.url)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("connected to kraken websocket")
+
+	return conn, nil
+}
+
+// Fetch subscribes to the ticker channel of the configured pairs and keeps reading from given connection
+func (k *KrakenWebsocketImpl) Fetch(ctx context.Context, conn *websocket.Conn) error {
+	defer conn.Close()
+	defer closeOnDone(ctx, conn)()
+
+	slog.Info("subscribing to kraken ticker", "pairs", k.pairs)
+
+	err := conn.WriteJSON(models.KrakenSubscribeRequest{
+		Method: models.KrakenMethodSubscribe,
+		Params: models.KrakenSubscribeParams{
+			Channel: models.KrakenChannelTicker,
+			Symbol:  k.pairs,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read: %w", err)
+		}
+
+		err = k.handleMessage(message)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handleMessage parses a message from the feed, heartbeats and status updates are ignored
+func (k *KrakenWebsocketImpl) handleMessage(message []byte) error {
+	var msg models.KrakenMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	if msg.Method != "" {
+		if msg.Success != nil && !*msg.Success {
+			return fmt.Errorf("kraken %s: %s", msg.Method, msg.Error)
+		}
+
+		slog.Info("kraken request acknowledged", "method", msg.Method)
+		return nil
+	}
+
+	if msg.Channel != models.KrakenChannelTicker {
+		return nil
+	}
+
+	// Kraken tickers have no timestamp, use the time they are received
+	timestamp := time.Now().UTC()
+
+	var bulk []models.PriceDatum
+	for _, ticker := range msg.Data {
+		datum, err := models.NewPriceDatumFromKrakenTicker(ticker, timestamp)
+		if err != nil {
+			slog.Error("create price datum from kraken", "err", err)
+			continue
+		}
+
+		bulk = append(bulk, datum)
+	}
+
+	// Publish the latest price
+	k.publishPrices(bulk)
+
+	return nil
+}
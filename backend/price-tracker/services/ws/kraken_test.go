@@ -0,0 +1,59 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKrakenWebsocketImpl_handleMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantErr     bool
+		wantPublish []string
+	}{
+		{
+			name:        "ticker snapshot",
+			message:     `{"channel":"ticker","type":"snapshot","data":[{"symbol":"BTC/USDT","bid":63999.9,"ask":64000.1,"last":64000.0},{"symbol":"ETH/USDT","last":2500.0}]}`,
+			wantPublish: []string{"BTC", "ETH"},
+		},
+		{
+			name:    "heartbeat",
+			message: `{"channel":"heartbeat"}`,
+		},
+		{
+			name:    "subscribe acknowledged",
+			message: `{"method":"subscribe","result":{"channel":"ticker","symbol":"BTC/USDT"},"success":true}`,
+		},
+		{
+			name:    "subscribe failed",
+			message: `{"method":"subscribe","error":"Currency pair not supported BTC/XYZ","success":false}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks := make(publishedTicks[models.PriceTick], 1)
+			k := NewKrakenWebsocket("", nil, ticks).(*KrakenWebsocketImpl)
+
+			err := k.handleMessage([]byte(tt.message))
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantPublish == nil {
+				assert.Empty(t, ticks)
+				return
+			}
+
+			data := (<-ticks).Prices
+			var symbols []string
+			for _, datum := range data {
+				assert.Equal(t, "kraken", datum.Source)
+				assert.Equal(t, "USDT", datum.Currency)
+				symbols = append(symbols, datum.Symbol)
+			}
+			assert.Equal(t, tt.wantPublish, symbols)
+		})
+	}
+}
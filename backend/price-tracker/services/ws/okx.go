@@ -0,0 +1,163 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/bus"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// okxPingInterval keeps the connection alive, OKX closes it after 30 seconds without messages
+const okxPingInterval = 20 * time.Second
+
+type OkxWebsocketImpl struct {
+	tickPublisher
+	url         string
+	instruments []string
+	writeMu     sync.Mutex
+}
+
+func NewOkxWebsocket(
+	url string,
+	instruments []string,
+	prices bus.Publisher[models.PriceTick],
+) WebSocketFetcher {
+	return &OkxWebsocketImpl{
+		tickPublisher: tickPublisher{
+			prices: prices,
+		},
+		url:         url,
+		instruments: instruments,
+	}
+}
+
+// Connect returns the connection to websocket
+func (o *OkxWebsocketImpl) Connect(ctx context.Context) (*websocket.Conn, error) {
+	conn, err := dial(ctx, o.url)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("connected to okx websocket")
+
+	return conn, nil
+}
+
+// Fetch subscribes to the tickers channel of the configured instruments and keeps reading from given connection
+func (o *OkxWebsocketImpl) Fetch(ctx context.Context, conn *websocket.Conn) error {
+	defer conn.Close()
+	defer closeOnDone(ctx, conn)()
+
+	slog.Info("subscribing to okx tickers", "instruments", o.instruments)
+
+	args := make([]models.OkxArg, 0, len(o.instruments))
+	for _, instrument := range o.instruments {
+		args = append(args, models.OkxArg{
+			Channel: models.OkxChannelTickers,
+			InstID:  instrument,
+		})
+	}
+
+	err := o.write(conn, websocket.TextMessage, models.OkxSubscribeRequest{
+		Op:   models.OkxOpSubscribe,
+		Args: args,
+	})
+	if err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go o.keepAlive(conn, done)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read: %w", err)
+		}
+
+		err = o.handleMessage(message)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// keepAlive sends a ping every okxPingInterval until done is closed
+func (o *OkxWebsocketImpl) keepAlive(conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(okxPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := o.write(conn, websocket.TextMessage, models.OkxPing)
+			if err != nil {
+				slog.Error("okx ping", "err", err)
+				return
+			}
+		}
+	}
+}
+
+// write serializes writes of subscribe requests and pings to the connection
+func (o *OkxWebsocketImpl) write(conn *websocket.Conn, messageType int, v any) error {
+	o.writeMu.Lock()
+	defer o.writeMu.Unlock()
+
+	if text, ok := v.(string); ok {
+		return conn.WriteMessage(messageType, []byte(text))
+	}
+
+	return conn.WriteJSON(v)
+}
+
+// handleMessage parses a message from the feed and dispatches events and pushed tickers
+func (o *OkxWebsocketImpl) handleMessage(message []byte) error {
+	if string(message) == models.OkxPong {
+		return nil
+	}
+
+	var msg models.OkxMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return fmt.Errorf("unmarshal: %w", err)
+	}
+
+	switch msg.Event {
+	case models.OkxEventError:
+		return fmt.Errorf("okx %s: %s", msg.Code, msg.Msg)
+	case models.OkxEventSubscribe:
+		slog.Info("okx subscription acknowledged", "instrument", msg.Arg.InstID)
+		return nil
+	}
+
+	if msg.Arg.Channel != models.OkxChannelTickers {
+		return nil
+	}
+
+	var bulk []models.PriceDatum
+	for _, ticker := range msg.Data {
+		datum, err := models.NewPriceDatumFromOkxTicker(ticker)
+		if err != nil {
+			slog.Error("create price datum from okx", "err", err)
+			continue
+		}
+
+		bulk = append(bulk, datum)
+	}
+
+	// Publish the latest price
+	o.publishPrices(bulk)
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOkxWebsocketImpl_handleMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		message     string
+		wantErr     bool
+		wantPublish *models.PriceDatum
+	}{
+		{
+			name:    "tickers",
+			message: `{"arg":{"channel":"tickers","instId":"ETH-USDT"},"data":[{"instType":"SPOT","instId":"ETH-USDT","last":"2500.25","ts":"1700000000000"}]}`,
+			wantPublish: &models.PriceDatum{
+				Timestamp: time.UnixMilli(1700000000000).UTC(),
+				Symbol:    "ETH",
+				Currency:  "USDT",
+				Source:    "okx",
+				Price:     decimal.RequireFromString("2500.25"),
+			},
+		},
+		{
+			name:    "pong",
+			message: `pong`,
+		},
+		{
+			name:    "subscribe event",
+			message: `{"event":"subscribe","arg":{"channel":"tickers","instId":"ETH-USDT"},"connId":"a4d3ae55"}`,
+		},
+		{
+			name:    "error event",
+			message: `{"event":"error","code":"60012","msg":"Invalid request"}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks := make(publishedTicks[models.PriceTick], 1)
+			o := NewOkxWebsocket("", nil, ticks).(*OkxWebsocketImpl)
+
+			err := o.handleMessage([]byte(tt.message))
+
+			assert.Equal(t, tt.wantErr, err != nil)
+			if tt.wantPublish == nil {
+				assert.Empty(t, ticks)
+				return
+			}
+
+			data := (<-ticks).Prices
+			assert.Equal(t, []models.PriceDatum{*tt.wantPublish}, data)
+		})
+	}
+}
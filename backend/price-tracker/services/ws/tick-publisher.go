@@ -0,0 +1,37 @@
+package ws
+
+import (
+	"backend/price-tracker/models"
+	"backend/price-tracker/services/bus"
+	"time"
+)
+
+// tickPublisher publishes the normalized prices and stats on the event bus, it is shared by all fetchers
+type tickPublisher struct {
+	prices bus.Publisher[models.PriceTick]
+	stats  bus.Publisher[models.MarketStatsTick]
+}
+
+// publishPrices publishes a batch of prices received now
+func (p *tickPublisher) publishPrices(bulk []models.PriceDatum) {
+	if len(bulk) == 0 {
+		return
+	}
+
+	p.prices.Publish(models.PriceTick{
+		Prices:     bulk,
+		ReceivedAt: time.Now().UTC(),
+	})
+}
+
+// publishMarketStats publishes a batch of market stats received now
+func (p *tickPublisher) publishMarketStats(stats []models.MarketStats) {
+	if len(stats) == 0 {
+		return
+	}
+
+	p.stats.Publish(models.MarketStatsTick{
+		Stats:      stats,
+		ReceivedAt: time.Now().UTC(),
+	})
+}
@@ -0,0 +1,8 @@
+package ws
+
+// publishedTicks is a bus.Publisher keeping the published events for the tests to read
+type publishedTicks[T any] chan T
+
+func (p publishedTicks[T]) Publish(event T) {
+	p <- event
+}
@@ -1,10 +1,69 @@
 package ws
 
-import "github.com/gorilla/websocket"
+import (
+	"context"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// closeWait bounds the time the exchange has to answer the close frame sent on shutdown
+const closeWait = 5 * time.Second
 
 type WebSocketFetcher interface {
 	// Connect returns the connection to websocket
-	Connect() (*websocket.Conn, error)
-	// Fetch keeps running every few seconds to retrieve latest price from given connection
-	Fetch(conn *websocket.Conn) error
+	Connect(ctx context.Context) (*websocket.Conn, error)
+	// Fetch keeps reading the latest prices from given connection until it fails,
+	// or closes it cleanly and returns nil once ctx is done
+	Fetch(ctx context.Context, conn *websocket.Conn) error
+}
+
+// HandshakeError is returned by Connect when the exchange answered the websocket upgrade with an HTTP error,
+// such as 429 when rate limited or 418 once banned for ignoring it
+type HandshakeError struct {
+	StatusCode int
+	// RetryAfter is the wait asked by the exchange, zero if it did not tell
+	RetryAfter time.Duration
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("dial: websocket: bad handshake, http status %d", e.StatusCode)
+}
+
+// dial connects to the websocket at url, an upgrade refused by the exchange is returned as a *HandshakeError
+func dial(ctx context.Context, url string) (*websocket.Conn, error) {
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			seconds, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+			return nil, &HandshakeError{
+				StatusCode: resp.StatusCode,
+				RetryAfter: time.Duration(max(seconds, 0)) * time.Second,
+			}
+		}
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+
+	return conn, nil
+}
+
+// closeOnDone sends a close frame on conn once ctx is done, the read loop of Fetch then ends
+// when the exchange answers it or after closeWait. The returned func stops watching ctx
+func closeOnDone(ctx context.Context, conn *websocket.Conn) func() {
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+		case <-ctx.Done():
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(closeWait))
+			conn.SetReadDeadline(time.Now().Add(closeWait))
+		}
+	}()
+
+	return func() {
+		close(stop)
+	}
 }
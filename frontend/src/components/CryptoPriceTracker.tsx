@@ -29,7 +29,7 @@ const CryptoPriceTracker = () => {
   const [symbol, setSymbol] = useState<string>("BTC");
   const [suggestions, setSuggestions] = useState<string[]>([]);
   const [showDropdown, setShowDropdown] = useState(false);
-  const { currentPrice, priceHistory, isLoading, error } = usePriceData(symbol);
+  const { currentPrice, stats, priceHistory, isLoading, error } = usePriceData(symbol);
 
   const { colorMode } = useColorMode();
   const bgColor = colorMode === "light" ? "white" : "gray.800";
@@ -160,7 +160,7 @@ const CryptoPriceTracker = () => {
         <>
           {/* Current Price */}
           {currentPrice ? (
-            <CurrentPrice price={currentPrice} symbol={symbol} />
+            <CurrentPrice price={currentPrice} symbol={symbol} stats={stats} />
           ) : (
             !isLoading &&
             !error && (
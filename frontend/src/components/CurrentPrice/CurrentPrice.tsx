@@ -3,16 +3,22 @@ import {
   Stat,
   StatLabel,
   StatHelpText,
+  StatArrow,
+  SimpleGrid,
   Text,
   useColorModeValue,
 } from '@chakra-ui/react'
+import { MarketStats } from '../../types/price'
 
 interface CurrentPriceProps {
   price: number
   symbol: string
+  stats?: MarketStats | null
 }
 
-const CurrentPrice: React.FC<CurrentPriceProps> = ({ price, symbol }) => {
+const formatDecimal = (value: string) => Number(value).toLocaleString()
+
+const CurrentPrice: React.FC<CurrentPriceProps> = ({ price, symbol, stats }) => {
   const statBg = useColorModeValue('blue.50', 'blue.900')
 
   return (
@@ -37,6 +43,22 @@ const CurrentPrice: React.FC<CurrentPriceProps> = ({ price, symbol }) => {
         >
           ${price.toLocaleString()}
         </Text>
+        {stats && (
+          <>
+            <StatHelpText fontSize="md">
+              <StatArrow type={Number(stats.price_change_percent) >= 0 ? 'increase' : 'decrease'} />
+              {stats.price_change_percent}% (24h)
+            </StatHelpText>
+            <SimpleGrid columns={2} spacingX={4} spacingY={1} fontSize="sm" color="gray.600">
+              <Text>Bid: ${formatDecimal(stats.bid_price)}</Text>
+              <Text>Ask: ${formatDecimal(stats.ask_price)}</Text>
+              <Text>24h High: ${formatDecimal(stats.high)}</Text>
+              <Text>24h Low: ${formatDecimal(stats.low)}</Text>
+              <Text>24h Volume: {formatDecimal(stats.volume)} {symbol}</Text>
+              <Text>24h Quote Volume: ${formatDecimal(stats.quote_volume)}</Text>
+            </SimpleGrid>
+          </>
+        )}
         <StatHelpText fontSize="sm">
           Last updated: {new Date().toLocaleTimeString()}
         </StatHelpText>
@@ -40,6 +40,7 @@ fetchCryptocurrencies();
 export const usePriceData = (symbol: string): PriceState => {
   const [state, setState] = useState<PriceState>({
     currentPrice: null,
+    stats: null,
     priceHistory: [],
     isLoading: false,
     error: null,
@@ -63,7 +64,8 @@ export const usePriceData = (symbol: string): PriceState => {
           throw new Error(historyResponse.data.meta.message || "Failed to fetch price history");
         }
 
-        const currentPrice = currentPriceResponse.data.data?.price ?? null;
+        const latestPrice = currentPriceResponse.data.data?.price;
+        const currentPrice = latestPrice != null ? Number(latestPrice) : null;
         const filteredHistory = Array.isArray(historyResponse.data.data)
           ? filterLast24Hours(historyResponse.data.data)
           : [];
@@ -74,6 +76,7 @@ export const usePriceData = (symbol: string): PriceState => {
 
         setState({
           currentPrice,
+          stats: currentPriceResponse.data.data?.stats ?? null,
           priceHistory: sortedHistory,
           isLoading: false,
           error: null,
@@ -100,6 +103,7 @@ export const usePriceData = (symbol: string): PriceState => {
           isLoading: false,
           error: `Error: ${errorMessage}`,
           currentPrice: null,
+          stats: null,
           priceHistory: [],
         }));
 
@@ -107,9 +111,20 @@ export const usePriceData = (symbol: string): PriceState => {
       }
     };
 
-    if (symbol) {
-      fetchData();
+    if (!symbol) {
+      return;
     }
+
+    fetchData();
+
+    // Live prices are pushed by the server, the browser reconnects and resumes the stream on its own
+    const source = new EventSource(`/api/price/stream?symbols=${symbol}&currency=USDT`);
+    source.addEventListener("price", (event) => {
+      const datum: PriceData = JSON.parse((event as MessageEvent).data);
+      setState((prev) => ({ ...prev, currentPrice: Number(datum.price) }));
+    });
+
+    return () => source.close();
   }, [symbol]);
 
   return state;
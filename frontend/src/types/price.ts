@@ -1,8 +1,26 @@
+// Best bid/ask and rolling 24h statistics of a market, decimals are serialized as strings
+export interface MarketStats {
+  timestamp: string
+  bid_price: string
+  bid_quantity: string
+  ask_price: string
+  ask_quantity: string
+  last_quantity: string
+  volume: string
+  quote_volume: string
+  high: string
+  low: string
+  price_change_percent: string
+}
+
 export interface PriceData {
   timestamp: string
   symbol: string
   currency: string
-  price: number
+  // Exact decimal, serialized as a string
+  price: string
+  // Only set on the latest price
+  stats?: MarketStats
 }
 
 export interface ApiResponse<T> {
@@ -15,6 +33,7 @@ export interface ApiResponse<T> {
 
 export interface PriceState {
   currentPrice: number | null
+  stats: MarketStats | null
   priceHistory: PriceData[]
   isLoading: boolean
   error: string | null